@@ -102,6 +102,24 @@ func TestLogWindowsEventConfig(t *testing.T) {
 	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/invalidLogWindowsEventsWithInvalidEventFormatType.json", false, expectedErrorMap3)
 }
 
+func TestLogJournaldConfig(t *testing.T) {
+	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/validLogJournald.json", true, map[string]int{})
+	expectedErrorMap := map[string]int{}
+	expectedErrorMap["enum"] = 1
+	expectedErrorMap["pattern"] = 1
+	expectedErrorMap["required"] = 1
+	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/invalidLogJournald.json", false, expectedErrorMap)
+}
+
+func TestLogSyslogConfig(t *testing.T) {
+	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/validLogSyslog.json", true, map[string]int{})
+	expectedErrorMap := map[string]int{}
+	expectedErrorMap["enum"] = 1
+	expectedErrorMap["pattern"] = 1
+	expectedErrorMap["required"] = 1
+	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/invalidLogSyslog.json", false, expectedErrorMap)
+}
+
 func TestMetricsConfig(t *testing.T) {
 	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/validLinuxMetrics.json", true, map[string]int{})
 	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/validWindowsMetrics.json", true, map[string]int{})
@@ -171,6 +189,23 @@ func TestInvalidLogFilterConfig(t *testing.T) {
 	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/invalidLogFilesWithFilters.json", false, expectedErrorMap)
 }
 
+func TestInvalidLogRateLimitConfig(t *testing.T) {
+	expectedErrorMap := map[string]int{
+		"enum":          1,
+		"number_any_of": 1,
+		"required":      1,
+	}
+	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/invalidLogFilesWithRateLimit.json", false, expectedErrorMap)
+}
+
+func TestInvalidLogMetricFiltersConfig(t *testing.T) {
+	expectedErrorMap := map[string]int{
+		"enum":     1,
+		"required": 1,
+	}
+	checkIfSchemaValidateAsExpected(t, "../../translator/config/sampleSchema/invalidLogFilesWithMetricFilters.json", false, expectedErrorMap)
+}
+
 // Validate all sampleConfig files schema
 func TestSampleConfigSchema(t *testing.T) {
 	if files, err := os.ReadDir("../../translator/tocwconfig/sampleConfig/"); err == nil {
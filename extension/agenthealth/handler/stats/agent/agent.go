@@ -29,6 +29,7 @@ type Stats struct {
 	RunningInContainer        *int     `json:"ric,omitempty"`
 	RegionType                *string  `json:"rt,omitempty"`
 	Mode                      *string  `json:"m,omitempty"`
+	RateLimitDroppedEvents    *int     `json:"rld,omitempty"`
 }
 
 // Merge the other Stats into the current. If the field is not nil,
@@ -76,6 +77,9 @@ func (s *Stats) Merge(other Stats) {
 	if other.Mode != nil {
 		s.Mode = other.Mode
 	}
+	if other.RateLimitDroppedEvents != nil {
+		s.RateLimitDroppedEvents = other.RateLimitDroppedEvents
+	}
 }
 
 func (s *Stats) Marshal() (string, error) {
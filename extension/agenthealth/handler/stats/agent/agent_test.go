@@ -35,6 +35,7 @@ func TestMerge(t *testing.T) {
 		RunningInContainer:        aws.Int(0),
 		RegionType:                aws.String("RegionType"),
 		Mode:                      aws.String("Mode"),
+		RateLimitDroppedEvents:    aws.Int(10),
 	})
 	assert.EqualValues(t, 1.5, *stats.CpuPercent)
 	assert.EqualValues(t, 133, *stats.MemoryBytes)
@@ -50,6 +51,7 @@ func TestMerge(t *testing.T) {
 	assert.EqualValues(t, 0, *stats.RunningInContainer)
 	assert.EqualValues(t, "RegionType", *stats.RegionType)
 	assert.EqualValues(t, "Mode", *stats.Mode)
+	assert.EqualValues(t, 10, *stats.RateLimitDroppedEvents)
 }
 
 func TestMarshal(t *testing.T) {
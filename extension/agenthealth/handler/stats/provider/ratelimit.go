@@ -0,0 +1,57 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+
+	"github.com/aws/amazon-cloudwatch-agent/extension/agenthealth/handler/stats/agent"
+)
+
+const (
+	putLogEventsOperation = "PutLogEvents"
+)
+
+var (
+	rateLimitSingleton *rateLimitStats
+	rateLimitOnce      sync.Once
+)
+
+// rateLimitStats counts the log events dropped by rate limits until they are
+// reported with the next PutLogEvents request.
+type rateLimitStats struct {
+	dropped atomic.Int64
+}
+
+var _ agent.StatsProvider = (*rateLimitStats)(nil)
+
+func (p *rateLimitStats) Stats(operation string) agent.Stats {
+	if operation != putLogEventsOperation {
+		return agent.Stats{}
+	}
+	dropped := p.dropped.Swap(0)
+	if dropped == 0 {
+		return agent.Stats{}
+	}
+	return agent.Stats{RateLimitDroppedEvents: aws.Int(int(dropped))}
+}
+
+func getRateLimitStats() *rateLimitStats {
+	rateLimitOnce.Do(func() {
+		rateLimitSingleton = &rateLimitStats{}
+	})
+	return rateLimitSingleton
+}
+
+func GetRateLimitStats() agent.StatsProvider {
+	return getRateLimitStats()
+}
+
+// AddRateLimitDroppedEvents adds to the number of log events dropped by rate limits.
+func AddRateLimitDroppedEvents(count int) {
+	getRateLimitStats().dropped.Add(int64(count))
+}
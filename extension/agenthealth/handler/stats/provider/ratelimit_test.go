@@ -0,0 +1,23 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitStats(t *testing.T) {
+	s := GetRateLimitStats()
+	assert.Nil(t, s.Stats(putLogEventsOperation).RateLimitDroppedEvents)
+	AddRateLimitDroppedEvents(2)
+	AddRateLimitDroppedEvents(3)
+	assert.Nil(t, s.Stats("PutMetricData").RateLimitDroppedEvents)
+	got := s.Stats(putLogEventsOperation).RateLimitDroppedEvents
+	assert.NotNil(t, got)
+	assert.Equal(t, 5, *got)
+	// the count is reset once reported
+	assert.Nil(t, s.Stats(putLogEventsOperation).RateLimitDroppedEvents)
+}
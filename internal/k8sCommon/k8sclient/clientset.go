@@ -39,6 +39,8 @@ type K8sClient struct {
 	Pod  PodClient
 	Node NodeClient
 
+	LocalPod LocalPodClient
+
 	ReplicaSet ReplicaSetClient
 }
 
@@ -67,6 +69,7 @@ func (c *K8sClient) init() {
 	c.Ep = new(epClient)
 	c.Pod = new(podClient)
 	c.Node = new(nodeClient)
+	c.LocalPod = new(localPodClient)
 	c.ReplicaSet = new(replicaSetClient)
 	c.inited = true
 }
@@ -86,6 +89,9 @@ func (c *K8sClient) shutdown() {
 	if c.Node != nil {
 		c.Node.Shutdown()
 	}
+	if c.LocalPod != nil {
+		c.LocalPod.Shutdown()
+	}
 	if c.ReplicaSet != nil {
 		c.ReplicaSet.Shutdown()
 	}
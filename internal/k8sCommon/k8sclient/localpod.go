@@ -0,0 +1,119 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package k8sclient
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/aws/amazon-cloudwatch-agent/cfg/envconfig"
+)
+
+// PodMetadata is the metadata of a pod enriching the logs of its containers.
+type PodMetadata struct {
+	Namespace string
+	Name      string
+	UID       string
+	Labels    map[string]string
+}
+
+// LocalPodClient watches the pods of the node the agent runs on, found from the HOST_NAME
+// environment variable, so that each agent of a daemonset only receives the updates of its own
+// pods rather than of all the pods of the cluster.
+type LocalPodClient interface {
+	// PodMetadata returns the metadata of the pod, false if it is not known. The pods are not
+	// known until they are listed, which happens in the background after Init.
+	PodMetadata(namespace, name string) (PodMetadata, bool)
+
+	Init()
+	Shutdown()
+}
+
+type localPodClient struct {
+	sync.Mutex
+
+	stopChan chan struct{}
+	store    cache.Store
+
+	inited bool
+}
+
+func (c *localPodClient) PodMetadata(namespace, name string) (PodMetadata, bool) {
+	c.Init()
+	obj, ok, err := c.store.GetByKey(namespace + "/" + name)
+	if err != nil || !ok {
+		return PodMetadata{}, false
+	}
+	pod := obj.(*v1.Pod)
+	return PodMetadata{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		UID:       string(pod.UID),
+		Labels:    pod.Labels,
+	}, true
+}
+
+// Init starts the watch of the pods without waiting for them to be listed.
+func (c *localPodClient) Init() {
+	c.Lock()
+	defer c.Unlock()
+	if c.inited {
+		return
+	}
+
+	c.stopChan = make(chan struct{})
+
+	c.store = cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	nodeName := os.Getenv(envconfig.HostName)
+	if nodeName == "" {
+		log.Printf("W! %s is not set, watching the pods of all the nodes", envconfig.HostName)
+	}
+	lw := createLocalPodListWatch(Get().ClientSet, nodeName)
+	reflector := cache.NewReflector(lw, &v1.Pod{}, c.store, 0)
+	go reflector.Run(c.stopChan)
+
+	c.inited = true
+}
+
+func (c *localPodClient) Shutdown() {
+	c.Lock()
+	defer c.Unlock()
+	if !c.inited {
+		return
+	}
+
+	close(c.stopChan)
+
+	c.inited = false
+}
+
+func createLocalPodListWatch(client kubernetes.Interface, nodeName string) cache.ListerWatcher {
+	ctx := context.Background()
+	var selector string
+	if nodeName != "" {
+		selector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+	}
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.ResourceVersion = ""
+			opts.FieldSelector = selector
+			return client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = selector
+			return client.CoreV1().Pods(metav1.NamespaceAll).Watch(ctx, opts)
+		},
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package k8sclient
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestLocalPodClient_PodMetadata(t *testing.T) {
+	client := &localPodClient{
+		stopChan: make(chan struct{}),
+		store:    cache.NewStore(cache.MetaNamespaceKeyFunc),
+		inited:   true, //make it true to avoid further initialization invocation.
+	}
+	defer close(client.stopChan)
+
+	client.store.Replace([]interface{}{
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				UID:       "11d078c2-6fed-49c3-83a8-b94915a6451f",
+				Name:      "guestbook-qbdv8",
+				Namespace: "default",
+				Labels:    map[string]string{"app": "guestbook"},
+			},
+		},
+	}, "")
+
+	pod, ok := client.PodMetadata("default", "guestbook-qbdv8")
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, pod, PodMetadata{
+		Namespace: "default",
+		Name:      "guestbook-qbdv8",
+		UID:       "11d078c2-6fed-49c3-83a8-b94915a6451f",
+		Labels:    map[string]string{"app": "guestbook"},
+	})
+	_, ok = client.PodMetadata("kube-system", "guestbook-qbdv8")
+	assert.Assert(t, !ok)
+}
+
+func TestLocalPodListWatch(t *testing.T) {
+	clientSet := fake.NewSimpleClientset()
+	var selectors []string
+	clientSet.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selectors = append(selectors, action.(k8stesting.ListAction).GetListRestrictions().Fields.String())
+		return true, &v1.PodList{}, nil
+	})
+
+	_, err := createLocalPodListWatch(clientSet, "ip-10-0-0-1.ec2.internal").List(metav1.ListOptions{})
+	assert.NilError(t, err)
+	_, err = createLocalPodListWatch(clientSet, "").List(metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, selectors, []string{"spec.nodeName=ip-10-0-0-1.ec2.internal", ""})
+}
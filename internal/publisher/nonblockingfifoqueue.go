@@ -13,6 +13,7 @@ import (
 type NonBlockingFifoQueue struct {
 	queue   *list.List
 	maxSize int
+	onDrop  func(interface{})
 	sync.Mutex
 }
 
@@ -26,6 +27,14 @@ func NewNonBlockingFifoQueue(size int) *NonBlockingFifoQueue {
 	}
 }
 
+// NewNonBlockingFifoQueueWithDropFn returns a queue calling onDrop with the values dropped from the
+// front, so that their owner can release them.
+func NewNonBlockingFifoQueueWithDropFn(size int, onDrop func(interface{})) *NonBlockingFifoQueue {
+	q := NewNonBlockingFifoQueue(size)
+	q.onDrop = onDrop
+	return q
+}
+
 func (u *NonBlockingFifoQueue) Dequeue() (interface{}, bool) {
 	u.Lock()
 	defer u.Unlock()
@@ -39,11 +48,15 @@ func (u *NonBlockingFifoQueue) Dequeue() (interface{}, bool) {
 
 func (u *NonBlockingFifoQueue) Enqueue(value interface{}) {
 	u.Lock()
-	defer u.Unlock()
-
+	var dropped interface{}
 	if u.queue.Len() == u.maxSize {
 		log.Printf("W! message is dropped due to nonblocking fifo queue is full")
-		u.queue.Remove(u.queue.Front())
+		dropped = u.queue.Remove(u.queue.Front())
 	}
 	u.queue.PushBack(value)
+	u.Unlock()
+
+	if dropped != nil && u.onDrop != nil {
+		u.onDrop(dropped)
+	}
 }
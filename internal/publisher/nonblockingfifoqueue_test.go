@@ -36,3 +36,20 @@ func TestNonBlockingFifoQueue(t *testing.T) {
 	assert.Equal(t, nil, v)
 	assert.Equal(t, false, ok)
 }
+
+func TestNonBlockingFifoQueueWithDropFn(t *testing.T) {
+	var dropped []interface{}
+	queue := NewNonBlockingFifoQueueWithDropFn(2, func(v interface{}) {
+		dropped = append(dropped, v)
+	})
+
+	queue.Enqueue(1)
+	queue.Enqueue(2)
+	assert.Empty(t, dropped)
+	queue.Enqueue(3)
+	queue.Enqueue(4)
+	assert.Equal(t, []interface{}{1, 2}, dropped)
+	v, ok := queue.Dequeue()
+	assert.Equal(t, 3, v)
+	assert.Equal(t, true, ok)
+}
@@ -0,0 +1,121 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package acktracker
+
+import (
+	"sync"
+)
+
+// DefaultMaxPending is the number of events waiting for their acknowledgement, or an earlier one,
+// after which the source waits for the oldest to be acknowledged.
+const DefaultMaxPending = 100000
+
+// Tracker tracks the acknowledgements of the events published by a source, each event having a
+// position in the source such as a file offset or a journal cursor. The position committed is the
+// position of the last event for which all the events published before were acknowledged too, so
+// that the events acknowledged out of order, by concurrent requests or retries, never move the
+// saved position past an event which was not. After a crash, the events which were not
+// acknowledged are read again, along with the acknowledged events following them.
+//
+// Once MaxPending events are waiting, Track waits for the oldest to be acknowledged, so that the
+// source is held back rather than the position being moved past an event which was not. The zero
+// value is a tracker with DefaultMaxPending.
+type Tracker[T any] struct {
+	MaxPending int
+
+	mu sync.Mutex
+	// pending are the events published and not committed yet, oldest first, next being the id of
+	// the first one.
+	pending []pendingEvent[T]
+	next    uint64
+	// room is closed once there is room for the events waiting in Track.
+	room chan struct{}
+}
+
+type pendingEvent[T any] struct {
+	position T
+	acked    bool
+}
+
+// Track registers the event published at the position and returns its id. It waits for the oldest
+// event to be acknowledged while MaxPending events are waiting, and returns false without
+// registering the event if done is closed first.
+func (a *Tracker[T]) Track(position T, done <-chan struct{}) (uint64, bool) {
+	a.mu.Lock()
+	for a.full() {
+		if a.room == nil {
+			a.room = make(chan struct{})
+		}
+		room := a.room
+		a.mu.Unlock()
+		select {
+		case <-room:
+		case <-done:
+			return 0, false
+		}
+		a.mu.Lock()
+	}
+	defer a.mu.Unlock()
+	a.pending = append(a.pending, pendingEvent[T]{position: position})
+	return a.next + uint64(len(a.pending)-1), true
+}
+
+// Full returns whether Track waits for the oldest event to be acknowledged.
+func (a *Tracker[T]) Full() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.full()
+}
+
+func (a *Tracker[T]) full() bool {
+	maxPending := a.MaxPending
+	if maxPending <= 0 {
+		maxPending = DefaultMaxPending
+	}
+	return len(a.pending) >= maxPending
+}
+
+// Ack acknowledges the event and returns the position to commit, false if the acknowledgement does
+// not move the committed position. The events acknowledged more than once are ignored.
+func (a *Tracker[T]) Ack(id uint64) (T, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if id < a.next || id-a.next >= uint64(len(a.pending)) {
+		var zero T
+		return zero, false
+	}
+	a.pending[id-a.next].acked = true
+	return a.commit()
+}
+
+// commit removes the acknowledged events at the front of the pending events, a.mu being held.
+func (a *Tracker[T]) commit() (T, bool) {
+	n := 0
+	for n < len(a.pending) && a.pending[n].acked {
+		n++
+	}
+	if n == 0 {
+		var zero T
+		return zero, false
+	}
+	position := a.pending[n-1].position
+	var zero pendingEvent[T]
+	for i := 0; i < n; i++ {
+		a.pending[i] = zero
+	}
+	a.pending = a.pending[n:]
+	a.next += uint64(n)
+	if a.room != nil && !a.full() {
+		close(a.room)
+		a.room = nil
+	}
+	return position, true
+}
+
+// Unacked returns the number of events waiting for their acknowledgement or an earlier one.
+func (a *Tracker[T]) Unacked() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.pending)
+}
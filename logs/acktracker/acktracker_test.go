@@ -0,0 +1,127 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package acktracker
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAckTracker(t *testing.T) {
+	var a Tracker[int64]
+	var ids []uint64
+	for i := 1; i <= 5; i++ {
+		id, _ := a.Track(int64(i*10), nil)
+		ids = append(ids, id)
+	}
+
+	_, ok := a.Ack(ids[2])
+	assert.False(t, ok, "an event acknowledged before the earlier ones is not committed")
+	offset, ok := a.Ack(ids[0])
+	assert.True(t, ok)
+	assert.EqualValues(t, 10, offset)
+	offset, ok = a.Ack(ids[1])
+	assert.True(t, ok)
+	assert.EqualValues(t, 30, offset, "the events acknowledged before are committed along")
+	_, ok = a.Ack(ids[1])
+	assert.False(t, ok, "an event acknowledged twice is ignored")
+	_, ok = a.Ack(ids[4])
+	assert.False(t, ok)
+	assert.Equal(t, 2, a.Unacked())
+	offset, ok = a.Ack(ids[3])
+	assert.True(t, ok)
+	assert.EqualValues(t, 50, offset)
+	assert.Equal(t, 0, a.Unacked())
+
+	// the positions are committed in the order the events are published, not in their order
+	id, _ := a.Track(5, nil)
+	offset, ok = a.Ack(id)
+	assert.True(t, ok)
+	assert.EqualValues(t, 5, offset)
+}
+
+func TestAckTrackerMaxPending(t *testing.T) {
+	a := Tracker[int64]{MaxPending: 2}
+	first, ok := a.Track(10, nil)
+	require.True(t, ok)
+	second, _ := a.Track(20, nil)
+	assert.True(t, a.Full())
+	_, ok = a.Ack(second)
+	assert.False(t, ok, "the event is not committed while the first one is pending")
+
+	// the source is held back until the oldest event is acknowledged, never moving past it
+	stop := make(chan struct{})
+	close(stop)
+	_, ok = a.Track(30, stop)
+	assert.False(t, ok)
+	assert.Equal(t, 2, a.Unacked())
+
+	tracked := make(chan uint64)
+	go func() {
+		id, _ := a.Track(30, nil)
+		tracked <- id
+	}()
+	select {
+	case <-tracked:
+		t.Fatal("the event is tracked while the oldest one is pending")
+	case <-time.After(50 * time.Millisecond):
+	}
+	committed, ok := a.Ack(first)
+	assert.True(t, ok)
+	assert.EqualValues(t, 20, committed)
+	third := <-tracked
+	committed, ok = a.Ack(third)
+	assert.True(t, ok)
+	assert.EqualValues(t, 30, committed)
+}
+
+// TestAckTrackerFaultInjection acknowledges the events in random orders, some events never being
+// acknowledged as if the agent crashed while they were sent, and checks that the committed offset
+// never moves past an event which was not acknowledged while it moves past all those which were.
+func TestAckTrackerFaultInjection(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for run := 0; run < 200; run++ {
+		var a Tracker[int64]
+		n := 1 + r.Intn(200)
+		ids := make([]uint64, n)
+		for i := range ids {
+			ids[i], _ = a.Track(int64(i+1), nil)
+		}
+		acked := make([]bool, n)
+		var order []int
+		for _, i := range r.Perm(n) {
+			// the lost events are in flight when the agent crashes
+			if r.Intn(10) == 0 {
+				continue
+			}
+			order = append(order, i)
+			// the duplicate acknowledgements are retries of the same event
+			if r.Intn(10) == 0 {
+				order = append(order, i)
+			}
+		}
+
+		var committed int64
+		for _, i := range order {
+			acked[i] = true
+			if offset, ok := a.Ack(ids[i]); ok {
+				require.Greater(t, offset, committed, "the committed offset only moves forward")
+				committed = offset
+			}
+			for j := 0; j < int(committed); j++ {
+				require.True(t, acked[j], "event %d was committed without being acknowledged in run %d", j, run)
+			}
+		}
+		contiguous := 0
+		for contiguous < n && acked[contiguous] {
+			contiguous++
+		}
+		require.EqualValues(t, contiguous, committed, "the acknowledged events are all committed in run %d", run)
+		require.Equal(t, n-contiguous, a.Unacked())
+	}
+}
@@ -29,6 +29,15 @@ type LogEvent interface {
 	Done()
 }
 
+// A RoutedLogEvent is a LogEvent published to its own log group and stream, for the sources
+// whose names have placeholders evaluated for each event. The names of the source are used
+// when they are empty.
+type RoutedLogEvent interface {
+	LogEvent
+	Group() string
+	Stream() string
+}
+
 // A LogSrc is a single source where log events are generated
 // e.g. a single log file
 type LogSrc interface {
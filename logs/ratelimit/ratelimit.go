@@ -0,0 +1,312 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/aws/amazon-cloudwatch-agent/extension/agenthealth/handler/stats/provider"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+const (
+	// PolicyBlock waits until the event is within the limit, slowing down the source.
+	PolicyBlock = "block"
+	// PolicyDropNewest drops the events over the limit.
+	PolicyDropNewest = "drop_newest"
+	// PolicyDropOldest queues the events over the limit and drops the oldest queued event once
+	// the queue is full.
+	PolicyDropOldest = "drop_oldest"
+	// PolicySample keeps one of every SampleRatio events over the limit and drops the others.
+	PolicySample = "sample"
+
+	defaultBufferSize  = 1000
+	defaultSampleRatio = 10
+)
+
+// closeTimeout bounds the time a drop_oldest publisher takes to pass its queued events on Close.
+var closeTimeout = 5 * time.Second
+
+// Config is a token bucket limit on the events and bytes per second. A zero limit is not enforced.
+type Config struct {
+	MaxEventsPerSecond float64 `toml:"max_events_per_second"`
+	MaxKBPerSecond     float64 `toml:"max_kb_per_second"`
+	OverflowPolicy     string  `toml:"overflow_policy"`
+	// BufferSize is the number of events queued by the drop_oldest policy.
+	BufferSize int `toml:"buffer_size"`
+	// SampleRatio is the ratio of events over the limit dropped for each event kept by the sample policy.
+	SampleRatio int `toml:"sample_ratio"`
+}
+
+// Init validates the config and sets the defaults of the unset fields.
+func (c *Config) Init() error {
+	if c.MaxEventsPerSecond < 0 || c.MaxKBPerSecond < 0 {
+		return fmt.Errorf("rate limit must not be negative, got %v events and %v KB per second", c.MaxEventsPerSecond, c.MaxKBPerSecond)
+	}
+	switch c.OverflowPolicy {
+	case "":
+		c.OverflowPolicy = PolicyBlock
+	case PolicyBlock, PolicyDropNewest, PolicyDropOldest, PolicySample:
+	default:
+		return fmt.Errorf("unsupported overflow policy %q", c.OverflowPolicy)
+	}
+	if c.BufferSize < 0 || c.SampleRatio < 0 {
+		return fmt.Errorf("buffer size and sample ratio must not be negative, got %v and %v", c.BufferSize, c.SampleRatio)
+	}
+	if c.BufferSize == 0 {
+		c.BufferSize = defaultBufferSize
+	}
+	if c.SampleRatio == 0 {
+		c.SampleRatio = defaultSampleRatio
+	}
+	return nil
+}
+
+// Limiter is a rate limit shared by all the sources of a file config or log group. The events
+// are passed through a Publisher, which applies the overflow policy.
+type Limiter struct {
+	cfg             Config
+	events, bytes   *rate.Limiter
+	droppedStatsKey []string
+	overflowed      atomic.Int64
+}
+
+// NewLimiter creates a limiter from an initialized config. The events dropped by the limiter are
+// counted in the profiler under droppedStatsKey and reported in the agent health stats.
+func NewLimiter(cfg Config, droppedStatsKey []string) *Limiter {
+	return &Limiter{
+		cfg:             cfg,
+		events:          newTokenBucket(cfg.MaxEventsPerSecond),
+		bytes:           newTokenBucket(cfg.MaxKBPerSecond * 1024),
+		droppedStatsKey: droppedStatsKey,
+	}
+}
+
+// newTokenBucket allows up to a second worth of tokens to be used at once.
+func newTokenBucket(perSecond float64) *rate.Limiter {
+	if perSecond == 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), int(math.Max(1, math.Ceil(perSecond))))
+}
+
+// allow takes the tokens of the event if they are available.
+func (l *Limiter) allow(e logs.LogEvent) bool {
+	now := time.Now()
+	events := l.events.ReserveN(now, 1)
+	bytes := l.bytes.ReserveN(now, l.size(e))
+	if events.DelayFrom(now) == 0 && bytes.DelayFrom(now) == 0 {
+		return true
+	}
+	events.CancelAt(now)
+	bytes.CancelAt(now)
+	return false
+}
+
+// wait takes the tokens of the event, waiting until they are available. It returns false without
+// taking the tokens if done or abort is closed first.
+func (l *Limiter) wait(e logs.LogEvent, done, abort <-chan struct{}) bool {
+	now := time.Now()
+	events := l.events.ReserveN(now, 1)
+	bytes := l.bytes.ReserveN(now, l.size(e))
+	delay := max(events.DelayFrom(now), bytes.DelayFrom(now))
+	if delay == 0 {
+		return true
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+	case <-abort:
+	}
+	events.Cancel()
+	bytes.Cancel()
+	return false
+}
+
+// size is the number of byte tokens taken by the event, which can't exceed the bucket size.
+func (l *Limiter) size(e logs.LogEvent) int {
+	if l.bytes.Limit() == rate.Inf {
+		return 0
+	}
+	return min(len(e.Message()), l.bytes.Burst())
+}
+
+// sample returns whether the event over the limit is kept by the sample policy.
+func (l *Limiter) sample() bool {
+	return l.overflowed.Add(1)%int64(l.cfg.SampleRatio) == 0
+}
+
+// drop marks the event done so that its source moves past it.
+func (l *Limiter) drop(e logs.LogEvent) {
+	e.Done()
+	profiler.Profiler.AddStats(l.droppedStatsKey, 1)
+	provider.AddRateLimitDroppedEvents(1)
+}
+
+// NewPublisher creates the publisher of a source, passing the events within the limit to the
+// output. Waiting for the limit is abandoned once done is closed.
+func (l *Limiter) NewPublisher(output func(logs.LogEvent), done <-chan struct{}) *Publisher {
+	p := &Publisher{
+		limiter: l,
+		output:  output,
+		done:    done,
+	}
+	if l.cfg.OverflowPolicy == PolicyDropOldest {
+		p.notify = make(chan struct{}, 1)
+		p.abort = make(chan struct{})
+		p.stopped = make(chan struct{})
+		go p.run()
+	}
+	return p
+}
+
+// Publisher applies the overflow policy of its limiter to the events of a source. Publish may be
+// called concurrently.
+type Publisher struct {
+	limiter *Limiter
+	output  func(logs.LogEvent)
+	done    <-chan struct{}
+
+	// queue, notify, abort and stopped are only used by the drop_oldest policy
+	mu      sync.Mutex
+	queue   []logs.LogEvent
+	closed  bool
+	notify  chan struct{}
+	abort   chan struct{}
+	stopped chan struct{}
+}
+
+func (p *Publisher) Publish(e logs.LogEvent) {
+	l := p.limiter
+	switch l.cfg.OverflowPolicy {
+	case PolicyDropNewest:
+		if !l.allow(e) {
+			l.drop(e)
+			return
+		}
+	case PolicySample:
+		if !l.allow(e) && !l.sample() {
+			l.drop(e)
+			return
+		}
+	case PolicyDropOldest:
+		p.enqueue(e)
+		return
+	default:
+		// the event is still published once done is closed, which only happens on shutdown
+		l.wait(e, p.done, nil)
+	}
+	p.output(e)
+}
+
+func (p *Publisher) enqueue(e logs.LogEvent) {
+	p.mu.Lock()
+	p.queue = append(p.queue, e)
+	var oldest logs.LogEvent
+	if len(p.queue) > p.limiter.cfg.BufferSize {
+		oldest = p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+	}
+	p.mu.Unlock()
+	if oldest != nil {
+		p.limiter.drop(oldest)
+	}
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the oldest queued event, waiting for one until the publisher is closed.
+func (p *Publisher) next() (logs.LogEvent, bool) {
+	for {
+		p.mu.Lock()
+		if len(p.queue) > 0 {
+			e := p.queue[0]
+			p.queue[0] = nil
+			p.queue = p.queue[1:]
+			p.mu.Unlock()
+			return e, true
+		}
+		closed := p.closed
+		p.mu.Unlock()
+		if closed {
+			return nil, false
+		}
+		select {
+		case <-p.notify:
+		case <-p.done:
+			return nil, false
+		case <-p.abort:
+			return nil, false
+		}
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.stopped)
+	for {
+		e, ok := p.next()
+		if !ok {
+			return
+		}
+		if !p.limiter.wait(e, p.done, p.abort) {
+			// the event is queued again, to be handled with the other queued events
+			p.mu.Lock()
+			p.queue = append([]logs.LogEvent{e}, p.queue...)
+			p.mu.Unlock()
+			return
+		}
+		p.output(e)
+	}
+}
+
+// Close passes the queued events to the output within the limit and returns once they are all
+// passed, or drops the events still queued after closeTimeout. The queued events are discarded
+// without being marked done once done is closed, so that their source reads them again on the next
+// start.
+func (p *Publisher) Close() {
+	if p.stopped == nil {
+		return
+	}
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+	timer := time.NewTimer(closeTimeout)
+	defer timer.Stop()
+	select {
+	case <-p.stopped:
+		return
+	case <-timer.C:
+	}
+	close(p.abort)
+	<-p.stopped
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	p.mu.Lock()
+	queue := p.queue
+	p.queue = nil
+	p.mu.Unlock()
+	for _, e := range queue {
+		p.limiter.drop(e)
+	}
+}
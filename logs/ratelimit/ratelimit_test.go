@@ -0,0 +1,209 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+type testEvent struct {
+	msg  string
+	done *atomic.Int64
+}
+
+func (e testEvent) Message() string { return e.msg }
+func (e testEvent) Time() time.Time { return time.Time{} }
+func (e testEvent) Done()           { e.done.Add(1) }
+
+type testOutput struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *testOutput) output(e logs.LogEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e.Message())
+}
+
+func (o *testOutput) published() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.events...)
+}
+
+func newTestLimiter(t *testing.T, cfg Config, name string) *Limiter {
+	t.Helper()
+	require.NoError(t, cfg.Init())
+	return NewLimiter(cfg, []string{"test", name, "dropped"})
+}
+
+func TestConfigInit(t *testing.T) {
+	cfg := Config{MaxEventsPerSecond: 10}
+	require.NoError(t, cfg.Init())
+	assert.Equal(t, PolicyBlock, cfg.OverflowPolicy)
+	assert.Equal(t, defaultBufferSize, cfg.BufferSize)
+	assert.Equal(t, defaultSampleRatio, cfg.SampleRatio)
+
+	assert.Error(t, (&Config{MaxEventsPerSecond: -1}).Init())
+	assert.Error(t, (&Config{OverflowPolicy: "drop_all"}).Init())
+	assert.Error(t, (&Config{BufferSize: -1}).Init())
+}
+
+func TestDropNewest(t *testing.T) {
+	l := newTestLimiter(t, Config{MaxEventsPerSecond: 5, OverflowPolicy: PolicyDropNewest}, t.Name())
+	var out testOutput
+	var done atomic.Int64
+	p := l.NewPublisher(out.output, nil)
+	for i := 0; i < 20; i++ {
+		p.Publish(testEvent{msg: "event", done: &done})
+	}
+	// the burst of the bucket is one second worth of events
+	assert.Len(t, out.published(), 5)
+	assert.EqualValues(t, 15, done.Load())
+	assert.EqualValues(t, 15, profiler.Profiler.GetStats()["test_"+t.Name()+"_dropped"])
+}
+
+func TestMaxKBPerSecond(t *testing.T) {
+	l := newTestLimiter(t, Config{MaxKBPerSecond: 1, OverflowPolicy: PolicyDropNewest}, t.Name())
+	var out testOutput
+	var done atomic.Int64
+	p := l.NewPublisher(out.output, nil)
+	for i := 0; i < 10; i++ {
+		p.Publish(testEvent{msg: strings.Repeat("a", 300), done: &done})
+	}
+	assert.Len(t, out.published(), 3)
+	assert.EqualValues(t, 7, done.Load())
+	// events larger than the bucket only need the whole bucket
+	l = newTestLimiter(t, Config{MaxKBPerSecond: 1, OverflowPolicy: PolicyDropNewest}, t.Name())
+	p = l.NewPublisher(out.output, nil)
+	p.Publish(testEvent{msg: strings.Repeat("a", 2048), done: &done})
+	assert.Len(t, out.published(), 4)
+}
+
+func TestSample(t *testing.T) {
+	l := newTestLimiter(t, Config{MaxEventsPerSecond: 1, OverflowPolicy: PolicySample, SampleRatio: 4}, t.Name())
+	var out testOutput
+	var done atomic.Int64
+	p := l.NewPublisher(out.output, nil)
+	for i := 0; i < 9; i++ {
+		p.Publish(testEvent{msg: "event", done: &done})
+	}
+	// one event within the limit and one of every four of the eight others
+	assert.Len(t, out.published(), 3)
+	assert.EqualValues(t, 6, done.Load())
+}
+
+func TestBlock(t *testing.T) {
+	l := newTestLimiter(t, Config{MaxEventsPerSecond: 20}, t.Name())
+	var out testOutput
+	var done atomic.Int64
+	p := l.NewPublisher(out.output, nil)
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		p.Publish(testEvent{msg: "event", done: &done})
+	}
+	assert.Len(t, out.published(), 30)
+	assert.Zero(t, done.Load())
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	// waiting is abandoned once done is closed
+	stop := make(chan struct{})
+	close(stop)
+	p = l.NewPublisher(out.output, stop)
+	start = time.Now()
+	for i := 0; i < 30; i++ {
+		p.Publish(testEvent{msg: "event", done: &done})
+	}
+	assert.Len(t, out.published(), 60)
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestDropOldest(t *testing.T) {
+	l := newTestLimiter(t, Config{MaxEventsPerSecond: 100, OverflowPolicy: PolicyDropOldest, BufferSize: 10}, t.Name())
+	block := make(chan struct{})
+	var out testOutput
+	var done atomic.Int64
+	p := l.NewPublisher(func(e logs.LogEvent) {
+		<-block
+		out.output(e)
+	}, nil)
+	// the first event is held by the blocked output, the next ones are queued
+	p.Publish(testEvent{msg: "0", done: &done})
+	require.Eventually(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return len(p.queue) == 0
+	}, time.Second, time.Millisecond)
+	for i := 1; i <= 15; i++ {
+		p.Publish(testEvent{msg: strings.Repeat("x", i), done: &done})
+	}
+	assert.EqualValues(t, 5, done.Load())
+	close(block)
+	p.Close()
+	got := out.published()
+	require.Len(t, got, 11)
+	assert.Equal(t, "0", got[0])
+	assert.Equal(t, strings.Repeat("x", 6), got[1])
+	assert.Equal(t, strings.Repeat("x", 15), got[10])
+}
+
+func TestDropOldestCloseAfterDone(t *testing.T) {
+	l := newTestLimiter(t, Config{MaxEventsPerSecond: 1, OverflowPolicy: PolicyDropOldest}, t.Name())
+	var out testOutput
+	var done atomic.Int64
+	stop := make(chan struct{})
+	p := l.NewPublisher(out.output, stop)
+	for i := 0; i < 5; i++ {
+		p.Publish(testEvent{msg: "event", done: &done})
+	}
+	close(stop)
+	p.Close()
+	// the queued events are neither published nor done, so that they are read again
+	assert.Less(t, len(out.published()), 5)
+	assert.Zero(t, done.Load())
+}
+
+func TestDropOldestCloseTimeout(t *testing.T) {
+	original := closeTimeout
+	closeTimeout = 100 * time.Millisecond
+	defer func() { closeTimeout = original }()
+	l := newTestLimiter(t, Config{MaxEventsPerSecond: 1, OverflowPolicy: PolicyDropOldest}, t.Name())
+	var out testOutput
+	var done atomic.Int64
+	p := l.NewPublisher(out.output, nil)
+	for i := 0; i < 5; i++ {
+		p.Publish(testEvent{msg: "event", done: &done})
+	}
+	start := time.Now()
+	p.Close()
+	assert.Less(t, time.Since(start), time.Second)
+	// the events still queued once the timeout expires are dropped
+	assert.Len(t, out.published(), 1)
+	assert.EqualValues(t, 4, done.Load())
+}
+
+func TestSharedLimiter(t *testing.T) {
+	l := newTestLimiter(t, Config{MaxEventsPerSecond: 10, OverflowPolicy: PolicyDropNewest}, t.Name())
+	var out1, out2 testOutput
+	var done atomic.Int64
+	p1 := l.NewPublisher(out1.output, nil)
+	p2 := l.NewPublisher(out2.output, nil)
+	for i := 0; i < 10; i++ {
+		p1.Publish(testEvent{msg: "event", done: &done})
+		p2.Publish(testEvent{msg: "event", done: &done})
+	}
+	assert.Equal(t, 10, len(out1.published())+len(out2.published()))
+	assert.EqualValues(t, 10, done.Load())
+}
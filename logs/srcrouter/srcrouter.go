@@ -0,0 +1,296 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+// Package srcrouter publishes the events of an input to a source for each log group and stream
+// rendered from the events, for the inputs whose names use the fields of their events.
+package srcrouter
+
+import (
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+const (
+	DefaultMaxSources  = 1000
+	DefaultIdleTimeout = 5 * time.Minute
+
+	// UnknownFieldValue replaces the fields missing from an event in the log group and stream names.
+	UnknownFieldValue = "unknown"
+
+	// evictionWarnInterval limits the warnings of the sources evicted because the router is full.
+	evictionWarnInterval = time.Minute
+)
+
+var (
+	fieldPlaceholderRegex = regexp.MustCompile(`\{([A-Z_][A-Z0-9_]*)\}`)
+	InvalidLogGroupChars  = regexp.MustCompile(`[^a-zA-Z0-9_\-/.#]`)
+	InvalidLogStreamChars = regexp.MustCompile(`[:*]`)
+)
+
+// Render replaces the {FIELD} placeholders with the values of the fields, the characters invalid
+// in the name being replaced with underscores.
+func Render(template string, value func(field string) (string, bool), invalidChars *regexp.Regexp) string {
+	return fieldPlaceholderRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		v, ok := value(placeholder[1 : len(placeholder)-1])
+		if !ok || v == "" {
+			return UnknownFieldValue
+		}
+		return invalidChars.ReplaceAllString(v, "_")
+	})
+}
+
+// Config is shared by the sources of a router.
+type Config struct {
+	Description string
+	Destination string
+	Retention   int
+	Class       string
+	// MaxSources is the number of sources after which the least recently used one is closed for
+	// each new source, DefaultMaxSources when not set.
+	MaxSources int
+	// IdleTimeout is the time after which the sources without events are closed,
+	// DefaultIdleTimeout when not set.
+	IdleTimeout time.Duration
+}
+
+// Router creates the sources of the log groups and streams when their first event is published,
+// and adds them with addSrc so that the log agent sets their output. As the names are rendered
+// from the events, which may come from the network, the sources are closed once idle or when there
+// are too many of them, and created again by their next event.
+type Router struct {
+	cfg    Config
+	addSrc func(logs.LogSrc)
+	done   chan struct{}
+
+	mu               sync.Mutex
+	srcs             map[string]*src
+	uses             uint64
+	lastEvictionWarn time.Time
+	startOnce        sync.Once
+	stopOnce         sync.Once
+}
+
+func New(cfg Config, addSrc func(logs.LogSrc)) *Router {
+	if cfg.MaxSources <= 0 {
+		cfg.MaxSources = DefaultMaxSources
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	return &Router{
+		cfg:    cfg,
+		addSrc: addSrc,
+		done:   make(chan struct{}),
+		srcs:   make(map[string]*src),
+	}
+}
+
+// Publish publishes the event to the source of the log group and stream. It returns false if the
+// router was stopped while waiting for the output of the source. The events published to a source
+// whose output stopped are dropped and done.
+func (r *Router) Publish(group, stream string, e logs.LogEvent) bool {
+	for {
+		s := r.get(group, stream)
+		if s == nil {
+			return false
+		}
+		select {
+		case <-s.ready:
+		case <-r.done:
+			return false
+		}
+		s.publishMu.Lock()
+		if s.closed {
+			// the source was evicted after it was found, so a new one is created
+			s.publishMu.Unlock()
+			continue
+		}
+		select {
+		case <-s.done:
+			e.Done()
+		default:
+			s.outputFn(e)
+		}
+		s.publishMu.Unlock()
+		return true
+	}
+}
+
+func (r *Router) get(group, stream string) *src {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.done:
+		return nil
+	default:
+	}
+	now := time.Now()
+	r.uses++
+	key := group + "\n" + stream
+	if s, ok := r.srcs[key]; ok {
+		s.lastUsed, s.lastUse = now, r.uses
+		return s
+	}
+	if len(r.srcs) >= r.cfg.MaxSources {
+		r.evictOldest(now)
+	}
+	s := &src{
+		router:   r,
+		group:    group,
+		stream:   stream,
+		ready:    make(chan struct{}),
+		done:     make(chan struct{}),
+		lastUsed: now,
+		lastUse:  r.uses,
+	}
+	r.srcs[key] = s
+	r.addSrc(s)
+	r.startOnce.Do(func() {
+		go r.runEviction()
+	})
+	return s
+}
+
+func (r *Router) evictOldest(now time.Time) {
+	var oldestKey string
+	var oldest *src
+	for key, s := range r.srcs {
+		if oldest == nil || s.lastUse < oldest.lastUse {
+			oldestKey, oldest = key, s
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	if now.Sub(r.lastEvictionWarn) >= evictionWarnInterval {
+		log.Printf("W! [logs] Too many log sources for %s, closing the least recently used %s/%s", r.cfg.Description, oldest.group, oldest.stream)
+		r.lastEvictionWarn = now
+	}
+	profiler.Profiler.AddStats([]string{"logs", "sourcesEvicted"}, 1)
+	r.evict(oldestKey, oldest)
+}
+
+// evict removes the source from the router and closes it once the event being published is.
+func (r *Router) evict(key string, s *src) {
+	delete(r.srcs, key)
+	go s.close()
+}
+
+func (r *Router) runEviction() {
+	ticker := time.NewTicker(r.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.evictIdle(time.Now())
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *Router) evictIdle(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, s := range r.srcs {
+		if now.Sub(s.lastUsed) >= r.cfg.IdleTimeout {
+			log.Printf("D! [logs] Closing idle log source %s/%s of %s", s.group, s.stream, r.cfg.Description)
+			r.evict(key, s)
+		}
+	}
+}
+
+// Len returns the number of sources open.
+func (r *Router) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.srcs)
+}
+
+// Stop stops the publishing of the events, without waiting for the events being published.
+func (r *Router) Stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
+
+// src is the source of the events rendered to the same log group and stream.
+type src struct {
+	router        *Router
+	group, stream string
+
+	outputFn  func(logs.LogEvent)
+	ready     chan struct{}
+	readyOnce sync.Once
+	done      chan struct{}
+	stopOnce  sync.Once
+
+	// publishMu is held while an event is published, so that the source is not closed meanwhile.
+	publishMu sync.Mutex
+	closed    bool
+	// lastUsed and lastUse are guarded by the mutex of the router, lastUse ordering the uses of
+	// the sources which may happen at the same time.
+	lastUsed time.Time
+	lastUse  uint64
+}
+
+var _ logs.LogSrc = (*src)(nil)
+
+func (s *src) SetOutput(fn func(logs.LogEvent)) {
+	if fn == nil {
+		return
+	}
+	s.readyOnce.Do(func() {
+		s.publishMu.Lock()
+		defer s.publishMu.Unlock()
+		s.outputFn = fn
+		close(s.ready)
+		if s.closed {
+			fn(nil)
+		}
+	})
+}
+
+// close ends the output of the source, a nil event telling the log agent that the source stopped.
+func (s *src) close() {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	s.closed = true
+	select {
+	case <-s.ready:
+		s.outputFn(nil)
+	default:
+	}
+}
+
+func (s *src) Group() string {
+	return s.group
+}
+
+func (s *src) Stream() string {
+	return s.stream
+}
+
+func (s *src) Description() string {
+	return s.router.cfg.Description
+}
+
+func (s *src) Destination() string {
+	return s.router.cfg.Destination
+}
+
+func (s *src) Retention() int {
+	return s.router.cfg.Retention
+}
+
+func (s *src) Class() string {
+	return s.router.cfg.Class
+}
+
+// Stop is called once the output of the source stopped.
+func (s *src) Stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
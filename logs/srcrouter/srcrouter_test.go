@@ -0,0 +1,154 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package srcrouter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+type testEvent struct {
+	msg  string
+	done bool
+}
+
+func (e *testEvent) Message() string {
+	return e.msg
+}
+
+func (e *testEvent) Time() time.Time {
+	return time.Time{}
+}
+
+func (e *testEvent) Done() {
+	e.done = true
+}
+
+// testOutput sets the output of the sources added, recording their events and whether they were
+// closed.
+type testOutput struct {
+	mu     sync.Mutex
+	events map[string][]string
+	closed map[string]int
+}
+
+func newTestOutput() *testOutput {
+	return &testOutput{events: map[string][]string{}, closed: map[string]int{}}
+}
+
+func (o *testOutput) addSrc(src logs.LogSrc) {
+	key := src.Group() + "/" + src.Stream()
+	src.SetOutput(func(e logs.LogEvent) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if e == nil {
+			o.closed[key]++
+			return
+		}
+		o.events[key] = append(o.events[key], e.Message())
+	})
+}
+
+func (o *testOutput) closedCount(key string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closed[key]
+}
+
+func TestRender(t *testing.T) {
+	fields := map[string]string{"HOSTNAME": "ip-10-0-0-1:a*b", "EMPTY": ""}
+	value := func(field string) (string, bool) {
+		v, ok := fields[field]
+		return v, ok
+	}
+	assert.Equal(t, "syslog/ip-10-0-0-1_a_b/unknown/unknown", Render("syslog/{HOSTNAME}/{EMPTY}/{MISSING}", value, InvalidLogGroupChars))
+	assert.Equal(t, "ip-10-0-0-1_a_b {instance_id}", Render("{HOSTNAME} {instance_id}", value, InvalidLogStreamChars))
+}
+
+func TestRouterPublish(t *testing.T) {
+	out := newTestOutput()
+	r := New(Config{Description: "test", Destination: "cloudwatchlogs", Retention: 7, Class: "STANDARD"}, out.addSrc)
+	defer r.Stop()
+
+	assert.True(t, r.Publish("group", "a", &testEvent{msg: "1"}))
+	assert.True(t, r.Publish("group", "b", &testEvent{msg: "2"}))
+	assert.True(t, r.Publish("group", "a", &testEvent{msg: "3"}))
+	assert.Equal(t, map[string][]string{"group/a": {"1", "3"}, "group/b": {"2"}}, out.events)
+	assert.Equal(t, 2, r.Len())
+
+	s := r.get("group", "a")
+	assert.Equal(t, "test", s.Description())
+	assert.Equal(t, "cloudwatchlogs", s.Destination())
+	assert.Equal(t, 7, s.Retention())
+	assert.Equal(t, "STANDARD", s.Class())
+
+	// the events of a source whose output stopped are done
+	s.Stop()
+	e := &testEvent{msg: "4"}
+	assert.True(t, r.Publish("group", "a", e))
+	assert.True(t, e.done)
+	assert.Equal(t, []string{"1", "3"}, out.events["group/a"])
+}
+
+func TestRouterMaxSources(t *testing.T) {
+	out := newTestOutput()
+	r := New(Config{MaxSources: 2}, out.addSrc)
+	defer r.Stop()
+
+	require.True(t, r.Publish("group", "a", &testEvent{msg: "1"}))
+	require.True(t, r.Publish("group", "b", &testEvent{msg: "2"}))
+	require.True(t, r.Publish("group", "a", &testEvent{msg: "3"}))
+	// b is the least recently used source
+	require.True(t, r.Publish("group", "c", &testEvent{msg: "4"}))
+	assert.Equal(t, 2, r.Len())
+	assert.Eventually(t, func() bool { return out.closedCount("group/b") == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, out.closedCount("group/a"))
+
+	// the source evicted is created again by its next event
+	require.True(t, r.Publish("group", "b", &testEvent{msg: "5"}))
+	assert.Equal(t, []string{"2", "5"}, out.events["group/b"])
+	assert.Eventually(t, func() bool { return out.closedCount("group/a") == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRouterIdleTimeout(t *testing.T) {
+	out := newTestOutput()
+	r := New(Config{IdleTimeout: time.Hour}, out.addSrc)
+	defer r.Stop()
+
+	require.True(t, r.Publish("group", "a", &testEvent{msg: "1"}))
+	r.evictIdle(time.Now())
+	assert.Equal(t, 1, r.Len())
+	r.evictIdle(time.Now().Add(time.Hour))
+	assert.Equal(t, 0, r.Len())
+	assert.Eventually(t, func() bool { return out.closedCount("group/a") == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRouterCloseBeforeOutput(t *testing.T) {
+	var srcs []logs.LogSrc
+	r := New(Config{MaxSources: 1}, func(src logs.LogSrc) { srcs = append(srcs, src) })
+
+	// the source is evicted before its output is set, which ends the output once set
+	s := r.get("group", "a")
+	r.get("group", "b")
+	assert.Eventually(t, func() bool {
+		s.publishMu.Lock()
+		defer s.publishMu.Unlock()
+		return s.closed
+	}, 5*time.Second, 10*time.Millisecond)
+	out := newTestOutput()
+	out.addSrc(srcs[0])
+	assert.Equal(t, 1, out.closedCount("group/a"))
+
+	// the events waiting for the output of their source are not published once stopped
+	r.Stop()
+	e := &testEvent{msg: "1"}
+	assert.False(t, r.Publish("group", "b", e))
+	assert.False(t, e.done)
+}
@@ -0,0 +1,61 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package template
+
+import (
+	"log"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	configaws "github.com/aws/amazon-cloudwatch-agent/cfg/aws"
+	"github.com/aws/amazon-cloudwatch-agent/internal/retryer"
+)
+
+const ec2TagsPath = "tags/instance/"
+
+var (
+	ec2TagsMu sync.Mutex
+	// ec2Tags caches the tags looked up, including the ones which can't be found, so that the
+	// instance metadata is only queried once for each tag.
+	ec2Tags = map[string]ec2TagValue{}
+
+	// getEC2Tag reads the tag from the instance metadata, which needs the tags to be allowed in the
+	// metadata options of the instance.
+	getEC2Tag = func(key string) (string, error) {
+		ses, err := session.NewSession()
+		if err != nil {
+			return "", err
+		}
+		md := ec2metadata.New(ses, &aws.Config{
+			LogLevel: configaws.SDKLogLevel(),
+			Logger:   configaws.SDKLogger{},
+			Retryer:  retryer.NewIMDSRetryer(retryer.GetDefaultRetryNumber()),
+		})
+		return md.GetMetadata(ec2TagsPath + key)
+	}
+)
+
+type ec2TagValue struct {
+	value string
+	found bool
+}
+
+func ec2Tag(key string) (string, bool) {
+	ec2TagsMu.Lock()
+	defer ec2TagsMu.Unlock()
+	if v, ok := ec2Tags[key]; ok {
+		return v.value, v.found
+	}
+	v, err := getEC2Tag(key)
+	if err != nil {
+		log.Printf("W! [logs] Unable to get the EC2 tag %s from the instance metadata: %v", key, err)
+		ec2Tags[key] = ec2TagValue{}
+		return "", false
+	}
+	ec2Tags[key] = ec2TagValue{value: v, found: true}
+	return v, true
+}
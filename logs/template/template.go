@@ -0,0 +1,172 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+// Package template evaluates the {kind:key} placeholders of the log group and stream names.
+package template
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// KindField is the field of an event, e.g. {field:tenant}, so the names using it are evaluated
+	// for each event.
+	KindField = "field"
+	// KindFile is the file of a source, {file:dirname} or {file:basename}.
+	KindFile = "file"
+	// KindEC2 is the metadata of the host, {ec2:tag:<key>} for the tags of the instance.
+	KindEC2 = "ec2"
+
+	FileDirname  = "dirname"
+	FileBasename = "basename"
+	ec2TagPrefix = "tag:"
+
+	unknownValue = "unknown"
+	// maxNameLength is the max length of both the log group and log stream names.
+	maxNameLength = 512
+)
+
+var (
+	placeholderRegex = regexp.MustCompile(`\{(` + KindField + `|` + KindFile + `|` + KindEC2 + `):([^{}]+)\}`)
+	// invalidValueChars are the characters of the values which are not valid in a log group name,
+	// the log stream names allowing a superset of them.
+	invalidValueChars = regexp.MustCompile(`[^a-zA-Z0-9_\-/.#]`)
+)
+
+// Resolver returns the value of a placeholder, false if it can't be resolved.
+type Resolver func(kind, key string) (string, bool)
+
+type placeholder struct {
+	kind string
+	key  string
+}
+
+// Template is a log group or stream name with placeholders. The text which is not a placeholder
+// of a known kind is kept as is, so that the other placeholders can be resolved elsewhere.
+type Template struct {
+	// literals surround the placeholders, there is always one more literal than placeholders.
+	literals     []string
+	placeholders []placeholder
+}
+
+func Parse(s string) Template {
+	var t Template
+	last := 0
+	for _, m := range placeholderRegex.FindAllStringSubmatchIndex(s, -1) {
+		t.literals = append(t.literals, s[last:m[0]])
+		t.placeholders = append(t.placeholders, placeholder{kind: s[m[2]:m[3]], key: s[m[4]:m[5]]})
+		last = m[1]
+	}
+	t.literals = append(t.literals, s[last:])
+	return t
+}
+
+// IsDynamic returns whether the name has placeholders evaluated for each event.
+func IsDynamic(s string) bool {
+	return Parse(s).Dynamic()
+}
+
+// Dynamic returns whether the template has placeholders evaluated for each event.
+func (t Template) Dynamic() bool {
+	for _, p := range t.placeholders {
+		if p.kind == KindField {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Template) String() string {
+	var sb strings.Builder
+	for i, p := range t.placeholders {
+		sb.WriteString(t.literals[i])
+		fmt.Fprintf(&sb, "{%s:%s}", p.kind, p.key)
+	}
+	sb.WriteString(t.literals[len(t.literals)-1])
+	return sb.String()
+}
+
+// Resolve returns the template with the placeholders resolved by r replaced by their value.
+func (t Template) Resolve(r Resolver) Template {
+	res := Template{literals: []string{t.literals[0]}}
+	for i, p := range t.placeholders {
+		if v, ok := r(p.kind, p.key); ok {
+			res.literals[len(res.literals)-1] += sanitize(v) + t.literals[i+1]
+			continue
+		}
+		res.placeholders = append(res.placeholders, p)
+		res.literals = append(res.literals, t.literals[i+1])
+	}
+	return res
+}
+
+// Execute returns the name with all the placeholders replaced, unknown for those r can't resolve.
+// The characters of the values which are not valid in a name are replaced with _.
+func (t Template) Execute(r Resolver) string {
+	var sb strings.Builder
+	for i, p := range t.placeholders {
+		sb.WriteString(t.literals[i])
+		v, ok := "", false
+		if r != nil {
+			v, ok = r(p.kind, p.key)
+		}
+		if !ok || v == "" {
+			v = unknownValue
+		}
+		sb.WriteString(sanitize(v))
+	}
+	sb.WriteString(t.literals[len(t.literals)-1])
+	name := sb.String()
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	return name
+}
+
+func sanitize(v string) string {
+	return invalidValueChars.ReplaceAllString(v, "_")
+}
+
+// FileResolver resolves the {file:dirname} and {file:basename} placeholders of the file.
+func FileResolver(filename string) Resolver {
+	return func(kind, key string) (string, bool) {
+		if kind != KindFile {
+			return "", false
+		}
+		switch key {
+		case FileDirname:
+			return filepath.Dir(filename), true
+		case FileBasename:
+			return filepath.Base(filename), true
+		}
+		return "", false
+	}
+}
+
+// FieldResolver resolves the {field:<name>} placeholders with the fields of an event.
+func FieldResolver(fields map[string]interface{}) Resolver {
+	return func(kind, key string) (string, bool) {
+		if kind != KindField {
+			return "", false
+		}
+		v, ok := fields[key]
+		if !ok || v == nil {
+			return "", false
+		}
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		return fmt.Sprint(v), true
+	}
+}
+
+// HostResolver resolves the {ec2:tag:<key>} placeholders with the tags of the instance.
+func HostResolver(kind, key string) (string, bool) {
+	if kind != KindEC2 || !strings.HasPrefix(key, ec2TagPrefix) {
+		return "", false
+	}
+	return ec2Tag(strings.TrimPrefix(key, ec2TagPrefix))
+}
@@ -0,0 +1,64 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package template
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"", "group", "/app/{field:tenant}/{ec2:tag:Environment}", "{file:dirname}{field:a}", "{instance_id}/{other:x}/{field:}"} {
+		assert.Equal(t, s, Parse(s).String())
+	}
+	assert.True(t, IsDynamic("/app/{field:tenant}"))
+	assert.False(t, IsDynamic("/app/{file:basename}/{ec2:tag:Environment}/{instance_id}"))
+}
+
+func TestExecute(t *testing.T) {
+	tmpl := Parse("/app/{field:tenant}/{field:region}/{field:id}-{instance_id}")
+	fields := map[string]interface{}{"tenant": "acme corp", "id": 42.0, "region": nil}
+	assert.Equal(t, "/app/acme_corp/unknown/42-{instance_id}", tmpl.Execute(FieldResolver(fields)))
+	assert.Equal(t, "/app/unknown/unknown/unknown-{instance_id}", tmpl.Execute(nil))
+
+	long := map[string]interface{}{"tenant": strings.Repeat("a", 600)}
+	assert.Len(t, tmpl.Execute(FieldResolver(long)), maxNameLength)
+}
+
+func TestResolve(t *testing.T) {
+	tmpl := Parse("{file:dirname}/{file:basename}/{field:tenant}/{file:other}")
+	resolved := tmpl.Resolve(FileResolver("/var/log/tenants/app.log"))
+	assert.Equal(t, "/var/log/tenants/app.log/{field:tenant}/{file:other}", resolved.String())
+	assert.True(t, resolved.Dynamic())
+	assert.Equal(t, "/var/log/tenants/app.log/acme/unknown", resolved.Execute(FieldResolver(map[string]interface{}{"tenant": "acme"})))
+
+	// the resolved values are not parsed again
+	tmpl = Parse("{field:a}-{field:b}")
+	assert.Equal(t, "_field_b_-{field:b}", tmpl.Resolve(FieldResolver(map[string]interface{}{"a": "{field:b}"})).String())
+}
+
+func TestHostResolver(t *testing.T) {
+	original := getEC2Tag
+	defer func() {
+		getEC2Tag = original
+		ec2Tags = map[string]ec2TagValue{}
+	}()
+	calls := 0
+	getEC2Tag = func(key string) (string, error) {
+		calls++
+		if key == "Environment" {
+			return "prod", nil
+		}
+		return "", errors.New("not found")
+	}
+
+	tmpl := Parse("/app/{ec2:tag:Environment}/{ec2:tag:Team}/{ec2:instance_id}")
+	assert.Equal(t, "/app/prod/{ec2:tag:Team}/{ec2:instance_id}", tmpl.Resolve(HostResolver).String())
+	assert.Equal(t, "/app/prod/unknown/unknown", tmpl.Execute(HostResolver))
+	// both the tags found and the missing ones are only looked up once
+	assert.Equal(t, 2, calls)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"sort"
 
 	"go.opentelemetry.io/collector/pdata/pmetric"
 
@@ -16,6 +17,10 @@ import (
 var bucketForZero int16 = math.MinInt16
 var bucketFactor = math.Log(1 + 0.1)
 
+// RelativeAccuracy is the largest relative error of the value of a bucket, which is the middle
+// of the bucket, to the values it holds.
+var RelativeAccuracy = math.Exp(bucketFactor/2) - 1
+
 type SEH1Distribution struct {
 	maximum     float64
 	minimum     float64
@@ -23,6 +28,10 @@ type SEH1Distribution struct {
 	sum         float64
 	buckets     map[int16]float64 // from bucket number (i.e. value) to the counter (i.e. weight)
 	unit        string
+	// groupSize consecutive buckets are counted in the middle one of the group, 1 keeps every bucket.
+	groupSize int16
+	// maxBuckets bounds the number of buckets of the non-zero values, 0 means no limit.
+	maxBuckets int
 }
 
 func NewSEH1Distribution() distribution.Distribution {
@@ -33,7 +42,24 @@ func NewSEH1Distribution() distribution.Distribution {
 		sum:         0,
 		buckets:     map[int16]float64{},
 		unit:        "",
+		groupSize:   1,
+	}
+}
+
+// NewSEH1DistributionWithAccuracy returns a distribution whose bucket values are within the
+// relative accuracy of the values they hold and which keeps at most maxBuckets buckets for
+// the non-zero values, 0 meaning no limit. The buckets are still SEH1 buckets, an accuracy
+// lower than RelativeAccuracy counts several consecutive buckets in the middle one. Once the
+// limit is reached, the lowest buckets are merged so that the high percentiles keep their
+// accuracy.
+func NewSEH1DistributionWithAccuracy(relativeAccuracy float64, maxBuckets int) distribution.Distribution {
+	dist := NewSEH1Distribution().(*SEH1Distribution)
+	// A group of n buckets, n being odd, is within exp(n*bucketFactor/2)-1 of its middle.
+	for n := dist.groupSize + 2; math.Exp(float64(n)*bucketFactor/2)-1 <= relativeAccuracy; n += 2 {
+		dist.groupSize = n
 	}
+	dist.maxBuckets = maxBuckets
+	return dist
 }
 
 func (seh1Distribution *SEH1Distribution) Maximum() float64 {
@@ -99,8 +125,9 @@ func (seh1Distribution *SEH1Distribution) AddEntryWithUnit(value float64, weight
 	}
 
 	//seh
-	bucketNumber := bucketNumber(value)
+	bucketNumber := seh1Distribution.bucketNumber(value)
 	seh1Distribution.buckets[bucketNumber] += weight
+	seh1Distribution.collapse()
 
 	//unit
 	if seh1Distribution.unit == "" {
@@ -126,8 +153,9 @@ func (seh1Distribution *SEH1Distribution) AddDistributionWithWeight(distribution
 		//seh
 		if fromSEH1Distribution, ok := distribution.(*SEH1Distribution); ok {
 			for bucketNumber, bucketCounts := range fromSEH1Distribution.buckets {
-				seh1Distribution.buckets[bucketNumber] += bucketCounts * weight
+				seh1Distribution.buckets[seh1Distribution.group(bucketNumber)] += bucketCounts * weight
 			}
+			seh1Distribution.collapse()
 		} else {
 			log.Printf("E! The from distribution type is not compatible with the to distribution type: from distribution type %T, to distribution type %T", seh1Distribution, distribution)
 			return
@@ -190,21 +218,55 @@ func (seh1Distribution *SEH1Distribution) CanAdd(value float64, sizeLimit int) b
 	if seh1Distribution.Size() < sizeLimit {
 		return true
 	}
-	bucketNumber := bucketNumber(value)
+	bucketNumber := seh1Distribution.bucketNumber(value)
 	if _, ok := seh1Distribution.buckets[bucketNumber]; ok {
 		return true
 	}
 	return false
 }
 
-func bucketNumber(value float64) int16 {
+func (seh1Distribution *SEH1Distribution) bucketNumber(value float64) int16 {
 	bucketNumber := bucketForZero
 	if value > 0 {
-		bucketNumber = int16(floor(math.Log(value) / bucketFactor))
+		bucketNumber = seh1Distribution.group(int16(floor(math.Log(value) / bucketFactor)))
 	}
 	return bucketNumber
 }
 
+// group returns the middle bucket of the group holding the bucket.
+func (seh1Distribution *SEH1Distribution) group(bucketNumber int16) int16 {
+	size := seh1Distribution.groupSize
+	if size <= 1 || bucketNumber == bucketForZero {
+		return bucketNumber
+	}
+	first := int16(floor(float64(bucketNumber)/float64(size))) * size
+	return first + size/2
+}
+
+// collapse merges the lowest buckets of the non-zero values into the next ones until there are
+// no more than maxBuckets of them.
+func (seh1Distribution *SEH1Distribution) collapse() {
+	size := len(seh1Distribution.buckets)
+	if _, ok := seh1Distribution.buckets[bucketForZero]; ok {
+		size--
+	}
+	if seh1Distribution.maxBuckets <= 0 || size <= seh1Distribution.maxBuckets {
+		return
+	}
+	bucketNumbers := make([]int, 0, len(seh1Distribution.buckets))
+	for bucketNumber := range seh1Distribution.buckets {
+		if bucketNumber != bucketForZero {
+			bucketNumbers = append(bucketNumbers, int(bucketNumber))
+		}
+	}
+	sort.Ints(bucketNumbers)
+	for i := 0; size > seh1Distribution.maxBuckets; i++ {
+		seh1Distribution.buckets[int16(bucketNumbers[i+1])] += seh1Distribution.buckets[int16(bucketNumbers[i])]
+		delete(seh1Distribution.buckets, int16(bucketNumbers[i]))
+		size--
+	}
+}
+
 // This method is faster than math.Floor
 func floor(fvalue float64) int64 {
 	ivalue := int64(fvalue)
@@ -90,6 +90,66 @@ func TestSEH1Distribution(t *testing.T) {
 	assert.ErrorIs(t, anotherDist.AddEntry(distribution.MinValue*1.001, 1), distribution.ErrUnsupportedValue)
 }
 
+func TestSEH1DistributionWithAccuracy(t *testing.T) {
+	// the native accuracy keeps every bucket
+	dist := NewSEH1DistributionWithAccuracy(0.05, 0)
+	assert.Equal(t, NewSEH1Distribution(), dist)
+
+	// groups of 3 buckets are within 1.1^1.5-1 of their middle
+	dist = NewSEH1DistributionWithAccuracy(0.2, 0)
+	for i := 1; i <= 1000; i++ {
+		assert.NoError(t, dist.AddEntry(float64(i), 1))
+	}
+	assert.NoError(t, dist.AddEntry(0, 1))
+	values, counts := dist.ValuesAndCounts()
+	assert.Equal(t, 1001.0, sum(counts))
+	native := NewSEH1Distribution()
+	native.AddDistribution(dist)
+	assert.Equal(t, len(values), native.Size())
+	assert.Less(t, dist.Size(), 30)
+	for i := 1; i <= 1000; i++ {
+		value := float64(i)
+		bucket := dist.(*SEH1Distribution).bucketNumber(value)
+		assert.InEpsilon(t, value, math.Exp((float64(bucket)+0.5)*bucketFactor), 0.2, "value %v", value)
+	}
+
+	// the lowest buckets are merged beyond the limit, the zero values are kept apart
+	dist = NewSEH1DistributionWithAccuracy(0.05, 10)
+	native = NewSEH1Distribution()
+	for i := 1; i <= 1000; i++ {
+		assert.NoError(t, dist.AddEntry(float64(i), 1))
+		assert.NoError(t, native.AddEntry(float64(i), 1))
+	}
+	assert.NoError(t, dist.AddEntry(0, 2))
+	assert.Equal(t, 11, dist.Size())
+	assert.Equal(t, 1002.0, dist.SampleCount())
+	values, counts = dist.ValuesAndCounts()
+	assert.Equal(t, 1002.0, sum(counts))
+	for i, value := range values {
+		if value == 0 {
+			assert.Equal(t, 2.0, counts[i])
+		}
+	}
+	// the highest bucket keeps its accuracy
+	assert.Equal(t, native.(*SEH1Distribution).buckets[bucketNumberOf(1000)], dist.(*SEH1Distribution).buckets[bucketNumberOf(1000)])
+
+	merged := NewSEH1DistributionWithAccuracy(0.05, 10)
+	merged.AddDistribution(native)
+	assert.Equal(t, 10, merged.Size())
+}
+
+func bucketNumberOf(value float64) int16 {
+	return NewSEH1Distribution().(*SEH1Distribution).bucketNumber(value)
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
 func cloneSEH1Distribution(dist *SEH1Distribution) *SEH1Distribution {
 	clonedDist := &SEH1Distribution{
 		maximum:     dist.maximum,
@@ -98,6 +158,8 @@ func cloneSEH1Distribution(dist *SEH1Distribution) *SEH1Distribution {
 		sum:         dist.sum,
 		buckets:     map[int16]float64{},
 		unit:        dist.unit,
+		groupSize:   dist.groupSize,
+		maxBuckets:  dist.maxBuckets,
 	}
 	for k, v := range dist.buckets {
 		clonedDist.buckets[k] = v
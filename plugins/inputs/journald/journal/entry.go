@@ -0,0 +1,94 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry is a journal entry. The timestamps are in microseconds.
+type Entry struct {
+	SeqnumID  ID
+	Seqnum    uint64
+	BootID    ID
+	Realtime  uint64
+	Monotonic uint64
+	XorHash   uint64
+	// Fields holds the values of the fields, which may be set more than once.
+	Fields map[string][][]byte
+}
+
+// Time returns the time the entry was received by journald.
+func (e *Entry) Time() time.Time {
+	return time.UnixMicro(int64(e.Realtime))
+}
+
+// Value returns the first value of the field.
+func (e *Entry) Value(name string) (string, bool) {
+	values := e.Fields[name]
+	if len(values) == 0 {
+		return "", false
+	}
+	return string(values[0]), true
+}
+
+// Cursor returns the position of the entry in the format of sd_journal_get_cursor.
+func (e *Entry) Cursor() string {
+	return fmt.Sprintf("s=%s;i=%x;b=%s;m=%x;t=%x;x=%x", e.SeqnumID, e.Seqnum, e.BootID, e.Monotonic, e.Realtime, e.XorHash)
+}
+
+// Cursor is a parsed position in the journal.
+type Cursor struct {
+	SeqnumID ID
+	Seqnum   uint64
+	Realtime uint64
+}
+
+// ParseCursor parses the sequence number and the realtime of a cursor, which are enough to find
+// the entries after it.
+func ParseCursor(s string) (*Cursor, error) {
+	c := &Cursor{}
+	var hasSeqnum, hasRealtime bool
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid journal cursor %q", s)
+		}
+		var err error
+		switch key {
+		case "s":
+			var b []byte
+			if b, err = hex.DecodeString(value); err == nil && len(b) != len(c.SeqnumID) {
+				err = fmt.Errorf("invalid id length %d", len(b))
+			}
+			copy(c.SeqnumID[:], b)
+		case "i":
+			c.Seqnum, err = strconv.ParseUint(value, 16, 64)
+			hasSeqnum = true
+		case "t":
+			c.Realtime, err = strconv.ParseUint(value, 16, 64)
+			hasRealtime = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal cursor %q: %w", s, err)
+		}
+	}
+	if !hasSeqnum || !hasRealtime {
+		return nil, fmt.Errorf("invalid journal cursor %q", s)
+	}
+	return c, nil
+}
+
+// before returns whether the cursor comes before the entry. The sequence numbers are only
+// comparable within the same sequence number id, the realtime is compared otherwise.
+func (c *Cursor) before(e *Entry) bool {
+	if c.SeqnumID == e.SeqnumID {
+		return e.Seqnum > c.Seqnum
+	}
+	return e.Realtime > c.Realtime
+}
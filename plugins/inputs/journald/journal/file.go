@@ -0,0 +1,354 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// The layout of the journal files is described in https://systemd.io/JOURNAL_FILE_FORMAT/
+const (
+	Signature = "LPKSHHRH"
+
+	// IncompatibleCompressedXZ and the following are the incompatible flags of the header.
+	IncompatibleCompressedXZ   = 1 << 0
+	IncompatibleCompressedLZ4  = 1 << 1
+	IncompatibleKeyedHash      = 1 << 2
+	IncompatibleCompressedZSTD = 1 << 3
+	IncompatibleCompact        = 1 << 4
+	incompatibleSupported      = IncompatibleCompressedXZ | IncompatibleCompressedLZ4 | IncompatibleKeyedHash | IncompatibleCompressedZSTD | IncompatibleCompact
+
+	// ObjectData and the following are the types of the objects.
+	ObjectData       = 1
+	ObjectEntry      = 3
+	ObjectEntryArray = 6
+
+	// ObjectCompressedXZ and the following are the compression flags of the data objects.
+	ObjectCompressedXZ   = 1 << 0
+	ObjectCompressedLZ4  = 1 << 1
+	ObjectCompressedZSTD = 1 << 2
+
+	// HeaderSize is the size of the header written by the current versions of systemd. Older
+	// versions write a shorter header, which holds at least the fields up to tail_entry_monotonic.
+	HeaderSize    = 264
+	minHeaderSize = 208
+
+	ObjectHeaderSize = 16
+	// EntryItemsOffset is the offset of the items of an entry object.
+	EntryItemsOffset = ObjectHeaderSize + 48
+	// EntryArrayItemsOffset is the offset of the items of an entry array object.
+	EntryArrayItemsOffset = ObjectHeaderSize + 8
+	// DataPayloadOffset is the offset of the payload of a data object, followed by 8 more bytes in
+	// compact files.
+	DataPayloadOffset = ObjectHeaderSize + 48
+
+	// maxObjectSize guards against reading a corrupted size.
+	maxObjectSize = 64 << 20
+)
+
+// The offsets of the header fields.
+const (
+	headerIncompatibleFlags = 12
+	headerFileID            = 24
+	headerSeqnumID          = 72
+	headerHeaderSize        = 88
+	headerNEntries          = 152
+	headerTailEntrySeqnum   = 160
+	headerEntryArrayOffset  = 176
+)
+
+var errNotJournal = errors.New("not a journal file")
+
+// ID is a 128 bit systemd id, formatted as 32 lowercase hexadecimal digits.
+type ID [16]byte
+
+func (id ID) String() string {
+	return fmt.Sprintf("%x", id[:])
+}
+
+type header struct {
+	incompatibleFlags uint32
+	fileID            ID
+	seqnumID          ID
+	nEntries          uint64
+	tailEntrySeqnum   uint64
+	entryArrayOffset  uint64
+}
+
+// file reads the entries of a journal file in the order they were written, following the chain of
+// entry arrays. Journal files are only appended to while they are online, so the entries linked
+// after the last read are returned by the next calls to nextEntryOffset.
+type file struct {
+	path string
+	f    *os.File
+	hdr  header
+
+	// read is the number of entries read, arrayOffset and arrayIndex the position of the next one.
+	read        uint64
+	arrayOffset uint64
+	arrayIndex  uint64
+	arraySize   uint64
+
+	decoder *zstd.Decoder
+}
+
+func openFile(path string) (*file, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	jf := &file{path: path, f: f}
+	if err = jf.readHeader(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return jf, nil
+}
+
+func (jf *file) close() {
+	if jf.decoder != nil {
+		jf.decoder.Close()
+	}
+	jf.f.Close()
+}
+
+func (jf *file) compact() bool {
+	return jf.hdr.incompatibleFlags&IncompatibleCompact != 0
+}
+
+func (jf *file) readHeader() error {
+	b := make([]byte, HeaderSize)
+	n, err := jf.f.ReadAt(b, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if n < minHeaderSize || string(b[:len(Signature)]) != Signature {
+		return errNotJournal
+	}
+	if size := binary.LittleEndian.Uint64(b[headerHeaderSize:]); size < minHeaderSize {
+		return fmt.Errorf("journal header size %d is too small", size)
+	}
+	hdr := header{
+		incompatibleFlags: binary.LittleEndian.Uint32(b[headerIncompatibleFlags:]),
+		nEntries:          binary.LittleEndian.Uint64(b[headerNEntries:]),
+		tailEntrySeqnum:   binary.LittleEndian.Uint64(b[headerTailEntrySeqnum:]),
+		entryArrayOffset:  binary.LittleEndian.Uint64(b[headerEntryArrayOffset:]),
+	}
+	copy(hdr.fileID[:], b[headerFileID:])
+	copy(hdr.seqnumID[:], b[headerSeqnumID:])
+	if unsupported := hdr.incompatibleFlags &^ incompatibleSupported; unsupported != 0 {
+		return fmt.Errorf("unsupported journal incompatible flags %#x", unsupported)
+	}
+	jf.hdr = hdr
+	return nil
+}
+
+// readObject reads the object at offset, checking its type.
+func (jf *file) readObject(offset uint64, objectType byte) ([]byte, error) {
+	if offset == 0 || offset%8 != 0 {
+		return nil, fmt.Errorf("invalid object offset %d", offset)
+	}
+	h := make([]byte, ObjectHeaderSize)
+	if _, err := jf.f.ReadAt(h, int64(offset)); err != nil {
+		return nil, err
+	}
+	if h[0] != objectType {
+		return nil, fmt.Errorf("object at offset %d has type %d instead of %d", offset, h[0], objectType)
+	}
+	size := binary.LittleEndian.Uint64(h[8:])
+	if size < ObjectHeaderSize || size > maxObjectSize {
+		return nil, fmt.Errorf("object at offset %d has invalid size %d", offset, size)
+	}
+	b := make([]byte, size)
+	copy(b, h)
+	if _, err := jf.f.ReadAt(b[ObjectHeaderSize:], int64(offset)+ObjectHeaderSize); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// nextEntryOffset returns the offset of the next entry, or 0 if all the linked entries were read.
+func (jf *file) nextEntryOffset() (uint64, error) {
+	if jf.read >= jf.hdr.nEntries {
+		if err := jf.readHeader(); err != nil {
+			return 0, err
+		}
+		if jf.read >= jf.hdr.nEntries {
+			return 0, nil
+		}
+	}
+	itemSize := uint64(8)
+	if jf.compact() {
+		itemSize = 4
+	}
+	if jf.arrayOffset == 0 {
+		if jf.hdr.entryArrayOffset == 0 {
+			return 0, nil
+		}
+		if err := jf.loadArray(jf.hdr.entryArrayOffset, itemSize); err != nil {
+			return 0, err
+		}
+	}
+	if jf.arrayIndex >= jf.arraySize {
+		b := make([]byte, 8)
+		if _, err := jf.f.ReadAt(b, int64(jf.arrayOffset)+ObjectHeaderSize); err != nil {
+			return 0, err
+		}
+		next := binary.LittleEndian.Uint64(b)
+		if next == 0 {
+			return 0, nil
+		}
+		if err := jf.loadArray(next, itemSize); err != nil {
+			return 0, err
+		}
+	}
+	b := make([]byte, itemSize)
+	if _, err := jf.f.ReadAt(b, int64(jf.arrayOffset+EntryArrayItemsOffset+jf.arrayIndex*itemSize)); err != nil {
+		return 0, err
+	}
+	var offset uint64
+	if itemSize == 4 {
+		offset = uint64(binary.LittleEndian.Uint32(b))
+	} else {
+		offset = binary.LittleEndian.Uint64(b)
+	}
+	if offset == 0 {
+		// not linked yet
+		return 0, nil
+	}
+	jf.arrayIndex++
+	jf.read++
+	return offset, nil
+}
+
+func (jf *file) loadArray(offset, itemSize uint64) error {
+	h := make([]byte, ObjectHeaderSize)
+	if _, err := jf.f.ReadAt(h, int64(offset)); err != nil {
+		return err
+	}
+	if h[0] != ObjectEntryArray {
+		return fmt.Errorf("object at offset %d is not an entry array", offset)
+	}
+	size := binary.LittleEndian.Uint64(h[8:])
+	if size < EntryArrayItemsOffset || size > maxObjectSize {
+		return fmt.Errorf("entry array at offset %d has invalid size %d", offset, size)
+	}
+	jf.arrayOffset = offset
+	jf.arrayIndex = 0
+	jf.arraySize = (size - EntryArrayItemsOffset) / itemSize
+	return nil
+}
+
+// skipAll moves past all the entries linked so far without reading them.
+func (jf *file) skipAll() error {
+	for {
+		offset, err := jf.nextEntryOffset()
+		if err != nil || offset == 0 {
+			return err
+		}
+	}
+}
+
+// readEntry reads the entry at offset. The fields are only read if withFields is set.
+func (jf *file) readEntry(offset uint64, withFields bool) (*Entry, error) {
+	b, err := jf.readObject(offset, ObjectEntry)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < EntryItemsOffset {
+		return nil, fmt.Errorf("entry at offset %d is too small", offset)
+	}
+	e := &Entry{
+		SeqnumID:  jf.hdr.seqnumID,
+		Seqnum:    binary.LittleEndian.Uint64(b[16:]),
+		Realtime:  binary.LittleEndian.Uint64(b[24:]),
+		Monotonic: binary.LittleEndian.Uint64(b[32:]),
+		XorHash:   binary.LittleEndian.Uint64(b[56:]),
+	}
+	copy(e.BootID[:], b[40:56])
+	if !withFields {
+		return e, nil
+	}
+	items := b[EntryItemsOffset:]
+	itemSize := 16
+	if jf.compact() {
+		itemSize = 4
+	}
+	e.Fields = make(map[string][][]byte, len(items)/itemSize)
+	for i := 0; i+itemSize <= len(items); i += itemSize {
+		var dataOffset uint64
+		if itemSize == 4 {
+			dataOffset = uint64(binary.LittleEndian.Uint32(items[i:]))
+		} else {
+			dataOffset = binary.LittleEndian.Uint64(items[i:])
+		}
+		payload, err := jf.readData(dataOffset)
+		if err != nil {
+			return nil, err
+		}
+		name, value, ok := bytes.Cut(payload, []byte("="))
+		if !ok {
+			continue
+		}
+		e.Fields[string(name)] = append(e.Fields[string(name)], value)
+	}
+	return e, nil
+}
+
+// readData returns the decompressed payload of the data object at offset.
+func (jf *file) readData(offset uint64) ([]byte, error) {
+	b, err := jf.readObject(offset, ObjectData)
+	if err != nil {
+		return nil, err
+	}
+	payloadOffset := DataPayloadOffset
+	if jf.compact() {
+		payloadOffset += 8
+	}
+	if len(b) < payloadOffset {
+		return nil, fmt.Errorf("data at offset %d is too small", offset)
+	}
+	payload := b[payloadOffset:]
+	switch flags := b[1]; {
+	case flags&ObjectCompressedZSTD != 0:
+		if jf.decoder == nil {
+			if jf.decoder, err = zstd.NewReader(nil); err != nil {
+				return nil, err
+			}
+		}
+		return jf.decoder.DecodeAll(payload, nil)
+	case flags&ObjectCompressedLZ4 != 0:
+		// the payload starts with the decompressed size
+		if len(payload) < 8 {
+			return nil, fmt.Errorf("lz4 data at offset %d is too small", offset)
+		}
+		size := binary.LittleEndian.Uint64(payload)
+		if size > maxObjectSize {
+			return nil, fmt.Errorf("lz4 data at offset %d has invalid size %d", offset, size)
+		}
+		dst := make([]byte, size)
+		n, err := lz4.UncompressBlock(payload[8:], dst)
+		if err != nil {
+			return nil, err
+		}
+		return dst[:n], nil
+	case flags&ObjectCompressedXZ != 0:
+		r, err := xz.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(io.LimitReader(r, maxObjectSize))
+	default:
+		return payload, nil
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+// Package journal reads the binary files of the systemd journal without linking libsystemd.
+package journal
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// DefaultDirectories are the directories of the persistent and the volatile journal.
+var DefaultDirectories = []string{"/var/log/journal", "/run/log/journal"}
+
+var fieldNameRegex = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+type journalFile struct {
+	*file
+	info os.FileInfo
+	// head is the next entry of the file, read ahead to order the entries of all the files.
+	head *Entry
+	// removed is set once the file is no longer in the directories, it is closed once read.
+	removed bool
+}
+
+// Journal reads the entries of the journal files in a set of directories, ordered by time. Like
+// sd_journal, the entries only match if they match one of the values of each of the fields with
+// matches. Journal is not safe for concurrent use.
+type Journal struct {
+	dirs  []string
+	files map[ID]*journalFile
+	// warned holds the files which could not be opened, which are retried silently, and dropped
+	// the files which could not be read, which are not opened again.
+	warned  map[string]bool
+	dropped map[ID]bool
+	matches map[string][][]byte
+	cursor  *Cursor
+}
+
+// Open opens the journal files in the directories and in their subdirectories, which are named
+// after the machine id.
+func Open(dirs []string) *Journal {
+	if len(dirs) == 0 {
+		dirs = DefaultDirectories
+	}
+	j := &Journal{
+		dirs:    dirs,
+		files:   make(map[ID]*journalFile),
+		warned:  make(map[string]bool),
+		dropped: make(map[ID]bool),
+		matches: make(map[string][][]byte),
+	}
+	j.Refresh()
+	return j
+}
+
+// AddMatch adds a FIELD=value match.
+func (j *Journal) AddMatch(match string) error {
+	name, value, ok := bytes.Cut([]byte(match), []byte("="))
+	if !ok || !fieldNameRegex.Match(name) {
+		return fmt.Errorf("invalid journal match %q, it must be FIELD=value", match)
+	}
+	j.matches[string(name)] = append(j.matches[string(name)], value)
+	return nil
+}
+
+// SeekCursor skips the entries up to the cursor.
+func (j *Journal) SeekCursor(cursor string) error {
+	c, err := ParseCursor(cursor)
+	if err != nil {
+		return err
+	}
+	j.cursor = c
+	for _, jf := range j.files {
+		j.skipBeforeCursor(jf)
+	}
+	return nil
+}
+
+// SeekTail skips the entries written so far.
+func (j *Journal) SeekTail() {
+	j.cursor = nil
+	for id, jf := range j.files {
+		jf.head = nil
+		if err := jf.skipAll(); err != nil {
+			j.drop(id, err)
+		}
+	}
+}
+
+// Refresh opens the journal files created since the last refresh. The files which were removed
+// are closed once their entries are read.
+func (j *Journal) Refresh() {
+	seen := make(map[ID]bool)
+	for _, path := range j.list() {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if id, ok := j.findFile(info); ok {
+			// the file is renamed when it is archived
+			j.files[id].path = path
+			seen[id] = true
+			continue
+		}
+		f, err := openFile(path)
+		if err != nil {
+			// the header of a new file may not be written yet
+			if !j.warned[path] {
+				log.Printf("W! [journald] Unable to open journal file: %v", err)
+				j.warned[path] = true
+			}
+			continue
+		}
+		delete(j.warned, path)
+		seen[f.hdr.fileID] = true
+		if j.dropped[f.hdr.fileID] {
+			f.close()
+			continue
+		}
+		if _, ok := j.files[f.hdr.fileID]; ok {
+			// a copy of an open file
+			f.close()
+			continue
+		}
+		jf := &journalFile{file: f, info: info}
+		j.files[f.hdr.fileID] = jf
+		j.skipBeforeCursor(jf)
+	}
+	for id, jf := range j.files {
+		jf.removed = !seen[id]
+	}
+}
+
+// Next returns the next entry matching the matches, or nil if none was written yet.
+func (j *Journal) Next() *Entry {
+	var next *journalFile
+	for id, jf := range j.files {
+		if jf.head == nil {
+			j.readHead(id, jf)
+		}
+		if jf.head == nil {
+			if jf.removed {
+				jf.close()
+				delete(j.files, id)
+			}
+			continue
+		}
+		if next == nil || less(jf.head, next.head) {
+			next = jf
+		}
+	}
+	if next == nil {
+		return nil
+	}
+	e := next.head
+	next.head = nil
+	return e
+}
+
+// Close closes the journal files.
+func (j *Journal) Close() {
+	for id, jf := range j.files {
+		jf.close()
+		delete(j.files, id)
+	}
+}
+
+func (j *Journal) list() []string {
+	var paths []string
+	for _, dir := range j.dirs {
+		for _, pattern := range []string{"*.journal", "*/*.journal"} {
+			matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+			paths = append(paths, matches...)
+		}
+	}
+	return paths
+}
+
+func (j *Journal) findFile(info os.FileInfo) (ID, bool) {
+	for id, jf := range j.files {
+		if os.SameFile(jf.info, info) {
+			return id, true
+		}
+	}
+	return ID{}, false
+}
+
+// readHead reads the next entry of the file after the cursor and matching the matches.
+func (j *Journal) readHead(id ID, jf *journalFile) {
+	for {
+		offset, err := jf.nextEntryOffset()
+		if err != nil {
+			j.drop(id, err)
+			return
+		}
+		if offset == 0 {
+			return
+		}
+		if j.cursor != nil {
+			e, err := jf.readEntry(offset, false)
+			if err != nil {
+				log.Printf("W! [journald] Skipping unreadable entry of journal file %s: %v", jf.path, err)
+				continue
+			}
+			if !j.cursor.before(e) {
+				continue
+			}
+		}
+		e, err := jf.readEntry(offset, true)
+		if err != nil {
+			log.Printf("W! [journald] Skipping unreadable entry of journal file %s: %v", jf.path, err)
+			continue
+		}
+		if j.match(e) {
+			jf.head = e
+			return
+		}
+	}
+}
+
+// skipBeforeCursor skips the whole file if its last entry is before the cursor.
+func (j *Journal) skipBeforeCursor(jf *journalFile) {
+	if j.cursor == nil || j.cursor.SeqnumID != jf.hdr.seqnumID || jf.hdr.tailEntrySeqnum > j.cursor.Seqnum {
+		return
+	}
+	if err := jf.skipAll(); err != nil {
+		j.drop(jf.hdr.fileID, err)
+	}
+}
+
+func (j *Journal) drop(id ID, err error) {
+	jf := j.files[id]
+	log.Printf("W! [journald] Stopped reading journal file %s: %v", jf.path, err)
+	j.dropped[id] = true
+	jf.close()
+	delete(j.files, id)
+}
+
+func (j *Journal) match(e *Entry) bool {
+	for name, values := range j.matches {
+		matched := false
+		for _, v := range e.Fields[name] {
+			for _, want := range values {
+				if bytes.Equal(v, want) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func less(a, b *Entry) bool {
+	if a.Realtime != b.Realtime {
+		return a.Realtime < b.Realtime
+	}
+	return a.Seqnum < b.Seqnum
+}
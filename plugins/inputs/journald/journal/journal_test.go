@@ -0,0 +1,205 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journal_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/journald/journal"
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/journald/journal/journaltest"
+)
+
+var start = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func writeEntries(t *testing.T, w *journaltest.Writer, from, to int, unit string) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		require.NoError(t, w.Write(start.Add(time.Duration(i)*time.Second),
+			fmt.Sprintf("MESSAGE=message %d", i),
+			"_SYSTEMD_UNIT="+unit,
+			fmt.Sprintf("PRIORITY=%d", i%8),
+		))
+	}
+}
+
+func messages(j *journal.Journal) []string {
+	var res []string
+	for e := j.Next(); e != nil; e = j.Next() {
+		msg, _ := e.Value("MESSAGE")
+		res = append(res, msg)
+	}
+	return res
+}
+
+func TestRead(t *testing.T) {
+	for name, opts := range map[string]journaltest.Options{
+		"Regular":     {},
+		"Compact":     {Compact: true},
+		"ZSTD":        {Compact: true, Compression: journal.ObjectCompressedZSTD},
+		"LZ4":         {Compression: journal.ObjectCompressedLZ4},
+		"XZ":          {Compression: journal.ObjectCompressedXZ},
+		"LargeArrays": {ArrayCapacity: 1000},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			w, err := journaltest.NewWriter(filepath.Join(dir, "system.journal"), opts)
+			require.NoError(t, err)
+			defer w.Close()
+			writeEntries(t, w, 0, 10, "sshd.service")
+			require.NoError(t, w.Write(start, "MESSAGE="+strings.Repeat("long ", 100), "MESSAGE=again"))
+
+			j := journal.Open([]string{dir})
+			defer j.Close()
+			e := j.Next()
+			require.NotNil(t, e)
+			assert.Equal(t, uint64(1), e.Seqnum)
+			assert.Equal(t, start, e.Time().UTC())
+			assert.Equal(t, [][]byte{[]byte("sshd.service")}, e.Fields["_SYSTEMD_UNIT"])
+			var got []string
+			for i := 0; i < 9; i++ {
+				msg, _ := j.Next().Value("MESSAGE")
+				got = append(got, msg)
+			}
+			assert.Equal(t, "message 1", got[0])
+			assert.Equal(t, "message 9", got[8])
+			e = j.Next()
+			require.NotNil(t, e)
+			assert.Equal(t, [][]byte{[]byte(strings.Repeat("long ", 100)), []byte("again")}, e.Fields["MESSAGE"])
+
+			// the entries written since are read on the next calls
+			assert.Nil(t, j.Next())
+			writeEntries(t, w, 10, 15, "sshd.service")
+			assert.Equal(t, []string{"message 10", "message 11", "message 12", "message 13", "message 14"}, messages(j))
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	dir := t.TempDir()
+	w, err := journaltest.NewWriter(filepath.Join(dir, "system.journal"), journaltest.Options{})
+	require.NoError(t, err)
+	defer w.Close()
+	writeEntries(t, w, 0, 4, "sshd.service")
+	writeEntries(t, w, 4, 8, "cron.service")
+	writeEntries(t, w, 8, 12, "nginx.service")
+
+	j := journal.Open([]string{dir})
+	defer j.Close()
+	// the values of the same field are OR'ed, the fields are AND'ed
+	require.NoError(t, j.AddMatch("_SYSTEMD_UNIT=sshd.service"))
+	require.NoError(t, j.AddMatch("_SYSTEMD_UNIT=nginx.service"))
+	for _, p := range []string{"0", "1", "2", "3"} {
+		require.NoError(t, j.AddMatch("PRIORITY="+p))
+	}
+	assert.Equal(t, []string{"message 0", "message 1", "message 2", "message 3", "message 8", "message 9", "message 10", "message 11"}, messages(j))
+
+	assert.Error(t, j.AddMatch("MESSAGE"))
+	assert.Error(t, j.AddMatch("message=a"))
+}
+
+func TestSeek(t *testing.T) {
+	dir := t.TempDir()
+	w, err := journaltest.NewWriter(filepath.Join(dir, "system.journal"), journaltest.Options{})
+	require.NoError(t, err)
+	defer w.Close()
+	writeEntries(t, w, 0, 10, "sshd.service")
+
+	j := journal.Open([]string{dir})
+	var cursor string
+	for i := 0; i < 6; i++ {
+		cursor = j.Next().Cursor()
+	}
+	j.Close()
+
+	j = journal.Open([]string{dir})
+	require.NoError(t, j.SeekCursor(cursor))
+	assert.Equal(t, []string{"message 6", "message 7", "message 8", "message 9"}, messages(j))
+	j.Close()
+
+	j = journal.Open([]string{dir})
+	defer j.Close()
+	j.SeekTail()
+	assert.Nil(t, j.Next())
+	writeEntries(t, w, 10, 11, "sshd.service")
+	assert.Equal(t, []string{"message 10"}, messages(j))
+
+	assert.Error(t, j.SeekCursor("s=1234"))
+}
+
+func TestRotation(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "0123456789abcdef0123456789abcdef")
+	require.NoError(t, os.Mkdir(dir, 0755))
+	system := filepath.Join(dir, "system.journal")
+	w, err := journaltest.NewWriter(system, journaltest.Options{})
+	require.NoError(t, err)
+	writeEntries(t, w, 0, 5, "sshd.service")
+
+	j := journal.Open([]string{filepath.Dir(dir)})
+	defer j.Close()
+	assert.Len(t, messages(j), 5)
+	var cursor string
+	writeEntries(t, w, 5, 7, "sshd.service")
+	for e := j.Next(); e != nil; e = j.Next() {
+		cursor = e.Cursor()
+	}
+
+	// the file is archived and a new one continues the sequence numbers
+	writeEntries(t, w, 7, 8, "sshd.service")
+	require.NoError(t, w.Close())
+	require.NoError(t, os.Rename(system, filepath.Join(dir, "system@0001-0002.journal")))
+	next, err := journaltest.NewWriter(system, journaltest.Options{SeqnumID: w.SeqnumID(), Seqnum: w.Seqnum()})
+	require.NoError(t, err)
+	defer next.Close()
+	writeEntries(t, next, 8, 10, "sshd.service")
+	user, err := journaltest.NewWriter(filepath.Join(dir, "user-1000.journal"), journaltest.Options{SeqnumID: w.SeqnumID(), Seqnum: 100})
+	require.NoError(t, err)
+	defer user.Close()
+	require.NoError(t, user.Write(start.Add(8500*time.Millisecond), "MESSAGE=user"))
+
+	j.Refresh()
+	assert.Equal(t, []string{"message 7", "message 8", "user", "message 9"}, messages(j))
+
+	// a new reader seeking the cursor skips the archived entries
+	j2 := journal.Open([]string{filepath.Dir(dir)})
+	defer j2.Close()
+	require.NoError(t, j2.SeekCursor(cursor))
+	assert.Equal(t, []string{"message 7", "message 8", "user", "message 9"}, messages(j2))
+}
+
+func TestInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.journal"), []byte("not a journal"), 0644))
+	w, err := journaltest.NewWriter(filepath.Join(dir, "system.journal"), journaltest.Options{})
+	require.NoError(t, err)
+	defer w.Close()
+	writeEntries(t, w, 0, 2, "sshd.service")
+
+	j := journal.Open([]string{dir})
+	defer j.Close()
+	assert.Equal(t, []string{"message 0", "message 1"}, messages(j))
+}
+
+func TestCursor(t *testing.T) {
+	e := &journal.Entry{Seqnum: 0x2a, Realtime: 0x5f1e, Monotonic: 7, XorHash: 9}
+	e.SeqnumID[0] = 0xab
+	c, err := journal.ParseCursor(e.Cursor())
+	require.NoError(t, err)
+	assert.Equal(t, e.SeqnumID, c.SeqnumID)
+	assert.Equal(t, e.Seqnum, c.Seqnum)
+	assert.Equal(t, e.Realtime, c.Realtime)
+	assert.Equal(t, "s=ab000000000000000000000000000000;i=2a;b=00000000000000000000000000000000;m=7;t=5f1e;x=9", e.Cursor())
+
+	for _, s := range []string{"", "i=1", "s=zz;i=1;t=1", "s=ab;i=1;t=1", "i=x;t=1"} {
+		_, err = journal.ParseCursor(s)
+		assert.Error(t, err, s)
+	}
+}
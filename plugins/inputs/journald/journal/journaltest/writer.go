@@ -0,0 +1,287 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+// Package journaltest writes journal files for the tests of the journal readers.
+package journaltest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/journald/journal"
+)
+
+const defaultArrayCapacity = 4
+
+// Options configures the layout of the written file.
+type Options struct {
+	// SeqnumID is shared by the files of the same journal, it is random when unset.
+	SeqnumID journal.ID
+	// Seqnum is the sequence number of the first entry, 1 when unset.
+	Seqnum uint64
+	// Compact writes the file in the compact mode of systemd 252.
+	Compact bool
+	// Compression is the compression flag of the data objects, if any.
+	Compression byte
+	// ArrayCapacity is the number of entries of each entry array, small to test the chaining.
+	ArrayCapacity int
+}
+
+// Writer appends entries to a journal file the way journald does, linking each entry into the
+// chain of entry arrays once it is written. The files have no hash tables, so they can't be read
+// by journalctl.
+type Writer struct {
+	f    *os.File
+	opts Options
+
+	fileID  journal.ID
+	bootID  journal.ID
+	seqnum  uint64
+	end     uint64
+	entries uint64
+
+	entryArrayOffset uint64
+	arrayOffset      uint64
+	arrayCount       int
+	tailSeqnum       uint64
+	headRealtime     uint64
+	tailRealtime     uint64
+}
+
+// NewWriter creates the journal file at path.
+func NewWriter(path string, opts Options) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.SeqnumID == (journal.ID{}) {
+		opts.SeqnumID = randomID()
+	}
+	if opts.Seqnum == 0 {
+		opts.Seqnum = 1
+	}
+	if opts.ArrayCapacity == 0 {
+		opts.ArrayCapacity = defaultArrayCapacity
+	}
+	w := &Writer{
+		f:      f,
+		opts:   opts,
+		fileID: randomID(),
+		bootID: randomID(),
+		seqnum: opts.Seqnum,
+		end:    journal.HeaderSize,
+	}
+	if err = w.writeHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// SeqnumID returns the sequence number id of the file.
+func (w *Writer) SeqnumID() journal.ID {
+	return w.opts.SeqnumID
+}
+
+// Seqnum returns the sequence number of the next entry.
+func (w *Writer) Seqnum() uint64 {
+	return w.seqnum
+}
+
+// Write appends an entry with the FIELD=value fields.
+func (w *Writer) Write(t time.Time, fields ...string) error {
+	itemSize := 16
+	if w.opts.Compact {
+		itemSize = 4
+	}
+	items := make([]byte, len(fields)*itemSize)
+	var xorHash uint64
+	for i, field := range fields {
+		if !strings.Contains(field, "=") {
+			return fmt.Errorf("field %q is not FIELD=value", field)
+		}
+		offset, err := w.writeData([]byte(field))
+		if err != nil {
+			return err
+		}
+		if w.opts.Compact {
+			binary.LittleEndian.PutUint32(items[i*itemSize:], uint32(offset))
+		} else {
+			binary.LittleEndian.PutUint64(items[i*itemSize:], offset)
+		}
+		xorHash ^= offset
+	}
+
+	realtime := uint64(t.UnixMicro())
+	b := make([]byte, journal.EntryItemsOffset-journal.ObjectHeaderSize, journal.EntryItemsOffset-journal.ObjectHeaderSize+len(items))
+	binary.LittleEndian.PutUint64(b[0:], w.seqnum)
+	binary.LittleEndian.PutUint64(b[8:], realtime)
+	binary.LittleEndian.PutUint64(b[16:], w.seqnum*1000)
+	copy(b[24:40], w.bootID[:])
+	binary.LittleEndian.PutUint64(b[40:], xorHash)
+	entryOffset, err := w.writeObject(journal.ObjectEntry, 0, append(b, items...))
+	if err != nil {
+		return err
+	}
+	if err = w.link(entryOffset); err != nil {
+		return err
+	}
+	if w.entries == 0 {
+		w.headRealtime = realtime
+	}
+	w.entries++
+	w.tailSeqnum = w.seqnum
+	w.tailRealtime = realtime
+	w.seqnum++
+	return w.writeHeader()
+}
+
+// Close closes the file.
+func (w *Writer) Close() error {
+	return w.f.Close()
+}
+
+func (w *Writer) writeData(payload []byte) (uint64, error) {
+	var flags byte
+	switch w.opts.Compression {
+	case journal.ObjectCompressedZSTD:
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return 0, err
+		}
+		payload = enc.EncodeAll(payload, nil)
+		enc.Close()
+		flags = journal.ObjectCompressedZSTD
+	case journal.ObjectCompressedXZ:
+		var buf bytes.Buffer
+		enc, err := xz.NewWriter(&buf)
+		if err != nil {
+			return 0, err
+		}
+		if _, err = enc.Write(payload); err != nil {
+			return 0, err
+		}
+		if err = enc.Close(); err != nil {
+			return 0, err
+		}
+		payload = buf.Bytes()
+		flags = journal.ObjectCompressedXZ
+	case journal.ObjectCompressedLZ4:
+		dst := make([]byte, 8+lz4.CompressBlockBound(len(payload)))
+		binary.LittleEndian.PutUint64(dst, uint64(len(payload)))
+		n, err := lz4.CompressBlock(payload, dst[8:], nil)
+		if err != nil {
+			return 0, err
+		}
+		// incompressible payloads are stored uncompressed
+		if n > 0 {
+			payload = dst[:8+n]
+			flags = journal.ObjectCompressedLZ4
+		}
+	}
+	// the hash, the links and the entry fields of the data objects are not used by the readers
+	size := journal.DataPayloadOffset - journal.ObjectHeaderSize
+	if w.opts.Compact {
+		size += 8
+	}
+	return w.writeObject(journal.ObjectData, flags, append(make([]byte, size), payload...))
+}
+
+// link adds the entry to the last entry array, chaining a new one once it is full.
+func (w *Writer) link(entryOffset uint64) error {
+	itemSize := 8
+	if w.opts.Compact {
+		itemSize = 4
+	}
+	if w.arrayOffset == 0 || w.arrayCount == w.opts.ArrayCapacity {
+		offset, err := w.writeObject(journal.ObjectEntryArray, 0, make([]byte, 8+w.opts.ArrayCapacity*itemSize))
+		if err != nil {
+			return err
+		}
+		if w.arrayOffset == 0 {
+			w.entryArrayOffset = offset
+		} else if err = w.writeUint64(w.arrayOffset+journal.ObjectHeaderSize, offset); err != nil {
+			return err
+		}
+		w.arrayOffset = offset
+		w.arrayCount = 0
+	}
+	b := make([]byte, itemSize)
+	if w.opts.Compact {
+		binary.LittleEndian.PutUint32(b, uint32(entryOffset))
+	} else {
+		binary.LittleEndian.PutUint64(b, entryOffset)
+	}
+	if _, err := w.f.WriteAt(b, int64(w.arrayOffset)+journal.EntryArrayItemsOffset+int64(w.arrayCount*itemSize)); err != nil {
+		return err
+	}
+	w.arrayCount++
+	return nil
+}
+
+func (w *Writer) writeObject(objectType, flags byte, payload []byte) (uint64, error) {
+	offset := w.end
+	size := journal.ObjectHeaderSize + len(payload)
+	b := make([]byte, journal.ObjectHeaderSize, size)
+	b[0] = objectType
+	b[1] = flags
+	binary.LittleEndian.PutUint64(b[8:], uint64(size))
+	if _, err := w.f.WriteAt(append(b, payload...), int64(offset)); err != nil {
+		return 0, err
+	}
+	w.end = offset + uint64(size+7)/8*8
+	return offset, nil
+}
+
+func (w *Writer) writeUint64(offset, v uint64) error {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	_, err := w.f.WriteAt(b, int64(offset))
+	return err
+}
+
+func (w *Writer) writeHeader() error {
+	b := make([]byte, journal.HeaderSize)
+	copy(b, journal.Signature)
+	var incompatible uint32
+	if w.opts.Compact {
+		incompatible |= journal.IncompatibleCompact
+	}
+	switch w.opts.Compression {
+	case journal.ObjectCompressedZSTD:
+		incompatible |= journal.IncompatibleCompressedZSTD
+	case journal.ObjectCompressedXZ:
+		incompatible |= journal.IncompatibleCompressedXZ
+	case journal.ObjectCompressedLZ4:
+		incompatible |= journal.IncompatibleCompressedLZ4
+	}
+	binary.LittleEndian.PutUint32(b[12:], incompatible)
+	copy(b[24:40], w.fileID[:])
+	copy(b[56:72], w.bootID[:])
+	copy(b[72:88], w.opts.SeqnumID[:])
+	binary.LittleEndian.PutUint64(b[88:], journal.HeaderSize)
+	binary.LittleEndian.PutUint64(b[96:], w.end-journal.HeaderSize)
+	binary.LittleEndian.PutUint64(b[152:], w.entries)
+	binary.LittleEndian.PutUint64(b[160:], w.tailSeqnum)
+	binary.LittleEndian.PutUint64(b[168:], w.opts.Seqnum)
+	binary.LittleEndian.PutUint64(b[176:], w.entryArrayOffset)
+	binary.LittleEndian.PutUint64(b[184:], w.headRealtime)
+	binary.LittleEndian.PutUint64(b[192:], w.tailRealtime)
+	_, err := w.f.WriteAt(b, 0)
+	return err
+}
+
+func randomID() journal.ID {
+	var id journal.ID
+	_, _ = rand.Read(id[:])
+	return id
+}
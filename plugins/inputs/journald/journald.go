@@ -0,0 +1,198 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journald
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/logscommon"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+// priorities maps the syslog priority names to the values of the PRIORITY field.
+var priorities = map[string]int{
+	"emerg":   0,
+	"alert":   1,
+	"crit":    2,
+	"err":     3,
+	"warning": 4,
+	"notice":  5,
+	"info":    6,
+	"debug":   7,
+}
+
+type JournalConfig struct {
+	// Units, Identifiers and Priority are shorthands for the matches on the _SYSTEMD_UNIT,
+	// SYSLOG_IDENTIFIER and PRIORITY fields.
+	Units       []string `toml:"units"`
+	Identifiers []string `toml:"identifiers"`
+	// Priority is the lowest priority collected, by name or value.
+	Priority string `toml:"priority"`
+	// Matches are FIELD=value journal matches, OR'ed for the same field and AND'ed otherwise.
+	Matches []string `toml:"matches"`
+	// Directories holds the journal files, the persistent and volatile journals when empty.
+	Directories []string `toml:"directories"`
+	// LogGroupName and LogStreamName may use the fields of the entries, e.g. {_SYSTEMD_UNIT}.
+	LogGroupName  string `toml:"log_group_name"`
+	LogStreamName string `toml:"log_stream_name"`
+	LogGroupClass string `toml:"log_group_class"`
+	Destination   string `toml:"destination"`
+	Retention     int    `toml:"retention_in_days"`
+	// MaxSources is the number of log groups and streams receiving entries after which the least
+	// recently used one is closed.
+	MaxSources int `toml:"max_sources"`
+}
+
+type Plugin struct {
+	FileStateFolder string          `toml:"file_state_folder"`
+	JournalConfig   []JournalConfig `toml:"journal_config"`
+	Destination     string          `toml:"destination"`
+	Log             telegraf.Logger `toml:"-"`
+
+	mu      sync.Mutex
+	started bool
+	readers []*journalReader
+	newSrcs []logs.LogSrc
+}
+
+func (p *Plugin) Description() string {
+	return "A plugin to collect the entries of the systemd journal"
+}
+
+func (p *Plugin) SampleConfig() string {
+	return `
+	file_state_folder = "/path/to/state/folder"
+
+	[[inputs.journald.journal_config]]
+	units = ["sshd.service", "nginx.service"]
+	priority = "warning"
+	log_group_name = "journal"
+	log_stream_name = "{_SYSTEMD_UNIT}"
+	destination = "cloudwatchlogs"
+	`
+}
+
+func (p *Plugin) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *Plugin) FindLogSrc() []logs.LogSrc {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	srcs := p.newSrcs
+	p.newSrcs = nil
+	return srcs
+}
+
+// addSrc is called by the readers for each new log group and stream rendered from the entries.
+func (p *Plugin) addSrc(src logs.LogSrc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.newSrcs = append(p.newSrcs, src)
+}
+
+func (p *Plugin) Start(acc telegraf.Accumulator) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return nil
+	}
+	for i := range p.JournalConfig {
+		jc := &p.JournalConfig[i]
+		matches, err := jc.matches()
+		if err != nil {
+			return err
+		}
+		stateFilePath, err := getStateFilePath(p, jc, matches)
+		if err != nil {
+			return err
+		}
+		destination := jc.Destination
+		if destination == "" {
+			destination = p.Destination
+		}
+		r, err := newJournalReader(jc, matches, destination, stateFilePath, p.addSrc)
+		if err != nil {
+			return err
+		}
+		p.readers = append(p.readers, r)
+	}
+	for _, r := range p.readers {
+		r.start()
+	}
+	p.started = true
+	return nil
+}
+
+func (p *Plugin) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.readers {
+		r.stop()
+	}
+	p.readers = nil
+}
+
+// matches returns the journal matches of the config.
+func (jc *JournalConfig) matches() ([]string, error) {
+	var matches []string
+	for _, unit := range jc.Units {
+		matches = append(matches, "_SYSTEMD_UNIT="+unit)
+	}
+	for _, identifier := range jc.Identifiers {
+		matches = append(matches, "SYSLOG_IDENTIFIER="+identifier)
+	}
+	if jc.Priority != "" {
+		priority, ok := priorities[jc.Priority]
+		if !ok {
+			var err error
+			if priority, err = strconv.Atoi(jc.Priority); err != nil || priority < 0 || priority > 7 {
+				return nil, fmt.Errorf("invalid journal priority %q", jc.Priority)
+			}
+		}
+		for i := 0; i <= priority; i++ {
+			matches = append(matches, "PRIORITY="+strconv.Itoa(i))
+		}
+	}
+	return append(matches, jc.Matches...), nil
+}
+
+// getStateFilePath returns a unique file pathname for a given JournalConfig.
+func getStateFilePath(p *Plugin, jc *JournalConfig, matches []string) (string, error) {
+	if p.FileStateFolder == "" {
+		return "", errors.New("empty FileStateFolder")
+	}
+	if err := os.MkdirAll(p.FileStateFolder, 0755); err != nil {
+		return "", err
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(matches, "\n")))
+	h.Write([]byte(strings.Join(jc.Directories, "\n")))
+	stateFileName := logscommon.JournaldPrefix +
+		escapeFileName(fmt.Sprintf("%s_%s_%x", jc.LogGroupName, jc.LogStreamName, h.Sum32()))
+	return filepath.Join(p.FileStateFolder, stateFileName), nil
+}
+
+// escapeFileName returns a valid filename string.
+func escapeFileName(filePath string) string {
+	escapedFilePath := filepath.ToSlash(filePath)
+	escapedFilePath = strings.Replace(escapedFilePath, "/", "_", -1)
+	escapedFilePath = strings.Replace(escapedFilePath, " ", "_", -1)
+	escapedFilePath = strings.Replace(escapedFilePath, ":", "_", -1)
+	return escapedFilePath
+}
+
+func init() {
+	inputs.Add("journald", func() telegraf.Input { return &Plugin{} })
+}
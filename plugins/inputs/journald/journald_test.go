@@ -0,0 +1,199 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journald
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/srcrouter"
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/journald/journal"
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/journald/journal/journaltest"
+)
+
+type testOutput struct {
+	mu     sync.Mutex
+	events map[string][]logs.LogEvent
+	srcs   []logs.LogSrc
+}
+
+func (o *testOutput) connect(src logs.LogSrc) {
+	key := src.Group() + "/" + src.Stream()
+	o.srcs = append(o.srcs, src)
+	src.SetOutput(func(e logs.LogEvent) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.events[key] = append(o.events[key], e)
+	})
+}
+
+func (o *testOutput) messages(key string) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var res []string
+	for _, e := range o.events[key] {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(e.Message()), &m); err == nil {
+			res = append(res, fmt.Sprint(m["MESSAGE"]))
+		}
+	}
+	return res
+}
+
+func (o *testOutput) done() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, events := range o.events {
+		for _, e := range events {
+			e.Done()
+		}
+	}
+}
+
+func newTestPlugin(dir, stateDir string) *Plugin {
+	return &Plugin{
+		FileStateFolder: stateDir,
+		Destination:     "cloudwatchlogs",
+		JournalConfig: []JournalConfig{{
+			Units:         []string{"sshd.service", "getty@tty1.service"},
+			Priority:      "warning",
+			Directories:   []string{dir},
+			LogGroupName:  "journal/{_SYSTEMD_UNIT}",
+			LogStreamName: "{_HOSTNAME}",
+			Retention:     7,
+		}},
+	}
+}
+
+// collect connects the sources found until the expected events are published.
+func collect(t *testing.T, p *Plugin, out *testOutput, key string, want []string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		for _, src := range p.FindLogSrc() {
+			out.connect(src)
+		}
+		return assert.ObjectsAreEqual(want, out.messages(key))
+	}, 5*time.Second, 10*time.Millisecond, "got %v", out.messages(key))
+}
+
+func TestJournald(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := t.TempDir()
+	w, err := journaltest.NewWriter(filepath.Join(dir, "system.journal"), journaltest.Options{Compact: true})
+	require.NoError(t, err)
+	defer w.Close()
+	now := time.Now()
+	// the entries written before the first start are skipped
+	require.NoError(t, w.Write(now, "MESSAGE=old", "_SYSTEMD_UNIT=sshd.service", "PRIORITY=3", "_HOSTNAME=host"))
+
+	p := newTestPlugin(dir, stateDir)
+	require.NoError(t, p.Start(nil))
+	require.NoError(t, w.Write(now, "MESSAGE=accepted", "_SYSTEMD_UNIT=sshd.service", "PRIORITY=4", "_HOSTNAME=host", "CODE_LINE=1"))
+	require.NoError(t, w.Write(now, "MESSAGE=debug", "_SYSTEMD_UNIT=sshd.service", "PRIORITY=7", "_HOSTNAME=host"))
+	require.NoError(t, w.Write(now, "MESSAGE=cron", "_SYSTEMD_UNIT=cron.service", "PRIORITY=3", "_HOSTNAME=host"))
+	require.NoError(t, w.Write(now, "MESSAGE=login", "_SYSTEMD_UNIT=getty@tty1.service", "PRIORITY=2"))
+
+	out := &testOutput{events: make(map[string][]logs.LogEvent)}
+	collect(t, p, out, "journal/sshd.service/host", []string{"accepted"})
+	collect(t, p, out, "journal/getty_tty1.service/unknown", []string{"login"})
+	assert.Len(t, out.events, 2)
+
+	e := out.events["journal/sshd.service/host"][0]
+	assert.Equal(t, now.UnixMicro(), e.Time().UnixMicro())
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(e.Message()), &m))
+	assert.Equal(t, "1", m["CODE_LINE"])
+	assert.Equal(t, fmt.Sprint(now.UnixMicro()), m["__REALTIME_TIMESTAMP"])
+	cursor, err := journal.ParseCursor(m["__CURSOR"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), cursor.Seqnum)
+
+	// the cursor of the entries done is saved
+	out.done()
+	require.NoError(t, w.Write(now, "MESSAGE=after stop", "_SYSTEMD_UNIT=sshd.service", "PRIORITY=4", "_HOSTNAME=host"))
+	collect(t, p, out, "journal/sshd.service/host", []string{"accepted", "after stop"})
+	p.Stop()
+	stateFiles, err := filepath.Glob(filepath.Join(stateDir, "Amazon_CloudWatch_Journald_*"))
+	require.NoError(t, err)
+	require.Len(t, stateFiles, 1)
+	state, err := os.ReadFile(stateFiles[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(state), ";i=5;")
+
+	// the entries after the saved cursor are read on the next start
+	p = newTestPlugin(dir, stateDir)
+	require.NoError(t, p.Start(nil))
+	defer p.Stop()
+	out = &testOutput{events: make(map[string][]logs.LogEvent)}
+	collect(t, p, out, "journal/sshd.service/host", []string{"after stop"})
+}
+
+func TestStoppedSource(t *testing.T) {
+	dir := t.TempDir()
+	w, err := journaltest.NewWriter(filepath.Join(dir, "system.journal"), journaltest.Options{})
+	require.NoError(t, err)
+	defer w.Close()
+
+	p := newTestPlugin(dir, t.TempDir())
+	require.NoError(t, p.Start(nil))
+	defer p.Stop()
+	now := time.Now()
+	require.NoError(t, w.Write(now, "MESSAGE=accepted", "_SYSTEMD_UNIT=sshd.service", "PRIORITY=4", "_HOSTNAME=host"))
+	out := &testOutput{events: make(map[string][]logs.LogEvent)}
+	collect(t, p, out, "journal/sshd.service/host", []string{"accepted"})
+	out.done()
+
+	// the entries published once the output of their source stopped are done, so that they do not
+	// hold the cursor
+	require.Len(t, out.srcs, 1)
+	out.srcs[0].Stop()
+	require.NoError(t, w.Write(now, "MESSAGE=dropped", "_SYSTEMD_UNIT=sshd.service", "PRIORITY=4", "_HOSTNAME=host"))
+	assert.Eventually(t, func() bool {
+		return strings.Contains(p.readers[0].cursor(), ";i=2;")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"accepted"}, out.messages("journal/sshd.service/host"))
+}
+
+func TestMatches(t *testing.T) {
+	jc := JournalConfig{Units: []string{"a.service"}, Identifiers: []string{"sudo"}, Priority: "2", Matches: []string{"_UID=0"}}
+	matches, err := jc.matches()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_SYSTEMD_UNIT=a.service", "SYSLOG_IDENTIFIER=sudo", "PRIORITY=0", "PRIORITY=1", "PRIORITY=2", "_UID=0"}, matches)
+
+	for _, priority := range []string{"8", "-1", "warn"} {
+		_, err = (&JournalConfig{Priority: priority}).matches()
+		assert.Error(t, err)
+	}
+	p := &Plugin{FileStateFolder: t.TempDir(), JournalConfig: []JournalConfig{{Matches: []string{"unit"}, Directories: []string{t.TempDir()}}}}
+	assert.Error(t, p.Start(nil))
+}
+
+func TestRender(t *testing.T) {
+	e := &journal.Entry{Fields: map[string][][]byte{
+		"_SYSTEMD_UNIT": {[]byte("user@1000.service")},
+		"_HOSTNAME":     {[]byte("ip-10-0-0-1:a*b")},
+	}}
+	assert.Equal(t, "/journal/user_1000.service/unknown", srcrouter.Render("/journal/{_SYSTEMD_UNIT}/{SYSLOG_IDENTIFIER}", e.Value, srcrouter.InvalidLogGroupChars))
+	assert.Equal(t, "ip-10-0-0-1_a_b {instance_id}", srcrouter.Render("{_HOSTNAME} {instance_id}", e.Value, srcrouter.InvalidLogStreamChars))
+}
+
+func TestMessage(t *testing.T) {
+	e := &journal.Entry{Realtime: 1, Monotonic: 2, Fields: map[string][][]byte{
+		"MESSAGE": {[]byte("a"), []byte("b")},
+		"BINARY":  {{0xff, 0x01}},
+	}}
+	msg, err := message(e)
+	require.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{"MESSAGE":["a","b"],"BINARY":[255,1],"__CURSOR":%q,"__REALTIME_TIMESTAMP":"1","__MONOTONIC_TIMESTAMP":"2"}`, e.Cursor()), msg)
+}
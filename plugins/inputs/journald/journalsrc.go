@@ -0,0 +1,262 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journald
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/acktracker"
+	"github.com/aws/amazon-cloudwatch-agent/logs/srcrouter"
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/journald/journal"
+)
+
+const (
+	pollInterval    = 250 * time.Millisecond
+	refreshInterval = time.Second
+)
+
+// journalReader reads the entries of a JournalConfig and publishes them to a source for each log
+// group and stream rendered from the entries. The cursor of the last entry published, with all
+// the entries before it, is saved in the state file.
+type journalReader struct {
+	cfg           *JournalConfig
+	description   string
+	stateFilePath string
+	j             *journal.Journal
+	router        *srcrouter.Router
+
+	acks           acktracker.Tracker[string]
+	lastAckWarning time.Time
+	cursorMu       sync.Mutex
+	committed      string
+	done           chan struct{}
+	wg             sync.WaitGroup
+}
+
+func newJournalReader(cfg *JournalConfig, matches []string, destination, stateFilePath string, addSrc func(logs.LogSrc)) (*journalReader, error) {
+	j := journal.Open(cfg.Directories)
+	for _, match := range matches {
+		if err := j.AddMatch(match); err != nil {
+			j.Close()
+			return nil, err
+		}
+	}
+	description := fmt.Sprintf("journald%v", matches)
+	r := &journalReader{
+		cfg:           cfg,
+		description:   description,
+		stateFilePath: stateFilePath,
+		j:             j,
+		router: srcrouter.New(srcrouter.Config{
+			Description: description,
+			Destination: destination,
+			Retention:   cfg.Retention,
+			Class:       cfg.LogGroupClass,
+			MaxSources:  cfg.MaxSources,
+		}, addSrc),
+		done: make(chan struct{}),
+	}
+	if cursor := r.loadState(); cursor != "" {
+		if err := j.SeekCursor(cursor); err != nil {
+			log.Printf("W! [journald] Unable to seek cursor from %s, reading new entries only: %v", stateFilePath, err)
+			j.SeekTail()
+		} else {
+			log.Printf("I! [journald] Reading from cursor %s in %s", cursor, stateFilePath)
+		}
+	} else {
+		j.SeekTail()
+	}
+	return r, nil
+}
+
+func (r *journalReader) start() {
+	r.wg.Add(1)
+	go r.runSaveState()
+	go r.run()
+}
+
+func (r *journalReader) run() {
+	defer r.j.Close()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	lastRefresh := time.Now()
+	for {
+		for e := r.j.Next(); e != nil; e = r.j.Next() {
+			if !r.publish(e) {
+				return
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-r.done:
+			return
+		}
+		if time.Since(lastRefresh) >= refreshInterval {
+			r.j.Refresh()
+			lastRefresh = time.Now()
+		}
+	}
+}
+
+// stop waits for the final state to be saved, but not for the entry being published.
+func (r *journalReader) stop() {
+	r.router.Stop()
+	close(r.done)
+	r.wg.Wait()
+}
+
+// publish returns false if the reader was stopped while waiting for the output of the source.
+func (r *journalReader) publish(e *journal.Entry) bool {
+	msg, err := message(e)
+	if err != nil {
+		log.Printf("W! [journald] Skipping entry %s: %v", e.Cursor(), err)
+		return true
+	}
+	if r.acks.Full() && time.Since(r.lastAckWarning) >= time.Minute {
+		log.Printf("W! [journald] More than %d entries of %s waiting for their acknowledgement, waiting for the oldest", acktracker.DefaultMaxPending, r.description)
+		r.lastAckWarning = time.Now()
+	}
+	id, ok := r.acks.Track(e.Cursor(), r.done)
+	if !ok {
+		return false
+	}
+	group := srcrouter.Render(r.cfg.LogGroupName, e.Value, srcrouter.InvalidLogGroupChars)
+	stream := srcrouter.Render(r.cfg.LogStreamName, e.Value, srcrouter.InvalidLogStreamChars)
+	return r.router.Publish(group, stream, &LogEvent{
+		msg:    msg,
+		t:      e.Time(),
+		reader: r,
+		ack:    id,
+	})
+}
+
+// message formats the entry as a JSON object like journalctl --output=json. The fields set more
+// than once are arrays of values, and the values which are not valid UTF-8 are arrays of bytes.
+func message(e *journal.Entry) (string, error) {
+	m := make(map[string]interface{}, len(e.Fields)+3)
+	for name, values := range e.Fields {
+		if len(values) == 1 {
+			m[name] = fieldValue(values[0])
+			continue
+		}
+		vs := make([]interface{}, len(values))
+		for i, v := range values {
+			vs[i] = fieldValue(v)
+		}
+		m[name] = vs
+	}
+	m["__CURSOR"] = e.Cursor()
+	m["__REALTIME_TIMESTAMP"] = strconv.FormatUint(e.Realtime, 10)
+	m["__MONOTONIC_TIMESTAMP"] = strconv.FormatUint(e.Monotonic, 10)
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+func fieldValue(v []byte) interface{} {
+	if utf8.Valid(v) {
+		return string(v)
+	}
+	bytes := make([]int, len(v))
+	for i, b := range v {
+		bytes[i] = int(b)
+	}
+	return bytes
+}
+
+func (r *journalReader) runSaveState() {
+	defer r.wg.Done()
+	t := time.NewTicker(100 * time.Millisecond)
+	defer t.Stop()
+
+	var lastSaved string
+	for {
+		select {
+		case <-t.C:
+			cursor := r.cursor()
+			if cursor == lastSaved {
+				continue
+			}
+			if err := r.saveState(cursor); err != nil {
+				log.Printf("E! [journald] Error happened when saving journal state %s to file state folder %s: %v", r.description, r.stateFilePath, err)
+				continue
+			}
+			lastSaved = cursor
+		case <-r.done:
+			if cursor := r.cursor(); cursor != lastSaved {
+				if err := r.saveState(cursor); err != nil {
+					log.Printf("E! [journald] Error happened during final journal state saving of %s to file state folder %s, duplicate log maybe sent at next start: %v", r.description, r.stateFilePath, err)
+				}
+			}
+			return
+		}
+	}
+}
+
+func (r *journalReader) saveState(cursor string) error {
+	if r.stateFilePath == "" || cursor == "" {
+		return nil
+	}
+	content := []byte(cursor + "\n" + r.cfg.LogGroupName)
+	return os.WriteFile(r.stateFilePath, content, 0644)
+}
+
+func (r *journalReader) loadState() string {
+	byteArray, err := os.ReadFile(r.stateFilePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("W! [journald] Issue encountered when reading cursor from file %s: %v", r.stateFilePath, err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(strings.Split(string(byteArray), "\n")[0])
+}
+
+// ack acknowledges the entry, the saved cursor only moving past the entries which are done along
+// with all the entries before them, whichever source they were published to.
+func (r *journalReader) ack(id uint64) {
+	if cursor, ok := r.acks.Ack(id); ok {
+		r.commit(cursor)
+	}
+}
+
+func (r *journalReader) commit(cursor string) {
+	r.cursorMu.Lock()
+	defer r.cursorMu.Unlock()
+	r.committed = cursor
+}
+
+// cursor returns the cursor of the last entry done with all the entries before it.
+func (r *journalReader) cursor() string {
+	r.cursorMu.Lock()
+	defer r.cursorMu.Unlock()
+	return r.committed
+}
+
+type LogEvent struct {
+	msg    string
+	t      time.Time
+	reader *journalReader
+	ack    uint64
+}
+
+func (le *LogEvent) Message() string {
+	return le.msg
+}
+
+func (le *LogEvent) Time() time.Time {
+	return le.t
+}
+
+func (le *LogEvent) Done() {
+	le.reader.ack(le.ack)
+}
@@ -0,0 +1,194 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/logfile/tail"
+)
+
+// archiveAckTimeout is how long a compressed file which was read completely waits for its events
+// to be published before it is given up and read again from its saved offset.
+const archiveAckTimeout = 5 * time.Minute
+
+// archiveFile is a compressed rotated file. It is read once by the tailer through a decompressing
+// reader, the offsets being counted in decompressed bytes, and its state is marked complete when
+// all of its events were published, so that it isn't read again.
+type archiveFile struct {
+	path     string
+	identity fileFingerprint
+
+	// read is set once the tailer reached the end of the decompressed content
+	read atomic.Bool
+	// pending is the number of published events which were not done yet
+	pending atomic.Int64
+}
+
+func (a *archiveFile) published() bool {
+	return a.read.Load() && a.pending.Load() == 0
+}
+
+// isArchiveFile returns whether the compressed file can be read. Only gzip and zstd compressed
+// files are supported, compressed tar files are not.
+func isArchiveFile(filename string) bool {
+	switch filepath.Ext(filename) {
+	case ".gz", ".zst":
+		return filepath.Ext(strings.TrimSuffix(filename, filepath.Ext(filename))) != ".tar"
+	}
+	return false
+}
+
+func newDecompressReader(filename string, r io.Reader) (io.ReadCloser, error) {
+	switch filepath.Ext(filename) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".zst":
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported compressed file %s", filename)
+}
+
+// archiveReader records the failure of the compressed file when its content can't be
+// decompressed, so that it isn't read again until it changes.
+type archiveReader struct {
+	*bufio.Reader
+	decompressor io.Closer
+	onFailure    func()
+}
+
+func (r *archiveReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.onFailure()
+	}
+	return n, err
+}
+
+func (r *archiveReader) Close() error {
+	return r.decompressor.Close()
+}
+
+// archiveFailed returns whether the compressed file failed to be decompressed since it last changed.
+func (t *LogFile) archiveFailed(filename string, modTime time.Time) bool {
+	t.archiveFailuresMu.Lock()
+	defer t.archiveFailuresMu.Unlock()
+	failedAt, ok := t.archiveFailures[filename]
+	if ok && !failedAt.Equal(modTime) {
+		delete(t.archiveFailures, filename)
+		return false
+	}
+	return ok
+}
+
+func (t *LogFile) setArchiveFailed(filename string, modTime time.Time) {
+	t.archiveFailuresMu.Lock()
+	defer t.archiveFailuresMu.Unlock()
+	t.archiveFailures[filename] = modTime
+}
+
+// newArchiveSrc creates the tailer src reading the compressed file, or returns nil if the file
+// was already read. The file is only decompressed by the tailer, see decompressArchive.
+func (t *LogFile) newArchiveSrc(fileconfig *FileConfig, filename string) (*tailerSrc, error) {
+	id, err := readFileIdentity(filename)
+	if err != nil {
+		return nil, err
+	}
+	if t.archiveFailed(filename, id.modTime) {
+		return nil, nil
+	}
+	stateFilePath := t.getStateFilePath(filename)
+	archive := &archiveFile{
+		path:     filename,
+		identity: id.fingerprint(),
+	}
+
+	var state *fileState
+	if s, err := readFileState(stateFilePath); err == nil && s.fingerprint != nil && id.sameFile(*s.fingerprint) {
+		if s.complete {
+			return nil, nil
+		}
+		state = &s
+	}
+
+	tailer, err := tail.TailFile(filename,
+		tail.Config{
+			ReOpen:      false,
+			Follow:      false,
+			MustExist:   true,
+			Poll:        true,
+			MaxLineSize: fileconfig.MaxEventSize,
+			IsUTF16:     fileconfig.isUTF16(),
+			Decompress: func(r io.Reader) (io.ReadCloser, int64, error) {
+				return t.decompressArchive(fileconfig, archive, id.modTime, stateFilePath, state, r)
+			},
+		})
+	if err != nil {
+		return nil, err
+	}
+	src := t.newTailerSrc(fileconfig, filename, tailer)
+	// the compressed file is kept once read
+	src.autoRemoval = false
+	src.archive = archive
+	return src, nil
+}
+
+// decompressArchive returns the reader of the decompressed content of the compressed file and the
+// offset it is resumed from: the offset saved for it, or the offset saved for the file it was
+// compressed from if that file was being tailed before it was rotated. Like other files, compressed
+// files which were there before the agent started are skipped, tail.ErrStop ending their tailer
+// without an error so that they are marked complete.
+func (t *LogFile) decompressArchive(fileconfig *FileConfig, archive *archiveFile, modTime time.Time, stateFilePath string, state *fileState, r io.Reader) (io.ReadCloser, int64, error) {
+	d, err := newDecompressReader(archive.path, r)
+	if err != nil {
+		t.setArchiveFailed(archive.path, modTime)
+		return nil, 0, err
+	}
+	ar := &archiveReader{
+		Reader:       bufio.NewReader(d),
+		decompressor: d,
+		onFailure:    func() { t.setArchiveFailed(archive.path, modTime) },
+	}
+
+	var offset int64
+	if state != nil {
+		offset = state.offset
+	} else {
+		head, err := ar.Peek(fingerprintSize)
+		if err != nil && err != io.EOF {
+			ar.Close()
+			t.setArchiveFailed(archive.path, modTime)
+			return nil, 0, err
+		}
+		if rotated, ok := t.findRotatedState(stateFilePath, fileIdentity{head: head}); ok {
+			t.Log.Infof("Reading from offset %v in %s, compressed from %s", rotated.offset, archive.path, rotated.filename)
+			offset = rotated.offset
+		} else if !fileconfig.FromBeginning && modTime.Before(t.startTime) {
+			ar.Close()
+			return nil, 0, tail.ErrStop
+		}
+	}
+
+	// the content before the offset is decompressed again to be skipped, the offset being reduced
+	// to the size of the content when it is shorter
+	skipped, err := io.CopyN(io.Discard, ar, offset)
+	if err != nil && err != io.EOF {
+		ar.Close()
+		return nil, 0, err
+	}
+	return ar, skipped, nil
+}
@@ -0,0 +1,217 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+func TestIsArchiveFile(t *testing.T) {
+	assert.True(t, isArchiveFile("/var/log/app.log.1.gz"))
+	assert.True(t, isArchiveFile("/var/log/app.log.1.zst"))
+	assert.False(t, isArchiveFile("/var/log/app.tar.gz"))
+	assert.False(t, isArchiveFile("/var/log/app.log.zip"))
+	assert.False(t, isArchiveFile("/var/log/app.log"))
+}
+
+func writeCompressedFile(t *testing.T, filename string, content []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	switch filepath.Ext(filename) {
+	case ".gz":
+		w := gzip.NewWriter(&buf)
+		_, err := w.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	case ".zst":
+		w, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		_, err = w.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+	require.NoError(t, os.WriteFile(filename, buf.Bytes(), 0644))
+}
+
+// readArchiveSrc publishes all events of the src the way the log agent does and waits for the
+// src to clean up.
+func readArchiveSrc(t *testing.T, tt *LogFile, lsrc logs.LogSrc) []string {
+	t.Helper()
+	evts := make(chan logs.LogEvent)
+	lsrc.SetOutput(func(e logs.LogEvent) {
+		evts <- e
+	})
+	var msgs []string
+	for e := range evts {
+		if e == nil {
+			break
+		}
+		msgs = append(msgs, e.Message())
+		e.Done()
+	}
+	lsrc.Stop()
+	require.Eventually(t, func() bool {
+		select {
+		case ts := <-tt.removeTailerSrcCh:
+			tt.removeTailerSrcCh <- ts
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	return msgs
+}
+
+func TestArchiveSrc(t *testing.T) {
+	multilineWaitPeriod = 10 * time.Millisecond
+	for _, ext := range []string{".gz", ".zst"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			stateFolder := t.TempDir()
+			archive := filepath.Join(dir, "app.log.1"+ext)
+			writeCompressedFile(t, archive, []byte("line1\nline2\nline3"))
+
+			tt := NewLogFile()
+			tt.Log = TestLogger{t}
+			tt.FileStateFolder = stateFolder
+			tt.FileConfig = []FileConfig{{FilePath: filepath.Join(dir, "app.log*"), ReadCompressedFiles: true}}
+			require.NoError(t, tt.FileConfig[0].init())
+			tt.started = true
+
+			lsrcs := tt.FindLogSrc()
+			require.Len(t, lsrcs, 1)
+			assert.Equal(t, archive, lsrcs[0].Description())
+			assert.Equal(t, []string{"line1", "line2", "line3"}, readArchiveSrc(t, tt, lsrcs[0]))
+
+			state, err := readFileState(tt.getStateFilePath(archive))
+			require.NoError(t, err)
+			assert.True(t, state.complete)
+			assert.Equal(t, archive, state.filename)
+			assert.Equal(t, int64(len("line1\nline2\nline3")), state.offset)
+
+			// the archive is only read once
+			assert.Empty(t, tt.FindLogSrc())
+			tt.Stop()
+		})
+	}
+}
+
+func TestArchiveSrcDisabled(t *testing.T) {
+	dir := t.TempDir()
+	writeCompressedFile(t, filepath.Join(dir, "app.log.1.gz"), []byte("line1\n"))
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = t.TempDir()
+	tt.FileConfig = []FileConfig{{FilePath: filepath.Join(dir, "app.log*")}}
+	require.NoError(t, tt.FileConfig[0].init())
+	tt.started = true
+
+	assert.Empty(t, tt.FindLogSrc())
+	tt.Stop()
+}
+
+func TestArchiveSrcSkipsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "app.log.1.gz")
+	writeCompressedFile(t, archive, []byte("line1\n"))
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = t.TempDir()
+	tt.FileConfig = []FileConfig{{FilePath: filepath.Join(dir, "app.log*"), ReadCompressedFiles: true}}
+	require.NoError(t, tt.FileConfig[0].init())
+	tt.started = true
+	tt.startTime = time.Now().Add(time.Minute)
+
+	// the file is only decompressed by the tailer, which skips it
+	lsrcs := tt.FindLogSrc()
+	require.Len(t, lsrcs, 1)
+	assert.Empty(t, readArchiveSrc(t, tt, lsrcs[0]))
+	state, err := readFileState(tt.getStateFilePath(archive))
+	require.NoError(t, err)
+	assert.True(t, state.complete)
+	assert.Empty(t, tt.FindLogSrc())
+	tt.Stop()
+}
+
+func TestArchiveSrcCorruptFile(t *testing.T) {
+	multilineWaitPeriod = 10 * time.Millisecond
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "app.log.1.gz")
+	writeCompressedFile(t, archive, []byte("line1\nline2\n"))
+	content, err := os.ReadFile(archive)
+	require.NoError(t, err)
+	// the end of the compressed content is missing
+	require.NoError(t, os.WriteFile(archive, content[:len(content)-8], 0644))
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = t.TempDir()
+	tt.FileConfig = []FileConfig{{FilePath: filepath.Join(dir, "app.log*"), ReadCompressedFiles: true}}
+	require.NoError(t, tt.FileConfig[0].init())
+	tt.started = true
+
+	lsrcs := tt.FindLogSrc()
+	require.Len(t, lsrcs, 1)
+	readArchiveSrc(t, tt, lsrcs[0])
+	state, err := readFileState(tt.getStateFilePath(archive))
+	if err == nil {
+		assert.False(t, state.complete)
+	}
+	// the file is not read again until it changes
+	assert.Empty(t, tt.FindLogSrc())
+	tt.Stop()
+}
+
+func TestArchiveSrcResumesRotatedFile(t *testing.T) {
+	multilineWaitPeriod = 10 * time.Millisecond
+	dir := t.TempDir()
+	stateFolder := t.TempDir()
+	var content strings.Builder
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&content, "line%d\n", i)
+	}
+	published := strings.Index(content.String(), "line290\n")
+
+	// the file was tailed up to line 290 before it was rotated and compressed
+	logFile := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(logFile, []byte(content.String()), 0644))
+	id, err := readFileIdentity(logFile)
+	require.NoError(t, err)
+	fp := id.fingerprint()
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = stateFolder
+	state := fileState{offset: int64(published), filename: logFile, fingerprint: &fp}
+	require.NoError(t, os.WriteFile(rotatedStateFilePath(tt.getStateFilePath(logFile), fp), state.marshal(), stateFileMode))
+	require.NoError(t, os.Remove(logFile))
+	archive := filepath.Join(dir, "app.log.1.gz")
+	writeCompressedFile(t, archive, []byte(content.String()))
+
+	tt.FileConfig = []FileConfig{{FilePath: filepath.Join(dir, "app.log*"), ReadCompressedFiles: true}}
+	require.NoError(t, tt.FileConfig[0].init())
+	tt.started = true
+	tt.startTime = time.Now().Add(time.Minute)
+
+	lsrcs := tt.FindLogSrc()
+	require.Len(t, lsrcs, 1)
+	msgs := readArchiveSrc(t, tt, lsrcs[0])
+	require.Len(t, msgs, 10)
+	assert.Equal(t, "line290", msgs[0])
+	tt.Stop()
+}
@@ -0,0 +1,240 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/k8sCommon/k8sclient"
+)
+
+const (
+	containerLogFormatAuto   = "auto"
+	containerLogFormatCRI    = "cri"
+	containerLogFormatDocker = "docker"
+
+	criTagPartial = "P"
+
+	namespacePlaceholder     = "{namespace}"
+	podNamePlaceholder       = "{pod_name}"
+	containerNamePlaceholder = "{container_name}"
+	unknownPlaceholderValue  = "unknown"
+)
+
+var (
+	// containerLogFileNameRegex matches the names of the links created by the kubelet in
+	// /var/log/containers, <pod>_<namespace>_<container>-<container id>.log.
+	containerLogFileNameRegex = regexp.MustCompile(`^([^_]+)_([^_]+)_(.+)-([0-9a-f]{64})\.log$`)
+
+	errInvalidCRILine = errors.New("invalid CRI log line")
+
+	// podMetadata returns the metadata of a pod of the node from the pod informer. The client is
+	// only created once, as it can't be created outside a cluster.
+	podMetadata = func() func(namespace, name string) (k8sclient.PodMetadata, bool) {
+		var once sync.Once
+		var podClient k8sclient.LocalPodClient
+		return func(namespace, name string) (k8sclient.PodMetadata, bool) {
+			once.Do(func() {
+				podClient = k8sclient.Get().LocalPod
+			})
+			if podClient == nil {
+				return k8sclient.PodMetadata{}, false
+			}
+			return podClient.PodMetadata(namespace, name)
+		}
+	}()
+)
+
+// ContainerLog decodes the lines written by the container runtimes, and publishes the content
+// of the containers as JSON documents with the metadata of their pod.
+type ContainerLog struct {
+	//The format of the lines, one of cri, docker or auto.
+	Format string `toml:"format"`
+	//Indicate whether the labels and uid of the pods are added from the Kubernetes API.
+	KubernetesMetadata bool `toml:"kubernetes_metadata"`
+}
+
+func (c *ContainerLog) init() error {
+	switch c.Format {
+	case "":
+		c.Format = containerLogFormatAuto
+	case containerLogFormatAuto, containerLogFormatCRI, containerLogFormatDocker:
+	default:
+		return fmt.Errorf("container log format %q is not supported", c.Format)
+	}
+	return nil
+}
+
+// containerInfo is the container of a log file, found from its name.
+type containerInfo struct {
+	namespace string
+	podName   string
+	name      string
+	id        string
+}
+
+func parseContainerLogFileName(filename string) (containerInfo, bool) {
+	match := containerLogFileNameRegex.FindStringSubmatch(filepath.Base(filename))
+	if match == nil {
+		return containerInfo{}, false
+	}
+	return containerInfo{podName: match[1], namespace: match[2], name: match[3], id: match[4]}, true
+}
+
+// containerRecord is the stream and time of a line written by the container runtime.
+type containerRecord struct {
+	stream string
+	time   time.Time
+}
+
+type dockerLine struct {
+	Log    string    `json:"log"`
+	Stream string    `json:"stream"`
+	Time   time.Time `json:"time"`
+}
+
+// containerLogDecoder decodes the lines of a container log file, and reassembles the partial lines
+// split by the runtime.
+type containerLogDecoder struct {
+	config   *ContainerLog
+	filename string
+	info     containerInfo
+	hasInfo  bool
+	maxSize  int
+
+	partial       strings.Builder
+	partialRecord containerRecord
+	hasPartial    bool
+}
+
+func newContainerLogDecoder(config *ContainerLog, filename string, maxSize int) *containerLogDecoder {
+	info, ok := parseContainerLogFileName(filename)
+	return &containerLogDecoder{config: config, filename: filename, info: info, hasInfo: ok, maxSize: maxSize}
+}
+
+// render replaces the {namespace}, {pod_name} and {container_name} placeholders with the
+// container of the log file.
+func (d *containerLogDecoder) render(s string) string {
+	if !strings.Contains(s, "{") {
+		return s
+	}
+	value := func(v string) string {
+		if !d.hasInfo {
+			return unknownPlaceholderValue
+		}
+		return v
+	}
+	return strings.NewReplacer(
+		namespacePlaceholder, value(d.info.namespace),
+		podNamePlaceholder, value(d.info.podName),
+		containerNamePlaceholder, value(d.info.name),
+	).Replace(s)
+}
+
+// decode returns the content and record of the line, false while the line is partial. The lines
+// which can't be decoded are returned as they are.
+func (d *containerLogDecoder) decode(line string) (string, containerRecord, bool) {
+	content, record, partial, err := d.decodeLine(line)
+	if err != nil {
+		log.Printf("D! [logfile] Unable to decode container log line from %s: %v", d.filename, err)
+		return line, containerRecord{}, true
+	}
+	if !d.hasPartial {
+		d.partialRecord = record
+	}
+	if partial || d.hasPartial {
+		if remaining := d.maxSize - d.partial.Len(); remaining > 0 {
+			if len(content) > remaining {
+				content = content[:remaining]
+			}
+			d.partial.WriteString(content)
+		}
+		d.hasPartial = partial
+		if partial {
+			return "", containerRecord{}, false
+		}
+		content = d.partial.String()
+		d.partial.Reset()
+	}
+	return content, d.partialRecord, true
+}
+
+func (d *containerLogDecoder) decodeLine(line string) (string, containerRecord, bool, error) {
+	format := d.config.Format
+	if format == containerLogFormatAuto {
+		format = containerLogFormatCRI
+		if strings.HasPrefix(line, "{") {
+			format = containerLogFormatDocker
+		}
+	}
+	if format == containerLogFormatDocker {
+		var l dockerLine
+		if err := json.Unmarshal([]byte(line), &l); err != nil {
+			return "", containerRecord{}, false, err
+		}
+		// the lines split by docker are the ones without line feed
+		content, complete := strings.CutSuffix(l.Log, "\n")
+		return content, containerRecord{stream: l.Stream, time: l.Time}, !complete, nil
+	}
+	// <time> <stream> <tag> <content>, the tag being P for the partial lines and F otherwise
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 {
+		return "", containerRecord{}, false, errInvalidCRILine
+	}
+	t, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return "", containerRecord{}, false, errInvalidCRILine
+	}
+	var content string
+	if len(fields) == 4 {
+		content = fields[3]
+	}
+	tag, _, _ := strings.Cut(fields[2], ":")
+	return content, containerRecord{stream: fields[1], time: t}, tag == criTagPartial, nil
+}
+
+// format returns the event as a JSON document with the record of its first line and the
+// metadata of its container.
+func (d *containerLogDecoder) format(msg string, record containerRecord) string {
+	doc := map[string]interface{}{"log": msg}
+	if record.stream != "" {
+		doc["stream"] = record.stream
+	}
+	if !record.time.IsZero() {
+		doc["time"] = record.time.UTC().Format(time.RFC3339Nano)
+	}
+	if d.hasInfo {
+		k8s := map[string]interface{}{
+			"namespace_name": d.info.namespace,
+			"pod_name":       d.info.podName,
+			"container_name": d.info.name,
+			"docker_id":      d.info.id,
+		}
+		if d.config.KubernetesMetadata {
+			if pod, ok := podMetadata(d.info.namespace, d.info.podName); ok {
+				k8s["pod_id"] = pod.UID
+				if len(pod.Labels) > 0 {
+					k8s["labels"] = pod.Labels
+				}
+			}
+		}
+		doc["kubernetes"] = k8s
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return msg
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,200 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/k8sCommon/k8sclient"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+const testContainerID = "8a3c6d1e5f0b9a7c2d4e6f8a0b1c3d5e7f9a1b3c5d7e9f1a3b5c7d9e1f3a5b7c"
+
+func TestContainerLogInit(t *testing.T) {
+	c := &ContainerLog{}
+	require.NoError(t, c.init())
+	assert.Equal(t, containerLogFormatAuto, c.Format)
+	assert.NoError(t, (&ContainerLog{Format: containerLogFormatCRI}).init())
+	assert.Error(t, (&ContainerLog{Format: "journald"}).init())
+}
+
+func TestParseContainerLogFileName(t *testing.T) {
+	info, ok := parseContainerLogFileName("/var/log/containers/web-7d4b9c-x2lkq_shop_nginx-proxy-" + testContainerID + ".log")
+	require.True(t, ok)
+	assert.Equal(t, containerInfo{namespace: "shop", podName: "web-7d4b9c-x2lkq", name: "nginx-proxy", id: testContainerID}, info)
+
+	for _, filename := range []string{"/var/log/messages", "/var/log/containers/web_shop_nginx.log", "/var/log/pods/shop_web_1/nginx/0.log"} {
+		_, ok = parseContainerLogFileName(filename)
+		assert.False(t, ok, filename)
+	}
+
+	d := newContainerLogDecoder(&ContainerLog{}, "/var/log/containers/web_shop_nginx-"+testContainerID+".log", 100)
+	assert.Equal(t, "/eks/shop/web/nginx", d.render("/eks/{namespace}/{pod_name}/{container_name}"))
+	d = newContainerLogDecoder(&ContainerLog{}, "/var/log/app.log", 100)
+	assert.Equal(t, "/eks/unknown", d.render("/eks/{namespace}"))
+}
+
+func TestContainerLogDecodeCRI(t *testing.T) {
+	d := newContainerLogDecoder(&ContainerLog{Format: containerLogFormatCRI}, "0.log", 10)
+	first := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+
+	content, record, ok := d.decode("2024-05-01T12:00:00.123456789Z stdout F hello world")
+	require.True(t, ok)
+	assert.Equal(t, "hello world", content)
+	assert.Equal(t, containerRecord{stream: "stdout", time: first}, record)
+
+	content, _, ok = d.decode("2024-05-01T12:00:01Z stderr F")
+	require.True(t, ok)
+	assert.Equal(t, "", content)
+
+	// the partial lines are reassembled with the record of the first one, up to the max size
+	_, _, ok = d.decode("2024-05-01T12:00:00.123456789Z stderr P abc ")
+	assert.False(t, ok)
+	_, _, ok = d.decode("2024-05-01T12:00:02Z stderr P defgh")
+	assert.False(t, ok)
+	content, record, ok = d.decode("2024-05-01T12:00:03Z stderr F ijkl")
+	require.True(t, ok)
+	assert.Equal(t, "abc defghi", content)
+	assert.Equal(t, containerRecord{stream: "stderr", time: first}, record)
+
+	// the lines which are not CRI are published as they are
+	for _, line := range []string{"plain line", "yesterday stdout F line"} {
+		content, record, ok = d.decode(line)
+		require.True(t, ok)
+		assert.Equal(t, line, content)
+		assert.Equal(t, containerRecord{}, record)
+	}
+}
+
+func TestContainerLogDecodeDocker(t *testing.T) {
+	d := newContainerLogDecoder(&ContainerLog{Format: containerLogFormatAuto}, "container-json.log", 100)
+	first := time.Date(2024, 5, 1, 12, 0, 0, 5000, time.UTC)
+
+	_, _, ok := d.decode(`{"log":"part one, ","stream":"stdout","time":"2024-05-01T12:00:00.000005Z"}`)
+	assert.False(t, ok)
+	content, record, ok := d.decode(`{"log":"part two\n","stream":"stdout","time":"2024-05-01T12:00:01Z"}`)
+	require.True(t, ok)
+	assert.Equal(t, "part one, part two", content)
+	assert.Equal(t, containerRecord{stream: "stdout", time: first}, record)
+
+	// the format is found for each line
+	content, record, ok = d.decode("2024-05-01T12:00:00.000005Z stdout F cri")
+	require.True(t, ok)
+	assert.Equal(t, "cri", content)
+	assert.Equal(t, containerRecord{stream: "stdout", time: first}, record)
+
+	content, _, ok = d.decode(`{"log":`)
+	require.True(t, ok)
+	assert.Equal(t, `{"log":`, content)
+}
+
+func testPodMetadata(pods map[string]k8sclient.PodMetadata) func(string, string) (k8sclient.PodMetadata, bool) {
+	return func(namespace, name string) (k8sclient.PodMetadata, bool) {
+		pod, ok := pods[namespace+"/"+name]
+		return pod, ok
+	}
+}
+
+func TestContainerLogFormat(t *testing.T) {
+	original := podMetadata
+	defer func() { podMetadata = original }()
+	podMetadata = testPodMetadata(map[string]k8sclient.PodMetadata{
+		"shop/web": {Namespace: "shop", Name: "web", UID: "uid-1", Labels: map[string]string{"app": "web"}},
+	})
+	record := containerRecord{stream: "stdout", time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+
+	d := newContainerLogDecoder(&ContainerLog{KubernetesMetadata: true}, "web_shop_nginx-"+testContainerID+".log", 100)
+	assert.JSONEq(t, `{"log":"<a> & b","stream":"stdout","time":"2024-05-01T12:00:00Z","kubernetes":{
+		"namespace_name":"shop","pod_name":"web","container_name":"nginx","docker_id":"`+testContainerID+`",
+		"pod_id":"uid-1","labels":{"app":"web"}}}`, d.format("<a> & b", record))
+	assert.Contains(t, d.format("<a>", record), `"log":"<a>"`)
+
+	// the pods missing from the informer only have the metadata found from the file name
+	d = newContainerLogDecoder(&ContainerLog{KubernetesMetadata: true}, "api_shop_go-"+testContainerID+".log", 100)
+	assert.JSONEq(t, `{"log":"a","kubernetes":{"namespace_name":"shop","pod_name":"api","container_name":"go","docker_id":"`+testContainerID+`"}}`, d.format("a", containerRecord{}))
+
+	d = newContainerLogDecoder(&ContainerLog{}, "app.log", 100)
+	assert.JSONEq(t, `{"log":"a","stream":"stdout","time":"2024-05-01T12:00:00Z"}`, d.format("a", record))
+}
+
+func TestLogFileContainerLog(t *testing.T) {
+	multilineWaitPeriod = 10 * time.Millisecond
+	original := podMetadata
+	defer func() { podMetadata = original }()
+	podMetadata = testPodMetadata(map[string]k8sclient.PodMetadata{"shop/web": {Namespace: "shop", Name: "web", UID: "uid-1"}})
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "web_shop_java-"+testContainerID+".log")
+	lines := []string{
+		"2024-05-01T12:00:00Z stdout F started",
+		"2024-05-01T12:00:01Z stderr P Exception in thread main: ",
+		"2024-05-01T12:00:02Z stderr F boom",
+		"2024-05-01T12:00:02Z stderr F \tat Main.main(Main.java:3)",
+		"2024-05-01T12:00:03Z stdout F done",
+	}
+	require.NoError(t, os.WriteFile(filename, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	// all the container log files are published, not only the last modified one
+	other := filepath.Join(dir, "api_kube-system_go-"+testContainerID+".log")
+	require.NoError(t, os.WriteFile(other, []byte("2024-05-01T12:00:00Z stdout F other\n"), 0644))
+	require.NoError(t, os.Chtimes(other, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = t.TempDir()
+	tt.FileConfig = []FileConfig{{
+		FilePath:      filepath.Join(dir, "*.log"),
+		FromBeginning: true,
+		LogGroupName:  "/eks/{namespace}",
+		LogStreamName: "{pod_name}/{container_name}",
+		ContainerLog:  &ContainerLog{KubernetesMetadata: true},
+	}}
+	require.NoError(t, tt.Start(nil))
+	defer tt.Stop()
+
+	lsrcs := tt.FindLogSrc()
+	require.Len(t, lsrcs, 2)
+	sort.Slice(lsrcs, func(i, j int) bool { return lsrcs[i].Group() > lsrcs[j].Group() })
+	assert.Equal(t, "/eks/kube-system", lsrcs[1].Group())
+	assert.Equal(t, "api/go", lsrcs[1].Stream())
+	defer lsrcs[1].Stop()
+	assert.Equal(t, "/eks/shop", lsrcs[0].Group())
+	assert.Equal(t, "web/java", lsrcs[0].Stream())
+	evts := make(chan logs.LogEvent, 3)
+	lsrcs[0].SetOutput(func(e logs.LogEvent) {
+		if e != nil {
+			evts <- e
+		}
+	})
+	defer lsrcs[0].Stop()
+
+	want := []struct {
+		msg string
+		t   time.Time
+	}{
+		{"started", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		// the continuation lines of the content are still grouped by the multiline pattern
+		{"Exception in thread main: boom\n\tat Main.main(Main.java:3)", time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC)},
+		{"done", time.Date(2024, 5, 1, 12, 0, 3, 0, time.UTC)},
+	}
+	for _, w := range want {
+		select {
+		case e := <-evts:
+			assert.Equal(t, w.t, e.Time().UTC())
+			assert.Contains(t, e.Message(), `"pod_id":"uid-1"`)
+			assert.Contains(t, e.Message(), `"log":`+strings.ReplaceAll(strings.ReplaceAll(`"`+w.msg+`"`, "\n", `\n`), "\t", `\t`))
+			e.Done()
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the log events")
+		}
+	}
+}
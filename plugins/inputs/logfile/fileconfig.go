@@ -17,6 +17,8 @@ import (
 	"golang.org/x/text/encoding/ianaindex"
 
 	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/ratelimit"
+	"github.com/aws/amazon-cloudwatch-agent/logs/template"
 	"github.com/aws/amazon-cloudwatch-agent/profiler"
 )
 
@@ -65,6 +67,8 @@ type FileConfig struct {
 	FromBeginning bool `toml:"from_beginning"`
 	//Indicate whether it is a named pipe.
 	Pipe bool `toml:"pipe"`
+	//Indicate whether gzip and zstd compressed files matching the file path are read once instead of skipped.
+	ReadCompressedFiles bool `toml:"read_compressed_files"`
 
 	//Indicate logType for scroll
 	LogType string `toml:"log_type"`
@@ -83,6 +87,18 @@ type FileConfig struct {
 
 	Filters []*LogFilter `toml:"filters"`
 
+	//Decode structured log events and publish them as normalized JSON documents.
+	Parser *LogParser `toml:"parser"`
+
+	//Limit the events and bytes per second published from all the files of the file config.
+	RateLimit *ratelimit.Config `toml:"rate_limit"`
+
+	//Extract metrics from the log events, gathered by the metrics pipeline.
+	MetricFilters []*MetricFilter `toml:"metric_filters"`
+
+	//Decode the CRI or docker json-file lines of the container log files and add the metadata of their pods.
+	ContainerLog *ContainerLog `toml:"container_log"`
+
 	//Time *time.Location Go type timezone info.
 	TimezoneLoc *time.Location
 	//Regexp go type timestampFromLogLine regex
@@ -94,6 +110,8 @@ type FileConfig struct {
 	//Decoder object
 	Enc         encoding.Encoding
 	sampleCount int
+	//Rate limiter shared by the files of the file config
+	rateLimiter *ratelimit.Limiter
 }
 
 // Initialize some variables in the FileConfig object based on the rest info fetched from the configuration file.
@@ -112,6 +130,9 @@ func (config *FileConfig) init() error {
 	if config.LogGroupName == "" && !config.PublishMultiLogs {
 		config.LogGroupName = logGroupName(config.FilePath)
 	}
+	// The host placeholders are resolved once, so that the instance metadata is not looked up for each file.
+	config.LogGroupName = template.Parse(config.LogGroupName).Resolve(template.HostResolver).String()
+	config.LogStreamName = template.Parse(config.LogStreamName).Resolve(template.HostResolver).String()
 	//If the timezone info is not specified, we will use the Local timezone as default value.
 	if config.Timezone == time.UTC.String() {
 		config.TimezoneLoc = time.UTC
@@ -160,6 +181,31 @@ func (config *FileConfig) init() error {
 		}
 	}
 
+	if config.Parser != nil {
+		if err = config.Parser.init(config.TimezoneLoc); err != nil {
+			return err
+		}
+	}
+
+	if config.RateLimit != nil {
+		if err = config.RateLimit.Init(); err != nil {
+			return fmt.Errorf("rate_limit has issue: %v", err)
+		}
+		config.rateLimiter = ratelimit.NewLimiter(*config.RateLimit, []string{"logfile", config.FilePath, "messages", "rate_limit_dropped"})
+	}
+
+	for _, f := range config.MetricFilters {
+		if err = f.init(config.Parser); err != nil {
+			return err
+		}
+	}
+
+	if config.ContainerLog != nil {
+		if err = config.ContainerLog.init(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -217,6 +263,15 @@ func (config *FileConfig) isMultilineStart(logValue string) bool {
 	return config.MultiLineStartPatternP.MatchString(logValue)
 }
 
+// This method determine whether the file is read as utf-16 little endian.
+func (config *FileConfig) isUTF16() bool {
+	switch config.Encoding {
+	case "utf-16", "utf-16le", "UTF-16", "UTF-16LE":
+		return true
+	}
+	return false
+}
+
 func ShouldPublish(logGroupName, logStreamName string, filters []*LogFilter, event logs.LogEvent) bool {
 	if len(filters) == 0 {
 		return true
@@ -241,6 +296,26 @@ func shouldPublishHelper(filters []*LogFilter, event logs.LogEvent) bool {
 	return true
 }
 
+// Redact replaces the sensitive values found by the redact filters in the message and
+// counts the substitutions made for the file.
+func Redact(logGroupName, logStreamName string, filters []*LogFilter, msg string) string {
+	redacting := false
+	redactedCount := 0
+	for _, filter := range filters {
+		if filter.Type != redactFilterType {
+			continue
+		}
+		redacting = true
+		var count int
+		msg, count = filter.Redact(msg)
+		redactedCount += count
+	}
+	if redacting {
+		profiler.Profiler.AddStats([]string{"logfile", logGroupName, logStreamName, "messages", "redacted"}, float64(redactedCount))
+	}
+	return msg
+}
+
 // The default log group name calculation logic if the log group name is not specified.
 // It will use the part before the last dot in the file path, e.g.
 // file path: "/tmp/TestLogFile.log.2017-07-11-14" -> log group name: "/tmp/TestLogFile.log"
@@ -0,0 +1,21 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+//go:build !windows
+// +build !windows
+
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func fileDevIno(_ *os.File, info os.FileInfo) (uint64, uint64, error) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || stat == nil {
+		return 0, 0, fmt.Errorf("unable to get the inode of %s", info.Name())
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), nil
+}
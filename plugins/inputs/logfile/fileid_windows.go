@@ -0,0 +1,19 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileDevIno returns the volume serial number and the file index, which together identify a
+// file on NTFS the way the device and inode do on unix.
+func fileDevIno(f *os.File, _ os.FileInfo) (uint64, uint64, error) {
+	var d syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(f.Fd()), &d); err != nil {
+		return 0, 0, err
+	}
+	return uint64(d.VolumeSerialNumber), uint64(d.FileIndexHigh)<<32 | uint64(d.FileIndexLow), nil
+}
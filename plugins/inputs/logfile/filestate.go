@@ -0,0 +1,316 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/logscommon"
+	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/logfile/tail"
+)
+
+const (
+	// fingerprintSize is the number of bytes at the start of a file hashed to identify it.
+	fingerprintSize = 1024
+
+	// rotatedStateSuffix is the suffix of the state files kept for files which were renamed or
+	// replaced, so that they are finished from their offset if they are picked up under a new name.
+	rotatedStateSuffix    = ".rotated"
+	rotatedStateRetention = time.Hour
+	// tmpStateSuffix is the suffix of the state files being written, renamed once complete.
+	tmpStateSuffix = ".tmp"
+
+	stateDevKey         = "dev"
+	stateInoKey         = "ino"
+	stateFingerprintKey = "fingerprint"
+	stateCompleteKey    = "complete"
+)
+
+var errNotRegularFile = errors.New("not a regular file")
+
+// fileFingerprint identifies a file by its device and inode and the hash of its first bytes. The
+// inode alone is not enough since it is reused once a file is removed, and the content alone is
+// not enough for files which start with the same header.
+type fileFingerprint struct {
+	dev, ino uint64
+	// size is the number of bytes hashed, which is less than fingerprintSize while the file is short
+	size int
+	sum  string
+}
+
+// fileKey is the device and inode of a file, which stay the same when it is renamed.
+type fileKey struct {
+	dev, ino uint64
+}
+
+func (fp fileFingerprint) key() fileKey {
+	return fileKey{dev: fp.dev, ino: fp.ino}
+}
+
+// fileIdentity is the identity of a file on disk, read when deciding where to start tailing it.
+type fileIdentity struct {
+	dev, ino uint64
+	size     int64
+	modTime  time.Time
+	head     []byte
+}
+
+// readFileIdentity reads the device, inode and first bytes of a regular file. Other files, such
+// as named pipes, can't be read without consuming them and have no identity.
+func readFileIdentity(filename string) (fileIdentity, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return fileIdentity{}, errNotRegularFile
+	}
+	f, err := tail.OpenFile(filename)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	defer f.Close()
+	if info, err = f.Stat(); err != nil {
+		return fileIdentity{}, err
+	}
+	dev, ino, err := fileDevIno(f, info)
+	if err != nil {
+		return fileIdentity{}, err
+	}
+	head := make([]byte, fingerprintSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fileIdentity{}, err
+	}
+	return fileIdentity{dev: dev, ino: ino, size: info.Size(), modTime: info.ModTime(), head: head[:n]}, nil
+}
+
+func (id fileIdentity) key() fileKey {
+	return fileKey{dev: id.dev, ino: id.ino}
+}
+
+func (id fileIdentity) fingerprint() fileFingerprint {
+	return fileFingerprint{dev: id.dev, ino: id.ino, size: len(id.head), sum: hashHead(id.head)}
+}
+
+// hasPrefix returns whether the file starts with the bytes the fingerprint was taken from.
+func (id fileIdentity) hasPrefix(fp fileFingerprint) bool {
+	return fp.size <= len(id.head) && hashHead(id.head[:fp.size]) == fp.sum
+}
+
+// sameFile returns whether the fingerprint was taken from this file, possibly under another name.
+func (id fileIdentity) sameFile(fp fileFingerprint) bool {
+	return id.dev == fp.dev && id.ino == fp.ino && id.hasPrefix(fp)
+}
+
+// sameContent returns whether the file is a copy of the file the fingerprint was taken from, as
+// left behind by copytruncate rotation. Only complete fingerprints are considered, since short
+// files are too likely to share their content.
+func (id fileIdentity) sameContent(fp fileFingerprint) bool {
+	return fp.size == fingerprintSize && id.hasPrefix(fp)
+}
+
+func hashHead(head []byte) string {
+	sum := sha256.Sum256(head)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileState is the content of a state file. The first two lines, the offset and the file name,
+// are the original format and are kept in place so that older agents can still read the offset.
+// The fingerprint follows as key=value lines and is nil for state files written before it was
+// added, in which case the offset is trusted as long as it fits in the file. Compressed files are
+// marked complete once all of their content was published.
+type fileState struct {
+	offset      int64
+	filename    string
+	fingerprint *fileFingerprint
+	complete    bool
+}
+
+func parseFileState(content []byte) (fileState, error) {
+	lines := strings.Split(string(content), "\n")
+	offset, err := strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+	if err != nil {
+		return fileState{}, fmt.Errorf("invalid offset %q: %w", lines[0], err)
+	}
+	if offset < 0 {
+		return fileState{}, fmt.Errorf("negative offset %v", offset)
+	}
+	state := fileState{offset: offset}
+	if len(lines) < 2 {
+		return state, nil
+	}
+	state.filename = lines[1]
+
+	var fp fileFingerprint
+	var hasDev, hasIno, hasFingerprint bool
+	for _, line := range lines[2:] {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case stateDevKey:
+			fp.dev, err = strconv.ParseUint(value, 10, 64)
+			hasDev = err == nil
+		case stateInoKey:
+			fp.ino, err = strconv.ParseUint(value, 10, 64)
+			hasIno = err == nil
+		case stateFingerprintKey:
+			size, sum, _ := strings.Cut(value, ":")
+			fp.size, err = strconv.Atoi(size)
+			fp.sum = sum
+			hasFingerprint = err == nil && fp.size >= 0 && fp.size <= fingerprintSize && sum != ""
+		case stateCompleteKey:
+			state.complete = value == "true"
+		}
+	}
+	if hasDev && hasIno && hasFingerprint {
+		state.fingerprint = &fp
+	}
+	return state, nil
+}
+
+func (s fileState) marshal() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(strconv.FormatInt(s.offset, 10))
+	buf.WriteString("\n")
+	buf.WriteString(s.filename)
+	if s.fingerprint != nil {
+		fmt.Fprintf(&buf, "\n%s=%d\n%s=%d\n%s=%d:%s",
+			stateDevKey, s.fingerprint.dev,
+			stateInoKey, s.fingerprint.ino,
+			stateFingerprintKey, s.fingerprint.size, s.fingerprint.sum)
+	}
+	if s.complete {
+		fmt.Fprintf(&buf, "\n%s=true", stateCompleteKey)
+	}
+	return buf.Bytes()
+}
+
+// matches returns whether the state was saved for the file, which is the case when the file
+// is the same or, for state files without a fingerprint, when the file is long enough.
+func (s fileState) matches(id fileIdentity) bool {
+	if s.fingerprint == nil {
+		return s.offset <= id.size
+	}
+	return id.sameFile(*s.fingerprint)
+}
+
+func readFileState(path string) (fileState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fileState{}, err
+	}
+	return parseFileState(content)
+}
+
+// writeFileState replaces the state file atomically, so that a crash while the state is saved
+// leaves the previous state rather than a truncated one.
+func writeFileState(path string, state fileState) error {
+	tmp := path + tmpStateSuffix
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, stateFileMode)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(state.marshal())
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+// rotatedStateFilePath returns the path the state of a renamed or replaced file is kept under.
+// The device and inode are part of the name so that every rotation keeps its own state.
+func rotatedStateFilePath(stateFilePath string, fp fileFingerprint) string {
+	return fmt.Sprintf("%s.%d_%d%s", stateFilePath, fp.dev, fp.ino, rotatedStateSuffix)
+}
+
+// stateIndex holds the fingerprints of the state files of a folder, so that looking for the state
+// of a file saved under another name only reads the state files which changed since the previous
+// lookup. Lookups are serialized, so that a rotated state is only claimed once.
+type stateIndex struct {
+	mu      sync.Mutex
+	entries map[string]stateIndexEntry
+}
+
+type stateIndexEntry struct {
+	info        os.FileInfo
+	fingerprint *fileFingerprint
+}
+
+// find returns the state saved for the file under another name than stateFilePath. A state saved
+// for the same file is preferred over one saved for a file with the same content. The state of a
+// file which was renamed is removed once it has been claimed.
+func (idx *stateIndex) find(folder, stateFilePath string, id fileIdentity) (fileState, bool) {
+	dirEntries, err := os.ReadDir(folder)
+	if err != nil {
+		return fileState{}, false
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	entries := make(map[string]stateIndexEntry, len(dirEntries))
+	var foundFile string
+	var sameFile bool
+	for _, e := range dirEntries {
+		file := filepath.Join(folder, e.Name())
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || strings.Contains(file, logscommon.WindowsEventLogPrefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		entry, ok := idx.entries[file]
+		if !ok || !os.SameFile(entry.info, info) || !entry.info.ModTime().Equal(info.ModTime()) || entry.info.Size() != info.Size() {
+			entry = stateIndexEntry{info: info}
+			if state, err := readFileState(file); err == nil {
+				entry.fingerprint = state.fingerprint
+			}
+		}
+		entries[file] = entry
+		if file == stateFilePath || entry.fingerprint == nil || sameFile {
+			continue
+		}
+		if id.sameFile(*entry.fingerprint) {
+			foundFile, sameFile = file, true
+		} else if foundFile == "" && id.sameContent(*entry.fingerprint) {
+			foundFile = file
+		}
+	}
+	idx.entries = entries
+	if foundFile == "" {
+		return fileState{}, false
+	}
+	// the state is read again for its latest offset
+	found, err := readFileState(foundFile)
+	if err != nil || found.fingerprint == nil {
+		return fileState{}, false
+	}
+	if strings.HasSuffix(foundFile, rotatedStateSuffix) {
+		os.Remove(foundFile)
+		delete(idx.entries, foundFile)
+	}
+	return found, true
+}
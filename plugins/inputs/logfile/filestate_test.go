@@ -0,0 +1,172 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileState(t *testing.T) {
+	testCases := map[string]struct {
+		content string
+		want    fileState
+		wantErr bool
+	}{
+		"OffsetOnly": {
+			content: "123",
+			want:    fileState{offset: 123},
+		},
+		"Legacy": {
+			content: "123\n/tmp/logfile.log",
+			want:    fileState{offset: 123, filename: "/tmp/logfile.log"},
+		},
+		"WithFingerprint": {
+			content: "123\n/tmp/logfile.log\ndev=1\nino=2\nfingerprint=10:abc",
+			want: fileState{offset: 123, filename: "/tmp/logfile.log", fingerprint: &fileFingerprint{
+				dev: 1, ino: 2, size: 10, sum: "abc",
+			}},
+		},
+		"WithIncompleteFingerprint": {
+			content: "123\n/tmp/logfile.log\nino=2\nfingerprint=10:abc",
+			want:    fileState{offset: 123, filename: "/tmp/logfile.log"},
+		},
+		"WithUnknownKeys": {
+			content: "123\n/tmp/logfile.log\ndev=1\nino=2\nfingerprint=10:abc\nfoo=bar",
+			want: fileState{offset: 123, filename: "/tmp/logfile.log", fingerprint: &fileFingerprint{
+				dev: 1, ino: 2, size: 10, sum: "abc",
+			}},
+		},
+		"Complete": {
+			content: "123\n/tmp/logfile.log.gz\ndev=1\nino=2\nfingerprint=10:abc\ncomplete=true",
+			want: fileState{offset: 123, filename: "/tmp/logfile.log.gz", fingerprint: &fileFingerprint{
+				dev: 1, ino: 2, size: 10, sum: "abc",
+			}, complete: true},
+		},
+		"InvalidOffset": {
+			content: "abc\n/tmp/logfile.log",
+			wantErr: true,
+		},
+		"NegativeOffset": {
+			content: "-1\n/tmp/logfile.log",
+			wantErr: true,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := parseFileState([]byte(testCase.content))
+			if testCase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.want, got)
+			if got.fingerprint != nil {
+				roundTrip, err := parseFileState(got.marshal())
+				require.NoError(t, err)
+				assert.Equal(t, got, roundTrip)
+			}
+		})
+	}
+}
+
+func TestFileIdentity(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "original.log")
+	require.NoError(t, os.WriteFile(original, []byte(strings.Repeat("a", fingerprintSize+10)), 0644))
+	copied := filepath.Join(dir, "copied.log")
+	require.NoError(t, os.WriteFile(copied, []byte(strings.Repeat("a", fingerprintSize)), 0644))
+	short := filepath.Join(dir, "short.log")
+	require.NoError(t, os.WriteFile(short, []byte("a"), 0644))
+
+	originalID, err := readFileIdentity(original)
+	require.NoError(t, err)
+	fp := originalID.fingerprint()
+	assert.Equal(t, fingerprintSize, fp.size)
+	assert.True(t, originalID.sameFile(fp))
+
+	copiedID, err := readFileIdentity(copied)
+	require.NoError(t, err)
+	assert.False(t, copiedID.sameFile(fp))
+	assert.True(t, copiedID.sameContent(fp))
+
+	shortID, err := readFileIdentity(short)
+	require.NoError(t, err)
+	shortFP := shortID.fingerprint()
+	assert.Equal(t, 1, shortFP.size)
+	assert.False(t, shortID.sameContent(fp))
+	// short fingerprints are only trusted for the same file
+	assert.True(t, originalID.hasPrefix(shortFP))
+	assert.False(t, originalID.sameContent(shortFP))
+
+	renamed := filepath.Join(dir, "renamed.log")
+	require.NoError(t, os.Rename(original, renamed))
+	renamedID, err := readFileIdentity(renamed)
+	require.NoError(t, err)
+	assert.True(t, renamedID.sameFile(fp))
+
+	_, err = readFileIdentity(dir)
+	assert.ErrorIs(t, err, errNotRegularFile)
+}
+
+func TestWriteFileState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	require.NoError(t, writeFileState(path, fileState{offset: 10, filename: "/var/log/app.log"}))
+
+	// a failure while the state is written, as when the agent crashes, keeps the previous state
+	require.NoError(t, os.Mkdir(path+tmpStateSuffix, 0700))
+	assert.Error(t, writeFileState(path, fileState{offset: 20, filename: "/var/log/app.log"}))
+	state, err := readFileState(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), state.offset)
+
+	require.NoError(t, os.Remove(path+tmpStateSuffix))
+	require.NoError(t, writeFileState(path, fileState{offset: 20, filename: "/var/log/app.log"}))
+	state, err = readFileState(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), state.offset)
+	_, err = os.Stat(path + tmpStateSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStateIndex(t *testing.T) {
+	folder := t.TempDir()
+	logFile := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(logFile, []byte(strings.Repeat("a", 2*fingerprintSize)), 0644))
+	id, err := readFileIdentity(logFile)
+	require.NoError(t, err)
+	fp := id.fingerprint()
+
+	var idx stateIndex
+	other := filepath.Join(folder, "other")
+	require.NoError(t, writeFileState(other, fileState{offset: 10, filename: "other"}))
+	_, ok := idx.find(folder, filepath.Join(folder, "app"), id)
+	assert.False(t, ok)
+	assert.Len(t, idx.entries, 1)
+
+	// the state files which changed are read again
+	require.NoError(t, writeFileState(other, fileState{offset: 20, filename: "other", fingerprint: &fp}))
+	state, ok := idx.find(folder, filepath.Join(folder, "app"), id)
+	require.True(t, ok)
+	assert.EqualValues(t, 20, state.offset)
+	// but not the state of the file itself
+	_, ok = idx.find(folder, other, id)
+	assert.False(t, ok)
+
+	// the rotated states are only claimed once
+	rotated := rotatedStateFilePath(filepath.Join(folder, "app"), fp)
+	require.NoError(t, writeFileState(rotated, fileState{offset: 30, filename: "app", fingerprint: &fp}))
+	require.NoError(t, os.Remove(other))
+	state, ok = idx.find(folder, filepath.Join(folder, "app"), id)
+	require.True(t, ok)
+	assert.EqualValues(t, 30, state.offset)
+	_, ok = idx.find(folder, filepath.Join(folder, "app"), id)
+	assert.False(t, ok)
+	assert.Empty(t, idx.entries)
+}
@@ -9,8 +9,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -18,6 +18,7 @@ import (
 
 	"github.com/aws/amazon-cloudwatch-agent/internal/logscommon"
 	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/template"
 	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/logfile/globpath"
 	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/logfile/tail"
 )
@@ -35,7 +36,20 @@ type LogFile struct {
 	configs           map[*FileConfig]map[string]*tailerSrc
 	done              chan struct{}
 	removeTailerSrcCh chan *tailerSrc
+	startMu           sync.Mutex
 	started           bool
+	startTime         time.Time
+	// states indexes the state files, see findRotatedState.
+	states stateIndex
+	// srcs are the tailer srcs whose state may still be saved, see Stop.
+	srcsMu sync.Mutex
+	srcs   []*tailerSrc
+	// archiveFailures holds the modification time of the compressed files which could not be
+	// decompressed, so that they are only tried again once they change.
+	archiveFailures   map[string]time.Time
+	archiveFailuresMu sync.Mutex
+	// metrics holds the metrics extracted by the metric filters until they are gathered.
+	metrics *logMetrics
 }
 
 func NewLogFile() *LogFile {
@@ -43,6 +57,8 @@ func NewLogFile() *LogFile {
 		configs:           make(map[*FileConfig]map[string]*tailerSrc),
 		done:              make(chan struct{}),
 		removeTailerSrcCh: make(chan *tailerSrc, 100),
+		archiveFailures:   make(map[string]time.Time),
+		metrics:           newLogMetrics(),
 	}
 }
 
@@ -85,6 +101,46 @@ const sampleConfig = `
       max_event_size = 262144
       ## Suffix to be added to truncated logline to indicate its truncation, defaults to "[Truncated...]"
       truncate_suffix = "[Truncated...]"
+      ## Decode structured log events (json, logfmt or regex named groups) and
+      ## publish them as normalized JSON documents. Events which cannot be parsed
+      ## are published unchanged.
+      [inputs.logs.file_config.parser]
+          format = "logfmt"
+          ## Regular expression with named capture groups, only used by the regex format
+          # pattern = "^(?P<time>\\S+) (?P<level>\\w+) (?P<msg>.*)$"
+          ## Field holding the event timestamp, RFC3339 and epoch numbers are
+          ## accepted when no timestamp_layout is set
+          timestamp_field = "time"
+          # timestamp_layout = ["2006-01-02T15:04:05Z07:00"]
+          drop_fields = ["pid"]
+          [inputs.logs.file_config.parser.rename_fields]
+              msg = "message"
+      ## Extract metrics from the log events, published to CloudWatch along with
+      ## the metrics of the metrics section at each collection interval.
+      [[inputs.logs.file_config.metric_filters]]
+          metric_name = "ErrorCount"
+          ## Defaults to the namespace of the metrics section
+          namespace = "MyApp"
+          pattern = "ERROR \\[(?P<component>\\w+)\\]"
+          ## One of count, sum or distribution, defaults to count
+          aggregation = "count"
+          ## Capture groups or parsed fields used as dimensions
+          dimensions = ["component"]
+      [[inputs.logs.file_config.metric_filters]]
+          metric_name = "Latency"
+          aggregation = "distribution"
+          ## Capture group or parsed field holding the value of sum and distribution
+          field = "latency"
+  [[inputs.logs.file_config]]
+      file_path = "/var/log/containers/*.log"
+      log_group_name = "/eks/{namespace}"
+      log_stream_name = "{pod_name}/{container_name}"
+      ## Decode the lines written by the container runtimes and add the metadata of their pods
+      [inputs.logs.file_config.container_log]
+          ## cri, docker or auto (default)
+          format = "auto"
+          ## Add the uid and labels of the pods from the Kubernetes API
+          kubernetes_metadata = true
 
 `
 
@@ -96,11 +152,24 @@ func (t *LogFile) Description() string {
 	return "Stream a log file, like the tail -f command"
 }
 
+// Gather passes the metrics extracted by the metric filters since the last call to the accumulator.
 func (t *LogFile) Gather(acc telegraf.Accumulator) error {
+	t.metrics.gather(acc)
 	return nil
 }
 
+// Start is called by both the log agent and the adapter receiver when the metric filters are
+// gathered by the metrics pipeline. Only the adapter receiver provides an accumulator.
 func (t *LogFile) Start(acc telegraf.Accumulator) error {
+	if acc != nil {
+		t.metrics.enable()
+	}
+	t.startMu.Lock()
+	defer t.startMu.Unlock()
+	if t.started {
+		return nil
+	}
+
 	// Create the log file state folder.
 	err := os.MkdirAll(t.FileStateFolder, 0755)
 	if err != nil {
@@ -131,6 +200,7 @@ func (t *LogFile) Start(acc telegraf.Accumulator) error {
 	}
 
 	t.started = true
+	t.startTime = time.Now()
 	t.Log.Infof("turned on logs plugin")
 	return nil
 }
@@ -139,11 +209,37 @@ func (t *LogFile) Stop() {
 	// Tailer srcs are stopped by log agent after the output plugin is stopped instead of here
 	// because the tailersrc would like to record an accurate uploaded offset
 	close(t.done)
+	// the srcs already stopped are done with the state folder once their final state is saved
+	t.srcsMu.Lock()
+	srcs := t.srcs
+	t.srcsMu.Unlock()
+	for _, ts := range srcs {
+		ts.waitStateSaved()
+	}
+}
+
+// addSrc registers the src until its state is saved for the last time.
+func (t *LogFile) addSrc(ts *tailerSrc) {
+	t.srcsMu.Lock()
+	defer t.srcsMu.Unlock()
+	// a new slice is used as Stop may be going through the previous one
+	srcs := make([]*tailerSrc, 0, len(t.srcs)+1)
+	for _, s := range t.srcs {
+		select {
+		case <-s.stateSaved:
+		default:
+			srcs = append(srcs, s)
+		}
+	}
+	t.srcs = append(srcs, ts)
 }
 
 // Try to find if there is any new file needs to be added for monitoring.
 func (t *LogFile) FindLogSrc() []logs.LogSrc {
-	if !t.started {
+	t.startMu.Lock()
+	started := t.started
+	t.startMu.Unlock()
+	if !started {
 		t.Log.Warn("not started with file state folder %s", t.FileStateFolder)
 		return nil
 	}
@@ -155,20 +251,31 @@ func (t *LogFile) FindLogSrc() []logs.LogSrc {
 	// Create a "tailer" for each file
 	for i := range t.FileConfig {
 		fileconfig := &t.FileConfig[i]
-		targetFiles, err := t.getTargetFiles(fileconfig)
+		targetFiles, archiveFiles, err := t.getTargetFiles(fileconfig)
 		if err != nil {
 			t.Log.Errorf("Failed to find target files for file config %v, with error: %v", fileconfig.FilePath, err)
 		}
+		dests, ok := t.configs[fileconfig]
+		if !ok {
+			dests = make(map[string]*tailerSrc)
+			t.configs[fileconfig] = dests
+		}
+		var tailed map[fileKey]string
 		for _, filename := range targetFiles {
-			dests, ok := t.configs[fileconfig]
-			if !ok {
-				dests = make(map[string]*tailerSrc)
-				t.configs[fileconfig] = dests
-			}
-
 			if _, ok := dests[filename]; ok {
 				continue
-			} else if fileconfig.AutoRemoval {
+			}
+			// the identity is read once for the file, to be matched with the tailed files and its state
+			id, idErr := readFileIdentity(filename)
+			if idErr == nil && len(dests) > 0 {
+				if tailed == nil {
+					tailed = tailedFiles(dests)
+				}
+				if name, ok := tailed[id.key()]; ok && name != filename {
+					continue
+				}
+			}
+			if fileconfig.AutoRemoval {
 				// This logic means auto_removal does not work with publish_multi_logs
 				for _, dst := range dests {
 					// Stop all other tailers in favor of the newly found file
@@ -177,18 +284,13 @@ func (t *LogFile) FindLogSrc() []logs.LogSrc {
 			}
 
 			var seekFile *tail.SeekInfo
-			offset, err := t.restoreState(filename)
+			offset, err := t.restoreState(filename, id, idErr)
 			if err == nil { // Missing state file would be an error too
 				seekFile = &tail.SeekInfo{Whence: io.SeekStart, Offset: offset}
 			} else if !fileconfig.Pipe && !fileconfig.FromBeginning {
 				seekFile = &tail.SeekInfo{Whence: io.SeekEnd, Offset: 0}
 			}
 
-			isutf16 := false
-			if fileconfig.Encoding == "utf-16" || fileconfig.Encoding == "utf-16le" || fileconfig.Encoding == "UTF-16" || fileconfig.Encoding == "UTF-16LE" {
-				isutf16 = true
-			}
-
 			tailer, err := tail.TailFile(filename,
 				tail.Config{
 					ReOpen:      false,
@@ -198,7 +300,7 @@ func (t *LogFile) FindLogSrc() []logs.LogSrc {
 					Pipe:        fileconfig.Pipe,
 					Poll:        true,
 					MaxLineSize: fileconfig.MaxEventSize,
-					IsUTF16:     isutf16,
+					IsUTF16:     fileconfig.isUTF16(),
 				})
 
 			if err != nil {
@@ -206,73 +308,129 @@ func (t *LogFile) FindLogSrc() []logs.LogSrc {
 				continue
 			}
 
-			var mlCheck func(string) bool
-			if fileconfig.MultiLineStartPattern != "" {
-				mlCheck = fileconfig.isMultilineStart
-			}
-
-			groupName := fileconfig.LogGroupName
-			streamName := fileconfig.LogStreamName
+			src := t.newTailerSrc(fileconfig, filename, tailer)
+			srcs = append(srcs, src)
 
-			// In case of multilog, the group and stream has to be generated here
-			// since it is based on the actual file name
-			if fileconfig.PublishMultiLogs {
-				if groupName == "" {
-					groupName = generateLogGroupName(filename)
-				} else {
-					streamName = generateLogStreamName(filename, fileconfig.LogStreamName)
-				}
+			dests[filename] = src
+			if tailed != nil && src.identity != nil {
+				tailed[src.identity.key()] = filename
 			}
-
-			destination := fileconfig.Destination
-			if destination == "" {
-				destination = t.Destination
+		}
+		for _, filename := range archiveFiles {
+			if _, ok := dests[filename]; ok {
+				continue
+			}
+			src, err := t.newArchiveSrc(fileconfig, filename)
+			if err != nil {
+				t.Log.Errorf("Failed to read compressed file %v with error: %v", filename, err)
+				continue
+			}
+			if src == nil {
+				continue
 			}
+			srcs = append(srcs, src)
+			dests[filename] = src
+		}
+	}
 
-			src := NewTailerSrc(
-				groupName, streamName,
-				t.Destination,
-				t.getStateFilePath(filename),
-				fileconfig.LogGroupClass,
-				tailer,
-				fileconfig.AutoRemoval,
-				mlCheck,
-				fileconfig.Filters,
-				fileconfig.timestampFromLogLine,
-				fileconfig.Enc,
-				fileconfig.MaxEventSize,
-				fileconfig.TruncateSuffix,
-				fileconfig.RetentionInDays,
-			)
-
-			src.AddCleanUpFn(func(ts *tailerSrc) func() {
-				return func() {
-					select {
-					case <-t.done: // No clean up needed after input plugin is stopped
-					case t.removeTailerSrcCh <- ts:
-					}
+	return srcs
+}
 
-				}
-			}(src))
+// resolveName resolves the placeholders of the file in the log group or stream name, the host ones
+// being resolved by the file config, and returns the template of the name when it has placeholders
+// evaluated for each event.
+func resolveName(name, filename string) (string, *template.Template) {
+	tmpl := template.Parse(name).Resolve(template.FileResolver(filename))
+	if !tmpl.Dynamic() {
+		return tmpl.Execute(nil), nil
+	}
+	return tmpl.String(), &tmpl
+}
 
-			srcs = append(srcs, src)
+// newTailerSrc creates the tailer src publishing the lines of the file as configured.
+func (t *LogFile) newTailerSrc(fileconfig *FileConfig, filename string, tailer *tail.Tail) *tailerSrc {
+	var mlCheck func(string) bool
+	if fileconfig.MultiLineStartPattern != "" {
+		mlCheck = fileconfig.isMultilineStart
+	}
 
-			dests[filename] = src
+	groupName := fileconfig.LogGroupName
+	streamName := fileconfig.LogStreamName
+
+	// In case of multilog, the group and stream has to be generated here
+	// since it is based on the actual file name
+	if fileconfig.PublishMultiLogs {
+		if groupName == "" {
+			groupName = generateLogGroupName(filename)
+		} else {
+			streamName = generateLogStreamName(filename, fileconfig.LogStreamName)
 		}
 	}
 
-	return srcs
+	// The container log files are routed by the container found from their name
+	var container *containerLogDecoder
+	if fileconfig.ContainerLog != nil {
+		container = newContainerLogDecoder(fileconfig.ContainerLog, filename, fileconfig.MaxEventSize)
+		groupName = container.render(groupName)
+		streamName = container.render(streamName)
+	}
+	groupName, groupTemplate := resolveName(groupName, filename)
+	streamName, streamTemplate := resolveName(streamName, filename)
+
+	destination := fileconfig.Destination
+	if destination == "" {
+		destination = t.Destination
+	}
+
+	src := NewTailerSrc(
+		groupName, streamName,
+		destination,
+		t.getStateFilePath(filename),
+		fileconfig.LogGroupClass,
+		tailer,
+		fileconfig.AutoRemoval,
+		mlCheck,
+		fileconfig.Filters,
+		fileconfig.Parser,
+		fileconfig.timestampFromLogLine,
+		fileconfig.Enc,
+		fileconfig.MaxEventSize,
+		fileconfig.TruncateSuffix,
+		fileconfig.RetentionInDays,
+	)
+	src.rateLimiter = fileconfig.rateLimiter
+	src.container = container
+	src.groupTemplate = groupTemplate
+	src.streamTemplate = streamTemplate
+	if len(fileconfig.MetricFilters) > 0 {
+		src.metricFilters = fileconfig.MetricFilters
+		src.metrics = t.metrics
+	}
+
+	src.AddCleanUpFn(func(ts *tailerSrc) func() {
+		return func() {
+			select {
+			case <-t.done: // No clean up needed after input plugin is stopped
+			case t.removeTailerSrcCh <- ts:
+			}
+
+		}
+	}(src))
+	t.addSrc(src)
+
+	return src
 }
 
-func (t *LogFile) getTargetFiles(fileconfig *FileConfig) ([]string, error) {
+// getTargetFiles returns the files to tail and, when compressed files are read, the compressed files.
+func (t *LogFile) getTargetFiles(fileconfig *FileConfig) ([]string, []string, error) {
 	filePath := fileconfig.FilePath
 	blacklistP := fileconfig.BlacklistRegexP
 	g, err := globpath.Compile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("file_path glob %s failed to compile, %s", filePath, err)
+		return nil, nil, fmt.Errorf("file_path glob %s failed to compile, %s", filePath, err)
 	}
 
-	var targetFileList []string
+	var targetFileList, archiveFileList []string
 	var targetFileName string
 	var targetModTime time.Time
 	for matchedFileName, matchedFileInfo := range g.Match() {
@@ -282,13 +440,9 @@ func (t *LogFile) getTargetFiles(fileconfig *FileConfig) ([]string, error) {
 			continue
 		}
 
-		if isCompressedFile(matchedFileName) {
-			continue
-		}
-
 		// If it's a dir or a symbolic link pointing to a dir, ignore it
 		if isDir, err := isDirectory(matchedFileName); err != nil {
-			return nil, fmt.Errorf("error tailing file %v with error: %v", matchedFileName, err)
+			return nil, nil, fmt.Errorf("error tailing file %v with error: %v", matchedFileName, err)
 		} else if isDir {
 			continue
 		}
@@ -298,7 +452,15 @@ func (t *LogFile) getTargetFiles(fileconfig *FileConfig) ([]string, error) {
 		if blacklistP != nil && blacklistP.MatchString(fileBaseName) {
 			continue
 		}
-		if !fileconfig.PublishMultiLogs {
+
+		if isCompressedFile(matchedFileName) {
+			if fileconfig.ReadCompressedFiles && isArchiveFile(matchedFileName) {
+				archiveFileList = append(archiveFileList, matchedFileName)
+			}
+			continue
+		}
+		// Each container log file is published to the log stream of its container
+		if !fileconfig.PublishMultiLogs && fileconfig.ContainerLog == nil {
 			if targetFileName == "" || matchedFileInfo.ModTime().After(targetModTime) {
 				targetFileName = matchedFileName
 				targetModTime = matchedFileInfo.ModTime()
@@ -312,35 +474,71 @@ func (t *LogFile) getTargetFiles(fileconfig *FileConfig) ([]string, error) {
 		targetFileList = append(targetFileList, targetFileName)
 	}
 
-	return targetFileList, nil
+	return targetFileList, archiveFileList, nil
 }
 
 // The plugin will look at the state folder, and restore the offset of the file seeked if such state exists.
-func (t *LogFile) restoreState(filename string) (int64, error) {
+// The state saved for the file name is only used when it was saved for the same file. Otherwise the
+// file is either a rotated file whose state was saved under its old name, which is finished from its
+// old offset, or a new file reusing the name, which is read from the beginning.
+func (t *LogFile) restoreState(filename string, id fileIdentity, idErr error) (int64, error) {
 	filePath := t.getStateFilePath(filename)
 
-	if _, err := os.Stat(filePath); err != nil {
+	state, err := readFileState(filePath)
+	if os.IsNotExist(err) {
 		t.Log.Debugf("The state file %s for %s does not exist: %v", filePath, filename, err)
-		return 0, err
+	} else if err != nil {
+		t.Log.Warnf("Issue encountered when reading offset from state file %s for %s: %v", filePath, filename, err)
 	}
 
-	byteArray, err := os.ReadFile(filePath)
-	if err != nil {
-		t.Log.Warnf("Issue encountered when reading offset from file %s: %v", filename, err)
-		return 0, err
+	if idErr != nil {
+		// without an identity the state saved for the name is trusted as is
+		if err != nil {
+			return 0, err
+		}
+		t.Log.Infof("Reading from offset %v in %s", state.offset, filename)
+		return state.offset, nil
 	}
 
-	offset, err := strconv.ParseInt(strings.Split(string(byteArray), "\n")[0], 10, 64)
-	if err != nil {
-		t.Log.Warnf("Issue encountered when parsing offset value %v: %v", byteArray, err)
-		return 0, err
+	if err == nil && state.matches(id) {
+		t.Log.Infof("Reading from offset %v in %s", state.offset, filename)
+		return state.offset, nil
 	}
+	if rotated, ok := t.findRotatedState(filePath, id); ok {
+		t.Log.Infof("Reading from offset %v in %s, rotated from %s", rotated.offset, filename, rotated.filename)
+		return rotated.offset, nil
+	}
+	if err == nil {
+		// keep the state around in case the file it was saved for shows up under another name
+		if state.fingerprint != nil {
+			if err = writeFileState(rotatedStateFilePath(filePath, *state.fingerprint), state); err != nil {
+				t.Log.Warnf("Issue encountered when keeping the state of the file replaced by %s: %v", filename, err)
+			}
+		}
+		t.Log.Infof("The file %s was replaced since its state was saved, reading from the beginning", filename)
+		return 0, nil
+	}
+	return 0, err
+}
 
-	if offset < 0 {
-		return 0, fmt.Errorf("negative state file offset, %v, %v", filePath, offset)
+// findRotatedState looks for the state of the file saved under another name. A state saved for the
+// same file is preferred over one saved for a file with the same content, which is what copytruncate
+// rotation leaves behind. The state of a file which was renamed is removed once it has been claimed.
+func (t *LogFile) findRotatedState(stateFilePath string, id fileIdentity) (fileState, bool) {
+	return t.states.find(t.FileStateFolder, stateFilePath, id)
+}
+
+// tailedFiles indexes the files tailed for a file config by their device and inode, so that a file
+// still being tailed under the name it had before it was renamed is picked up under the new name
+// once that tailer reaches the end of it.
+func tailedFiles(dests map[string]*tailerSrc) map[fileKey]string {
+	tailed := make(map[fileKey]string, len(dests))
+	for name, src := range dests {
+		if src.identity != nil {
+			tailed[src.identity.key()] = name
+		}
 	}
-	t.Log.Infof("Reading from offset %v in %s", offset, filename)
-	return offset, nil
+	return tailed
 }
 
 func (t *LogFile) getStateFilePath(filename string) string {
@@ -357,7 +555,8 @@ func (t *LogFile) cleanupStateFolder() {
 		t.Log.Errorf("Error happens in cleanup state folder %s: %v", t.FileStateFolder, err)
 	}
 	for _, file := range files {
-		if info, err := os.Stat(file); err != nil || info.IsDir() {
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
 			t.Log.Debugf("File %v does not exist or is a dirctory: %v, %v", file, err, info)
 			continue
 		}
@@ -366,6 +565,23 @@ func (t *LogFile) cleanupStateFolder() {
 			continue
 		}
 
+		if strings.HasSuffix(file, tmpStateSuffix) {
+			// left behind by a crash while the state was saved, the previous state being kept
+			if err = os.Remove(file); err != nil {
+				t.Log.Errorf("Error happens when deleting temporary state file %s: %v", file, err)
+			}
+			continue
+		}
+
+		if strings.HasSuffix(file, rotatedStateSuffix) {
+			if time.Since(info.ModTime()) > rotatedStateRetention {
+				if err = os.Remove(file); err != nil {
+					t.Log.Errorf("Error happens when deleting old state file %s: %v", file, err)
+				}
+			}
+			continue
+		}
+
 		byteArray, err := os.ReadFile(file)
 		if err != nil {
 			t.Log.Errorf("Error happens when reading the content from file %s in clean up state fodler step: %v", file, err)
@@ -402,7 +618,7 @@ func (t *LogFile) cleanUpStoppedTailerSrc() {
 	}
 }
 
-// Compressed file should be skipped.
+// Compressed file should be skipped unless compressed files are read.
 // This func is to determine whether the file is compressed or not based on the file name suffix.
 func isCompressedFile(filename string) bool {
 	suffix := filepath.Ext(filename)
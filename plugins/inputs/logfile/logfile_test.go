@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -19,6 +20,8 @@ import (
 	"golang.org/x/text/transform"
 
 	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/ratelimit"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
 )
 
 const (
@@ -89,6 +92,126 @@ func TestLogs(t *testing.T) {
 	tt.Stop()
 }
 
+func TestLogsRateLimit(t *testing.T) {
+	multilineWaitPeriod = 10 * time.Millisecond
+	tmpfile, err := createTempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	for i := 0; i < 20; i++ {
+		_, err = fmt.Fprintf(tmpfile, "line%d\n", i)
+		require.NoError(t, err)
+	}
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = t.TempDir()
+	tt.FileConfig = []FileConfig{{
+		FilePath:      tmpfile.Name(),
+		FromBeginning: true,
+		RateLimit:     &ratelimit.Config{MaxEventsPerSecond: 5, OverflowPolicy: ratelimit.PolicyDropNewest},
+	}}
+	require.NoError(t, tt.FileConfig[0].init())
+	tt.started = true
+
+	lsrcs := tt.FindLogSrc()
+	require.Len(t, lsrcs, 1)
+	evts := make(chan logs.LogEvent, 20)
+	lsrcs[0].SetOutput(func(e logs.LogEvent) {
+		if e != nil {
+			evts <- e
+		}
+	})
+
+	statKey := fmt.Sprintf("logfile_%s_messages_rate_limit_dropped", tmpfile.Name())
+	var published []string
+	require.Eventually(t, func() bool {
+		for {
+			select {
+			case e := <-evts:
+				published = append(published, e.Message())
+				e.Done()
+			default:
+				return len(published)+int(profiler.Profiler.GetStats()[statKey]) == 20
+			}
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	// the burst of the limit is one second worth of events
+	assert.GreaterOrEqual(t, len(published), 5)
+	assert.Equal(t, []string{"line0", "line1", "line2", "line3", "line4"}, published[:5])
+
+	lsrcs[0].Stop()
+	tt.Stop()
+}
+
+func TestLogsDynamicNames(t *testing.T) {
+	multilineWaitPeriod = 10 * time.Millisecond
+	tmpfile, err := createTempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString("tenant=acme msg=first\n{\"tenant\":\"globex\"}\nmsg=none\n")
+	require.NoError(t, err)
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = t.TempDir()
+	tt.FileConfig = []FileConfig{{
+		FilePath:      tmpfile.Name(),
+		FromBeginning: true,
+		LogGroupName:  "/app/{field:tenant}",
+		LogStreamName: "{file:basename}",
+	}, {
+		FilePath:      tmpfile.Name(),
+		FromBeginning: true,
+		LogGroupName:  "/app",
+		LogStreamName: "{file:basename}-{field:tenant}",
+		Parser:        &LogParser{Format: parserFormatLogfmt},
+	}}
+	for i := range tt.FileConfig {
+		require.NoError(t, tt.FileConfig[i].init())
+	}
+	tt.started = true
+
+	lsrcs := tt.FindLogSrc()
+	require.Len(t, lsrcs, 2)
+	basename := filepath.Base(tmpfile.Name())
+	want := map[string][]string{
+		// the fields of the events which are JSON objects are used when they are not parsed
+		"/app/{field:tenant}": {"/app/unknown/" + basename, "/app/globex/" + basename, "/app/unknown/" + basename},
+		"/app":                {"/app/" + basename + "-acme", "/app/" + basename + "-globex", "/app/" + basename + "-unknown"},
+	}
+	for _, lsrc := range lsrcs {
+		evts := make(chan logs.LogEvent, 3)
+		lsrc.SetOutput(func(e logs.LogEvent) {
+			if e != nil {
+				evts <- e
+			}
+		})
+		var got []string
+		for range want[lsrc.Group()] {
+			select {
+			case e := <-evts:
+				re, ok := e.(logs.RoutedLogEvent)
+				require.True(t, ok)
+				group := re.Group()
+				if group == "" {
+					group = lsrc.Group()
+				}
+				stream := re.Stream()
+				if stream == "" {
+					stream = lsrc.Stream()
+				}
+				got = append(got, group+"/"+stream)
+				e.Done()
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the log events")
+			}
+		}
+		assert.Equal(t, want[lsrc.Group()], got)
+		lsrc.Stop()
+	}
+	tt.Stop()
+}
+
 func TestLogsEncoding(t *testing.T) {
 	multilineWaitPeriod = 10 * time.Millisecond
 	//2 * rune_len when it is coded in gbk encoding.
@@ -208,7 +331,7 @@ func TestRestoreState(t *testing.T) {
 	tt := NewLogFile()
 	tt.Log = TestLogger{t}
 	tt.FileStateFolder = tmpfolder
-	roffset, err := tt.restoreState(logFilePath)
+	roffset, err := restoreState(tt, logFilePath)
 	require.NoError(t, err)
 	assert.Equal(t, offset, roffset, fmt.Sprintf("The actual offset is %d, different from the expected offset %d.", roffset, offset))
 
@@ -219,13 +342,96 @@ func TestRestoreState(t *testing.T) {
 		[]byte(strconv.FormatInt(offset, 10)+"\n"+logFilePath),
 		os.ModePerm)
 	require.NoError(t, err)
-	roffset, err = tt.restoreState(logFilePath)
+	roffset, err = restoreState(tt, logFilePath)
 	require.Error(t, err)
 	assert.Equal(t, int64(0), roffset, fmt.Sprintf("The actual offset is %d, different from the expected offset %d.", roffset, offset))
 
 	tt.Stop()
 }
 
+func restoreState(tt *LogFile, filename string) (int64, error) {
+	id, err := readFileIdentity(filename)
+	return tt.restoreState(filename, id, err)
+}
+
+func TestRestoreStateWithFingerprint(t *testing.T) {
+	tmpfolder := t.TempDir()
+	logFolder := t.TempDir()
+	logFilePath := filepath.Join(logFolder, "app.log")
+	content := []byte(strings.Repeat("line of the original file\n", 100))
+	require.NoError(t, os.WriteFile(logFilePath, content, 0644))
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = tmpfolder
+
+	writeState := func(offset int64, filename string) {
+		id, err := readFileIdentity(filename)
+		require.NoError(t, err)
+		fp := id.fingerprint()
+		state := fileState{offset: offset, filename: filename, fingerprint: &fp}
+		require.NoError(t, os.WriteFile(tt.getStateFilePath(filename), state.marshal(), stateFileMode))
+	}
+
+	// same file
+	writeState(100, logFilePath)
+	offset, err := restoreState(tt, logFilePath)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, offset)
+
+	// renamed and replaced by a new file
+	rotatedFilePath := logFilePath + ".1"
+	require.NoError(t, os.Rename(logFilePath, rotatedFilePath))
+	require.NoError(t, os.WriteFile(logFilePath, []byte("line of the new file\n"), 0644))
+	offset, err = restoreState(tt, logFilePath)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, offset)
+	rotatedStates, _ := filepath.Glob(filepath.Join(tmpfolder, "*"+rotatedStateSuffix))
+	assert.Len(t, rotatedStates, 1)
+
+	// the rotated file is finished from its old offset once the new file has its own state
+	writeState(10, logFilePath)
+	offset, err = restoreState(tt, rotatedFilePath)
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, offset)
+	rotatedStates, _ = filepath.Glob(filepath.Join(tmpfolder, "*"+rotatedStateSuffix))
+	assert.Len(t, rotatedStates, 0)
+
+	// copytruncate
+	require.NoError(t, os.WriteFile(logFilePath, content, 0644))
+	writeState(200, logFilePath)
+	copiedFilePath := logFilePath + ".2"
+	require.NoError(t, os.WriteFile(copiedFilePath, content, 0644))
+	require.NoError(t, os.Truncate(logFilePath, 0))
+	offset, err = restoreState(tt, copiedFilePath)
+	require.NoError(t, err)
+	assert.EqualValues(t, 200, offset)
+	offset, err = restoreState(tt, logFilePath)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, offset)
+}
+
+func TestRestoreStateMigration(t *testing.T) {
+	tmpfolder := t.TempDir()
+	logFilePath := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(logFilePath, []byte(strings.Repeat("a", 100)), 0644))
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = tmpfolder
+
+	// state files without a fingerprint are trusted as long as the offset fits in the file
+	require.NoError(t, os.WriteFile(tt.getStateFilePath(logFilePath), []byte("50\n"+logFilePath), stateFileMode))
+	offset, err := restoreState(tt, logFilePath)
+	require.NoError(t, err)
+	assert.EqualValues(t, 50, offset)
+
+	require.NoError(t, os.WriteFile(tt.getStateFilePath(logFilePath), []byte("500\n"+logFilePath), stateFileMode))
+	offset, err = restoreState(tt, logFilePath)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, offset)
+}
+
 func TestMultipleFilesForSameConfig(t *testing.T) {
 	multilineWaitPeriod = 10 * time.Millisecond
 	tmpfile1, err := createTempFile("", "tmp1_")
@@ -442,6 +648,8 @@ func createWriteRead(t *testing.T, prefix string, logFile *LogFile, done chan bo
 	for i := 0; i < numLines; i++ {
 		logEvent := <-evts
 		require.Equal(t, msg, logEvent.Message())
+		// acknowledged like the output does, the file not being read further otherwise
+		logEvent.Done()
 		if isParent && i == numLines/2 {
 			// Halfway through start child goroutine to create another temp file.
 			go createWriteRead(t, prefix, logFile, done2, false)
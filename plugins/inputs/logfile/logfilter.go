@@ -4,8 +4,13 @@
 package logfile
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/aws/amazon-cloudwatch-agent/logs"
 )
@@ -13,20 +18,66 @@ import (
 const (
 	includeFilterType = "include"
 	excludeFilterType = "exclude"
+	redactFilterType  = "redact"
+
+	maskRedactStrategy   = "mask"
+	hashRedactStrategy   = "hash"
+	removeRedactStrategy = "remove"
+
+	redactMask = "****"
 )
 
 var (
-	validFilterTypes    = []string{includeFilterType, excludeFilterType}
+	validFilterTypes    = []string{includeFilterType, excludeFilterType, redactFilterType}
 	validFilterTypesSet = map[string]bool{
 		includeFilterType: true,
 		excludeFilterType: true,
+		redactFilterType:  true,
+	}
+	validRedactStrategiesSet = map[string]bool{
+		maskRedactStrategy:   true,
+		hashRedactStrategy:   true,
+		removeRedactStrategy: true,
 	}
 )
 
+// redactDetector finds sensitive values in log messages. Only the first capture group is
+// replaced if the expression has one, otherwise the whole match is replaced.
+type redactDetector struct {
+	expressionP *regexp.Regexp
+	// validate rejects false positives, every match is redacted when nil.
+	validate func(string) bool
+}
+
+var redactDetectors = map[string]redactDetector{
+	"credit_card": {
+		expressionP: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		validate:    isLuhnValid,
+	},
+	"email": {
+		expressionP: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	"bearer_token": {
+		expressionP: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+	},
+	"aws_secret_key": {
+		expressionP: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	},
+}
+
 type LogFilter struct {
-	Type        string `toml:"type"`
-	Expression  string `toml:"expression"`
+	Type       string `toml:"type"`
+	Expression string `toml:"expression"`
+	//The built-in detectors used by the redact filter type in addition to the expression.
+	Detectors []string `toml:"detectors"`
+	//How the redact filter type replaces the sensitive values, one of mask, hash or remove.
+	Strategy string `toml:"strategy"`
+	//The secret key of the HMAC-SHA256 of the hash strategy, so that the hashes of guessable values
+	//such as card numbers can't be reversed by hashing every candidate.
+	HashKey string `toml:"hash_key"`
+
 	expressionP *regexp.Regexp
+	detectors   []redactDetector
 }
 
 func (filter *LogFilter) init() error {
@@ -34,6 +85,10 @@ func (filter *LogFilter) init() error {
 		return fmt.Errorf("filter type %s is incorrect, valid types are: %v", filter.Type, validFilterTypes)
 	}
 
+	if filter.Type == redactFilterType {
+		return filter.initRedact()
+	}
+
 	var err error
 	if filter.expressionP, err = regexp.Compile(filter.Expression); err != nil {
 		return fmt.Errorf("filter regex has issue, regexp: Compile( %v ): %v", filter.Expression, err.Error())
@@ -41,7 +96,123 @@ func (filter *LogFilter) init() error {
 	return nil
 }
 
+func (filter *LogFilter) initRedact() error {
+	if filter.Strategy == "" {
+		filter.Strategy = maskRedactStrategy
+	}
+	if _, present := validRedactStrategiesSet[filter.Strategy]; !present {
+		return fmt.Errorf("redact strategy %s is incorrect, valid strategies are: %v", filter.Strategy, []string{maskRedactStrategy, hashRedactStrategy, removeRedactStrategy})
+	}
+	if filter.Strategy == hashRedactStrategy && filter.HashKey == "" {
+		return fmt.Errorf("redact strategy %s requires a hash_key", hashRedactStrategy)
+	}
+	for _, name := range filter.Detectors {
+		detector, ok := redactDetectors[name]
+		if !ok {
+			return fmt.Errorf("redact detector %s is incorrect, valid detectors are: %v", name, redactDetectorNames())
+		}
+		filter.detectors = append(filter.detectors, detector)
+	}
+	if filter.Expression != "" {
+		expressionP, err := regexp.Compile(filter.Expression)
+		if err != nil {
+			return fmt.Errorf("filter regex has issue, regexp: Compile( %v ): %v", filter.Expression, err.Error())
+		}
+		filter.detectors = append(filter.detectors, redactDetector{expressionP: expressionP})
+	}
+	if len(filter.detectors) == 0 {
+		return fmt.Errorf("redact filter requires an expression or at least one detector")
+	}
+	return nil
+}
+
 func (filter *LogFilter) ShouldPublish(event logs.LogEvent) bool {
+	if filter.Type == redactFilterType {
+		return true
+	}
 	match := filter.expressionP.MatchString(event.Message())
 	return (filter.Type == includeFilterType) == match
 }
+
+// Redact replaces the sensitive values found by the detectors of a redact filter and returns
+// the message along with the number of substitutions made.
+func (filter *LogFilter) Redact(msg string) (string, int) {
+	if filter.Type != redactFilterType {
+		return msg, 0
+	}
+	total := 0
+	for _, detector := range filter.detectors {
+		var count int
+		msg, count = detector.redact(msg, filter.Strategy, filter.HashKey)
+		total += count
+	}
+	return msg, total
+}
+
+func (d redactDetector) redact(msg, strategy, hashKey string) (string, int) {
+	matches := d.expressionP.FindAllStringSubmatchIndex(msg, -1)
+	if len(matches) == 0 {
+		return msg, 0
+	}
+	var sb strings.Builder
+	count, last := 0, 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if len(match) > 3 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		value := msg[start:end]
+		if d.validate != nil && !d.validate(value) {
+			continue
+		}
+		sb.WriteString(msg[last:start])
+		sb.WriteString(redactValue(value, strategy, hashKey))
+		last = end
+		count++
+	}
+	sb.WriteString(msg[last:])
+	return sb.String(), count
+}
+
+func redactValue(value, strategy, hashKey string) string {
+	switch strategy {
+	case hashRedactStrategy:
+		mac := hmac.New(sha256.New, []byte(hashKey))
+		mac.Write([]byte(value))
+		return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)[:8])
+	case removeRedactStrategy:
+		return ""
+	default:
+		return redactMask
+	}
+}
+
+// isLuhnValid checks the credit card number checksum, ignoring spaces and dashes.
+func isLuhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func redactDetectorNames() []string {
+	names := make([]string, 0, len(redactDetectors))
+	for name := range redactDetectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,192 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+)
+
+const (
+	parserFormatJSON   = "json"
+	parserFormatLogfmt = "logfmt"
+	parserFormatRegex  = "regex"
+)
+
+var errNoParsedFields = errors.New("no fields found")
+
+// LogParser decodes structured log events and rewrites them as normalized JSON documents.
+type LogParser struct {
+	//The format of the log events, one of json, logfmt or regex.
+	Format string `toml:"format"`
+	//The regex with named capture groups used by the regex format.
+	Pattern string `toml:"pattern"`
+	//The field holding the timestamp of the log event.
+	TimestampField string `toml:"timestamp_field"`
+	//The layouts used to parse the timestamp field. RFC3339 and epoch numbers are accepted when empty.
+	TimestampLayout []string `toml:"timestamp_layout"`
+	//The fields removed from the parsed document.
+	DropFields []string `toml:"drop_fields"`
+	//The fields renamed in the parsed document, keyed by the original name.
+	RenameFields map[string]string `toml:"rename_fields"`
+
+	patternP *regexp.Regexp
+	location *time.Location
+}
+
+func (p *LogParser) init(location *time.Location) error {
+	p.location = location
+	switch p.Format {
+	case parserFormatJSON, parserFormatLogfmt:
+	case parserFormatRegex:
+		var err error
+		if p.patternP, err = regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("parser pattern has issue, regexp: Compile( %v ): %v", p.Pattern, err.Error())
+		}
+		if !hasNamedGroup(p.patternP) {
+			return fmt.Errorf("parser pattern %v has no named capture group", p.Pattern)
+		}
+	default:
+		return fmt.Errorf("parser format %q is not supported", p.Format)
+	}
+	return nil
+}
+
+// Parse decodes the log event and returns it as a JSON document along with the timestamp
+// found in the timestamp field. The timestamp is zero if the field is not configured or missing.
+func (p *LogParser) Parse(msg string) (string, time.Time, error) {
+	msg, _, timestamp, err := p.parse(msg)
+	return msg, timestamp, err
+}
+
+// parse is Parse also returning the fields of the JSON document.
+func (p *LogParser) parse(msg string) (string, map[string]interface{}, time.Time, error) {
+	fields, err := p.decode(msg)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+
+	var timestamp time.Time
+	if p.TimestampField != "" {
+		if v, ok := fields[p.TimestampField]; ok {
+			if timestamp, err = p.timestamp(v); err != nil {
+				return "", nil, time.Time{}, err
+			}
+		}
+	}
+	for _, field := range p.DropFields {
+		delete(fields, field)
+	}
+	for from, to := range p.RenameFields {
+		if v, ok := fields[from]; ok {
+			delete(fields, from)
+			fields[to] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err = enc.Encode(fields); err != nil {
+		return "", nil, time.Time{}, err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), fields, timestamp, nil
+}
+
+func (p *LogParser) decode(msg string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	switch p.Format {
+	case parserFormatJSON:
+		dec := json.NewDecoder(strings.NewReader(msg))
+		// keep the numbers as they are in the original message
+		dec.UseNumber()
+		if err := dec.Decode(&fields); err != nil {
+			return nil, err
+		}
+		if rest := strings.TrimSpace(msg[dec.InputOffset():]); rest != "" {
+			return nil, fmt.Errorf("unexpected data after the JSON document: %.32q", rest)
+		}
+	case parserFormatLogfmt:
+		// plain text is decoded as keys without values, so a record needs a key=value pair
+		var hasValue bool
+		dec := logfmt.NewDecoder(strings.NewReader(msg))
+		for dec.ScanRecord() {
+			for dec.ScanKeyval() {
+				fields[string(dec.Key())] = string(dec.Value())
+				hasValue = hasValue || len(dec.Value()) > 0
+			}
+		}
+		if err := dec.Err(); err != nil {
+			return nil, err
+		}
+		if !hasValue {
+			return nil, errNoParsedFields
+		}
+	case parserFormatRegex:
+		match := p.patternP.FindStringSubmatch(msg)
+		if match == nil {
+			return nil, fmt.Errorf("pattern %v does not match", p.Pattern)
+		}
+		for i, name := range p.patternP.SubexpNames() {
+			if name != "" {
+				fields[name] = match[i]
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errNoParsedFields
+	}
+	return fields, nil
+}
+
+func (p *LogParser) timestamp(v interface{}) (time.Time, error) {
+	s := fmt.Sprint(v)
+	for _, layout := range p.TimestampLayout {
+		if t, err := time.ParseInLocation(layout, s, p.location); err == nil {
+			return t, nil
+		}
+	}
+	if len(p.TimestampLayout) == 0 {
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return epochToTime(f), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse timestamp field %v with value %q", p.TimestampField, s)
+}
+
+func hasNamedGroup(r *regexp.Regexp) bool {
+	for _, name := range r.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// epochToTime guesses the unit of an epoch timestamp from its magnitude.
+func epochToTime(f float64) time.Time {
+	switch {
+	case f >= 1e17:
+		return time.Unix(0, int64(f))
+	case f >= 1e14:
+		return time.UnixMicro(int64(f))
+	case f >= 1e11:
+		return time.UnixMilli(int64(f))
+	default:
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9))
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogParserInit(t *testing.T) {
+	assert.NoError(t, (&LogParser{Format: parserFormatJSON}).init(time.UTC))
+	assert.NoError(t, (&LogParser{Format: parserFormatRegex, Pattern: `(?P<level>\w+)`}).init(time.UTC))
+	assert.Error(t, (&LogParser{Format: "xml"}).init(time.UTC))
+	assert.Error(t, (&LogParser{Format: parserFormatRegex, Pattern: `(\w+`}).init(time.UTC))
+	assert.Error(t, (&LogParser{Format: parserFormatRegex, Pattern: `(\w+)`}).init(time.UTC))
+}
+
+func TestLogParserJSON(t *testing.T) {
+	p := &LogParser{
+		Format:         parserFormatJSON,
+		TimestampField: "ts",
+		DropFields:     []string{"pid"},
+		RenameFields:   map[string]string{"msg": "message"},
+	}
+	require.NoError(t, p.init(time.UTC))
+
+	msg, ts, err := p.Parse(`{"ts":"2024-03-01T10:00:00.5Z","level":"info","msg":"a <b> & c","pid":42,"latency":0.10000000000000001}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"latency":0.10000000000000001,"level":"info","message":"a <b> & c","ts":"2024-03-01T10:00:00.5Z"}`, msg)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 5e8, time.UTC), ts.UTC())
+
+	_, _, err = p.Parse("not json")
+	assert.Error(t, err)
+	_, _, err = p.Parse(`{"ts":"yesterday"}`)
+	assert.Error(t, err)
+	_, _, err = p.Parse(`{"level":"info"} {"level":"warn"}`)
+	assert.Error(t, err)
+	_, _, err = p.Parse(`{"level":"info"} trailing text`)
+	assert.Error(t, err)
+	msg, _, err = p.Parse(" {\"level\":\"info\"} \r\n")
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"info"}`, msg)
+}
+
+func TestLogParserLogfmt(t *testing.T) {
+	p := &LogParser{Format: parserFormatLogfmt, TimestampField: "time"}
+	require.NoError(t, p.init(time.UTC))
+
+	msg, ts, err := p.Parse(`time=1709287200123 level=warn msg="disk almost full" path=/var`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"warn","msg":"disk almost full","path":"/var","time":"1709287200123"}`, msg)
+	assert.Equal(t, time.UnixMilli(1709287200123), ts)
+
+	_, _, err = p.Parse("")
+	assert.ErrorIs(t, err, errNoParsedFields)
+	// plain text lines have no key=value pair and are published unchanged
+	_, _, err = p.Parse("server started ok")
+	assert.ErrorIs(t, err, errNoParsedFields)
+	msg, _, err = p.Parse("started ok=true")
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok":"true","started":""}`, msg)
+}
+
+func TestLogParserRegex(t *testing.T) {
+	p := &LogParser{
+		Format:          parserFormatRegex,
+		Pattern:         `^(?P<time>\S+ \S+) \[(?P<level>\w+)\] (?P<message>.*)$`,
+		TimestampField:  "time",
+		TimestampLayout: []string{"2006-01-02 15:04:05"},
+		DropFields:      []string{"time"},
+	}
+	location, _ := time.LoadLocation("America/New_York")
+	require.NoError(t, p.init(location))
+
+	msg, ts, err := p.Parse("2024-03-01 10:00:00 [ERROR] connection reset")
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"ERROR","message":"connection reset"}`, msg)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, location), ts)
+
+	_, _, err = p.Parse("connection reset")
+	assert.Error(t, err)
+}
+
+func TestLogParserNoTimestampField(t *testing.T) {
+	p := &LogParser{Format: parserFormatJSON, TimestampField: "time"}
+	require.NoError(t, p.init(time.UTC))
+
+	msg, ts, err := p.Parse(`{"level":"info"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `{"level":"info"}`, msg)
+	assert.True(t, ts.IsZero())
+}
+
+func TestEpochToTime(t *testing.T) {
+	expected := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	assert.True(t, expected.Equal(epochToTime(1709287200)))
+	assert.True(t, expected.Equal(epochToTime(1709287200000)))
+	assert.True(t, expected.Equal(epochToTime(1709287200000000)))
+	assert.True(t, expected.Equal(epochToTime(1709287200000000000)))
+	assert.True(t, expected.Add(500*time.Millisecond).Equal(epochToTime(1709287200.5)))
+}
@@ -0,0 +1,229 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+)
+
+const (
+	countAggregation        = "count"
+	sumAggregation          = "sum"
+	distributionAggregation = "distribution"
+
+	// namespaceTagKey is the special attribute used by the cloudwatch output as the namespace of the metric.
+	namespaceTagKey = "aws:Namespace"
+	// metricValueField is the field name which is not appended to the metric name.
+	metricValueField = "value"
+)
+
+// MetricFilter extracts a metric from the log events of a file config. The events are matched
+// with the pattern, the value and the dimensions are taken from its named capture groups or from
+// the fields decoded by the parser.
+type MetricFilter struct {
+	//The name of the metric.
+	MetricName string `toml:"metric_name"`
+	//The namespace of the metric, the namespace of the metrics section is used when empty.
+	Namespace string `toml:"namespace"`
+	//The regex matched against the log events, all the events are matched when empty.
+	Pattern string `toml:"pattern"`
+	//How the matched events are aggregated, one of count, sum or distribution. Defaults to count.
+	Aggregation string `toml:"aggregation"`
+	//The capture group or parsed field holding the value of the sum and distribution aggregations.
+	Field string `toml:"field"`
+	//The capture groups or parsed fields used as dimensions of the metric.
+	Dimensions []string `toml:"dimensions"`
+
+	patternP *regexp.Regexp
+}
+
+func (f *MetricFilter) init(parser *LogParser) error {
+	if f.MetricName == "" {
+		return fmt.Errorf("metric filter %v has no metric_name", f)
+	}
+	switch f.Aggregation {
+	case "":
+		f.Aggregation = countAggregation
+	case countAggregation:
+	case sumAggregation, distributionAggregation:
+		if f.Field == "" {
+			return fmt.Errorf("metric filter %v has no field for the %s aggregation", f.MetricName, f.Aggregation)
+		}
+	default:
+		return fmt.Errorf("metric filter %v has unsupported aggregation %q", f.MetricName, f.Aggregation)
+	}
+	if f.Pattern != "" {
+		var err error
+		if f.patternP, err = regexp.Compile(f.Pattern); err != nil {
+			return fmt.Errorf("metric filter pattern has issue, regexp: Compile( %v ): %v", f.Pattern, err.Error())
+		}
+	}
+	if parser != nil {
+		return nil
+	}
+	// without a parser, the value and the dimensions can only come from the pattern
+	names := append([]string{}, f.Dimensions...)
+	if f.Aggregation != countAggregation {
+		names = append(names, f.Field)
+	}
+	for _, name := range names {
+		if f.patternP == nil || f.patternP.SubexpIndex(name) < 0 {
+			return fmt.Errorf("metric filter %v uses %q which is neither a capture group of its pattern nor a parsed field", f.MetricName, name)
+		}
+	}
+	return nil
+}
+
+// apply adds the metric of the event to the metrics if the event matches. Events missing the
+// value or a dimension are skipped.
+func (f *MetricFilter) apply(metrics *logMetrics, msg string, fields map[string]interface{}) {
+	var match []string
+	if f.patternP != nil {
+		if match = f.patternP.FindStringSubmatch(msg); match == nil {
+			return
+		}
+	}
+	lookup := func(name string) (string, bool) {
+		if f.patternP != nil {
+			if i := f.patternP.SubexpIndex(name); i >= 0 {
+				return match[i], match[i] != ""
+			}
+		}
+		if v, ok := fields[name]; ok && v != nil {
+			return fmt.Sprint(v), true
+		}
+		return "", false
+	}
+
+	tags := make(map[string]string, len(f.Dimensions)+1)
+	for _, dimension := range f.Dimensions {
+		v, ok := lookup(dimension)
+		if !ok {
+			return
+		}
+		tags[dimension] = v
+	}
+	if f.Namespace != "" {
+		tags[namespaceTagKey] = f.Namespace
+	}
+	value := float64(1)
+	if f.Aggregation != countAggregation {
+		v, ok := lookup(f.Field)
+		if !ok {
+			return
+		}
+		var err error
+		if value, err = strconv.ParseFloat(v, 64); err != nil {
+			log.Printf("D! [logfile] Metric filter %s field %s is not a number: %q", f.MetricName, f.Field, v)
+			return
+		}
+	}
+	metrics.add(f.MetricName, f.Aggregation, tags, value)
+}
+
+// logMetrics aggregates the metrics extracted by the metric filters of all the file configs until
+// they are gathered. Nothing is aggregated until the metrics are enabled, since they are only
+// gathered by the metrics pipeline.
+type logMetrics struct {
+	enabled atomic.Bool
+
+	mu      sync.Mutex
+	entries map[string]*logMetric
+}
+
+type logMetric struct {
+	name        string
+	aggregation string
+	tags        map[string]string
+	count       int64
+	sum         float64
+	dist        distribution.Distribution
+}
+
+func newLogMetrics() *logMetrics {
+	return &logMetrics{entries: make(map[string]*logMetric)}
+}
+
+func (m *logMetrics) enable() {
+	m.enabled.Store(true)
+}
+
+func (m *logMetrics) add(name, aggregation string, tags map[string]string, value float64) {
+	if !m.enabled.Load() {
+		return
+	}
+	key := metricKey(name, tags)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		e = &logMetric{name: name, aggregation: aggregation, tags: tags}
+		if aggregation == distributionAggregation {
+			e.dist = regular.NewRegularDistribution()
+		}
+		m.entries[key] = e
+	}
+	switch e.aggregation {
+	case distributionAggregation:
+		if err := e.dist.AddEntry(value, 1); err != nil {
+			log.Printf("D! [logfile] Unable to add %v to metric %s: %v", value, name, err)
+		}
+	case sumAggregation:
+		e.sum += value
+	default:
+		e.count++
+	}
+}
+
+// gather passes the metrics aggregated since the last gather to the accumulator.
+func (m *logMetrics) gather(acc telegraf.Accumulator) {
+	m.mu.Lock()
+	entries := m.entries
+	m.entries = make(map[string]*logMetric)
+	m.mu.Unlock()
+
+	now := time.Now()
+	for _, e := range entries {
+		switch e.aggregation {
+		case distributionAggregation:
+			if e.dist.Size() > 0 {
+				acc.AddHistogram(e.name, map[string]interface{}{metricValueField: e.dist}, e.tags, now)
+			}
+		case sumAggregation:
+			acc.AddFields(e.name, map[string]interface{}{metricValueField: e.sum}, e.tags, now)
+		default:
+			acc.AddFields(e.name, map[string]interface{}{metricValueField: e.count}, e.tags, now)
+		}
+	}
+}
+
+func metricKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, k := range keys {
+		sb.WriteString("|")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(tags[k])
+	}
+	return sb.String()
+}
@@ -0,0 +1,138 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+)
+
+func TestMetricFilterInit(t *testing.T) {
+	f := &MetricFilter{MetricName: "errors", Pattern: "ERROR"}
+	require.NoError(t, f.init(nil))
+	assert.Equal(t, countAggregation, f.Aggregation)
+
+	assert.Error(t, (&MetricFilter{Pattern: "ERROR"}).init(nil))
+	assert.Error(t, (&MetricFilter{MetricName: "m", Aggregation: "avg"}).init(nil))
+	assert.Error(t, (&MetricFilter{MetricName: "m", Aggregation: sumAggregation}).init(nil))
+	assert.Error(t, (&MetricFilter{MetricName: "m", Pattern: `(\w+`}).init(nil))
+	// the field and dimensions must be capture groups without a parser
+	assert.Error(t, (&MetricFilter{MetricName: "m", Pattern: `(?P<v>\d+)`, Aggregation: sumAggregation, Field: "latency"}).init(nil))
+	assert.Error(t, (&MetricFilter{MetricName: "m", Dimensions: []string{"level"}}).init(nil))
+	assert.NoError(t, (&MetricFilter{MetricName: "m", Pattern: `(?P<v>\d+)`, Aggregation: sumAggregation, Field: "v"}).init(nil))
+	assert.NoError(t, (&MetricFilter{MetricName: "m", Dimensions: []string{"level"}}).init(&LogParser{Format: parserFormatJSON}))
+}
+
+func TestMetricFilterApply(t *testing.T) {
+	metrics := newLogMetrics()
+	metrics.enable()
+	count := &MetricFilter{MetricName: "errors", Namespace: "app", Pattern: `ERROR \[(?P<component>\w+)\]`, Dimensions: []string{"component"}}
+	sum := &MetricFilter{MetricName: "bytes", Pattern: `sent (?P<bytes>\d+)`, Aggregation: sumAggregation, Field: "bytes"}
+	dist := &MetricFilter{MetricName: "latency", Aggregation: distributionAggregation, Field: "latency", Dimensions: []string{"path"}}
+	require.NoError(t, count.init(nil))
+	require.NoError(t, sum.init(nil))
+	require.NoError(t, dist.init(&LogParser{Format: parserFormatJSON}))
+
+	for _, msg := range []string{"ERROR [db] timeout", "ERROR [db] refused", "ERROR [api] failed", "INFO [db] ok", "sent 10", "sent 32", "sent many"} {
+		count.apply(metrics, msg, nil)
+		sum.apply(metrics, msg, nil)
+	}
+	dist.apply(metrics, "", map[string]interface{}{"path": "/a", "latency": "0.5"})
+	dist.apply(metrics, "", map[string]interface{}{"path": "/a", "latency": 1.5})
+	// events missing the value or a dimension are skipped
+	dist.apply(metrics, "", map[string]interface{}{"latency": 2})
+	dist.apply(metrics, "", map[string]interface{}{"path": "/a"})
+
+	var acc testutil.Accumulator
+	metrics.gather(&acc)
+	require.Len(t, acc.Metrics, 4)
+	assert.True(t, acc.HasPoint("errors", map[string]string{"component": "db", namespaceTagKey: "app"}, "value", int64(2)))
+	assert.True(t, acc.HasPoint("errors", map[string]string{"component": "api", namespaceTagKey: "app"}, "value", int64(1)))
+	assert.True(t, acc.HasPoint("bytes", map[string]string{}, "value", float64(42)))
+	for _, m := range acc.Metrics {
+		if m.Measurement != "latency" {
+			continue
+		}
+		assert.Equal(t, telegraf.Histogram, m.Type)
+		assert.Equal(t, map[string]string{"path": "/a"}, m.Tags)
+		d := m.Fields["value"].(distribution.Distribution)
+		assert.EqualValues(t, 2, d.SampleCount())
+		assert.EqualValues(t, 2, d.Sum())
+	}
+
+	// the metrics are reset once gathered
+	acc.ClearMetrics()
+	metrics.gather(&acc)
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestMetricFilterDisabled(t *testing.T) {
+	metrics := newLogMetrics()
+	f := &MetricFilter{MetricName: "errors", Pattern: "ERROR"}
+	require.NoError(t, f.init(nil))
+	f.apply(metrics, "ERROR", nil)
+	var acc testutil.Accumulator
+	metrics.gather(&acc)
+	assert.Empty(t, acc.Metrics)
+}
+
+func TestLogFileMetricFilters(t *testing.T) {
+	multilineWaitPeriod = 10 * time.Millisecond
+	tmpfile, err := createTempFile("", "")
+	require.NoError(t, err)
+	defer tmpfile.Close()
+	_, err = tmpfile.WriteString("level=error msg=a latency=3\nlevel=info msg=b latency=1\nlevel=error msg=c latency=5\n")
+	require.NoError(t, err)
+
+	tt := NewLogFile()
+	tt.Log = TestLogger{t}
+	tt.FileStateFolder = t.TempDir()
+	tt.FileConfig = []FileConfig{{
+		FilePath:      tmpfile.Name(),
+		FromBeginning: true,
+		Parser:        &LogParser{Format: parserFormatLogfmt},
+		MetricFilters: []*MetricFilter{
+			{MetricName: "errors", Pattern: "level=error"},
+			{MetricName: "latency", Aggregation: sumAggregation, Field: "latency", Dimensions: []string{"level"}},
+		},
+	}}
+	var acc testutil.Accumulator
+	// started by the log agent and by the adapter receiver
+	require.NoError(t, tt.Start(nil))
+	require.NoError(t, tt.Start(&acc))
+	defer tt.Stop()
+
+	lsrcs := tt.FindLogSrc()
+	require.Len(t, lsrcs, 1)
+	evts := make(chan logs.LogEvent, 3)
+	lsrcs[0].SetOutput(func(e logs.LogEvent) {
+		if e != nil {
+			evts <- e
+		}
+	})
+	defer lsrcs[0].Stop()
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-evts:
+			// the raw events are still published
+			assert.Contains(t, e.Message(), `"msg"`)
+			e.Done()
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the log events")
+		}
+	}
+
+	require.NoError(t, tt.Gather(&acc))
+	assert.True(t, acc.HasPoint("errors", map[string]string{}, "value", int64(2)))
+	assert.True(t, acc.HasPoint("latency", map[string]string{"level": "error"}, "value", float64(8)))
+	assert.True(t, acc.HasPoint("latency", map[string]string{"level": "info"}, "value", float64(1)))
+}
@@ -68,6 +68,11 @@ type Config struct {
 
 	// Special handling for utf16
 	IsUTF16 bool
+
+	// Decompress, when set, returns the reader of the decompressed content of the file and the
+	// offset in the content it starts from, which replaces Location. The offsets of the lines are
+	// offsets in the decompressed content. Decompressed files can't be followed.
+	Decompress func(io.Reader) (io.ReadCloser, int64, error)
 }
 
 type Tail struct {
@@ -75,8 +80,9 @@ type Tail struct {
 	Lines    chan *Line
 	Config
 
-	file   *os.File
-	reader *bufio.Reader
+	file         *os.File
+	decompressed io.ReadCloser
+	reader       *bufio.Reader
 
 	watcher watch.FileWatcher
 	changes *watch.FileChanges
@@ -98,6 +104,9 @@ func TailFile(filename string, config Config) (*Tail, error) {
 	if config.ReOpen && !config.Follow {
 		return nil, errors.New("cannot set ReOpen without Follow.")
 	}
+	if config.Decompress != nil && config.Follow {
+		return nil, errors.New("cannot set Decompress with Follow.")
+	}
 
 	t := &Tail{
 		Filename:      filename,
@@ -140,6 +149,11 @@ func TailFile(filename string, config Config) (*Tail, error) {
 // it may readed one line in the chan(tail.Lines),
 // so it may lost one line.
 func (tail *Tail) Tell() (offset int64, err error) {
+	if tail.Decompress != nil {
+		tail.lk.Lock()
+		defer tail.lk.Unlock()
+		return tail.curOffset, nil
+	}
 	if tail.file == nil {
 		return
 	}
@@ -177,6 +191,9 @@ func (tail *Tail) close() {
 		tail.Logger.Errorf("Dropped %v lines for stopped tail for file %v", tail.dropCnt, tail.Filename)
 	}
 	close(tail.Lines)
+	if tail.decompressed != nil {
+		tail.decompressed.Close()
+	}
 	tail.closeFile()
 }
 
@@ -325,11 +342,21 @@ func (tail *Tail) tailFileSync() {
 			return
 		}
 	}
-	// openReader should be invoked before seekTo
-	tail.openReader()
+	if tail.Decompress != nil {
+		if err := tail.openDecompressed(); err != nil {
+			// ErrStop tells that the file should not be read
+			if err != ErrStop {
+				tail.Killf("Decompress error on %s: %s", tail.Filename, err)
+			}
+			return
+		}
+	} else {
+		// openReader should be invoked before seekTo
+		tail.openReader()
+	}
 
 	// Seek to requested location on first open of the file.
-	if tail.Location != nil {
+	if tail.Location != nil && tail.Decompress == nil {
 		err := tail.seekTo(*tail.Location)
 		tail.Logger.Debugf("Seeked %s - %+v\n", tail.Filename, tail.Location)
 		if err != nil {
@@ -488,6 +515,25 @@ func (tail *Tail) openReader() {
 	tail.lk.Unlock()
 }
 
+// openDecompressed reads the file through the reader returned by Decompress, from the offset it
+// returned.
+func (tail *Tail) openDecompressed() error {
+	r, offset, err := tail.Decompress(tail.file)
+	if err != nil {
+		return err
+	}
+	tail.lk.Lock()
+	tail.decompressed = r
+	if tail.MaxLineSize > 0 {
+		tail.reader = bufio.NewReaderSize(r, tail.MaxLineSize+2)
+	} else {
+		tail.reader = bufio.NewReader(r)
+	}
+	tail.curOffset = offset
+	tail.lk.Unlock()
+	return nil
+}
+
 func (tail *Tail) seekEnd() error {
 	return tail.seekTo(SeekInfo{Offset: 0, Whence: os.SEEK_END})
 }
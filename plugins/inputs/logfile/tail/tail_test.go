@@ -4,9 +4,13 @@
 package tail
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -121,6 +125,46 @@ func TestStopAtEOF(t *testing.T) {
 	verifyTailerExited(t, tail)
 }
 
+func TestDecompress(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte("line1\nline2\nline3"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	filename := filepath.Join(t.TempDir(), "app.log.gz")
+	assert.NoError(t, os.WriteFile(filename, buf.Bytes(), 0644))
+
+	tail, err := TailFile(filename, Config{
+		MustExist: true,
+		Poll:      true,
+		Logger:    &testLogger{},
+		Decompress: func(r io.Reader) (io.ReadCloser, int64, error) {
+			d, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, 0, err
+			}
+			// the reader starts after the first line
+			_, err = io.CopyN(io.Discard, d, 6)
+			return d, 6, err
+		},
+	})
+	assert.NoError(t, err)
+
+	var lines []*Line
+	for line := range tail.Lines {
+		lines = append(lines, line)
+	}
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "line2", lines[0].Text)
+	assert.Equal(t, int64(12), lines[0].Offset)
+	assert.Equal(t, "line3", lines[1].Text)
+	assert.Equal(t, int64(17), lines[1].Offset)
+	assert.NoError(t, tail.UnexpectedError())
+
+	_, err = TailFile(filename, Config{Follow: true, Decompress: func(io.Reader) (io.ReadCloser, int64, error) { return nil, 0, nil }})
+	assert.Error(t, err)
+}
+
 func setup(t *testing.T) (*os.File, *Tail, *testLogger) {
 	tmpfile, err := os.CreateTemp("", "example")
 	if err != nil {
@@ -5,16 +5,22 @@ package logfile
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
 	"os"
-	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/text/encoding"
 
 	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/acktracker"
+	"github.com/aws/amazon-cloudwatch-agent/logs/ratelimit"
+	"github.com/aws/amazon-cloudwatch-agent/logs/template"
 	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/logfile/tail"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
 )
 
 const (
@@ -41,7 +47,13 @@ type LogEvent struct {
 	msg    string
 	t      time.Time
 	offset fileOffset
-	src    *tailerSrc
+	// ack is the id of the event in the ack tracker of the src.
+	ack uint64
+	src *tailerSrc
+	// group and stream are the names evaluated for the event, empty if the names of the src are
+	// the same for all its events.
+	group  string
+	stream string
 }
 
 func (le LogEvent) Message() string {
@@ -53,7 +65,15 @@ func (le LogEvent) Time() time.Time {
 }
 
 func (le LogEvent) Done() {
-	le.src.Done(le.offset)
+	le.src.Done(le.ack)
+}
+
+func (le LogEvent) Group() string {
+	return le.group
+}
+
+func (le LogEvent) Stream() string {
+	return le.stream
 }
 
 type tailerSrc struct {
@@ -73,10 +93,29 @@ type tailerSrc struct {
 	outputFn        func(logs.LogEvent)
 	isMLStart       func(string) bool
 	filters         []*LogFilter
+	parser          *LogParser
+	rateLimiter     *ratelimit.Limiter
+	publisher       *ratelimit.Publisher
+	metricFilters   []*MetricFilter
+	metrics         *logMetrics
+	container       *containerLogDecoder
+	groupTemplate   *template.Template
+	streamTemplate  *template.Template
+	acks            acktracker.Tracker[fileOffset]
+	lastAckWarning  time.Time
 	offsetCh        chan fileOffset
 	done            chan struct{}
 	startTailerOnce sync.Once
-	cleanUpFns      []func()
+	started         atomic.Bool
+	// stateSaved is closed once runSaveState returns.
+	stateSaved chan struct{}
+	cleanUpFns []func()
+
+	// identity is the fingerprint of the tailed file, nil if it can't be identified. The device and
+	// inode never change while the hash is extended by runSaveState until the file is long enough.
+	identity *fileFingerprint
+	// archive is the compressed file read through a decompressing reader, nil for other files.
+	archive *archiveFile
 }
 
 // Verify tailerSrc implements LogSrc
@@ -88,6 +127,7 @@ func NewTailerSrc(
 	autoRemoval bool,
 	isMultilineStartFn func(string) bool,
 	filters []*LogFilter,
+	parser *LogParser,
 	timestampFn func(string) time.Time,
 	enc encoding.Encoding,
 	maxEventSize int,
@@ -104,16 +144,23 @@ func NewTailerSrc(
 		autoRemoval:     autoRemoval,
 		isMLStart:       isMultilineStartFn,
 		filters:         filters,
+		parser:          parser,
 		timestampFn:     timestampFn,
 		enc:             enc,
 		maxEventSize:    maxEventSize,
 		truncateSuffix:  truncateSuffix,
 		retentionInDays: retentionInDays,
 
-		offsetCh: make(chan fileOffset, 2000),
-		done:     make(chan struct{}),
+		offsetCh:   make(chan fileOffset, 2000),
+		done:       make(chan struct{}),
+		stateSaved: make(chan struct{}),
+	}
+	if !tailer.Pipe {
+		if id, err := readFileIdentity(tailer.Filename); err == nil {
+			fp := id.fingerprint()
+			ts.identity = &fp
+		}
 	}
-	go ts.runSaveState()
 	return ts
 }
 
@@ -122,7 +169,16 @@ func (ts *tailerSrc) SetOutput(fn func(logs.LogEvent)) {
 		return
 	}
 	ts.outputFn = fn
-	ts.startTailerOnce.Do(func() { go ts.runTail() })
+	ts.startTailerOnce.Do(func() {
+		if ts.rateLimiter != nil {
+			ts.publisher = ts.rateLimiter.NewPublisher(func(e logs.LogEvent) {
+				ts.outputFn(e)
+			}, ts.done)
+		}
+		ts.started.Store(true)
+		go ts.runSaveState()
+		go ts.runTail()
+	})
 }
 
 func (ts *tailerSrc) Group() string {
@@ -148,7 +204,20 @@ func (ts *tailerSrc) Retention() int {
 func (ts *tailerSrc) Class() string {
 	return ts.class
 }
-func (ts *tailerSrc) Done(offset fileOffset) {
+
+// Done acknowledges the event, the offset saved only moving past the events acknowledged along
+// with all the events published before them.
+func (ts *tailerSrc) Done(ack uint64) {
+	if ts.archive != nil {
+		ts.archive.pending.Add(-1)
+	}
+	if offset, ok := ts.acks.Ack(ack); ok {
+		ts.commit(offset)
+	}
+}
+
+// commit hands the offset over to runSaveState.
+func (ts *tailerSrc) commit(offset fileOffset) {
 	// ts.offsetCh will only be blocked when the runSaveState func has exited,
 	// which only happens when the original file has been removed, thus making
 	// Keeping its offset useless
@@ -162,6 +231,19 @@ func (ts *tailerSrc) Stop() {
 	close(ts.done)
 }
 
+// waitStateSaved waits for the final state of the src to be saved once it is stopped, and returns
+// right away when it is still running.
+func (ts *tailerSrc) waitStateSaved() {
+	select {
+	case <-ts.done:
+	default:
+		return
+	}
+	if ts.started.Load() {
+		<-ts.stateSaved
+	}
+}
+
 func (ts *tailerSrc) AddCleanUpFn(f func()) {
 	ts.cleanUpFns = append(ts.cleanUpFns, f)
 }
@@ -172,6 +254,9 @@ func (ts *tailerSrc) runTail() {
 	defer t.Stop()
 	var init string
 	var msgBuf bytes.Buffer
+	// record, initRecord and msgRecord are the container records of the line, of init and of
+	// the first line of msgBuf
+	var record, initRecord, msgRecord containerRecord
 	var cnt int
 	fo := &fileOffset{}
 
@@ -182,17 +267,11 @@ func (ts *tailerSrc) runTail() {
 		case line, ok := <-ts.tailer.Lines:
 			if !ok {
 				if msgBuf.Len() > 0 {
-					msg := msgBuf.String()
-					e := &LogEvent{
-						msg:    msg,
-						t:      ts.timestampFn(msg),
-						offset: *fo,
-						src:    ts,
-					}
-
-					if ShouldPublish(ts.group, ts.stream, ts.filters, e) {
-						ts.outputFn(e)
-					}
+					ts.publish(msgBuf.String(), *fo, msgRecord)
+				}
+				// a compressed file which failed to be decompressed is not complete
+				if ts.archive != nil && ts.tailer.UnexpectedError() == nil {
+					ts.archive.read.Store(true)
 				}
 				return
 			}
@@ -212,13 +291,23 @@ func (ts *tailerSrc) runTail() {
 				}
 			}
 
+			if ts.container != nil {
+				var complete bool
+				// the offset is only moved once the partial lines are complete
+				if text, record, complete = ts.container.decode(text); !complete {
+					continue
+				}
+			}
+
 			if ts.isMLStart == nil {
 				msgBuf.Reset()
 				msgBuf.WriteString(text)
+				msgRecord = record
 				fo.SetOffset(line.Offset)
 				init = ""
 			} else if ts.isMLStart(text) || (!ignoreUntilNextEvent && msgBuf.Len() == 0) {
 				init = text
+				initRecord = record
 				ignoreUntilNextEvent = false
 			} else if ignoreUntilNextEvent || msgBuf.Len() >= ts.maxEventSize {
 				ignoreUntilNextEvent = true
@@ -236,22 +325,12 @@ func (ts *tailerSrc) runTail() {
 			}
 
 			if msgBuf.Len() > 0 {
-				msg := msgBuf.String()
-				e := &LogEvent{
-					msg:    msg,
-					t:      ts.timestampFn(msg),
-					offset: *fo,
-					src:    ts,
-				}
-				// Note: This only checks against the truncated log message, so it is not necessary to load
-				//       the entire log message for filtering.
-				if ShouldPublish(ts.group, ts.stream, ts.filters, e) {
-					ts.outputFn(e)
-				}
+				ts.publish(msgBuf.String(), *fo, msgRecord)
 			}
 
 			msgBuf.Reset()
 			msgBuf.WriteString(init)
+			msgRecord = initRecord
 			fo.SetOffset(line.Offset)
 			cnt = 0
 		case <-t.C:
@@ -263,16 +342,7 @@ func (ts *tailerSrc) runTail() {
 				continue
 			}
 
-			msg := msgBuf.String()
-			e := &LogEvent{
-				msg:    msg,
-				t:      ts.timestampFn(msg),
-				offset: *fo,
-				src:    ts,
-			}
-			if ShouldPublish(ts.group, ts.stream, ts.filters, e) {
-				ts.outputFn(e)
-			}
+			ts.publish(msgBuf.String(), *fo, msgRecord)
 			msgBuf.Reset()
 			cnt = 0
 		case <-ts.done:
@@ -281,6 +351,92 @@ func (ts *tailerSrc) runTail() {
 	}
 }
 
+// publish publishes the event, record being the container record of its first line when the
+// file is a container log.
+func (ts *tailerSrc) publish(msg string, offset fileOffset, record containerRecord) {
+	e := &LogEvent{
+		msg:    msg,
+		offset: offset,
+		src:    ts,
+	}
+	if record.time.IsZero() {
+		e.t = ts.timestampFn(msg)
+	} else {
+		e.t = record.time
+	}
+	// Note: This only checks against the truncated log message, so it is not necessary to load
+	//       the entire log message for filtering.
+	if !ShouldPublish(ts.group, ts.stream, ts.filters, e) {
+		return
+	}
+	e.msg = Redact(ts.group, ts.stream, ts.filters, e.msg)
+	msg = e.msg
+	var fields map[string]interface{}
+	if ts.parser != nil {
+		fields = ts.parse(e)
+	}
+	// the metrics are extracted from the message as read, before it is rewritten by the parser
+	for _, f := range ts.metricFilters {
+		f.apply(ts.metrics, msg, fields)
+	}
+	if ts.groupTemplate != nil || ts.streamTemplate != nil {
+		ts.route(e, fields)
+	}
+	if ts.container != nil {
+		e.msg = ts.container.format(e.msg, record)
+	}
+	if ts.acks.Full() && time.Since(ts.lastAckWarning) >= time.Minute {
+		log.Printf("W! [logfile] More than %d events from %s waiting for their acknowledgement, waiting for the oldest", acktracker.DefaultMaxPending, ts.tailer.Filename)
+		ts.lastAckWarning = time.Now()
+	}
+	var ok bool
+	if e.ack, ok = ts.acks.Track(offset, ts.done); !ok {
+		// the src is stopped, the event being read again on the next start
+		return
+	}
+	if ts.archive != nil {
+		ts.archive.pending.Add(1)
+	}
+	if ts.publisher != nil {
+		ts.publisher.Publish(e)
+		return
+	}
+	ts.outputFn(e)
+}
+
+// route evaluates the log group and stream names of the event with the fields decoded by the
+// parser, or those of the event when it is a JSON object.
+func (ts *tailerSrc) route(e *LogEvent, fields map[string]interface{}) {
+	if fields == nil && strings.HasPrefix(e.msg, "{") {
+		_ = json.Unmarshal([]byte(e.msg), &fields)
+	}
+	r := template.FieldResolver(fields)
+	if ts.groupTemplate != nil {
+		e.group = ts.groupTemplate.Execute(r)
+	}
+	if ts.streamTemplate != nil {
+		e.stream = ts.streamTemplate.Execute(r)
+	}
+}
+
+// parse rewrites the event as the JSON document decoded by the parser and returns its fields.
+// Events which cannot be parsed are published unchanged.
+func (ts *tailerSrc) parse(e *LogEvent) map[string]interface{} {
+	msg, fields, t, err := ts.parser.parse(e.msg)
+	failedCount := 0
+	if err != nil {
+		log.Printf("D! [logfile] Unable to parse log event from %s: %v", ts.tailer.Filename, err)
+		failedCount = 1
+	} else {
+		e.msg = msg
+		if !t.IsZero() {
+			e.t = t
+		}
+	}
+	profiler.Profiler.AddStats([]string{"logfile", ts.group, ts.stream, "messages", "parse_failed"}, float64(failedCount))
+	return fields
+}
+
 func (ts *tailerSrc) cleanUp() {
 	if ts.autoRemoval {
 		if err := os.Remove(ts.tailer.Filename); err != nil {
@@ -289,20 +445,34 @@ func (ts *tailerSrc) cleanUp() {
 			log.Printf("I! [logfile] Successfully removed file %v with auto_removal feature", ts.tailer.Filename)
 		}
 	}
-	for _, clf := range ts.cleanUpFns {
-		clf()
+	// the compressed file is only cleaned up once its state is complete, so that it is not
+	// picked up again while its last events are published
+	if ts.archive == nil {
+		for _, clf := range ts.cleanUpFns {
+			clf()
+		}
 	}
 
+	if ts.publisher != nil {
+		// the events still queued by the rate limit are published before the end of the src
+		ts.publisher.Close()
+	}
 	if ts.outputFn != nil {
 		ts.outputFn(nil) // inform logs agent the tailer src's exit, to stop runSrcToDest
 	}
 }
 
 func (ts *tailerSrc) runSaveState() {
+	defer close(ts.stateSaved)
 	t := time.NewTicker(100 * time.Millisecond)
 	defer t.Stop()
+	if ts.archive != nil {
+		defer ts.cleanUpArchive()
+	}
 
 	var offset, lastSavedOffset fileOffset
+	done := ts.done
+	var archiveAckTimeoutCh <-chan time.Time
 	for {
 		select {
 		case o := <-ts.offsetCh:
@@ -310,6 +480,13 @@ func (ts *tailerSrc) runSaveState() {
 				offset = o
 			}
 		case <-t.C:
+			if archiveAckTimeoutCh != nil && ts.archive.published() {
+				err := ts.saveArchiveComplete(offset.offset)
+				if err != nil {
+					log.Printf("E! [logfile] Error happened when saving completion of compressed file %s to file state folder %s, it will be read again: %v", ts.archive.path, ts.stateFilePath, err)
+				}
+				return
+			}
 			if offset == lastSavedOffset {
 				continue
 			}
@@ -320,13 +497,29 @@ func (ts *tailerSrc) runSaveState() {
 			}
 			lastSavedOffset = offset
 		case <-ts.tailer.FileDeletedCh:
+			// the file may have been renamed rather than removed, keep its state in case it shows up under the new name
+			if err := ts.saveRotatedState(offset.offset); err != nil {
+				log.Printf("W! [logfile] Error happened while keeping the state of rotated file %s: %v", ts.tailer.Filename, err)
+			}
 			log.Printf("W! [logfile] deleting state file %s", ts.stateFilePath)
 			err := os.Remove(ts.stateFilePath)
 			if err != nil {
 				log.Printf("W! [logfile] Error happened while deleting state file %s on cleanup: %v", ts.stateFilePath, err)
 			}
 			return
-		case <-ts.done:
+		case <-archiveAckTimeoutCh:
+			log.Printf("W! [logfile] Timed out waiting for the events of compressed file %s to be published, it will be read again from offset %d", ts.archive.path, offset.offset)
+			if err := ts.saveState(offset.offset); err != nil {
+				log.Printf("E! [logfile] Error happened when saving file state %s to file state folder %s: %v", ts.archive.path, ts.stateFilePath, err)
+			}
+			return
+		case <-done:
+			if ts.archive != nil && ts.archive.read.Load() {
+				// wait for the events still being published before the compressed file is marked complete
+				done = nil
+				archiveAckTimeoutCh = time.After(archiveAckTimeout)
+				continue
+			}
 			err := ts.saveState(offset.offset)
 			if err != nil {
 				log.Printf("E! [logfile] Error happened during final file state saving of logfile %s to file state folder %s, duplicate log maybe sent at next start: %v", ts.tailer.Filename, ts.stateFilePath, err)
@@ -341,6 +534,51 @@ func (ts *tailerSrc) saveState(offset int64) error {
 		return nil
 	}
 
-	content := []byte(strconv.FormatInt(offset, 10) + "\n" + ts.tailer.Filename)
-	return os.WriteFile(ts.stateFilePath, content, stateFileMode)
+	return writeFileState(ts.stateFilePath, ts.fileState(offset))
+}
+
+func (ts *tailerSrc) saveRotatedState(offset int64) error {
+	if ts.stateFilePath == "" || offset == 0 || ts.identity == nil {
+		return nil
+	}
+
+	return writeFileState(rotatedStateFilePath(ts.stateFilePath, *ts.identity), ts.fileState(offset))
+}
+
+func (ts *tailerSrc) saveArchiveComplete(offset int64) error {
+	state := fileState{offset: offset, filename: ts.archive.path, fingerprint: &ts.archive.identity, complete: true}
+	return writeFileState(ts.stateFilePath, state)
+}
+
+func (ts *tailerSrc) cleanUpArchive() {
+	for _, clf := range ts.cleanUpFns {
+		clf()
+	}
+}
+
+func (ts *tailerSrc) fileState(offset int64) fileState {
+	if ts.archive != nil {
+		return fileState{offset: offset, filename: ts.archive.path, fingerprint: &ts.archive.identity}
+	}
+	state := fileState{offset: offset, filename: ts.tailer.Filename}
+	if ts.identity != nil {
+		ts.extendFingerprint(offset)
+		fp := *ts.identity
+		state.fingerprint = &fp
+	}
+	return state
+}
+
+// extendFingerprint rehashes the start of a file which was shorter than the fingerprint size when
+// the tailer started, as long as the file name still points to the same file.
+func (ts *tailerSrc) extendFingerprint(offset int64) {
+	if ts.identity.size >= fingerprintSize || offset <= int64(ts.identity.size) {
+		return
+	}
+	id, err := readFileIdentity(ts.tailer.Filename)
+	if err != nil || !id.sameFile(*ts.identity) {
+		return
+	}
+	fp := id.fingerprint()
+	ts.identity.size, ts.identity.sum = fp.size, fp.sum
 }
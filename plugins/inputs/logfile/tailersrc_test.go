@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -66,6 +69,7 @@ func TestTailerSrc(t *testing.T) {
 		false, // AutoRemoval
 		regexp.MustCompile("^[\\S]").MatchString,
 		nil,
+		nil, // parser
 		parseRFC3339Timestamp,
 		nil, // encoding
 		defaultMaxEventSize,
@@ -177,6 +181,7 @@ func TestOffsetDoneCallBack(t *testing.T) {
 		false, // AutoRemoval
 		regexp.MustCompile("^[\\S]").MatchString,
 		nil,
+		nil, // parser
 		parseRFC3339Timestamp,
 		nil, // encoding
 		defaultMaxEventSize,
@@ -390,12 +395,13 @@ func setupTailer(t *testing.T, multiLineFn func(string) bool, maxEventSize int)
 		t.Name(),
 		t.Name(),
 		"destination",
-		util.InfrequentAccessLogGroupClass,
 		statefile.Name(),
+		util.InfrequentAccessLogGroupClass,
 		tailer,
 		false, // AutoRemoval
 		multiLineFn,
 		config.Filters,
+		config.Parser,
 		parseRFC3339Timestamp,
 		nil, // encoding
 		maxEventSize,
@@ -464,3 +470,154 @@ func teardown(resources tailerTestResources) {
 	os.Remove(resources.file.Name())
 	os.Remove(resources.statefile.Name())
 }
+
+// TestTailerSrcCommitsContiguousOffset stops the source while one of its events is not
+// acknowledged, the events following it being acknowledged out of order, and checks that the
+// events are read again from the event which was not acknowledged.
+func TestTailerSrcCommitsContiguousOffset(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	const line = "line-%d\n"
+	var content string
+	for i := 0; i < 10; i++ {
+		content += fmt.Sprintf(line, i)
+	}
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0600))
+	stateFilePath := filepath.Join(dir, "state")
+
+	tailer, err := tail.TailFile(filename, tail.Config{
+		Location:    &tail.SeekInfo{Whence: io.SeekStart, Offset: 0},
+		MustExist:   true,
+		Poll:        true,
+		MaxLineSize: defaultMaxEventSize,
+	})
+	require.NoError(t, err)
+	ts := NewTailerSrc("group", "stream", "destination", stateFilePath, util.StandardLogGroupClass,
+		tailer, false, nil, nil, nil, parseRFC3339Timestamp, nil, defaultMaxEventSize, defaultTruncateSuffix, -1)
+
+	var events []logs.LogEvent
+	read := make(chan struct{})
+	ts.SetOutput(func(e logs.LogEvent) {
+		if e == nil {
+			close(read)
+			return
+		}
+		events = append(events, e)
+	})
+	<-read
+	require.Len(t, events, 10)
+
+	const lost = 4
+	for _, i := range rand.New(rand.NewSource(1)).Perm(len(events)) {
+		if i != lost {
+			events[i].Done()
+		}
+	}
+	expected := int64(len(fmt.Sprintf(line, 0)) * lost)
+	assert.Eventually(t, func() bool {
+		state, err := readFileState(stateFilePath)
+		return err == nil && state.offset == expected
+	}, 5*time.Second, 10*time.Millisecond)
+	ts.Stop()
+	time.Sleep(200 * time.Millisecond)
+
+	state, err := readFileState(stateFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, expected, state.offset, "the offset is not saved past the event which was not acknowledged")
+	assert.Equal(t, fmt.Sprintf(line, lost), content[state.offset:state.offset+int64(len(fmt.Sprintf(line, lost)))])
+	_, err = os.Stat(stateFilePath + tmpStateSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// TestTailerSrcAckFaultInjection acknowledges the events out of order while one of them is never
+// acknowledged, which holds the source back once too many events are waiting, then restarts the
+// source from the state saved as if the agent crashed and checks that no event is lost.
+func TestTailerSrcAckFaultInjection(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	const lines, lost, maxPending = 100, 30, 8
+	var content string
+	for i := 0; i < lines; i++ {
+		content += fmt.Sprintf("line-%03d\n", i)
+	}
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0600))
+	stateFilePath := filepath.Join(dir, "state")
+
+	newSrc := func(offset int64, maxPending int) (*tailerSrc, chan struct{}, func() []logs.LogEvent) {
+		tailer, err := tail.TailFile(filename, tail.Config{
+			Location:    &tail.SeekInfo{Whence: io.SeekStart, Offset: offset},
+			MustExist:   true,
+			Poll:        true,
+			MaxLineSize: defaultMaxEventSize,
+		})
+		require.NoError(t, err)
+		ts := NewTailerSrc("group", "stream", "destination", stateFilePath, util.StandardLogGroupClass,
+			tailer, false, nil, nil, nil, parseRFC3339Timestamp, nil, defaultMaxEventSize, defaultTruncateSuffix, -1)
+		ts.acks.MaxPending = maxPending
+		var mu sync.Mutex
+		var events []logs.LogEvent
+		read := make(chan struct{})
+		ts.SetOutput(func(e logs.LogEvent) {
+			if e == nil {
+				close(read)
+				return
+			}
+			mu.Lock()
+			events = append(events, e)
+			mu.Unlock()
+		})
+		take := func() []logs.LogEvent {
+			mu.Lock()
+			defer mu.Unlock()
+			taken := events
+			events = nil
+			return taken
+		}
+		return ts, read, take
+	}
+
+	ts, _, take := newSrc(0, maxPending)
+	r := rand.New(rand.NewSource(1))
+	acked := map[string]bool{}
+	received := 0
+	for {
+		time.Sleep(200 * time.Millisecond)
+		batch := take()
+		if len(batch) == 0 {
+			break
+		}
+		received += len(batch)
+		for _, i := range r.Perm(len(batch)) {
+			if msg := batch[i].Message(); msg != fmt.Sprintf("line-%03d", lost) {
+				batch[i].Done()
+				acked[msg] = true
+			}
+		}
+	}
+	assert.Equal(t, lost+maxPending, received, "the file is not read further while too many events are waiting")
+	expected := int64(len(content) / lines * lost)
+	assert.Eventually(t, func() bool {
+		state, err := readFileState(stateFilePath)
+		return err == nil && state.offset == expected
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the agent crashes, the state saved being the one read on the next start
+	state, err := readFileState(stateFilePath)
+	require.NoError(t, err)
+	ts.Stop()
+	ts.waitStateSaved()
+
+	ts, read, take := newSrc(state.offset, 0)
+	<-read
+	reread := map[string]bool{}
+	for _, e := range take() {
+		reread[e.Message()] = true
+	}
+	ts.Stop()
+	ts.waitStateSaved()
+	for i := 0; i < lines; i++ {
+		msg := fmt.Sprintf("line-%03d", i)
+		assert.True(t, acked[msg] || reread[msg], "%s is lost", msg)
+	}
+	assert.True(t, reread[fmt.Sprintf("line-%03d", lost)])
+}
@@ -9,7 +9,8 @@ import (
 )
 
 type Calculator struct {
-	deltaCalculator *DeltaCalculator
+	deltaCalculator          *DeltaCalculator
+	histogramDeltaCalculator *HistogramDeltaCalculator
 }
 
 func appendValidValue(pmb PrometheusMetricBatch, pm *PrometheusMetric) PrometheusMetricBatch {
@@ -25,6 +26,8 @@ func (c *Calculator) Calculate(pmb PrometheusMetricBatch) (result PrometheusMetr
 	var gauges PrometheusMetricBatch
 	var counters PrometheusMetricBatch
 	var summaries PrometheusMetricBatch
+	var histograms PrometheusMetricBatch
+	var histogramBuckets PrometheusMetricBatch
 
 	for _, pm := range pmb {
 		if pm.isGauge() {
@@ -43,17 +46,37 @@ func (c *Calculator) Calculate(pmb PrometheusMetricBatch) (result PrometheusMetr
 			} else {
 				summaries = appendValidValue(summaries, pm)
 			}
+		} else if pm.isHistogram() {
+			if pm.histogram != nil {
+				// native histogram
+				if calculatedMetric := c.histogramDeltaCalculator.calculate(pm); calculatedMetric != nil {
+					histograms = append(histograms, calculatedMetric)
+				}
+			} else if strings.HasSuffix(pm.metricName, histogramBucketSuffix) {
+				// buckets are assembled into a distribution once all their deltas are known
+				if calculatedMetric := c.deltaCalculator.calculate(pm); calculatedMetric != nil {
+					histogramBuckets = append(histogramBuckets, calculatedMetric)
+				}
+			} else if strings.HasSuffix(pm.metricName, histogramSummaryCountSuffix) ||
+				strings.HasSuffix(pm.metricName, histogramSummarySumSuffix) {
+				if calculatedMetric := c.deltaCalculator.calculate(pm); calculatedMetric != nil {
+					histograms = append(histograms, calculatedMetric)
+				}
+			}
 		}
 	}
+	histograms = append(histograms, assembleClassicHistograms(histogramBuckets)...)
 
 	result = append(result, gauges...)
 	result = append(result, counters...)
 	result = append(result, summaries...)
+	result = append(result, histograms...)
 	return
 }
 
 func NewCalculator() *Calculator {
 	return &Calculator{
-		deltaCalculator: NewDeltaCalculator(),
+		deltaCalculator:          NewDeltaCalculator(),
+		histogramDeltaCalculator: NewHistogramDeltaCalculator(),
 	}
 }
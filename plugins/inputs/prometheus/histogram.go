@@ -0,0 +1,181 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package prometheus
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/model/histogram"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/mapWithExpiry"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+)
+
+const histogramBucketLabel = "le"
+
+type histogramDataPoint struct {
+	histogram *histogram.FloatHistogram
+	timeInMS  int64
+}
+
+// HistogramDeltaCalculator converts the cumulative native histograms scraped from
+// a target into the observations made since the previous scrape.
+type HistogramDeltaCalculator struct {
+	preDataPoints       *mapWithExpiry.MapWithExpiry
+	lastCleanUpTimeInMs int64
+}
+
+func (hc *HistogramDeltaCalculator) calculate(pm *PrometheusMetric) (res *PrometheusMetric) {
+	metricKey := getUniqMetricKey(pm)
+	cur := pm.histogram
+	curTimeInMS := pm.timeInMS
+
+	if v, ok := hc.preDataPoints.Get(metricKey); ok {
+		preDataPoint := v.(histogramDataPoint)
+		if curTimeInMS > preDataPoint.timeInMS {
+			delta := cur
+			if !cur.DetectReset(preDataPoint.histogram) {
+				delta = cur.Copy().Sub(preDataPoint.histogram)
+			}
+			if dist := nativeHistogramToDistribution(delta); dist != nil {
+				pm.distribution = dist
+				res = pm
+			}
+		}
+	}
+
+	// Clean up the stale cache periodically
+	if curTimeInMS-hc.lastCleanUpTimeInMs >= CleanUpTimeThreshold {
+		hc.preDataPoints.CleanUp(time.Now())
+		hc.lastCleanUpTimeInMs = curTimeInMS
+	}
+
+	hc.preDataPoints.Set(metricKey, histogramDataPoint{histogram: cur, timeInMS: curTimeInMS})
+
+	return
+}
+
+func NewHistogramDeltaCalculator() *HistogramDeltaCalculator {
+	return &HistogramDeltaCalculator{preDataPoints: mapWithExpiry.NewMapWithExpiry(CacheTTL), lastCleanUpTimeInMs: 0}
+}
+
+// nativeHistogramToDistribution adds every populated bucket of the histogram to a distribution,
+// using the geometric midpoint of the exponential bucket as its value.
+// Returns nil if there are no observations.
+func nativeHistogramToDistribution(fh *histogram.FloatHistogram) distribution.Distribution {
+	dist := regular.NewRegularDistribution()
+	if fh.ZeroCount > 0 {
+		addBucketEntry(dist, 0, fh.ZeroCount)
+	}
+	it := fh.PositiveBucketIterator()
+	for it.Next() {
+		b := it.At()
+		addBucketEntry(dist, math.Sqrt(b.Lower*b.Upper), b.Count)
+	}
+	if it := fh.NegativeBucketIterator(); it.Next() {
+		log.Printf("D! Drop negative buckets of native histogram as negative values are not supported")
+	}
+	if dist.SampleCount() == 0 {
+		return nil
+	}
+	return dist
+}
+
+type classicHistogramBucket struct {
+	upperBound float64
+	count      float64
+}
+
+type classicHistogram struct {
+	pm      *PrometheusMetric
+	buckets []classicHistogramBucket
+}
+
+// assembleClassicHistograms merges the <basename>_bucket series sharing the same labels (other than le)
+// into one distribution metric named <basename>. The bucket counts are expected to be deltas already.
+func assembleClassicHistograms(pmb PrometheusMetricBatch) (result PrometheusMetricBatch) {
+	histograms := make(map[string]*classicHistogram)
+	var keys []string
+	for _, pm := range pmb {
+		le, ok := pm.tags[histogramBucketLabel]
+		if !ok {
+			log.Printf("D! Drop histogram bucket without %s label: %v", histogramBucketLabel, pm.metricName)
+			continue
+		}
+		upperBound, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			log.Printf("D! Drop histogram bucket with invalid %s label %q: %v", histogramBucketLabel, le, pm.metricName)
+			continue
+		}
+
+		tags := make(map[string]string, len(pm.tags))
+		for k, v := range pm.tags {
+			if k != histogramBucketLabel {
+				tags[k] = v
+			}
+		}
+		hpm := &PrometheusMetric{
+			tags:       tags,
+			metricName: normalizeMetricName(pm.metricName, []string{histogramBucketSuffix}),
+			metricType: pm.metricType,
+			timeInMS:   pm.timeInMS,
+		}
+		key := getUniqMetricKey(hpm)
+		h, ok := histograms[key]
+		if !ok {
+			h = &classicHistogram{pm: hpm}
+			histograms[key] = h
+			keys = append(keys, key)
+		}
+		h.buckets = append(h.buckets, classicHistogramBucket{upperBound: upperBound, count: pm.metricValue})
+	}
+
+	for _, key := range keys {
+		h := histograms[key]
+		if dist := classicHistogramToDistribution(h.buckets); dist != nil {
+			h.pm.distribution = dist
+			result = append(result, h.pm)
+		}
+	}
+	return
+}
+
+// classicHistogramToDistribution converts cumulative (le) bucket counts into a distribution,
+// using the midpoint of each bucket as its value. The +Inf bucket uses the largest finite bound.
+// Returns nil if there are no observations.
+func classicHistogramToDistribution(buckets []classicHistogramBucket) distribution.Distribution {
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].upperBound < buckets[j].upperBound
+	})
+	dist := regular.NewRegularDistribution()
+	var lowerBound, cumulativeCount float64
+	for _, b := range buckets {
+		count := b.count - cumulativeCount
+		cumulativeCount = math.Max(cumulativeCount, b.count)
+		value := lowerBound
+		if !math.IsInf(b.upperBound, 1) {
+			value = (lowerBound + b.upperBound) / 2
+			lowerBound = b.upperBound
+		}
+		addBucketEntry(dist, value, count)
+	}
+	if dist.SampleCount() == 0 {
+		return nil
+	}
+	return dist
+}
+
+func addBucketEntry(dist distribution.Distribution, value float64, count float64) {
+	if count <= 0 {
+		return
+	}
+	if err := dist.AddEntry(value, count); err != nil {
+		log.Printf("D! Drop histogram bucket with value %v: %v", value, err)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
+)
+
+func classicHistogramBatch(timeInMS int64, sum float64, count float64, buckets map[string]float64) PrometheusMetricBatch {
+	pmb := PrometheusMetricBatch{
+		{tags: map[string]string{"path": "/"}, metricName: "latency_sum", metricValue: sum, metricType: "histogram", timeInMS: timeInMS},
+		{tags: map[string]string{"path": "/"}, metricName: "latency_count", metricValue: count, metricType: "histogram", timeInMS: timeInMS},
+	}
+	for le, v := range buckets {
+		pmb = append(pmb, &PrometheusMetric{
+			tags:        map[string]string{"path": "/", "le": le},
+			metricName:  "latency_bucket",
+			metricValue: v,
+			metricType:  "histogram",
+			timeInMS:    timeInMS,
+		})
+	}
+	return pmb
+}
+
+func TestCalculator_ClassicHistogram(t *testing.T) {
+	c := NewCalculator()
+
+	result := c.Calculate(classicHistogramBatch(1000, 10, 5, map[string]float64{"1": 2, "2": 4, "+Inf": 5}))
+	assert.Empty(t, result, "first scrape only initializes the cumulative values")
+
+	result = c.Calculate(classicHistogramBatch(2000, 25, 10, map[string]float64{"1": 4, "2": 8, "+Inf": 10}))
+	require.Len(t, result, 3)
+
+	var dist *regular.RegularDistribution
+	values := map[string]float64{}
+	for _, pm := range result {
+		if pm.distribution != nil {
+			assert.Equal(t, "latency", pm.metricName)
+			assert.Equal(t, map[string]string{"path": "/"}, pm.tags)
+			dist = pm.distribution.(*regular.RegularDistribution)
+		} else {
+			values[pm.metricName] = pm.metricValue
+		}
+	}
+	assert.Equal(t, map[string]float64{"latency_sum": 15, "latency_count": 5}, values)
+	require.NotNil(t, dist)
+	assert.EqualValues(t, 5, dist.SampleCount())
+	assert.EqualValues(t, 2, dist.GetCount(0.5))
+	assert.EqualValues(t, 2, dist.GetCount(1.5))
+	assert.EqualValues(t, 1, dist.GetCount(2))
+	assert.EqualValues(t, 2, dist.Maximum())
+}
+
+func TestCalculator_NativeHistogram(t *testing.T) {
+	c := NewCalculator()
+	newNative := func(timeInMS int64, zero float64, positive []float64) *PrometheusMetric {
+		return &PrometheusMetric{
+			tags:       map[string]string{"path": "/"},
+			metricName: "latency",
+			metricType: "histogram",
+			timeInMS:   timeInMS,
+			histogram: &histogram.FloatHistogram{
+				Schema:          0,
+				ZeroThreshold:   0.001,
+				ZeroCount:       zero,
+				Count:           zero + positive[0] + positive[1],
+				PositiveSpans:   []histogram.Span{{Offset: 1, Length: 2}},
+				PositiveBuckets: positive,
+			},
+		}
+	}
+
+	assert.Empty(t, c.Calculate(PrometheusMetricBatch{newNative(1000, 1, []float64{1, 1})}))
+
+	result := c.Calculate(PrometheusMetricBatch{newNative(2000, 2, []float64{4, 1})})
+	require.Len(t, result, 1)
+	dist := result[0].distribution.(*regular.RegularDistribution)
+	assert.EqualValues(t, 4, dist.SampleCount())
+	assert.EqualValues(t, 1, dist.GetCount(0))
+	// schema 0 bucket with index 1 covers (1, 2]
+	assert.EqualValues(t, 3, dist.GetCount(1.4142135623730951))
+
+	// counter reset uses the current histogram as is
+	result = c.Calculate(PrometheusMetricBatch{newNative(3000, 0, []float64{1, 0})})
+	require.Len(t, result, 1)
+	assert.EqualValues(t, 1, result[0].distribution.SampleCount())
+}
+
+func Test_mergeMetrics_distribution(t *testing.T) {
+	mm := mergeMetrics(PrometheusMetricBatch{
+		{tags: map[string]string{"path": "/"}, metricName: "latency_sum", metricValue: 1},
+		{tags: map[string]string{"path": "/"}, metricName: "latency", distribution: regular.NewRegularDistribution()},
+	})
+	require.Len(t, mm, 1)
+	assert.Equal(t, 1.0, mm[0].fields["latency_sum"])
+	assert.Equal(t, regular.NewRegularDistribution(), mm[0].fields["latency"])
+}
+
+func TestMetricAppender_AppendHistogram(t *testing.T) {
+	mr := metricsReceiver{}
+	ma := mr.Appender(nil)
+	ls := []labels.Label{
+		{Name: "__name__", Value: "latency"},
+		{Name: "path", Value: "/"},
+	}
+	h := &histogram.Histogram{Count: 1, ZeroCount: 1}
+
+	_, err := ma.AppendHistogram(0, ls, 10, h, nil)
+	assert.NoError(t, err)
+	mac, _ := ma.(*metricAppender)
+	require.Len(t, mac.batch, 1)
+	assert.Equal(t, "latency", mac.batch[0].metricName)
+	assert.Equal(t, h.ToFloat(nil), mac.batch[0].histogram)
+}
+
+func TestScrapeNativeHistogramProtobuf(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	latency := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:                        "latency",
+		Help:                        "Latency of the requests.",
+		NativeHistogramBucketFactor: 1.1,
+	})
+	registry.MustRegister(latency)
+	for _, v := range []float64{0.5, 1, 2, 4} {
+		latency.Observe(v)
+	}
+	var contentTypes []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		contentTypes = append(contentTypes, r.Header.Get("Accept"))
+		mu.Unlock()
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	conf, err := config.Load(`
+scrape_configs:
+  - job_name: native
+    scrape_interval: 100ms
+    scrape_timeout: 100ms
+`, false, log.NewNopLogger())
+	require.NoError(t, err)
+	preferProtobuf(conf.ScrapeConfigs[0])
+	assert.Equal(t, config.PrometheusProto, conf.ScrapeConfigs[0].ScrapeProtocols[0])
+
+	pmbCh := make(chan PrometheusMetricBatch, 10)
+	manager, err := scrape.NewManager(&scrape.Options{}, log.NewNopLogger(), &metricsReceiver{pmbCh: pmbCh}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	require.NoError(t, manager.ApplyConfig(conf))
+	targets := make(chan map[string][]*targetgroup.Group, 1)
+	targets <- map[string][]*targetgroup.Group{"native": {{
+		Targets: []model.LabelSet{{model.AddressLabel: model.LabelValue(strings.TrimPrefix(server.URL, "http://"))}},
+		Source:  "native",
+	}}}
+	go manager.Run(targets)
+	defer manager.Stop()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case batch := <-pmbCh:
+			for _, pm := range batch {
+				if pm.metricName == "latency" && pm.histogram != nil {
+					assert.EqualValues(t, 4, pm.histogram.Count)
+					assert.EqualValues(t, 7.5, pm.histogram.Sum)
+					mu.Lock()
+					assert.True(t, strings.HasPrefix(contentTypes[0], config.ScrapeProtocolsHeaders[config.PrometheusProto]))
+					mu.Unlock()
+					return
+				}
+			}
+		case <-timeout:
+			t.Fatal("the native histogram was not scraped")
+		}
+	}
+}
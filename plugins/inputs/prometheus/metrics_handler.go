@@ -11,6 +11,7 @@ import (
 	"github.com/influxdata/telegraf"
 
 	"github.com/aws/amazon-cloudwatch-agent/internal/containerinsightscommon"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
 )
 
 // Use metricMaterial instead of mbMetric to avoid unnecessary tags&fields copy
@@ -46,7 +47,7 @@ func (mh *metricsHandler) handle(pmb PrometheusMetricBatch) {
 	// Add metric type info
 	pmb = mh.mtHandler.Handle(pmb)
 
-	// Filter out Histogram and untyped Metrics and adding logging
+	// Filter out untyped Metrics and adding logging
 	pmb = mh.filter.Filter(pmb)
 
 	// do calculation: calculate delta for counter
@@ -59,7 +60,20 @@ func (mh *metricsHandler) handle(pmb PrometheusMetricBatch) {
 	mh.setEmfMetadata(metricMaterials)
 
 	for _, metricMaterial := range metricMaterials {
-		mh.acc.AddFields("prometheus", metricMaterial.fields, metricMaterial.tags, time.UnixMilli(metricMaterial.timeInMS))
+		// distributions can only be converted by the accumulator as histograms
+		histogramFields := map[string]interface{}{}
+		for k, v := range metricMaterial.fields {
+			if _, ok := v.(distribution.Distribution); ok {
+				histogramFields[k] = v
+				delete(metricMaterial.fields, k)
+			}
+		}
+		if len(metricMaterial.fields) > 0 {
+			mh.acc.AddFields("prometheus", metricMaterial.fields, metricMaterial.tags, time.UnixMilli(metricMaterial.timeInMS))
+		}
+		if len(histogramFields) > 0 {
+			mh.acc.AddHistogram("prometheus", histogramFields, metricMaterial.tags, time.UnixMilli(metricMaterial.timeInMS))
+		}
 	}
 }
 
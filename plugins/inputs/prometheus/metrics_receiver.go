@@ -16,6 +16,8 @@ import (
 	"github.com/prometheus/prometheus/model/metadata"
 	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/storage"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
 )
 
 type PrometheusMetricBatch []*PrometheusMetric
@@ -31,6 +33,10 @@ type PrometheusMetric struct {
 	metricValue             float64
 	metricType              string
 	timeInMS                int64 // Unix time in milli-seconds
+	// histogram is the cumulative native histogram as scraped, distribution the
+	// observations of a histogram since the previous scrape.
+	histogram    *histogram.FloatHistogram
+	distribution distribution.Distribution
 }
 
 func (pm *PrometheusMetric) isValueValid() bool {
@@ -67,6 +73,16 @@ func (mr *metricsReceiver) feed(batch PrometheusMetricBatch) error {
 }
 
 func (ma *metricAppender) Append(ref storage.SeriesRef, ls labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	pm, err := newPrometheusMetric(ls, t)
+	if err != nil {
+		return 0, err
+	}
+	pm.metricValue = v
+	ma.batch = append(ma.batch, pm)
+	return 0, nil //return 0 to indicate caching is not supported
+}
+
+func newPrometheusMetric(ls labels.Labels, t int64) (*PrometheusMetric, error) {
 	metricName := ""
 
 	labelMap := make(map[string]string, len(ls))
@@ -81,7 +97,7 @@ func (ma *metricAppender) Append(ref storage.SeriesRef, ls labels.Labels, t int6
 	if metricName == "" {
 		// The error should never happen, print log here for debugging
 		log.Println("E! receive invalid prometheus metric, metricName is missing")
-		return 0, errors.New("metricName of the times-series is missing")
+		return nil, errors.New("metricName of the times-series is missing")
 	}
 
 	pm := &PrometheusMetric{
@@ -89,7 +105,6 @@ func (ma *metricAppender) Append(ref storage.SeriesRef, ls labels.Labels, t int6
 		metricNameBeforeRelabel: ls.Get(savedScrapeNameLabel),
 		jobBeforeRelabel:        ls.Get(savedScrapeJobLabel),
 		instanceBeforeRelabel:   ls.Get(savedScrapeInstanceLabel),
-		metricValue:             v,
 		timeInMS:                t,
 	}
 
@@ -99,8 +114,7 @@ func (ma *metricAppender) Append(ref storage.SeriesRef, ls labels.Labels, t int6
 	delete(labelMap, savedScrapeInstanceLabel)
 
 	pm.tags = labelMap
-	ma.batch = append(ma.batch, pm)
-	return 0, nil //return 0 to indicate caching is not supported
+	return pm, nil
 }
 
 func (ma *metricAppender) Commit() error {
@@ -124,6 +138,17 @@ func (ma *metricAppender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels,
 }
 
 func (ma *metricAppender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, fh *histogram.FloatHistogram) (storage.SeriesRef, error) {
-	// This code should no longer be used
-	return ref, nil
+	pm, err := newPrometheusMetric(l, t)
+	if err != nil {
+		return 0, err
+	}
+	if fh == nil {
+		if h == nil {
+			return 0, errors.New("histogram of the times-series is missing")
+		}
+		fh = h.ToFloat(nil)
+	}
+	pm.histogram = fh
+	ma.batch = append(ma.batch, pm)
+	return 0, nil
 }
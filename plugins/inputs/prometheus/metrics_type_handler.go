@@ -133,6 +133,26 @@ func getScrapeTargetInfo(pmb PrometheusMetricBatch) (job string, instance string
 	return "", "", fmt.Errorf("job and/or instance not found from %d metrics job=%q instance=%q", len(pmb), job, instance)
 }
 
+// lookupMetadata returns the metadata of the metric family of the metric.
+func lookupMetadata(mc metadataCache, pm *PrometheusMetric) (scrape.MetricMetadata, bool) {
+	// normalize the summary metric first, then if metric name == standardMetricName, it means it is not been normalized by summary
+	// , then normalize the counter suffix if it failed to find metadata.
+	standardMetricName := normalizeMetricName(pm.metricNameBeforeRelabel, histogramSummarySuffixes)
+	mm, ok := mc.Metadata(standardMetricName)
+	if !ok {
+		if pm.metricName != standardMetricName {
+			// perform a 2nd lookup with the original metric name
+			// It could happen if non histogram/summary ends with one of those _count/_sum suffixes
+			mm, ok = mc.Metadata(pm.metricNameBeforeRelabel)
+		} else {
+			// normalize the counter type suffixes, like "_total" suffix
+			standardMetricName = normalizeMetricName(pm.metricNameBeforeRelabel, counterSuffixes)
+			mm, ok = mc.Metadata(standardMetricName)
+		}
+	}
+	return mm, ok
+}
+
 // Decorate the Metrics with Metric Types.
 // Filter out Summary, Histogram and untyped Metrics and adding logging.
 func (mth *metricsTypeHandler) Handle(pmb PrometheusMetricBatch) (result PrometheusMetricBatch) {
@@ -159,21 +179,7 @@ func (mth *metricsTypeHandler) Handle(pmb PrometheusMetricBatch) (result Prometh
 		if pm.metricNameBeforeRelabel != pm.metricName {
 			log.Printf("D! metric name changed from %q to %q during relabel", pm.metricNameBeforeRelabel, pm.metricName)
 		}
-		// normalize the summary metric first, then if metric name == standardMetricName, it means it is not been normalized by summary
-		// , then normalize the counter suffix if it failed to find metadata.
-		standardMetricName := normalizeMetricName(pm.metricNameBeforeRelabel, histogramSummarySuffixes)
-		mm, ok := mc.Metadata(standardMetricName)
-		if !ok {
-			if pm.metricName != standardMetricName {
-				// perform a 2nd lookup with the original metric name
-				// It could happen if non histogram/summary ends with one of those _count/_sum suffixes
-				mm, ok = mc.Metadata(pm.metricNameBeforeRelabel)
-			} else {
-				// normalize the counter type suffixes, like "_total" suffix
-				standardMetricName = normalizeMetricName(pm.metricNameBeforeRelabel, counterSuffixes)
-				mm, ok = mc.Metadata(standardMetricName)
-			}
-		}
+		mm, ok := lookupMetadata(mc, pm)
 		if ok {
 			pm.metricType = string(mm.Type)
 			pm.tags[prometheusMetricTypeKey] = pm.metricType
@@ -5,6 +5,7 @@ package prometheus
 
 import (
 	_ "embed"
+	"fmt"
 	"sync"
 
 	"github.com/influxdata/telegraf"
@@ -20,6 +21,7 @@ type Prometheus struct {
 	PrometheusConfigPath string                                      `toml:"prometheus_config_path"`
 	ClusterName          string                                      `toml:"cluster_name"`
 	ECSSDConfig          *ecsservicediscovery.ServiceDiscoveryConfig `toml:"ecs_service_discovery"`
+	RemoteWrite          *RemoteWriteConfig                          `toml:"remote_write"`
 	mbCh                 chan PrometheusMetricBatch
 	shutDownChan         chan interface{}
 	wg                   sync.WaitGroup
@@ -30,7 +32,7 @@ func (p *Prometheus) SampleConfig() string {
 }
 
 func (p *Prometheus) Description() string {
-	return "Prometheus is used to scrape metrics from prometheus exporter"
+	return "Prometheus is used to scrape metrics from prometheus exporter and receive metrics pushed with prometheus remote write"
 }
 
 func (p *Prometheus) Gather(_ telegraf.Accumulator) error {
@@ -48,6 +50,27 @@ func (p *Prometheus) Start(accIn telegraf.Accumulator) error {
 		mtHandler:   mth,
 	}
 
+	// Listen before starting anything else, so that the plugin fails to start on an unusable address.
+	if p.RemoteWrite != nil {
+		rwCh := make(chan PrometheusMetricBatch, remoteWriteBatchBuffer)
+		rw := newRemoteWriteReceiver(p.RemoteWrite, rwCh)
+		if err := rw.listen(); err != nil {
+			return fmt.Errorf("failed to listen for prometheus remote write on %q: %w", p.RemoteWrite.ListenAddress, err)
+		}
+		// The pushed metrics are typed with the metadata sent with them instead of the scrape targets,
+		// and have their own delta calculation.
+		remoteWriteHandler := &metricsHandler{mbCh: rwCh,
+			acc:         accIn,
+			calculator:  NewCalculator(),
+			filter:      NewMetricsFilter(),
+			clusterName: p.ClusterName,
+			mtHandler:   &metricsTypeHandler{ms: rw.metadata},
+		}
+		p.wg.Add(2)
+		go rw.start(p.shutDownChan, &p.wg)
+		go remoteWriteHandler.start(p.shutDownChan, &p.wg)
+	}
+
 	ecssd := &ecsservicediscovery.ServiceDiscovery{Config: p.ECSSDConfig}
 
 	// Start ECS Service Discovery when in ECS
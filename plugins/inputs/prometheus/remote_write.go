@@ -0,0 +1,262 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+const (
+	defaultRemoteWritePath = "/api/v1/write"
+	// remoteWriteSource replaces the job and instance of the time series pushed without them, which
+	// are only used to log the metrics without metadata.
+	remoteWriteSource = "remote_write"
+	// remoteWriteBatchBuffer is the number of pushed requests waiting to be handled. The requests are
+	// rejected once it is full, so that the sender retries them.
+	remoteWriteBatchBuffer = 100
+	remoteWriteReadTimeout = time.Minute
+	remoteWriteStopTimeout = 5 * time.Second
+	// remoteWriteMaxBodySize is the maximum size of the compressed body of a request.
+	remoteWriteMaxBodySize = 32 << 20
+	// maxMissingMetadataLogged bounds the metric families remembered as logged for their missing
+	// metadata, the families past it being dropped without a log.
+	maxMissingMetadataLogged = 10000
+)
+
+// RemoteWriteConfig is the endpoint receiving the metrics pushed with the Prometheus remote-write
+// protocol.
+type RemoteWriteConfig struct {
+	ListenAddress string `toml:"listen_address"`
+	Path          string `toml:"path"`
+}
+
+// remoteWriteMetadata keeps the types of the metric families sent in the metadata of the
+// remote-write requests. Unlike the scraped metrics, the pushed metrics don't come from a scrape
+// target of the agent, so the metadata is shared by all the jobs and instances.
+type remoteWriteMetadata struct {
+	mu       sync.RWMutex
+	metadata map[string]scrape.MetricMetadata
+	// missing are the metric families logged for their missing metadata.
+	missing map[string]struct{}
+}
+
+var _ metadataService = (*remoteWriteMetadata)(nil)
+var _ metadataCache = (*remoteWriteMetadata)(nil)
+
+func newRemoteWriteMetadata() *remoteWriteMetadata {
+	return &remoteWriteMetadata{metadata: make(map[string]scrape.MetricMetadata), missing: make(map[string]struct{})}
+}
+
+func (m *remoteWriteMetadata) Get(_, _ string) (metadataCache, error) {
+	return m, nil
+}
+
+func (m *remoteWriteMetadata) Metadata(metricName string) (scrape.MetricMetadata, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mm, ok := m.metadata[metricName]
+	return mm, ok
+}
+
+func (m *remoteWriteMetadata) update(metadata []prompb.MetricMetadata) {
+	if len(metadata) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, md := range metadata {
+		m.metadata[md.MetricFamilyName] = scrape.MetricMetadata{
+			Metric: md.MetricFamilyName,
+			// The names of the types are the same in lower case, e.g. COUNTER and counter.
+			Type: model.MetricType(strings.ToLower(md.Type.String())),
+			Help: md.Help,
+			Unit: md.Unit,
+		}
+		delete(m.missing, md.MetricFamilyName)
+	}
+}
+
+// filter drops the metrics of the families without metadata, which could not be typed, logging
+// each family once instead of each time series. The remote write clients only send the metadata
+// when send_metadata is enabled, and only periodically, so the first requests may not be typed.
+func (m *remoteWriteMetadata) filter(batch PrometheusMetricBatch) PrometheusMetricBatch {
+	result := batch[:0]
+	for _, pm := range batch {
+		if _, ok := lookupMetadata(m, pm); ok || isInternalMetric(pm.metricName) {
+			result = append(result, pm)
+			continue
+		}
+		m.logMissing(normalizeMetricName(pm.metricNameBeforeRelabel, histogramSummarySuffixes))
+	}
+	return result
+}
+
+func (m *remoteWriteMetadata) logMissing(family string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.missing[family]; ok || len(m.missing) >= maxMissingMetadataLogged {
+		return
+	}
+	m.missing[family] = struct{}{}
+	log.Printf("W! No metadata received for the Prometheus remote write metric family %s, its metrics are dropped until it is. Check that send_metadata is enabled in the remote write config of the sender.", family)
+}
+
+// remoteWriteReceiver implements the Prometheus remote-write v1 protocol, and feeds the time series
+// of each request as a batch handled like the scraped metrics.
+type remoteWriteReceiver struct {
+	pmbCh    chan<- PrometheusMetricBatch
+	metadata *remoteWriteMetadata
+	server   *http.Server
+	listener net.Listener
+}
+
+func newRemoteWriteReceiver(cfg *RemoteWriteConfig, pmbCh chan<- PrometheusMetricBatch) *remoteWriteReceiver {
+	rw := &remoteWriteReceiver{
+		pmbCh:    pmbCh,
+		metadata: newRemoteWriteMetadata(),
+	}
+	path := cfg.Path
+	if path == "" {
+		path = defaultRemoteWritePath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, rw)
+	rw.server = &http.Server{
+		Addr:              cfg.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: remoteWriteReadTimeout,
+		ReadTimeout:       remoteWriteReadTimeout,
+	}
+	return rw
+}
+
+// listen binds the listen address, so that an invalid or used address fails the start of the plugin.
+func (rw *remoteWriteReceiver) listen() error {
+	listener, err := net.Listen("tcp", rw.server.Addr)
+	if err != nil {
+		return err
+	}
+	rw.listener = listener
+	return nil
+}
+
+func (rw *remoteWriteReceiver) start(shutDownChan chan interface{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	go func() {
+		if err := rw.server.Serve(rw.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("E! Prometheus remote write receiver stopped: %v", err)
+		}
+	}()
+	<-shutDownChan
+	ctx, cancel := context.WithTimeout(context.Background(), remoteWriteStopTimeout)
+	defer cancel()
+	if err := rw.server.Shutdown(ctx); err != nil {
+		log.Printf("W! Failed to shut down the Prometheus remote write receiver: %v", err)
+	}
+}
+
+func (rw *remoteWriteReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req, err := remote.DecodeWriteRequest(http.MaxBytesReader(w, r.Body, remoteWriteMaxBodySize))
+	if err != nil {
+		log.Printf("D! Failed to decode Prometheus remote write request: %v", err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The metadata is sent periodically, either alone or with the time series, so it is kept before
+	// handling the time series which may need it.
+	rw.metadata.update(req.Metadata)
+
+	batch := rw.metadata.filter(newRemoteWriteBatch(req.Timeseries))
+	if len(batch) > 0 {
+		select {
+		case rw.pmbCh <- batch:
+		default:
+			log.Println("W! Prometheus remote write request rejected due to channel full")
+			http.Error(w, "too many requests waiting to be handled", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newRemoteWriteBatch converts the samples and native histograms of the time series to Prometheus
+// metrics. The pushed metrics are not relabeled by the agent, so their names, jobs and instances
+// before relabel are their labels.
+func newRemoteWriteBatch(timeseries []prompb.TimeSeries) PrometheusMetricBatch {
+	var batch PrometheusMetricBatch
+	for _, ts := range timeseries {
+		for _, s := range ts.Samples {
+			pm, err := newRemoteWriteMetric(ts.Labels, s.Timestamp)
+			if err != nil {
+				break
+			}
+			pm.metricValue = s.Value
+			batch = append(batch, pm)
+		}
+		for _, h := range ts.Histograms {
+			pm, err := newRemoteWriteMetric(ts.Labels, h.Timestamp)
+			if err != nil {
+				break
+			}
+			if h.IsFloatHistogram() {
+				pm.histogram = remote.FloatHistogramProtoToFloatHistogram(h)
+			} else {
+				pm.histogram = remote.HistogramProtoToFloatHistogram(h)
+			}
+			batch = append(batch, pm)
+		}
+	}
+	return batch
+}
+
+func newRemoteWriteMetric(ls []prompb.Label, t int64) (*PrometheusMetric, error) {
+	labelMap := make(map[string]string, len(ls))
+	for _, l := range ls {
+		labelMap[l.Name] = l.Value
+	}
+	metricName := labelMap[model.MetricNameLabel]
+	if metricName == "" {
+		log.Println("E! receive invalid prometheus remote write time series, metricName is missing")
+		return nil, errors.New("metricName of the times-series is missing")
+	}
+	delete(labelMap, model.MetricNameLabel)
+
+	pm := &PrometheusMetric{
+		tags:                    labelMap,
+		metricName:              metricName,
+		metricNameBeforeRelabel: metricName,
+		jobBeforeRelabel:        labelMap[model.JobLabel],
+		instanceBeforeRelabel:   labelMap[model.InstanceLabel],
+		timeInMS:                t,
+	}
+	if pm.jobBeforeRelabel == "" {
+		pm.jobBeforeRelabel = remoteWriteSource
+	}
+	if pm.instanceBeforeRelabel == "" {
+		pm.instanceBeforeRelabel = remoteWriteSource
+	}
+	return pm, nil
+}
@@ -0,0 +1,198 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package prometheus
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeWriteRequest(t *testing.T, req *prompb.WriteRequest) []byte {
+	data, err := req.Marshal()
+	require.NoError(t, err)
+	return snappy.Encode(nil, data)
+}
+
+func postWriteRequest(rw *remoteWriteReceiver, body []byte) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, defaultRemoteWritePath, bytes.NewReader(body))
+	r.Header.Set("Content-Encoding", "snappy")
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	w := httptest.NewRecorder()
+	rw.ServeHTTP(w, r)
+	return w
+}
+
+func newTimeSeries(value float64, timestamp int64, ls ...string) prompb.TimeSeries {
+	ts := prompb.TimeSeries{Samples: []prompb.Sample{{Value: value, Timestamp: timestamp}}}
+	for i := 0; i < len(ls); i += 2 {
+		ts.Labels = append(ts.Labels, prompb.Label{Name: ls[i], Value: ls[i+1]})
+	}
+	return ts
+}
+
+func TestRemoteWriteReceiver_InvalidRequests(t *testing.T) {
+	rw := newRemoteWriteReceiver(&RemoteWriteConfig{}, make(chan PrometheusMetricBatch, 1))
+
+	w := postWriteRequest(rw, []byte("not snappy"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	r := httptest.NewRequest(http.MethodGet, defaultRemoteWritePath, nil)
+	w = httptest.NewRecorder()
+	rw.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestRemoteWriteReceiver_BodyTooLarge(t *testing.T) {
+	rw := newRemoteWriteReceiver(&RemoteWriteConfig{}, make(chan PrometheusMetricBatch, 1))
+	w := postWriteRequest(rw, make([]byte, remoteWriteMaxBodySize+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestRemoteWriteReceiver_MissingMetadata(t *testing.T) {
+	ch := make(chan PrometheusMetricBatch, 2)
+	rw := newRemoteWriteReceiver(&RemoteWriteConfig{}, ch)
+	timeseries := []prompb.TimeSeries{
+		newTimeSeries(1, 1000, "__name__", "up"),
+		newTimeSeries(1, 1000, "__name__", "latency_seconds_sum"),
+		newTimeSeries(1, 1000, "__name__", "latency_seconds_count"),
+	}
+
+	// the metrics without metadata are dropped, their family being only logged once
+	body := encodeWriteRequest(t, &prompb.WriteRequest{Timeseries: timeseries})
+	require.Equal(t, http.StatusNoContent, postWriteRequest(rw, body).Code)
+	batch := <-ch
+	require.Len(t, batch, 1)
+	assert.Equal(t, "up", batch[0].metricName)
+	assert.Equal(t, map[string]struct{}{"latency_seconds": {}}, rw.metadata.missing)
+
+	body = encodeWriteRequest(t, &prompb.WriteRequest{
+		Timeseries: timeseries,
+		Metadata:   []prompb.MetricMetadata{{MetricFamilyName: "latency_seconds", Type: prompb.MetricMetadata_SUMMARY}},
+	})
+	require.Equal(t, http.StatusNoContent, postWriteRequest(rw, body).Code)
+	assert.Len(t, <-ch, 3)
+	assert.Empty(t, rw.metadata.missing)
+}
+
+func TestRemoteWriteReceiver_ChannelFull(t *testing.T) {
+	rw := newRemoteWriteReceiver(&RemoteWriteConfig{}, make(chan PrometheusMetricBatch, 1))
+	body := encodeWriteRequest(t, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{newTimeSeries(1, 1000, "__name__", "up")},
+	})
+
+	assert.Equal(t, http.StatusNoContent, postWriteRequest(rw, body).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, postWriteRequest(rw, body).Code)
+}
+
+func TestRemoteWriteReceiver_Metadata(t *testing.T) {
+	ch := make(chan PrometheusMetricBatch, 1)
+	rw := newRemoteWriteReceiver(&RemoteWriteConfig{}, ch)
+	body := encodeWriteRequest(t, &prompb.WriteRequest{
+		Metadata: []prompb.MetricMetadata{
+			{MetricFamilyName: "http_requests_total", Type: prompb.MetricMetadata_COUNTER},
+			{MetricFamilyName: "request_duration_seconds", Type: prompb.MetricMetadata_GAUGEHISTOGRAM},
+		},
+	})
+
+	assert.Equal(t, http.StatusNoContent, postWriteRequest(rw, body).Code)
+	// metadata only requests are not fed to the handler
+	assert.Len(t, ch, 0)
+	mm, ok := rw.metadata.Metadata("http_requests_total")
+	assert.True(t, ok)
+	assert.Equal(t, "counter", string(mm.Type))
+	mm, ok = rw.metadata.Metadata("request_duration_seconds")
+	assert.True(t, ok)
+	assert.Equal(t, "gaugehistogram", string(mm.Type))
+	_, ok = rw.metadata.Metadata("unknown")
+	assert.False(t, ok)
+}
+
+func TestNewRemoteWriteBatch(t *testing.T) {
+	batch := newRemoteWriteBatch([]prompb.TimeSeries{
+		newTimeSeries(1, 1000, "__name__", "up", "job", "node", "instance", "host:9100"),
+		newTimeSeries(2, 1000, "__name__", "rule_result"),
+		newTimeSeries(3, 1000, "job", "missing_name"),
+	})
+
+	require.Len(t, batch, 2)
+	assert.Equal(t, PrometheusMetric{
+		tags:                    map[string]string{"job": "node", "instance": "host:9100"},
+		metricName:              "up",
+		metricNameBeforeRelabel: "up",
+		jobBeforeRelabel:        "node",
+		instanceBeforeRelabel:   "host:9100",
+		metricValue:             1,
+		timeInMS:                1000,
+	}, *batch[0])
+	assert.Equal(t, PrometheusMetric{
+		tags:                    map[string]string{},
+		metricName:              "rule_result",
+		metricNameBeforeRelabel: "rule_result",
+		jobBeforeRelabel:        remoteWriteSource,
+		instanceBeforeRelabel:   remoteWriteSource,
+		metricValue:             2,
+		timeInMS:                1000,
+	}, *batch[1])
+}
+
+func TestRemoteWriteReceiver_Handle(t *testing.T) {
+	ch := make(chan PrometheusMetricBatch, 2)
+	rw := newRemoteWriteReceiver(&RemoteWriteConfig{}, ch)
+	acc := &testutil.Accumulator{}
+	handler := &metricsHandler{
+		acc:         acc,
+		calculator:  NewCalculator(),
+		filter:      NewMetricsFilter(),
+		clusterName: "test-cluster",
+		mtHandler:   &metricsTypeHandler{ms: rw.metadata},
+	}
+	metadata := []prompb.MetricMetadata{
+		{MetricFamilyName: "http_requests", Type: prompb.MetricMetadata_COUNTER},
+		{MetricFamilyName: "temperature", Type: prompb.MetricMetadata_GAUGE},
+	}
+	for i, value := range []float64{10, 25} {
+		timestamp := int64(1000 * (i + 1))
+		body := encodeWriteRequest(t, &prompb.WriteRequest{
+			Timeseries: []prompb.TimeSeries{
+				newTimeSeries(value, timestamp, "__name__", "http_requests_total", "job", "app", "instance", "host:8080"),
+				newTimeSeries(value, timestamp, "__name__", "temperature"),
+				newTimeSeries(value, timestamp, "__name__", "untyped"),
+			},
+			Metadata: metadata,
+		})
+		require.Equal(t, http.StatusNoContent, postWriteRequest(rw, body).Code)
+		handler.handle(<-ch)
+	}
+
+	// the counter is published from the second request as the delta from the first one
+	require.Len(t, acc.Metrics, 3)
+	var temperatures []interface{}
+	for _, m := range acc.Metrics {
+		if v, ok := m.Fields["temperature"]; ok {
+			temperatures = append(temperatures, v)
+			assert.Equal(t, map[string]string{
+				"prom_metric_type": "gauge",
+				"ClusterName":      "test-cluster",
+				"JobName":          "default",
+			}, m.Tags)
+			continue
+		}
+		assert.Equal(t, map[string]interface{}{"http_requests_total": float64(15)}, m.Fields)
+		assert.Equal(t, map[string]string{
+			"prom_metric_type": "counter",
+			"ClusterName":      "test-cluster",
+			"job":              "app",
+			"instance":         "host:8080",
+		}, m.Tags)
+	}
+	assert.Equal(t, []interface{}{float64(10), float64(25)}, temperatures)
+}
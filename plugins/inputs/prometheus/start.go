@@ -24,6 +24,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"slices"
 	"sync"
 	"syscall"
 
@@ -312,6 +313,7 @@ func reloadConfig(filename string, logger log.Logger, rls ...func(*config.Config
 	// - __name__ https://github.com/aws/amazon-cloudwatch-agent/issues/190
 	// - job and instance https://github.com/aws/amazon-cloudwatch-agent/issues/193
 	for _, sc := range conf.ScrapeConfigs {
+		preferProtobuf(sc)
 		relabelConfigs := []*relabel.Config{
 			// job
 			{
@@ -352,3 +354,12 @@ func reloadConfig(filename string, logger log.Logger, rls ...func(*config.Config
 	level.Info(logger).Log("msg", "Completed loading of configuration file", "filename", filename)
 	return nil
 }
+
+// preferProtobuf proposes the protobuf exposition format first to the targets of the scrape config
+// unless its scrape_protocols are set, since the native histograms are only exposed in protobuf.
+// The targets which don't support it keep being scraped in the text formats.
+func preferProtobuf(sc *config.ScrapeConfig) {
+	if slices.Equal(sc.ScrapeProtocols, config.DefaultScrapeProtocols) {
+		sc.ScrapeProtocols = config.DefaultProtoFirstScrapeProtocols
+	}
+}
@@ -56,7 +56,11 @@ func mergePrometheusMetrics(mm *metricMaterial, pm *PrometheusMetric) *metricMat
 		mm = &metricMaterial{tags: pm.tags, fields: map[string]interface{}{}, timeInMS: pm.timeInMS}
 	}
 
-	mm.fields[pm.metricName] = pm.metricValue
+	if pm.distribution != nil {
+		mm.fields[pm.metricName] = pm.distribution
+	} else {
+		mm.fields[pm.metricName] = pm.metricValue
+	}
 	return mm
 }
 
@@ -0,0 +1,243 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+const (
+	dogStatsDEventPrefix        = "_e{"
+	dogStatsDServiceCheckPrefix = "_sc|"
+
+	containerIDTag = "container_id"
+	// emptyTagValue is used for tags without a value since cloudwatch does not allow empty strings
+	emptyTagValue = "<empty>"
+
+	defaultEventsDestination = "cloudwatchlogs"
+)
+
+var (
+	errInvalidEvent        = errors.New("invalid dogstatsd event")
+	errInvalidServiceCheck = errors.New("invalid dogstatsd service check")
+
+	serviceCheckStatuses = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+)
+
+// parseDataDogTags adds the comma separated tags, e.g. "country:china,environment", to the map.
+func parseDataDogTags(tagstr string, tags map[string]string) {
+	for _, tag := range strings.Split(tagstr, ",") {
+		k, v, ok := strings.Cut(tag, ":")
+		if !ok {
+			// just a tag
+			v = emptyTagValue
+		}
+		if k != "" {
+			tags[k] = v
+		}
+	}
+}
+
+// splitDataDogValues handles the packed values of DogStatsD v1.1, e.g. "1:2:3|d", by giving every
+// value without a type the suffix of the next value that has one.
+func splitDataDogValues(bits []string) []string {
+	var values, pending []string
+	for _, bit := range bits {
+		i := strings.Index(bit, "|")
+		if i < 0 {
+			pending = append(pending, bit)
+			continue
+		}
+		for _, v := range pending {
+			values = append(values, v+bit[i:])
+		}
+		pending = pending[:0]
+		values = append(values, bit)
+	}
+	// values without any type are left for the caller to reject
+	return append(values, pending...)
+}
+
+// dogStatsDLogEvent is a DogStatsD event or service check encoded as a JSON log event.
+type dogStatsDLogEvent struct {
+	msg string
+	t   time.Time
+}
+
+func (e *dogStatsDLogEvent) Message() string { return e.msg }
+func (e *dogStatsDLogEvent) Time() time.Time { return e.t }
+func (e *dogStatsDLogEvent) Done()           {}
+
+// parseEvent parses a DogStatsD event:
+// _e{<title length>,<text length>}:<title>|<text>|d:<timestamp>|h:<hostname>|p:<priority>|t:<alert type>|s:<source type>|k:<aggregation key>|#<tags>|c:<container id>
+func parseEvent(line string, now time.Time) (*dogStatsDLogEvent, error) {
+	header, rest, ok := strings.Cut(strings.TrimPrefix(line, dogStatsDEventPrefix), "}:")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing header: %s", errInvalidEvent, line)
+	}
+	titleLenStr, textLenStr, _ := strings.Cut(header, ",")
+	titleLen, err := strconv.Atoi(titleLenStr)
+	if err != nil || titleLen <= 0 {
+		return nil, fmt.Errorf("%w: invalid title length: %s", errInvalidEvent, line)
+	}
+	textLen, err := strconv.Atoi(textLenStr)
+	if err != nil || textLen < 0 || len(rest) < titleLen+1+textLen || rest[titleLen] != '|' {
+		return nil, fmt.Errorf("%w: invalid text length: %s", errInvalidEvent, line)
+	}
+
+	fields := map[string]interface{}{
+		"type":       "event",
+		"title":      rest[:titleLen],
+		"text":       strings.ReplaceAll(rest[titleLen+1:titleLen+1+textLen], `\n`, "\n"),
+		"priority":   "normal",
+		"alert_type": "info",
+	}
+	t := now
+	for _, segment := range strings.Split(rest[titleLen+1+textLen:], "|") {
+		switch {
+		case segment == "":
+		case strings.HasPrefix(segment, "p:"):
+			fields["priority"] = segment[2:]
+		case strings.HasPrefix(segment, "t:"):
+			fields["alert_type"] = segment[2:]
+		case strings.HasPrefix(segment, "s:"):
+			fields["source_type_name"] = segment[2:]
+		case strings.HasPrefix(segment, "k:"):
+			fields["aggregation_key"] = segment[2:]
+		default:
+			parseCommonField(segment, fields, &t)
+		}
+	}
+	return newDogStatsDLogEvent(fields, t)
+}
+
+// parseServiceCheck parses a DogStatsD service check:
+// _sc|<name>|<status>|d:<timestamp>|h:<hostname>|#<tags>|c:<container id>|m:<message>
+func parseServiceCheck(line string, now time.Time) (*dogStatsDLogEvent, error) {
+	parts := strings.SplitN(line, "|", 4)
+	if len(parts) < 3 || parts[1] == "" {
+		return nil, fmt.Errorf("%w: missing name or status: %s", errInvalidServiceCheck, line)
+	}
+	status, err := strconv.Atoi(parts[2])
+	if err != nil || status < 0 || status >= len(serviceCheckStatuses) {
+		return nil, fmt.Errorf("%w: invalid status: %s", errInvalidServiceCheck, line)
+	}
+
+	fields := map[string]interface{}{
+		"type":        "service_check",
+		"name":        parts[1],
+		"status":      serviceCheckStatuses[status],
+		"status_code": status,
+	}
+	t := now
+	if len(parts) == 4 {
+		options := parts[3]
+		// the message is the last field and may contain pipes
+		if i := strings.Index("|"+options, "|m:"); i >= 0 {
+			fields["message"] = strings.ReplaceAll(options[i+2:], `\n`, "\n")
+			options = strings.TrimSuffix(options[:i], "|")
+		}
+		for _, segment := range strings.Split(options, "|") {
+			parseCommonField(segment, fields, &t)
+		}
+	}
+	return newDogStatsDLogEvent(fields, t)
+}
+
+// parseCommonField parses the fields shared by events and service checks. Unknown fields are ignored.
+func parseCommonField(segment string, fields map[string]interface{}, t *time.Time) {
+	switch {
+	case strings.HasPrefix(segment, "d:"):
+		if sec, err := strconv.ParseInt(segment[2:], 10, 64); err == nil {
+			*t = time.Unix(sec, 0)
+		}
+	case strings.HasPrefix(segment, "h:"):
+		fields["hostname"] = segment[2:]
+	case strings.HasPrefix(segment, "c:"):
+		fields[containerIDTag] = segment[2:]
+	case strings.HasPrefix(segment, "#"):
+		tags := map[string]string{}
+		parseDataDogTags(segment[1:], tags)
+		fields["tags"] = tags
+	}
+}
+
+func newDogStatsDLogEvent(fields map[string]interface{}, t time.Time) (*dogStatsDLogEvent, error) {
+	msg, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return &dogStatsDLogEvent{msg: string(msg), t: t}, nil
+}
+
+// eventLogSrc is the log source of the DogStatsD events and service checks. Events are buffered
+// until the log agent sets the output and dropped once the buffer is full.
+type eventLogSrc struct {
+	group  string
+	stream string
+
+	events   chan logs.LogEvent
+	done     chan struct{}
+	stopOnce sync.Once
+	drops    int
+}
+
+var _ logs.LogSrc = (*eventLogSrc)(nil)
+
+func newEventLogSrc(group, stream string, bufferSize int) *eventLogSrc {
+	if bufferSize <= 0 {
+		bufferSize = defaultAllowPendingMessage
+	}
+	return &eventLogSrc{
+		group:  group,
+		stream: stream,
+		events: make(chan logs.LogEvent, bufferSize),
+		done:   make(chan struct{}),
+	}
+}
+
+func (src *eventLogSrc) publish(e logs.LogEvent) {
+	select {
+	case src.events <- e:
+	default:
+		src.drops++
+		if src.drops == 1 || src.drops%cap(src.events) == 0 {
+			log.Printf("E! Error: statsd event queue full. We have dropped %d events so far.\n", src.drops)
+		}
+	}
+}
+
+func (src *eventLogSrc) SetOutput(fn func(logs.LogEvent)) {
+	go func() {
+		for {
+			select {
+			case e := <-src.events:
+				fn(e)
+			case <-src.done:
+				fn(nil)
+				return
+			}
+		}
+	}()
+}
+
+func (src *eventLogSrc) Group() string       { return src.group }
+func (src *eventLogSrc) Stream() string      { return src.stream }
+func (src *eventLogSrc) Destination() string { return defaultEventsDestination }
+func (src *eventLogSrc) Description() string { return "statsd events" }
+func (src *eventLogSrc) Retention() int      { return -1 }
+func (src *eventLogSrc) Class() string       { return "" }
+
+func (src *eventLogSrc) Stop() {
+	src.stopOnce.Do(func() { close(src.done) })
+}
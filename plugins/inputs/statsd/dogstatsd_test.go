@@ -0,0 +1,197 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+)
+
+func TestParseEvent(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	testCases := map[string]struct {
+		line    string
+		want    string
+		wantT   time.Time
+		wantErr bool
+	}{
+		"Minimal": {
+			line:  "_e{5,4}:title|text",
+			want:  `{"alert_type":"info","priority":"normal","text":"text","title":"title","type":"event"}`,
+			wantT: now,
+		},
+		"AllFields": {
+			line: `_e{10,12}:deploy|api|line1\nline2|d:1656581400|h:host-1|p:low|t:warning|s:jenkins|k:deploys|#env:prod,canary|c:abc123`,
+			want: `{"aggregation_key":"deploys","alert_type":"warning","container_id":"abc123","hostname":"host-1",` +
+				`"priority":"low","source_type_name":"jenkins","tags":{"canary":"<empty>","env":"prod"},` +
+				`"text":"line1\nline2","title":"deploy|api","type":"event"}`,
+			wantT: time.Unix(1656581400, 0),
+		},
+		"MultiByteTitle": {
+			line:  "_e{6,0}:héllo|",
+			want:  `{"alert_type":"info","priority":"normal","text":"","title":"héllo","type":"event"}`,
+			wantT: now,
+		},
+		"MissingHeader": {
+			line:    "_e{5,4}title|text",
+			wantErr: true,
+		},
+		"TextTooLong": {
+			line:    "_e{5,10}:title|text",
+			wantErr: true,
+		},
+		"WrongTitleLength": {
+			line:    "_e{4,4}:title|text",
+			wantErr: true,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e, err := parseEvent(testCase.line, now)
+			if testCase.wantErr {
+				assert.ErrorIs(t, err, errInvalidEvent)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.want, e.Message())
+			assert.Equal(t, testCase.wantT, e.Time())
+		})
+	}
+}
+
+func TestParseServiceCheck(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	testCases := map[string]struct {
+		line    string
+		want    string
+		wantT   time.Time
+		wantErr bool
+	}{
+		"Minimal": {
+			line:  "_sc|redis.can_connect|0",
+			want:  `{"name":"redis.can_connect","status":"OK","status_code":0,"type":"service_check"}`,
+			wantT: now,
+		},
+		"AllFields": {
+			line: `_sc|redis.can_connect|2|d:1656581400|h:host-1|#env:prod|c:abc123|m:connection refused | retrying\nagain`,
+			want: `{"container_id":"abc123","hostname":"host-1","message":"connection refused | retrying\nagain",` +
+				`"name":"redis.can_connect","status":"CRITICAL","status_code":2,"tags":{"env":"prod"},"type":"service_check"}`,
+			wantT: time.Unix(1656581400, 0),
+		},
+		"OnlyMessage": {
+			line:  "_sc|check|3|m:no data",
+			want:  `{"message":"no data","name":"check","status":"UNKNOWN","status_code":3,"type":"service_check"}`,
+			wantT: now,
+		},
+		"InvalidStatus": {
+			line:    "_sc|check|4",
+			wantErr: true,
+		},
+		"MissingStatus": {
+			line:    "_sc|check",
+			wantErr: true,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e, err := parseServiceCheck(testCase.line, now)
+			if testCase.wantErr {
+				assert.ErrorIs(t, err, errInvalidServiceCheck)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.want, e.Message())
+			assert.Equal(t, testCase.wantT, e.Time())
+		})
+	}
+}
+
+func TestParse_DataDogDistribution(t *testing.T) {
+	s := NewTestStatsd()
+	s.ParseDataDogTags = true
+	acc := &testutil.Accumulator{}
+
+	require.NoError(t, s.parseStatsdLine("request.latency:1:2:3|d|@0.5|#env:prod|c:abc123|T1656581400"))
+	require.NoError(t, s.parseStatsdLine("request.latency:4|d|#env:prod|c:abc123"))
+	assert.Error(t, s.parseStatsdLine("request.latency:4:5"))
+	require.NoError(t, s.Gather(acc))
+
+	require.Len(t, acc.Metrics, 1)
+	m := acc.Metrics[0]
+	assert.Equal(t, "request_latency", m.Measurement)
+	assert.Equal(t, map[string]string{"metric_type": "distribution", "env": "prod", containerIDTag: "abc123"}, m.Tags)
+	dist, ok := m.Fields[defaultFieldName].(distribution.Distribution)
+	require.True(t, ok)
+	assert.EqualValues(t, 7, dist.SampleCount())
+	assert.EqualValues(t, 1, dist.Minimum())
+	assert.EqualValues(t, 4, dist.Maximum())
+	assert.EqualValues(t, 16, dist.Sum())
+}
+
+func TestParse_DataDogPackedValues(t *testing.T) {
+	assert.Equal(t, []string{"1|c", "2|c", "3|ms", "4"}, splitDataDogValues([]string{"1", "2|c", "3|ms", "4"}))
+	assert.Equal(t, []string{"1|c|@0.1", "2|c|@0.1"}, splitDataDogValues([]string{"1", "2|c|@0.1"}))
+}
+
+func TestStatsdEvents(t *testing.T) {
+	s := &Statsd{
+		ServiceAddress:         "127.0.0.1:0",
+		AllowedPendingMessages: defaultAllowPendingMessage,
+		ParseDataDogTags:       true,
+		EventsLogGroupName:     "statsd-events",
+		EventsLogStreamName:    "host-1",
+	}
+	require.NoError(t, s.Start(&testutil.Accumulator{}))
+	// the log agent starts the log collections again
+	require.NoError(t, s.Start(nil))
+	defer s.Stop()
+
+	srcs := s.FindLogSrc()
+	require.Len(t, srcs, 1)
+	assert.Empty(t, s.FindLogSrc())
+	src := srcs[0]
+	assert.Equal(t, "statsd-events", src.Group())
+	assert.Equal(t, "host-1", src.Stream())
+	assert.Equal(t, "cloudwatchlogs", src.Destination())
+
+	events := make(chan logs.LogEvent, 10)
+	src.SetOutput(func(e logs.LogEvent) {
+		events <- e
+	})
+	s.in <- []byte("_e{5,4}:title|text\n_sc|check|1\n_e{5,4}:invalid\nvalid:1|c")
+
+	for _, want := range []string{`"type":"event"`, `"status":"WARNING"`} {
+		select {
+		case e := <-events:
+			assert.Contains(t, e.Message(), want)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the event")
+		}
+	}
+
+	src.Stop()
+	select {
+	case e := <-events:
+		assert.Nil(t, e)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the end of the events")
+	}
+}
+
+func TestStatsdEventsNotConfigured(t *testing.T) {
+	s := NewTestStatsd()
+	s.ParseDataDogTags = true
+	assert.Empty(t, s.FindLogSrc())
+	e, err := parseEvent("_e{5,4}:title|text", time.Now())
+	require.NoError(t, err)
+	// discarded without a log group
+	s.parseLogEvent(e, err)
+}
@@ -0,0 +1,210 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const defaultMaxConnections = 250
+
+// parseServiceAddress splits the service address into the network and the address to listen on.
+// Addresses without a scheme are UDP addresses, e.g. ":8125".
+func parseServiceAddress(serviceAddress string) (string, string, error) {
+	network, address, ok := strings.Cut(serviceAddress, "://")
+	if !ok {
+		return "udp", serviceAddress, nil
+	}
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+	default:
+		return "", "", fmt.Errorf("statsd service_address %q has unsupported scheme %q", serviceAddress, network)
+	}
+	if address == "" {
+		return "", "", fmt.Errorf("statsd service_address %q has no address", serviceAddress)
+	}
+	return network, address, nil
+}
+
+func isStreamNetwork(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	}
+	return false
+}
+
+// listen binds the service address and starts accepting packets or connections.
+func (s *Statsd) listen() error {
+	network, address, err := parseServiceAddress(s.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	if network == "unix" || network == "unixgram" {
+		removeStaleSocket(address)
+	}
+
+	if isStreamNetwork(network) {
+		s.streamListener, err = net.Listen(network, address)
+		if err != nil {
+			return fmt.Errorf("statsd listen on %s: %w", s.ServiceAddress, err)
+		}
+		s.conns = make(map[net.Conn]struct{})
+		log.Println("I! Statsd listener listening on: ", s.streamListener.Addr().String())
+		s.wg.Add(1)
+		go s.streamListen()
+		return nil
+	}
+
+	s.listener, err = net.ListenPacket(network, address)
+	if err != nil {
+		return fmt.Errorf("statsd listen on %s: %w", s.ServiceAddress, err)
+	}
+	if network == "unixgram" {
+		s.socketPath = address
+	}
+	log.Println("I! Statsd listener listening on: ", s.listener.LocalAddr().String())
+	s.wg.Add(1)
+	go s.packetListen()
+	return nil
+}
+
+// removeStaleSocket removes a socket file left behind by a previous run so that it can be bound again.
+func removeStaleSocket(path string) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+}
+
+// packetListen reads datagrams from the udp or unixgram listener.
+func (s *Statsd) packetListen() {
+	defer s.wg.Done()
+	buf := make([]byte, UDP_MAX_PACKET_SIZE)
+	for {
+		n, _, err := s.listener.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("E! Error READ: %s\n", err.Error())
+			continue
+		}
+		bufCopy := make([]byte, n)
+		copy(bufCopy, buf[:n])
+		s.enqueue(bufCopy)
+	}
+}
+
+// streamListen accepts connections from the tcp or unix listener. Connections above
+// MaxConnections are closed right away.
+func (s *Statsd) streamListen() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.streamListener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("E! Error ACCEPT: %s\n", err.Error())
+			continue
+		}
+		if !s.trackConn(conn) {
+			conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Statsd) trackConn(conn net.Conn) bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	if s.MaxConnections > 0 && len(s.conns) >= s.MaxConnections {
+		log.Printf("W! statsd refused connection from %s, max_connections %d reached\n", conn.RemoteAddr(), s.MaxConnections)
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Statsd) untrackConn(conn net.Conn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	delete(s.conns, conn)
+	conn.Close()
+}
+
+// handleConn reads newline delimited metrics from the connection until it is closed, sends a
+// line longer than MaxLineLength or stays idle for longer than ConnectionIdleTimeout.
+func (s *Statsd) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer s.untrackConn(conn)
+
+	idleTimeout := time.Duration(s.ConnectionIdleTimeout)
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), s.MaxLineLength)
+	for {
+		if idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		lineCopy := make([]byte, len(line))
+		copy(lineCopy, line)
+		s.enqueue(lineCopy)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("W! statsd closed connection from %s: %s\n", conn.RemoteAddr(), err.Error())
+	}
+}
+
+// enqueue hands the packet to the parser, dropping it if allowed_pending_messages is reached.
+func (s *Statsd) enqueue(packet []byte) {
+	select {
+	case s.in <- packet:
+	default:
+		drops := atomic.AddInt64(&s.drops, 1)
+		if drops == 1 || s.AllowedPendingMessages == 0 || drops%int64(s.AllowedPendingMessages) == 0 {
+			log.Printf(dropwarn, drops)
+		}
+	}
+}
+
+// closeListeners stops accepting new packets and closes the open connections.
+func (s *Statsd) closeListeners() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.socketPath != "" {
+		os.Remove(s.socketPath)
+	}
+	if s.streamListener != nil {
+		s.streamListener.Close()
+	}
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestListener(t *testing.T, serviceAddress string) *Statsd {
+	t.Helper()
+	s := &Statsd{
+		ServiceAddress:         serviceAddress,
+		AllowedPendingMessages: defaultAllowPendingMessage,
+		MaxConnections:         defaultMaxConnections,
+		DeleteCounters:         true,
+	}
+	require.NoError(t, s.Start(&testutil.Accumulator{}))
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func listenerAddr(s *Statsd) string {
+	if s.streamListener != nil {
+		return s.streamListener.Addr().String()
+	}
+	return s.listener.LocalAddr().String()
+}
+
+func assertCounter(t *testing.T, s *Statsd, name string, expected int64) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		s.Lock()
+		defer s.Unlock()
+		for _, c := range s.counters {
+			if c.name == name && c.fields[defaultFieldName] == expected {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestParseServiceAddress(t *testing.T) {
+	testCases := map[string]struct {
+		network string
+		address string
+		wantErr bool
+	}{
+		":8125":                     {network: "udp", address: ":8125"},
+		"udp://127.0.0.1:8125":      {network: "udp", address: "127.0.0.1:8125"},
+		"tcp://:8125":               {network: "tcp", address: ":8125"},
+		"unix:///var/run/statsd":    {network: "unix", address: "/var/run/statsd"},
+		"unixgram:///var/run/stats": {network: "unixgram", address: "/var/run/stats"},
+		"http://:8125":              {wantErr: true},
+		"tcp://":                    {wantErr: true},
+	}
+	for serviceAddress, testCase := range testCases {
+		t.Run(serviceAddress, func(t *testing.T) {
+			network, address, err := parseServiceAddress(serviceAddress)
+			if testCase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.network, network)
+			assert.Equal(t, testCase.address, address)
+		})
+	}
+}
+
+func TestListenUDP(t *testing.T) {
+	s := newTestListener(t, "127.0.0.1:0")
+	conn, err := net.Dial("udp", listenerAddr(s))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("udp.counter:1|c\nudp.counter:2|c"))
+	require.NoError(t, err)
+	assertCounter(t, s, "udp_counter", 3)
+}
+
+func TestListenTCP(t *testing.T) {
+	s := newTestListener(t, "tcp://127.0.0.1:0")
+	conn, err := net.Dial("tcp", listenerAddr(s))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = fmt.Fprint(conn, "tcp.counter:1|c\ntcp.cou")
+	require.NoError(t, err)
+	// lines split across writes are only parsed once complete
+	_, err = fmt.Fprint(conn, "nter:2|c\n\n")
+	require.NoError(t, err)
+	assertCounter(t, s, "tcp_counter", 3)
+}
+
+func TestListenUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statsd.sock")
+	s := newTestListener(t, "unix://"+path)
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = fmt.Fprint(conn, "unix.counter:5|c\n")
+	require.NoError(t, err)
+	assertCounter(t, s, "unix_counter", 5)
+}
+
+func TestListenUnixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statsd.sock")
+	s := &Statsd{ServiceAddress: "unixgram://" + path, AllowedPendingMessages: defaultAllowPendingMessage}
+	require.NoError(t, s.Start(&testutil.Accumulator{}))
+	conn, err := net.Dial("unixgram", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("unixgram.counter:7|c"))
+	require.NoError(t, err)
+	assertCounter(t, s, "unixgram_counter", 7)
+
+	s.Stop()
+	assert.NoFileExists(t, path)
+	// rebinding the same path works after a restart
+	s2 := newTestListener(t, "unixgram://"+path)
+	assert.NotNil(t, s2.listener)
+}
+
+func TestListenInvalidAddress(t *testing.T) {
+	s := &Statsd{ServiceAddress: "http://:8125"}
+	assert.Error(t, s.Start(&testutil.Accumulator{}))
+}
+
+func TestStreamConnectionLimits(t *testing.T) {
+	s := &Statsd{
+		ServiceAddress:         "tcp://127.0.0.1:0",
+		AllowedPendingMessages: defaultAllowPendingMessage,
+		MaxConnections:         1,
+		MaxLineLength:          32,
+		ConnectionIdleTimeout:  config.Duration(200 * time.Millisecond),
+	}
+	require.NoError(t, s.Start(&testutil.Accumulator{}))
+	defer s.Stop()
+
+	first, err := net.Dial("tcp", listenerAddr(s))
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = fmt.Fprint(first, "first.counter:1|c\n")
+	require.NoError(t, err)
+	assertCounter(t, s, "first_counter", 1)
+
+	// connections above the limit are closed right away
+	second, err := net.Dial("tcp", listenerAddr(s))
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = bufio.NewReader(second).ReadByte()
+	assert.Error(t, err)
+
+	// idle connections are closed, which frees a slot for a new one
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = bufio.NewReader(first).ReadByte()
+	assert.Error(t, err)
+
+	assert.Eventually(t, func() bool {
+		s.connsMu.Lock()
+		defer s.connsMu.Unlock()
+		return len(s.conns) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// lines longer than the limit close the connection
+	third, err := net.Dial("tcp", listenerAddr(s))
+	require.NoError(t, err)
+	defer third.Close()
+	_, err = fmt.Fprintf(third, "%s:1|c\n", make([]byte, 64))
+	require.NoError(t, err)
+	third.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = bufio.NewReader(third).ReadByte()
+	assert.Error(t, err)
+}
+
+func TestEnqueueDrops(t *testing.T) {
+	s := NewTestStatsd()
+	s.AllowedPendingMessages = 1
+	s.in = make(chan []byte, 1)
+	s.enqueue([]byte("a:1|c"))
+	s.enqueue([]byte("b:1|c"))
+	s.enqueue([]byte("c:1|c"))
+	assert.EqualValues(t, 2, s.drops)
+}
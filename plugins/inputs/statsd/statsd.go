@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net"
 	"sort"
 	"strconv"
@@ -16,9 +17,12 @@ import (
 
 	//"github.com/influxdata/telegraf/plugins/parsers/graphite"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/plugins/inputs"
 
+	"github.com/aws/amazon-cloudwatch-agent/logs"
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/seh1"
 	"github.com/aws/amazon-cloudwatch-agent/plugins/inputs/statsd/graphite"
 )
 
@@ -31,6 +35,10 @@ const (
 
 	defaultSeparator           = "_"
 	defaultAllowPendingMessage = 10000
+
+	defaultSketchRelativeAccuracy = 0.05
+	defaultSketchMaxBuckets       = 2048
+	maxSketchMaxBuckets           = 65536
 )
 
 var dropwarn = "E! Error: statsd message queue full. " +
@@ -38,9 +46,17 @@ var dropwarn = "E! Error: statsd message queue full. " +
 	"You may want to increase allowed_pending_messages in the config\n"
 
 type Statsd struct {
-	// Address & Port to serve from
+	// Address & Port to serve from. UDP is used unless the address has a tcp://, unix:// or
+	// unixgram:// scheme.
 	ServiceAddress string
 
+	// MaxConnections limits the concurrent connections of the tcp and unix listeners.
+	MaxConnections int
+	// ConnectionIdleTimeout closes tcp and unix connections which have not sent data for the duration.
+	ConnectionIdleTimeout config.Duration
+	// MaxLineLength closes tcp and unix connections which send a longer line.
+	MaxLineLength int
+
 	// Number of messages allowed to queue up in between calls to Gather. If this
 	// fills up, packets will get dropped until the next Gather interval is ran.
 	AllowedPendingMessages int
@@ -50,11 +66,24 @@ type Statsd struct {
 	DeleteSets     bool
 	DeleteTimings  bool
 
+	// TimingSketch aggregates timings into SEH1 distributions, whose buckets bound the memory per
+	// series, instead of keeping every distinct value until the next Gather.
+	TimingSketch                 bool
+	TimingSketchRelativeAccuracy float64
+	TimingSketchMaxBuckets       int
+	// TimingPercentiles are emitted as <field>_p<percentile> fields when TimingSketch is enabled.
+	TimingPercentiles []float64
+
 	// MetricSeparator is the separator between parts of the metric name.
 	MetricSeparator string
 	// This flag enables parsing of tags in the dogstatsd extension to the
 	// statsd protocol (http://docs.datadoghq.com/guides/dogstatsd/)
+	// along with the distributions, events, service checks and container IDs.
 	ParseDataDogTags bool
+	// The log group and stream receiving the dogstatsd events and service checks.
+	// Events are discarded when the log group is not set.
+	EventsLogGroupName  string
+	EventsLogStreamName string
 
 	// UDPPacketSize is deprecated, it's only here for legacy support
 	// we now always create 1 max size buffer and then copy only what we need
@@ -65,7 +94,7 @@ type Statsd struct {
 	sync.Mutex
 	wg sync.WaitGroup
 	// drops tracks the number of dropped metrics.
-	drops int
+	drops int64
 
 	// Channel for all incoming statsd packets
 	in   chan []byte
@@ -82,7 +111,19 @@ type Statsd struct {
 	// bucket -> influx templates
 	Templates []string
 
-	listener *net.UDPConn
+	listener       net.PacketConn
+	streamListener net.Listener
+	// socketPath is the unixgram socket removed when the listener stops.
+	socketPath string
+	connsMu    sync.Mutex
+	conns      map[net.Conn]struct{}
+
+	// runningMu guards running, the log agent starts the plugin as well since it is a log collection.
+	runningMu sync.Mutex
+	running   bool
+
+	eventSrc      *eventLogSrc
+	eventSrcFound bool
 
 	graphiteParser *graphite.GraphiteParser
 }
@@ -131,9 +172,17 @@ func (_ *Statsd) Description() string {
 }
 
 const sampleConfig = `
-  ## Address and port to host UDP listener on
+  ## Address and port to host UDP listener on. Use the tcp://, unix:// or
+  ## unixgram:// scheme to listen on a TCP or Unix domain socket instead,
+  ## e.g. "tcp://:8125" or "unix:///var/run/statsd.sock"
   service_address = ":8125"
 
+  ## Limits of the TCP and Unix stream connections, which send newline
+  ## delimited metrics
+  # max_connections = 250
+  # connection_idle_timeout = "0s"
+  # max_line_length = 65536
+
   ## The following configuration options control when telegraf clears it's cache
   ## of previous values. If set to false, then telegraf will only clear it's
   ## cache when the daemon is restarted.
@@ -151,8 +200,14 @@ const sampleConfig = `
 
   ## Parses tags in the datadog statsd format
   ## http://docs.datadoghq.com/guides/dogstatsd/
+  ## along with distributions, events, service checks and container IDs
   parse_data_dog_tags = false
 
+  ## The log group and stream receiving the datadog events and service checks
+  ## as JSON log events. Events are discarded when the log group is not set.
+  # events_log_group_name = "statsd-events"
+  # events_log_stream_name = "my-host"
+
   ## Statsd data translation templates, more info can be read here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md#graphite
   # templates = [
@@ -166,6 +221,16 @@ const sampleConfig = `
   ## The aggregation interval for the metrics
   metric_aggregation_interval = "60s"
 
+  ## Aggregate timings & histograms into SEH1 distributions which cap the memory
+  ## used per series, values are kept within the relative accuracy (at least
+  ## 0.05 and less than 1). Once a sketch has max buckets, its lowest buckets
+  ## are merged.
+  # timing_sketch = false
+  # timing_sketch_relative_accuracy = 0.05
+  # timing_sketch_max_buckets = 2048
+  ## Percentiles of the sketches emitted as <field>_p<percentile> fields
+  # timing_percentiles = [50.0, 90.0, 99.0]
+
 `
 
 func (_ *Statsd) SampleConfig() string {
@@ -178,6 +243,10 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 	now := time.Now()
 
 	for _, metric := range s.timings {
+		if s.TimingSketch {
+			s.gatherSketches(acc, metric, now)
+			continue
+		}
 		acc.AddHistogram(metric.name, metric.fields, metric.tags, now)
 	}
 	if s.DeleteTimings {
@@ -212,7 +281,88 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 	return nil
 }
 
+func (s *Statsd) gatherSketches(acc telegraf.Accumulator, metric cachedtimings, now time.Time) {
+	distributions := make(map[string]interface{}, len(metric.fields))
+	percentiles := make(map[string]interface{}, len(metric.fields)*len(s.TimingPercentiles))
+	for field, value := range metric.fields {
+		dist := value.(distribution.Distribution)
+		distributions[field] = toDistribution(dist)
+		for _, p := range s.TimingPercentiles {
+			percentiles[field+"_p"+strconv.FormatFloat(p, 'f', -1, 64)] = quantile(dist, p/100)
+		}
+	}
+	acc.AddHistogram(metric.name, distributions, metric.tags, now)
+	if len(percentiles) > 0 {
+		acc.AddFields(metric.name, percentiles, metric.tags, now)
+	}
+}
+
+// toDistribution copies the buckets of the sketch into the distribution type used by the outputs,
+// which do not read the buckets of an SEH1 distribution as values. The minimum, maximum and sum
+// are approximated within the relative accuracy of the sketch.
+func toDistribution(sketch distribution.Distribution) distribution.Distribution {
+	// Assume function pointer is valid.
+	dist := distribution.NewDistribution()
+	values, counts := sketch.ValuesAndCounts()
+	for i := range values {
+		value := math.Min(sketch.Maximum(), math.Max(sketch.Minimum(), values[i]))
+		if err := dist.AddEntryWithUnit(value, counts[i], sketch.Unit()); err != nil {
+			log.Printf("W! error: %s, value: %v", err, value)
+		}
+	}
+	return dist
+}
+
+// quantile returns the value of the bucket of the distribution holding the quantile in [0, 1],
+// the minimum and maximum being exact.
+func quantile(dist distribution.Distribution, q float64) float64 {
+	if dist.SampleCount() == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return dist.Minimum()
+	}
+	if q >= 1 {
+		return dist.Maximum()
+	}
+	values, counts := dist.ValuesAndCounts()
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.Slice(indexes, func(i, j int) bool { return values[indexes[i]] < values[indexes[j]] })
+	rank := q * dist.SampleCount()
+	var cumulative float64
+	for _, i := range indexes {
+		cumulative += counts[i]
+		if cumulative >= rank {
+			return math.Min(dist.Maximum(), math.Max(dist.Minimum(), values[i]))
+		}
+	}
+	return dist.Maximum()
+}
+
+// validateTimingSketch replaces the invalid timing sketch settings by their defaults.
+func (s *Statsd) validateTimingSketch() {
+	if s.TimingSketchRelativeAccuracy < defaultSketchRelativeAccuracy || s.TimingSketchRelativeAccuracy >= 1 {
+		log.Printf("W! statsd timing_sketch_relative_accuracy %v is not in [%v, 1), using %v",
+			s.TimingSketchRelativeAccuracy, defaultSketchRelativeAccuracy, defaultSketchRelativeAccuracy)
+		s.TimingSketchRelativeAccuracy = defaultSketchRelativeAccuracy
+	}
+	if s.TimingSketchMaxBuckets <= 0 || s.TimingSketchMaxBuckets > maxSketchMaxBuckets {
+		log.Printf("W! statsd timing_sketch_max_buckets %d is not in [1, %d], using %d",
+			s.TimingSketchMaxBuckets, maxSketchMaxBuckets, defaultSketchMaxBuckets)
+		s.TimingSketchMaxBuckets = defaultSketchMaxBuckets
+	}
+}
+
 func (s *Statsd) Start(_ telegraf.Accumulator) error {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	if s.running {
+		return nil
+	}
+
 	// Make data structures
 	s.done = make(chan struct{})
 	s.in = make(chan []byte, s.AllowedPendingMessages)
@@ -225,51 +375,31 @@ func (s *Statsd) Start(_ telegraf.Accumulator) error {
 	if s.MetricSeparator == "" {
 		s.MetricSeparator = defaultSeparator
 	}
+	if s.TimingSketch {
+		s.validateTimingSketch()
+	}
+	if len(s.TimingPercentiles) > 0 && !s.TimingSketch {
+		log.Printf("W! statsd timing_percentiles are ignored unless timing_sketch is enabled")
+	}
 
-	s.wg.Add(2)
-	// Start the UDP listener
-	go s.udpListen()
-	// Start the line parser
-	go s.parser()
-	log.Printf("I! Started the statsd service on %s\n", s.ServiceAddress)
-	return nil
-}
-
-// udpListen starts listening for udp packets on the configured port.
-func (s *Statsd) udpListen() error {
-	defer s.wg.Done()
-	var err error
-	address, _ := net.ResolveUDPAddr("udp", s.ServiceAddress)
-	s.listener, err = net.ListenUDP("udp", address)
-	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
+	if s.MaxLineLength <= 0 {
+		s.MaxLineLength = UDP_MAX_PACKET_SIZE
 	}
-	log.Println("I! Statsd listener listening on: ", s.listener.LocalAddr().String())
 
-	buf := make([]byte, UDP_MAX_PACKET_SIZE)
-	for {
-		select {
-		case <-s.done:
-			return nil
-		default:
-			n, _, err := s.listener.ReadFromUDP(buf)
-			if err != nil && !strings.Contains(err.Error(), "closed network") {
-				log.Printf("E! Error READ: %s\n", err.Error())
-				continue
-			}
-			bufCopy := make([]byte, n)
-			copy(bufCopy, buf[:n])
+	if s.EventsLogGroupName != "" && s.eventSrc == nil {
+		s.eventSrc = newEventLogSrc(s.EventsLogGroupName, s.EventsLogStreamName, s.AllowedPendingMessages)
+	}
 
-			select {
-			case s.in <- bufCopy:
-			default:
-				s.drops++
-				if s.drops == 1 || s.AllowedPendingMessages == 0 || s.drops%s.AllowedPendingMessages == 0 {
-					log.Printf(dropwarn, s.drops)
-				}
-			}
-		}
+	// Start the listener
+	if err := s.listen(); err != nil {
+		return err
 	}
+	// Start the line parser
+	s.wg.Add(1)
+	go s.parser()
+	s.running = true
+	log.Printf("I! Started the statsd service on %s\n", s.ServiceAddress)
+	return nil
 }
 
 // parser monitors the s.in channel, if there is a packet ready, it parses the
@@ -286,7 +416,13 @@ func (s *Statsd) parser() error {
 			lines := strings.Split(string(packet), "\n")
 			for _, line := range lines {
 				line = strings.TrimSpace(line)
-				if line != "" {
+				switch {
+				case line == "":
+				case s.ParseDataDogTags && strings.HasPrefix(line, dogStatsDEventPrefix):
+					s.parseLogEvent(parseEvent(line, time.Now()))
+				case s.ParseDataDogTags && strings.HasPrefix(line, dogStatsDServiceCheckPrefix):
+					s.parseLogEvent(parseServiceCheck(line, time.Now()))
+				default:
 					s.parseStatsdLine(line)
 				}
 			}
@@ -294,6 +430,30 @@ func (s *Statsd) parser() error {
 	}
 }
 
+// parseLogEvent hands the parsed dogstatsd event or service check to the event log source.
+func (s *Statsd) parseLogEvent(e *dogStatsDLogEvent, err error) {
+	if err != nil {
+		log.Printf("E! Error: %s\n", err.Error())
+		return
+	}
+	if s.eventSrc == nil {
+		log.Printf("D! statsd events_log_group_name is not set, discarding event %s\n", e.msg)
+		return
+	}
+	s.eventSrc.publish(e)
+}
+
+// FindLogSrc returns the log source of the dogstatsd events and service checks once.
+func (s *Statsd) FindLogSrc() []logs.LogSrc {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	if s.eventSrc == nil || s.eventSrcFound {
+		return nil
+	}
+	s.eventSrcFound = true
+	return []logs.LogSrc{s.eventSrc}
+}
+
 // parseStatsdLine will parse the given statsd line, validating it as it goes.
 // If the line is valid, it will be cached for the next call to Gather()
 func (s *Statsd) parseStatsdLine(line string) error {
@@ -305,30 +465,22 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		// users.online:1|c|@0.5|#country:china,environment:production
 		// users.online:1|c|#sometagwithnovalue
 		// we will split on the pipe and remove any elements that are datadog
-		// tags, parse them, and rebuild the line sans the datadog tags
+		// tags, container IDs or timestamps, parse them, and rebuild the line
+		// sans the datadog extensions
+		// users.online:1|c|#sometag|c:<container id>|T1656581400
 		pipesplit := strings.Split(line, "|")
-		for _, segment := range pipesplit {
-			if len(segment) > 0 && segment[0] == '#' {
+		for i, segment := range pipesplit {
+			switch {
+			case len(segment) > 0 && segment[0] == '#':
 				// we have ourselves a tag; they are comma separated
-				tagstr := segment[1:]
-				tags := strings.Split(tagstr, ",")
-				for _, tag := range tags {
-					ts := strings.SplitN(tag, ":", 2)
-					var k, v string
-					switch len(ts) {
-					case 1:
-						// just a tag
-						k = ts[0]
-						v = "<empty>" //cloudwatch does not allow empty string
-					case 2:
-						k = ts[0]
-						v = ts[1]
-					}
-					if k != "" {
-						lineTags[k] = v
-					}
+				parseDataDogTags(segment[1:], lineTags)
+			case i > 1 && strings.HasPrefix(segment, "c:"):
+				if segment[2:] != "" {
+					lineTags[containerIDTag] = segment[2:]
 				}
-			} else {
+			case i > 1 && len(segment) > 1 && segment[0] == 'T':
+				// the metrics are sent with the time of the collection
+			default:
 				recombinedSegments = append(recombinedSegments, segment)
 			}
 		}
@@ -344,6 +496,9 @@ func (s *Statsd) parseStatsdLine(line string) error {
 
 	// Extract bucket name from individual metric bits
 	bucketName, bits := bits[0], bits[1:]
+	if s.ParseDataDogTags {
+		bits = splitDataDogValues(bits)
+	}
 
 	// Add a metric for each bit available
 	for _, bit := range bits {
@@ -375,7 +530,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 
 		// Validate metric type
 		switch pipesplit[1] {
-		case "g", "c", "s", "ms", "h":
+		case "g", "c", "s", "ms", "h", "d":
 			m.mtype = pipesplit[1]
 		default:
 			log.Printf("E! Error: Statsd Metric type %s unsupported", pipesplit[1])
@@ -392,7 +547,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		}
 
 		switch m.mtype {
-		case "g", "ms", "h":
+		case "g", "ms", "h", "d":
 			v, err := strconv.ParseFloat(pipesplit[0], 64)
 			if err != nil {
 				log.Printf("E! Error: parsing value to float64: %s\n", line)
@@ -432,6 +587,8 @@ func (s *Statsd) parseStatsdLine(line string) error {
 			m.tags["metric_type"] = "timing"
 		case "h":
 			m.tags["metric_type"] = "histogram"
+		case "d":
+			m.tags["metric_type"] = "distribution"
 		}
 
 		if len(lineTags) > 0 {
@@ -519,7 +676,7 @@ func (s *Statsd) aggregate(m metric) {
 	defer s.Unlock()
 
 	switch m.mtype {
-	case "ms", "h":
+	case "ms", "h", "d":
 		// Check if the measurement exists
 		cached, ok := s.timings[m.hash]
 		if !ok {
@@ -533,8 +690,12 @@ func (s *Statsd) aggregate(m metric) {
 		// this will be the default field name, eg. "value"
 		field, ok := cached.fields[m.field]
 		if !ok {
-			// Assume function pointer is valid.
-			field = distribution.NewDistribution()
+			if s.TimingSketch {
+				field = seh1.NewSEH1DistributionWithAccuracy(s.TimingSketchRelativeAccuracy, s.TimingSketchMaxBuckets)
+			} else {
+				// Assume function pointer is valid.
+				field = distribution.NewDistribution()
+			}
 		}
 		weight := 1.0
 		if m.samplerate > 0 {
@@ -605,10 +766,16 @@ func (s *Statsd) aggregate(m metric) {
 
 func (s *Statsd) Stop() {
 	log.Println("D! Stopping the statsd service")
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	if !s.running {
+		return
+	}
 	close(s.done)
-	s.listener.Close()
+	s.closeListeners()
 	s.wg.Wait()
 	close(s.in)
+	s.running = false
 	log.Println("D! Stopped the statsd service")
 }
 
@@ -618,10 +785,15 @@ func init() {
 			ServiceAddress:         ":8125",
 			MetricSeparator:        "_",
 			AllowedPendingMessages: defaultAllowPendingMessage,
+			MaxConnections:         defaultMaxConnections,
+			MaxLineLength:          UDP_MAX_PACKET_SIZE,
 			DeleteCounters:         true,
 			DeleteGauges:           true,
 			DeleteSets:             true,
 			DeleteTimings:          true,
+
+			TimingSketchRelativeAccuracy: defaultSketchRelativeAccuracy,
+			TimingSketchMaxBuckets:       defaultSketchMaxBuckets,
 		}
 	})
 }
@@ -11,8 +11,10 @@ import (
 
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/regular"
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/seh1"
 )
 
@@ -293,6 +295,108 @@ func TestParse_Timings(t *testing.T) {
 	assert.Equal(t, dist, fields[defaultFieldName])
 }
 
+func TestParse_TimingSketch(t *testing.T) {
+	s := NewTestStatsd()
+	s.TimingSketch = true
+	s.TimingPercentiles = []float64{50, 99.9}
+	s.DeleteTimings = true
+	acc := &testutil.Accumulator{}
+
+	for i := 1; i <= 1000; i++ {
+		assert.NoError(t, s.parseStatsdLine(fmt.Sprintf("test.timing:%d|ms", i)))
+	}
+	assert.NoError(t, s.parseStatsdLine("test.timing:1000|ms|@0.5"))
+	s.Gather(acc)
+
+	assert.Equal(t, 2, len(acc.Metrics))
+	for _, metric := range acc.Metrics {
+		assert.Equal(t, "test_timing", metric.Measurement)
+		assert.Equal(t, map[string]string{"metric_type": "timing"}, metric.Tags)
+		if dist, ok := metric.Fields[defaultFieldName].(distribution.Distribution); ok {
+			assert.IsType(t, &seh1.SEH1Distribution{}, dist)
+			assert.EqualValues(t, 1002, dist.SampleCount())
+			// the values are aggregated in the buckets of the distribution
+			assert.Less(t, dist.Size(), 100)
+			continue
+		}
+		assert.Equal(t, 2, len(metric.Fields))
+		// the percentiles are within the width of the buckets
+		assert.InEpsilon(t, 500, metric.Fields["value_p50"], 0.1)
+		assert.InEpsilon(t, 1000, metric.Fields["value_p99.9"], 0.1)
+	}
+	assert.Empty(t, s.timings)
+}
+
+func TestParse_TimingSketchAccuracy(t *testing.T) {
+	s := NewTestStatsd()
+	s.TimingSketch = true
+	s.TimingSketchRelativeAccuracy = 0.01
+	s.TimingSketchMaxBuckets = 0
+	s.validateTimingSketch()
+	assert.Equal(t, defaultSketchRelativeAccuracy, s.TimingSketchRelativeAccuracy)
+	assert.Equal(t, defaultSketchMaxBuckets, s.TimingSketchMaxBuckets)
+
+	s.TimingSketchRelativeAccuracy = 0.2
+	s.TimingSketchMaxBuckets = 10
+	s.validateTimingSketch()
+	for i := 1; i <= 1000; i++ {
+		assert.NoError(t, s.parseStatsdLine(fmt.Sprintf("test.timing:%d|ms", i)))
+	}
+	require.Equal(t, 1, len(s.timings))
+	var sketch distribution.Distribution
+	for _, cached := range s.timings {
+		sketch = cached.fields[defaultFieldName].(distribution.Distribution)
+	}
+	assert.Equal(t, 10, sketch.Size())
+	values, _ := sketch.ValuesAndCounts()
+	for _, value := range values {
+		// the highest buckets are within the relative accuracy
+		if value > 500 {
+			assert.InEpsilon(t, 1000, value, 0.4)
+		}
+	}
+
+	// the outputs read the values of the sketches whatever their distribution type
+	defer func() { distribution.NewDistribution = seh1.NewSEH1Distribution }()
+	distribution.NewDistribution = regular.NewRegularDistribution
+	acc := &testutil.Accumulator{}
+	s.Gather(acc)
+	require.Equal(t, 1, len(acc.Metrics))
+	dist := acc.Metrics[0].Fields[defaultFieldName].(distribution.Distribution)
+	assert.IsType(t, &regular.RegularDistribution{}, dist)
+	assert.EqualValues(t, 1000, dist.SampleCount())
+	distValues, _ := dist.ValuesAndCounts()
+	assert.ElementsMatch(t, clamp(values, 1, 1000), distValues)
+}
+
+func clamp(values []float64, min, max float64) []float64 {
+	for i := range values {
+		values[i] = math.Min(max, math.Max(min, values[i]))
+	}
+	return values
+}
+
+func TestQuantile(t *testing.T) {
+	dist := seh1.NewSEH1Distribution()
+	assert.EqualValues(t, 0, quantile(dist, 0.5))
+	for i := 1; i <= 100000; i++ {
+		require.NoError(t, dist.AddEntry(float64(i), 1))
+	}
+	assert.EqualValues(t, 1, quantile(dist, 0))
+	assert.EqualValues(t, 100000, quantile(dist, 1))
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999} {
+		expected := q * 100000
+		assert.InEpsilon(t, expected, quantile(dist, q), 0.1, "quantile %v", q)
+	}
+
+	// the zero values are in their own bucket
+	dist = seh1.NewSEH1Distribution()
+	require.NoError(t, dist.AddEntry(0, 9))
+	require.NoError(t, dist.AddEntry(10, 1))
+	assert.EqualValues(t, 0, quantile(dist, 0.5))
+	assert.InEpsilon(t, 10, quantile(dist, 0.95), 0.1)
+}
+
 func TestParseScientificNotation(t *testing.T) {
 	s := NewTestStatsd()
 	sciNotationLines := []string{
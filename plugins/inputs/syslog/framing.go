@@ -0,0 +1,89 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// frameReader splits a stream into messages with the framing methods of RFC 6587. Each frame is
+// either octet-counted, MSG-LEN SP SYSLOG-MSG, or terminated by a line feed. The frames of the
+// same stream may use both methods, the octet-counted ones starting with a digit.
+type frameReader struct {
+	r      *bufio.Reader
+	maxLen int
+}
+
+func newFrameReader(r io.Reader, maxLen int) *frameReader {
+	return &frameReader{r: bufio.NewReaderSize(r, maxLen), maxLen: maxLen}
+}
+
+// next returns the next frame, valid until the following call. The non-transparent frames longer
+// than the max length are truncated, the octet-counted ones are an error as the stream can not be
+// framed again.
+func (f *frameReader) next() ([]byte, error) {
+	for {
+		c, err := f.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if c[0] >= '1' && c[0] <= '9' {
+			return f.nextOctetCounted()
+		}
+		frame, err := f.nextLine()
+		if err != nil || len(bytes.TrimRight(frame, "\r\n")) > 0 {
+			return frame, err
+		}
+	}
+}
+
+func (f *frameReader) nextOctetCounted() ([]byte, error) {
+	prefix, err := f.r.ReadSlice(' ')
+	if err != nil {
+		if err == bufio.ErrBufferFull {
+			err = fmt.Errorf("invalid frame length %.10q", prefix)
+		}
+		return nil, err
+	}
+	n, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid frame length %q", prefix)
+	}
+	if n > f.maxLen {
+		return nil, fmt.Errorf("frame length %d exceeds the max message size %d", n, f.maxLen)
+	}
+	frame, err := f.r.Peek(n)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	_, err = f.r.Discard(n)
+	return frame, err
+}
+
+func (f *frameReader) nextLine() ([]byte, error) {
+	line, err := f.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// the frame is returned truncated, after skipping the rest of the line
+		truncated := append([]byte(nil), line...)
+		for err == bufio.ErrBufferFull {
+			_, err = f.r.ReadSlice('\n')
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		return truncated, err
+	}
+	if err == io.EOF && len(line) > 0 {
+		// the last frame may not be terminated when the connection is closed
+		return line, nil
+	}
+	return line, err
+}
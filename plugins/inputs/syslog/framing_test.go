@@ -0,0 +1,50 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func frames(t *testing.T, input string, maxLen int) ([]string, error) {
+	t.Helper()
+	f := newFrameReader(strings.NewReader(input), maxLen)
+	var res []string
+	for {
+		frame, err := f.next()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return res, err
+		}
+		res = append(res, string(frame))
+	}
+}
+
+func TestFrameReader(t *testing.T) {
+	got, err := frames(t, "5 <1>a\n<2>b\n\r\n7 <3>c\nd\n\n<4>e\n5 <5>f 5 <6>g\n<7>h", 64)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"<1>a\n", "<2>b\n", "<3>c\nd\n", "<4>e\n", "<5>f ", "<6>g\n", "<7>h"}, got)
+}
+
+func TestFrameReaderTooLong(t *testing.T) {
+	got, err := frames(t, "<1>"+strings.Repeat("a", 40)+"\n<2>b\n", 16)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"<1>aaaaaaaaaaaaa", "<2>b\n"}, got)
+
+	got, err = frames(t, "5 <1>a\n100 <2>b", 16)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"<1>a\n"}, got)
+
+	for _, input := range []string{"12x <1>a", "12 <1>a", "123456789012345678"} {
+		_, err = frames(t, input, 16)
+		assert.Error(t, err, input)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// maxMessageSize is the max size of the messages received, the larger ones are truncated or
+	// dropped.
+	maxMessageSize = 256 * 1024
+	// maxParseErrorLogs is the number of parse errors logged for each listener, so that a
+	// misconfigured sender does not flood the agent log.
+	maxParseErrorLogs = 10
+	// defaultMaxConnections is the number of TCP or TLS connections of a listener after which the
+	// new connections are closed.
+	defaultMaxConnections = 1000
+	// connReadTimeout closes the connections which did not send a message for that long, so that
+	// the senders which went away do not hold a connection forever.
+	connReadTimeout = 5 * time.Minute
+	// rejectWarnInterval limits the warnings of the connections rejected.
+	rejectWarnInterval = time.Minute
+)
+
+// listener receives the messages of a SyslogConfig and publishes them with its router.
+type listener struct {
+	router   *router
+	maxConns int
+
+	packetConn net.PacketConn
+	listener   net.Listener
+
+	mu             sync.Mutex
+	conns          map[net.Conn]struct{}
+	parseErrors    int
+	lastRejectWarn time.Time
+}
+
+func newListener(cfg *SyslogConfig, r *router) (*listener, error) {
+	l := &listener{router: r, maxConns: cfg.MaxConnections, conns: make(map[net.Conn]struct{})}
+	if l.maxConns <= 0 {
+		l.maxConns = defaultMaxConnections
+	}
+	var err error
+	switch cfg.Protocol {
+	case protocolUDP:
+		l.packetConn, err = net.ListenPacket("udp", cfg.Address)
+	case protocolTCP:
+		l.listener, err = net.Listen("tcp", cfg.Address)
+	case protocolTLS:
+		var tlsConfig *tls.Config
+		if tlsConfig, err = cfg.ServerConfig.TLSConfig(); err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil || len(tlsConfig.Certificates) == 0 {
+			return nil, errors.New("tls_cert and tls_key are required by the tls protocol")
+		}
+		l.listener, err = tls.Listen("tcp", cfg.Address, tlsConfig)
+	default:
+		err = errors.New("unsupported protocol " + cfg.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// addr returns the address the listener is bound to.
+func (l *listener) addr() net.Addr {
+	if l.packetConn != nil {
+		return l.packetConn.LocalAddr()
+	}
+	return l.listener.Addr()
+}
+
+func (l *listener) start() {
+	if l.packetConn != nil {
+		go l.runPacketConn()
+	} else {
+		go l.runListener()
+	}
+}
+
+// stop closes the listener and the connections accepted, without waiting for the messages being
+// published.
+func (l *listener) stop() {
+	l.router.Stop()
+	if l.packetConn != nil {
+		l.packetConn.Close()
+		return
+	}
+	l.listener.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+func (l *listener) runPacketConn() {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := l.packetConn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("E! [syslog] Error reading from %s: %v", l.router.description, err)
+			}
+			return
+		}
+		if !l.handle(buf[:n], addr) {
+			return
+		}
+	}
+}
+
+func (l *listener) runListener() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("W! [syslog] Error accepting connection on %s: %v", l.router.description, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		tracked, stopped := l.track(conn)
+		if !tracked {
+			conn.Close()
+			if stopped {
+				return
+			}
+			continue
+		}
+		go l.runConn(conn)
+	}
+}
+
+// track adds the connection to the ones closed on stop. The connection is not tracked if the
+// listener stopped, or has too many connections.
+func (l *listener) track(conn net.Conn) (tracked bool, stopped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns == nil {
+		return false, true
+	}
+	if len(l.conns) >= l.maxConns {
+		if now := time.Now(); now.Sub(l.lastRejectWarn) >= rejectWarnInterval {
+			log.Printf("W! [syslog] Closing connection from %s to %s, which has %d connections already", conn.RemoteAddr(), l.router.description, len(l.conns))
+			l.lastRejectWarn = now
+		}
+		return false, false
+	}
+	l.conns[conn] = struct{}{}
+	return true, false
+}
+
+func (l *listener) runConn(conn net.Conn) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+	}()
+	frames := newFrameReader(conn, maxMessageSize)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(connReadTimeout)); err != nil {
+			return
+		}
+		frame, err := frames.next()
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				log.Printf("D! [syslog] Closing idle connection from %s to %s", conn.RemoteAddr(), l.router.description)
+			} else if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Printf("W! [syslog] Closing connection from %s to %s: %v", conn.RemoteAddr(), l.router.description, err)
+			}
+			return
+		}
+		if !l.handle(frame, conn.RemoteAddr()) {
+			return
+		}
+	}
+}
+
+// handle parses and publishes a message. It returns false if the listener stopped.
+func (l *listener) handle(b []byte, addr net.Addr) bool {
+	received := time.Now()
+	m, err := parse(b, received)
+	if err != nil {
+		l.mu.Lock()
+		l.parseErrors++
+		logged := l.parseErrors <= maxParseErrorLogs
+		l.mu.Unlock()
+		if logged {
+			log.Printf("W! [syslog] Dropping invalid message from %s to %s: %v", addr, l.router.description, err)
+		}
+		return true
+	}
+	if m.Hostname == "" {
+		// like a relay, the sender is the host of the messages without hostname
+		if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+			m.Hostname = host
+		}
+	}
+	return l.router.publish(m, b, received)
+}
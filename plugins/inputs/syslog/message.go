@@ -0,0 +1,198 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const nilValue = "-"
+
+// facilities are the names of the syslog facility codes, as used by rsyslog.
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var (
+	errMissingPriority = errors.New("missing priority")
+	errInvalidPriority = errors.New("invalid priority")
+	utf8BOM            = []byte{0xef, 0xbb, 0xbf}
+)
+
+// Message is a syslog message of either RFC 5424 or RFC 3164 format. The fields missing from the
+// message, or set to the nil value in RFC 5424, are empty.
+type Message struct {
+	Facility       int
+	Severity       int
+	Timestamp      time.Time
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData string
+	Msg            string
+}
+
+// FacilityName returns the name of the facility, e.g. local0.
+func (m *Message) FacilityName() string {
+	return facilities[m.Facility]
+}
+
+// parse parses an RFC 5424 message, or an RFC 3164 one when the priority is not followed by a
+// version. The year of the RFC 3164 timestamps is guessed from now.
+func parse(b []byte, now time.Time) (*Message, error) {
+	b = bytes.TrimRight(b, "\r\n\x00")
+	if len(b) == 0 || b[0] != '<' {
+		return nil, errMissingPriority
+	}
+	end := bytes.IndexByte(b, '>')
+	if end < 2 || end > 4 {
+		return nil, errInvalidPriority
+	}
+	pri, err := strconv.Atoi(string(b[1:end]))
+	if err != nil || pri < 0 || pri >= len(facilities)*8 {
+		return nil, errInvalidPriority
+	}
+	m := &Message{Facility: pri / 8, Severity: pri % 8}
+	b = b[end+1:]
+	if len(b) >= 2 && b[0] >= '1' && b[0] <= '9' && (b[1] == ' ' || len(b) > 2 && b[1] >= '0' && b[1] <= '9' && b[2] == ' ') {
+		return m, m.parseRFC5424(b)
+	}
+	m.parseRFC3164(b, now)
+	return m, nil
+}
+
+// parseRFC5424 parses VERSION SP TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP
+// STRUCTURED-DATA [SP MSG].
+func (m *Message) parseRFC5424(b []byte) error {
+	var fields [6]string
+	for i := range fields {
+		var field []byte
+		field, b, _ = bytes.Cut(b, []byte{' '})
+		if len(field) == 0 {
+			return fmt.Errorf("missing header field %d", i+1)
+		}
+		if string(field) != nilValue {
+			fields[i] = string(field)
+		}
+	}
+	if version := fields[0]; version != "1" {
+		return fmt.Errorf("unsupported version %s", version)
+	}
+	if fields[1] != "" {
+		t, err := time.Parse(time.RFC3339Nano, fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %w", err)
+		}
+		m.Timestamp = t
+	}
+	m.Hostname, m.AppName, m.ProcID, m.MsgID = fields[2], fields[3], fields[4], fields[5]
+
+	n, err := structuredDataLen(b)
+	if err != nil {
+		return err
+	}
+	if sd := string(b[:n]); sd != nilValue {
+		m.StructuredData = sd
+	}
+	b = b[n:]
+	if len(b) > 0 {
+		if b[0] != ' ' {
+			return errors.New("invalid structured data")
+		}
+		m.Msg = string(bytes.TrimPrefix(b[1:], utf8BOM))
+	}
+	return nil
+}
+
+// structuredDataLen returns the length of the nil value or of the SD-ELEMENTs at the start of b.
+// The closing brackets and quotes may be escaped in the param values.
+func structuredDataLen(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, errors.New("missing structured data")
+	}
+	if b[0] != '[' {
+		if b[0] == '-' && (len(b) == 1 || b[1] == ' ') {
+			return 1, nil
+		}
+		return 0, errors.New("invalid structured data")
+	}
+	i := 0
+	for i < len(b) && b[i] == '[' {
+		inValue := false
+		for i++; i < len(b) && (inValue || b[i] != ']'); i++ {
+			switch {
+			case inValue && b[i] == '\\':
+				i++
+			case b[i] == '"':
+				inValue = !inValue
+			}
+		}
+		if i >= len(b) {
+			return 0, errors.New("unterminated structured data")
+		}
+		i++
+	}
+	return i, nil
+}
+
+// parseRFC3164 parses [TIMESTAMP SP HOSTNAME SP] [TAG[PID]: ] MSG leniently, as the format
+// is only a description of the existing implementations. The timestamp may also be RFC 3339,
+// and the hostname is only expected after a timestamp.
+func (m *Message) parseRFC3164(b []byte, now time.Time) {
+	if t, n, ok := parseRFC3164Timestamp(b, now); ok {
+		m.Timestamp = t
+		b = bytes.TrimLeft(b[n:], " ")
+		if token, rest, ok := bytes.Cut(b, []byte{' '}); ok && !bytes.HasSuffix(token, []byte{':'}) && !bytes.Contains(token, []byte{'['}) {
+			m.Hostname = string(token)
+			b = rest
+		}
+	}
+	m.Msg = string(b)
+	token, rest, ok := bytes.Cut(b, []byte{' '})
+	if !ok || !bytes.HasSuffix(token, []byte{':'}) {
+		return
+	}
+	tag := token[:len(token)-1]
+	if open := bytes.IndexByte(tag, '['); open >= 0 {
+		if open == 0 || tag[len(tag)-1] != ']' {
+			return
+		}
+		m.ProcID = string(tag[open+1 : len(tag)-1])
+		tag = tag[:open]
+	}
+	if len(tag) == 0 {
+		return
+	}
+	m.AppName = string(tag)
+	m.Msg = string(rest)
+}
+
+// parseRFC3164Timestamp parses a Mmm dd hh:mm:ss timestamp in the local time zone, or an RFC 3339
+// one. It returns the length of the timestamp.
+func parseRFC3164Timestamp(b []byte, now time.Time) (time.Time, int, bool) {
+	const layout = time.Stamp
+	if len(b) >= len(layout) {
+		if t, err := time.ParseInLocation(layout, string(b[:len(layout)]), now.Location()); err == nil {
+			// the year is the one closest to now, so that the messages of December received in
+			// January are not in the future.
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.AddDate(0, 1, 0)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, len(layout), true
+		}
+	}
+	token, _, _ := bytes.Cut(b, []byte{' '})
+	if t, err := time.Parse(time.RFC3339Nano, string(token)); err == nil {
+		return t, len(token), true
+	}
+	return time.Time{}, 0, false
+}
@@ -0,0 +1,162 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs/srcrouter"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	now := time.Now()
+	for name, tc := range map[string]struct {
+		input string
+		want  Message
+	}{
+		"Full": {
+			input: `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event`,
+			want: Message{
+				Facility:       20,
+				Severity:       5,
+				Timestamp:      time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:       "mymachine.example.com",
+				AppName:        "evntslog",
+				ProcID:         "42",
+				MsgID:          "ID47",
+				StructuredData: `[exampleSDID@32473 iut="3" eventSource="Application"]`,
+				Msg:            "An application event",
+			},
+		},
+		"NilValues": {
+			input: "<34>1 - - - - - -\n",
+			want:  Message{Facility: 4, Severity: 2},
+		},
+		"BOM": {
+			input: "<13>1 2003-08-24T05:14:15.000003-07:00 host su - ID47 - \xef\xbb\xbf'su root' failed",
+			want: Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2003, 8, 24, 12, 14, 15, 3000, time.UTC),
+				Hostname:  "host",
+				AppName:   "su",
+				MsgID:     "ID47",
+				Msg:       "'su root' failed",
+			},
+		},
+		"EscapedStructuredData": {
+			input: `<13>1 - host app - - [a@1 k="x\]\"y"][b@1] msg`,
+			want: Message{
+				Facility:       1,
+				Severity:       5,
+				Hostname:       "host",
+				AppName:        "app",
+				StructuredData: `[a@1 k="x\]\"y"][b@1]`,
+				Msg:            "msg",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m, err := parse([]byte(tc.input), now)
+			require.NoError(t, err)
+			assert.True(t, tc.want.Timestamp.Equal(m.Timestamp), m.Timestamp)
+			tc.want.Timestamp = m.Timestamp
+			assert.Equal(t, tc.want, *m)
+		})
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for name, tc := range map[string]struct {
+		input string
+		want  Message
+	}{
+		"Full": {
+			input: "<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8",
+			want: Message{
+				Facility:  4,
+				Severity:  2,
+				Timestamp: time.Date(2023, 10, 11, 22, 14, 15, 0, time.UTC),
+				Hostname:  "mymachine",
+				AppName:   "su",
+				ProcID:    "123",
+				Msg:       "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		"PaddedDay": {
+			input: "<13>Jan  1 00:00:01 host cron: job",
+			want: Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+				Hostname:  "host",
+				AppName:   "cron",
+				Msg:       "job",
+			},
+		},
+		"NoHostname": {
+			input: "<13>Jan  1 00:00:01 sshd[7]: accepted",
+			want: Message{
+				Facility:  1,
+				Severity:  5,
+				Timestamp: time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+				AppName:   "sshd",
+				ProcID:    "7",
+				Msg:       "accepted",
+			},
+		},
+		"RFC3339Timestamp": {
+			input: "<86>2024-01-01T10:00:00.5+00:00 host sudo: session opened",
+			want: Message{
+				Facility:  10,
+				Severity:  6,
+				Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 500000000, time.UTC),
+				Hostname:  "host",
+				AppName:   "sudo",
+				Msg:       "session opened",
+			},
+		},
+		"NoTimestamp": {
+			input: "<190>link down on port 7",
+			want:  Message{Facility: 23, Severity: 6, Msg: "link down on port 7"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m, err := parse([]byte(tc.input), now)
+			require.NoError(t, err)
+			assert.True(t, tc.want.Timestamp.Equal(m.Timestamp), m.Timestamp)
+			tc.want.Timestamp = m.Timestamp
+			assert.Equal(t, tc.want, *m)
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"no priority",
+		"<>1 - - - - - -",
+		"<192>message",
+		"<1a>message",
+		"<13>2 - - - - - -",
+		"<13>1 - host",
+		"<13>1 yesterday - - - - -",
+		"<13>1 - - - - - [unterminated",
+		"<13>1 - - - - - [a@1]msg",
+	} {
+		_, err := parse([]byte(input), time.Now())
+		assert.Error(t, err, input)
+	}
+}
+
+func TestRender(t *testing.T) {
+	m := &Message{Facility: 16, Hostname: "ip-10-0-0-1:a*b"}
+	assert.Equal(t, "syslog/ip-10-0-0-1_a_b/local0/unknown", srcrouter.Render("syslog/{HOSTNAME}/{FACILITY}/{APP_NAME}", m.field, srcrouter.InvalidLogGroupChars))
+	assert.Equal(t, "ip-10-0-0-1_a_b {instance_id}", srcrouter.Render("{HOSTNAME} {instance_id}", m.field, srcrouter.InvalidLogStreamChars))
+}
@@ -0,0 +1,140 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/tls"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+const (
+	protocolUDP = "udp"
+	protocolTCP = "tcp"
+	protocolTLS = "tls"
+
+	defaultAddress = ":514"
+)
+
+type SyslogConfig struct {
+	// Protocol is udp, the default, tcp or tls. The TCP and TLS messages are octet-counted or
+	// terminated by a line feed.
+	Protocol string `toml:"protocol"`
+	Address  string `toml:"address"`
+	// ServerConfig holds the certificate of the tls protocol, and the CAs of the clients when
+	// they are verified.
+	tls.ServerConfig
+	// LogGroupName and LogStreamName may use the {HOSTNAME}, {APP_NAME} and {FACILITY} of the
+	// messages.
+	LogGroupName  string `toml:"log_group_name"`
+	LogStreamName string `toml:"log_stream_name"`
+	LogGroupClass string `toml:"log_group_class"`
+	Destination   string `toml:"destination"`
+	Retention     int    `toml:"retention_in_days"`
+	// MaxSources is the number of log groups and streams receiving messages after which the least
+	// recently used one is closed.
+	MaxSources int `toml:"max_sources"`
+	// MaxConnections is the number of TCP or TLS connections after which the new ones are closed.
+	MaxConnections int `toml:"max_connections"`
+}
+
+type Plugin struct {
+	SyslogConfig []SyslogConfig  `toml:"syslog_config"`
+	Destination  string          `toml:"destination"`
+	Log          telegraf.Logger `toml:"-"`
+
+	mu        sync.Mutex
+	started   bool
+	listeners []*listener
+	newSrcs   []logs.LogSrc
+}
+
+func (p *Plugin) Description() string {
+	return "A plugin to receive the syslog messages sent over UDP, TCP or TLS"
+}
+
+func (p *Plugin) SampleConfig() string {
+	return `
+	[[inputs.syslog.syslog_config]]
+	protocol = "tcp"
+	address = ":514"
+	log_group_name = "syslog/{HOSTNAME}"
+	log_stream_name = "{APP_NAME}"
+	destination = "cloudwatchlogs"
+	`
+}
+
+func (p *Plugin) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *Plugin) FindLogSrc() []logs.LogSrc {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	srcs := p.newSrcs
+	p.newSrcs = nil
+	return srcs
+}
+
+// addSrc is called by the routers for each new log group and stream rendered from the messages.
+func (p *Plugin) addSrc(src logs.LogSrc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.newSrcs = append(p.newSrcs, src)
+}
+
+func (p *Plugin) Start(acc telegraf.Accumulator) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return nil
+	}
+	var listeners []*listener
+	for i := range p.SyslogConfig {
+		sc := &p.SyslogConfig[i]
+		if sc.Protocol == "" {
+			sc.Protocol = protocolUDP
+		}
+		if sc.Address == "" {
+			sc.Address = defaultAddress
+		}
+		destination := sc.Destination
+		if destination == "" {
+			destination = p.Destination
+		}
+		description := fmt.Sprintf("syslog %s://%s", sc.Protocol, sc.Address)
+		l, err := newListener(sc, newRouter(sc, description, destination, p.addSrc))
+		if err != nil {
+			for _, l := range listeners {
+				l.stop()
+			}
+			return fmt.Errorf("unable to listen on %s: %w", description, err)
+		}
+		listeners = append(listeners, l)
+	}
+	for _, l := range listeners {
+		l.start()
+	}
+	p.listeners = listeners
+	p.started = true
+	return nil
+}
+
+func (p *Plugin) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, l := range p.listeners {
+		l.stop()
+	}
+	p.listeners = nil
+}
+
+func init() {
+	inputs.Add("syslog", func() telegraf.Input { return &Plugin{} })
+}
@@ -0,0 +1,208 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	cryptotls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/tls"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+type testOutput struct {
+	mu     sync.Mutex
+	events map[string][]logs.LogEvent
+	srcs   []logs.LogSrc
+}
+
+func (o *testOutput) connect(src logs.LogSrc) {
+	key := src.Group() + "/" + src.Stream()
+	o.srcs = append(o.srcs, src)
+	src.SetOutput(func(e logs.LogEvent) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.events[key] = append(o.events[key], e)
+	})
+}
+
+func (o *testOutput) messages(key string) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var res []string
+	for _, e := range o.events[key] {
+		res = append(res, e.Message())
+	}
+	return res
+}
+
+// collect connects the sources found until the expected messages are published.
+func collect(t *testing.T, p *Plugin, out *testOutput, key string, want []string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		for _, src := range p.FindLogSrc() {
+			out.connect(src)
+		}
+		return assert.ObjectsAreEqual(want, out.messages(key))
+	}, 5*time.Second, 10*time.Millisecond, "got %v", out.messages(key))
+}
+
+func startPlugin(t *testing.T, sc SyslogConfig) (*Plugin, string) {
+	t.Helper()
+	sc.Address = "127.0.0.1:0"
+	sc.LogGroupName = "syslog/{FACILITY}"
+	sc.LogStreamName = "{HOSTNAME}/{APP_NAME}"
+	p := &Plugin{Destination: "cloudwatchlogs", SyslogConfig: []SyslogConfig{sc}}
+	require.NoError(t, p.Start(nil))
+	t.Cleanup(p.Stop)
+	return p, p.listeners[0].addr().String()
+}
+
+func TestUDP(t *testing.T) {
+	p, addr := startPlugin(t, SyslogConfig{Protocol: protocolUDP, Retention: 7})
+	conn, err := net.Dial("udp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	for _, msg := range []string{
+		"<165>1 2003-10-11T22:14:15.003Z host app 42 - - first\n",
+		"<38>Oct 11 22:14:15 sshd[7]: accepted",
+		"invalid",
+	} {
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err)
+	}
+
+	out := &testOutput{events: make(map[string][]logs.LogEvent)}
+	collect(t, p, out, "syslog/local4/host/app", []string{"<165>1 2003-10-11T22:14:15.003Z host app 42 - - first"})
+	// the sender is the host of the messages without hostname
+	collect(t, p, out, "syslog/auth/127.0.0.1/sshd", []string{"<38>Oct 11 22:14:15 sshd[7]: accepted"})
+
+	e := out.events["syslog/local4/host/app"][0]
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), e.Time().UTC())
+	for _, src := range out.srcs {
+		assert.Equal(t, "cloudwatchlogs", src.Destination())
+		assert.Equal(t, 7, src.Retention())
+		assert.Equal(t, "syslog udp://127.0.0.1:0", src.Description())
+	}
+}
+
+func TestTCP(t *testing.T) {
+	p, addr := startPlugin(t, SyslogConfig{Protocol: protocolTCP})
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<13>1 - host app - - - line\n33 <13>1 - host app - - - multi\nline<13>1 - host app - - - last"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	out := &testOutput{events: make(map[string][]logs.LogEvent)}
+	collect(t, p, out, "syslog/user/host/app", []string{
+		"<13>1 - host app - - - line",
+		"<13>1 - host app - - - multi\nline",
+		"<13>1 - host app - - - last",
+	})
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir)
+	_, err := newListener(&SyslogConfig{Protocol: protocolTLS, Address: "127.0.0.1:0"}, newRouter(&SyslogConfig{}, "", "", nil))
+	assert.Error(t, err)
+
+	p, addr := startPlugin(t, SyslogConfig{Protocol: protocolTLS, ServerConfig: tls.ServerConfig{TLSCert: certFile, TLSKey: keyFile}})
+	pool := x509.NewCertPool()
+	caPEM, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	require.True(t, pool.AppendCertsFromPEM(caPEM))
+	conn, err := cryptotls.Dial("tcp", addr, &cryptotls.Config{RootCAs: pool, ServerName: "localhost"})
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("30 <86>1 - host sudo - - - secret"))
+	require.NoError(t, err)
+
+	out := &testOutput{events: make(map[string][]logs.LogEvent)}
+	collect(t, p, out, "syslog/authpriv/host/sudo", []string{"<86>1 - host sudo - - - secret"})
+}
+
+func TestStop(t *testing.T) {
+	p := &Plugin{SyslogConfig: []SyslogConfig{{Protocol: protocolTCP, Address: "127.0.0.1:0", LogGroupName: "syslog"}}}
+	require.NoError(t, p.Start(nil))
+	conn, err := net.Dial("tcp", p.listeners[0].addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	// the message waits for the output of its source, which is never set
+	_, err = conn.Write([]byte("<13>waiting\n"))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(p.FindLogSrc()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	p.Stop()
+
+	// the connections are closed
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	p = &Plugin{SyslogConfig: []SyslogConfig{{Protocol: "sctp"}}}
+	assert.Error(t, p.Start(nil))
+}
+
+func TestMaxConnections(t *testing.T) {
+	p, addr := startPlugin(t, SyslogConfig{Protocol: protocolTCP, MaxConnections: 1})
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.Write([]byte("<13>1 - host app - - - first\n"))
+	require.NoError(t, err)
+	out := &testOutput{events: make(map[string][]logs.LogEvent)}
+	collect(t, p, out, "syslog/user/host/app", []string{"<13>1 - host app - - - first"})
+
+	// the second connection is closed while the first one is open
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	assert.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+// writeCertificate writes a self-signed certificate for localhost and its key.
+func writeCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
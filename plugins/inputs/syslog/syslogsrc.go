@@ -0,0 +1,78 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package syslog
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/srcrouter"
+)
+
+// router publishes the messages of a listener to a source for each log group and stream rendered
+// from the messages.
+type router struct {
+	*srcrouter.Router
+	cfg         *SyslogConfig
+	description string
+}
+
+func newRouter(cfg *SyslogConfig, description, destination string, addSrc func(logs.LogSrc)) *router {
+	return &router{
+		Router: srcrouter.New(srcrouter.Config{
+			Description: description,
+			Destination: destination,
+			Retention:   cfg.Retention,
+			Class:       cfg.LogGroupClass,
+			MaxSources:  cfg.MaxSources,
+		}, addSrc),
+		cfg:         cfg,
+		description: description,
+	}
+}
+
+// publish returns false if the router was stopped while waiting for the output of the source.
+func (r *router) publish(m *Message, raw []byte, received time.Time) bool {
+	group := srcrouter.Render(r.cfg.LogGroupName, m.field, srcrouter.InvalidLogGroupChars)
+	stream := srcrouter.Render(r.cfg.LogStreamName, m.field, srcrouter.InvalidLogStreamChars)
+	t := m.Timestamp
+	if t.IsZero() {
+		t = received
+	}
+	return r.Publish(group, stream, &LogEvent{
+		msg: strings.TrimRight(string(raw), "\r\n\x00"),
+		t:   t,
+	})
+}
+
+// field returns the {HOSTNAME}, {APP_NAME} and {FACILITY} of the message, the facility by name.
+func (m *Message) field(name string) (string, bool) {
+	switch name {
+	case "HOSTNAME":
+		return m.Hostname, true
+	case "APP_NAME":
+		return m.AppName, true
+	case "FACILITY":
+		return m.FacilityName(), true
+	}
+	return "", false
+}
+
+type LogEvent struct {
+	msg string
+	t   time.Time
+}
+
+func (le *LogEvent) Message() string {
+	return le.msg
+}
+
+func (le *LogEvent) Time() time.Time {
+	return le.t
+}
+
+// Done does nothing, as the messages received can not be read again.
+func (le *LogEvent) Done() {
+}
@@ -0,0 +1,211 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package archive
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/outputs"
+
+	configaws "github.com/aws/amazon-cloudwatch-agent/cfg/aws"
+	"github.com/aws/amazon-cloudwatch-agent/internal"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+const (
+	defaultMaxFileSizeMB    = 100
+	defaultRotationInterval = time.Hour
+	defaultUploadInterval   = time.Minute
+)
+
+// Archive is a log backend which writes log events into gzip compressed files on the
+// local disk, optionally uploading the rotated files to S3.
+type Archive struct {
+	Directory        string            `toml:"directory"`
+	MaxFileSizeMB    int64             `toml:"max_file_size_mb"`
+	RotationInterval internal.Duration `toml:"rotation_interval"`
+
+	// Rotated files are uploaded and removed from the local disk when a bucket is set.
+	S3Bucket           string `toml:"s3_bucket"`
+	S3KeyPrefix        string `toml:"s3_key_prefix"`
+	S3EndpointOverride string `toml:"s3_endpoint_override"`
+	S3ForcePathStyle   bool   `toml:"s3_force_path_style"`
+
+	Region    string `toml:"region"`
+	AccessKey string `toml:"access_key"`
+	SecretKey string `toml:"secret_key"`
+	RoleARN   string `toml:"role_arn"`
+	Profile   string `toml:"profile"`
+	Filename  string `toml:"shared_credential_file"`
+	Token     string `toml:"token"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	mu        sync.Mutex
+	dests     map[string]*archiveDest
+	uploader  uploader
+	initOnce  sync.Once
+	closeOnce sync.Once
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+}
+
+func (a *Archive) Connect() error {
+	return nil
+}
+
+func (a *Archive) Close() error {
+	a.closeOnce.Do(a.close)
+	return nil
+}
+
+func (a *Archive) close() {
+	a.mu.Lock()
+	dests := make([]*archiveDest, 0, len(a.dests))
+	for _, d := range a.dests {
+		dests = append(dests, d)
+	}
+	a.mu.Unlock()
+
+	close(a.stopChan)
+	a.wg.Wait()
+	for _, d := range dests {
+		d.stop()
+	}
+	a.uploadRotated()
+}
+
+// Write ignores metrics, the archive only receives log events through CreateDest.
+func (a *Archive) Write([]telegraf.Metric) error {
+	return nil
+}
+
+func (a *Archive) CreateDest(group, stream string, _ int, _ string) logs.LogDest {
+	// Connect is not called when the agent runs the OTel pipelines, so the backend is set up with the first destination.
+	a.initOnce.Do(a.init)
+
+	key := group + "/" + stream
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if d, ok := a.dests[key]; ok {
+		return d
+	}
+	d := newArchiveDest(a.Directory, group, stream, a.MaxFileSizeMB*1024*1024, a.RotationInterval.Duration, a.Log)
+	a.dests[key] = d
+	return d
+}
+
+func (a *Archive) init() {
+	if err := recoverPartialFiles(a.Directory); err != nil {
+		a.Log.Errorf("Unable to recover partially written archive files in %v: %v", a.Directory, err)
+	}
+	if a.S3Bucket != "" && a.uploader == nil {
+		credentialConfig := &configaws.CredentialConfig{
+			Region:    a.Region,
+			AccessKey: a.AccessKey,
+			SecretKey: a.SecretKey,
+			RoleARN:   a.RoleARN,
+			Profile:   a.Profile,
+			Filename:  a.Filename,
+			Token:     a.Token,
+		}
+		a.uploader = s3.New(
+			credentialConfig.Credentials(),
+			&aws.Config{
+				Endpoint:         aws.String(a.S3EndpointOverride),
+				S3ForcePathStyle: aws.Bool(a.S3ForcePathStyle),
+				LogLevel:         configaws.SDKLogLevel(),
+				Logger:           configaws.SDKLogger{},
+			},
+		)
+	}
+	a.wg.Add(1)
+	go a.run()
+}
+
+// run flushes the active files, rotates the files which exceeded the rotation interval and uploads
+// the rotated files.
+func (a *Archive) run() {
+	defer a.wg.Done()
+	ticker := time.NewTicker(defaultUploadInterval)
+	defer ticker.Stop()
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	for {
+		select {
+		case <-flushTicker.C:
+			a.mu.Lock()
+			for _, d := range a.dests {
+				d.flushIfPending()
+			}
+			a.mu.Unlock()
+		case <-ticker.C:
+			a.mu.Lock()
+			for _, d := range a.dests {
+				d.rotateIfExpired()
+			}
+			a.mu.Unlock()
+			a.uploadRotated()
+		case <-a.stopChan:
+			return
+		}
+	}
+}
+
+func (a *Archive) uploadRotated() {
+	if a.uploader == nil {
+		return
+	}
+	if err := uploadDir(a.uploader, a.Directory, a.S3Bucket, a.S3KeyPrefix, a.Log); err != nil {
+		a.Log.Errorf("Unable to upload archive files to s3 bucket %v, will retry: %v", a.S3Bucket, err)
+	}
+}
+
+func (a *Archive) Description() string {
+	return "Write log events to compressed files on the local disk with optional upload to S3"
+}
+
+var sampleConfig = `
+  ## Directory the compressed log files are written to, one sub directory per log group.
+  directory = "/opt/aws/amazon-cloudwatch-agent/logs/archive"
+
+  ## The active file of each log stream is rotated once its uncompressed size
+  ## or age exceeds the limits below.
+  #max_file_size_mb = 100
+  #rotation_interval = "1h"
+
+  ## Upload rotated files to S3 and remove them from the local disk.
+  #s3_bucket = ""
+  #s3_key_prefix = ""
+  #s3_endpoint_override = ""
+  #s3_force_path_style = false
+
+  ## Amazon REGION and credentials used for the upload
+  #region = "us-east-1"
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+`
+
+func (a *Archive) SampleConfig() string {
+	return sampleConfig
+}
+
+func init() {
+	outputs.Add("archive", func() telegraf.Output {
+		return &Archive{
+			MaxFileSizeMB:    defaultMaxFileSizeMB,
+			RotationInterval: internal.Duration{Duration: defaultRotationInterval},
+			dests:            make(map[string]*archiveDest),
+			stopChan:         make(chan struct{}),
+		}
+	})
+}
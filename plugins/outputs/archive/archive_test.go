@@ -0,0 +1,224 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package archive
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/influxdata/telegraf/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+type evtMock struct {
+	m    string
+	done func()
+}
+
+func (e evtMock) Message() string { return e.m }
+func (e evtMock) Time() time.Time { return time.Now() }
+func (e evtMock) Done() {
+	if e.done != nil {
+		e.done()
+	}
+}
+
+type uploaderMock struct {
+	objects map[string]string
+	err     error
+}
+
+func (u *uploaderMock) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	b, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	u.objects[*in.Bucket+"/"+*in.Key] = string(b)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func newTestArchive(dir string) *Archive {
+	return &Archive{
+		Directory:        dir,
+		MaxFileSizeMB:    defaultMaxFileSizeMB,
+		RotationInterval: internal.Duration{Duration: defaultRotationInterval},
+		Log:              models.NewLogger("outputs", "archive", ""),
+		dests:            make(map[string]*archiveDest),
+		stopChan:         make(chan struct{}),
+	}
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		require.NoError(t, err)
+	}
+	return string(b)
+}
+
+func archivedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"+archiveFileSuffix))
+	require.NoError(t, err)
+	return files
+}
+
+func TestArchivePublishAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	a := newTestArchive(dir)
+
+	dest := a.CreateDest("/aws/group", "stream", -1, "")
+	assert.Same(t, dest, a.CreateDest("/aws/group", "stream", -1, ""))
+
+	var done int
+	require.NoError(t, dest.Publish([]logs.LogEvent{
+		evtMock{"line1", func() { done++ }},
+		evtMock{"line2\n", func() { done++ }},
+	}))
+	// the events are only done once flushed
+	assert.Equal(t, 0, done)
+	d := dest.(*archiveDest)
+	d.flushIfPending()
+	assert.Equal(t, 2, done)
+
+	// flushed events are readable before the file is rotated
+	active := filepath.Join(dir, "%2Faws%2Fgroup", "stream"+archiveFileSuffix+partialFileSuffix)
+	assert.Equal(t, "line1\nline2\n", readGzip(t, active))
+	assert.Empty(t, archivedFiles(t, dir))
+
+	d.openedAt = time.Now().Add(-2 * defaultRotationInterval)
+	d.rotateIfExpired()
+	files := archivedFiles(t, dir)
+	require.Len(t, files, 1)
+	assert.Equal(t, "line1\nline2\n", readGzip(t, files[0]))
+	assert.NoFileExists(t, active)
+
+	require.NoError(t, a.Close())
+	assert.ErrorIs(t, dest.Publish([]logs.LogEvent{evtMock{m: "line3"}}), logs.ErrOutputStopped)
+	require.NoError(t, a.Close())
+}
+
+func TestArchiveFlush(t *testing.T) {
+	dir := t.TempDir()
+	d := newArchiveDest(dir, "group", "stream", 0, time.Hour, models.NewLogger("outputs", "archive", ""))
+
+	var done int
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{"short", func() { done++ }}}))
+	assert.Equal(t, 0, done)
+	// the events are flushed once flushSize bytes are written
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{strings.Repeat("a", flushSize), func() { done++ }}}))
+	assert.Equal(t, 2, done)
+
+	// the events not flushed yet are done when the file is rotated on stop
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{"last", func() { done++ }}}))
+	assert.Equal(t, 2, done)
+	d.stop()
+	assert.Equal(t, 3, done)
+	files := archivedFiles(t, dir)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(readGzip(t, files[0]), "a\nlast\n"))
+}
+
+func TestArchiveRotateFailure(t *testing.T) {
+	dir := t.TempDir()
+	d := newArchiveDest(dir, "group", "stream", 0, time.Hour, models.NewLogger("outputs", "archive", ""))
+
+	var done int
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{"lost", func() { done++ }}}))
+	// the gzip stream cannot be closed, the events are written again to a new active file
+	require.NoError(t, d.file.Close())
+	d.rotate()
+	assert.Equal(t, 0, done)
+	require.NotNil(t, d.gz)
+	require.Len(t, d.pending, 1)
+
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{"next", func() { done++ }}}))
+	d.flushIfPending()
+	assert.Equal(t, 2, done)
+	assert.Equal(t, "lost\nnext\n", readGzip(t, d.activePath()))
+
+	// the pending events are left undone when the file cannot be closed on stop
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{"unsynced", func() { done++ }}}))
+	require.NoError(t, d.file.Close())
+	d.stop()
+	assert.Equal(t, 2, done)
+	assert.Nil(t, d.gz)
+	assert.NoFileExists(t, d.activePath())
+}
+
+func TestArchiveRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	d := newArchiveDest(dir, "group", "stream", 10, time.Hour, models.NewLogger("outputs", "archive", ""))
+
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{m: "short"}}))
+	assert.Empty(t, archivedFiles(t, dir))
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{m: "exceeds the size"}}))
+	files := archivedFiles(t, dir)
+	require.Len(t, files, 1)
+	assert.Equal(t, "short\nexceeds the size\n", readGzip(t, files[0]))
+}
+
+func TestArchiveRecoverPartialFiles(t *testing.T) {
+	dir := t.TempDir()
+	d := newArchiveDest(dir, "group", "stream", 0, time.Hour, models.NewLogger("outputs", "archive", ""))
+	require.NoError(t, d.Publish([]logs.LogEvent{evtMock{m: "before crash"}}))
+	// the events flushed before the crash are recovered
+	d.flushIfPending()
+
+	require.NoError(t, recoverPartialFiles(dir))
+	files := archivedFiles(t, dir)
+	require.Len(t, files, 1)
+	assert.Equal(t, "before crash\n", readGzip(t, files[0]))
+
+	assert.NoError(t, recoverPartialFiles(filepath.Join(dir, "missing")))
+}
+
+func TestArchiveUpload(t *testing.T) {
+	dir := t.TempDir()
+	u := &uploaderMock{objects: map[string]string{}, err: errors.New("unreachable")}
+	a := newTestArchive(dir)
+	a.S3Bucket = "bucket"
+	a.S3KeyPrefix = "prefix"
+	a.uploader = u
+
+	dest := a.CreateDest("group", "stream", -1, "")
+	require.NoError(t, dest.Publish([]logs.LogEvent{evtMock{m: "line"}}))
+	dest.(*archiveDest).stop()
+
+	// failed uploads are kept on disk and retried
+	a.uploadRotated()
+	files := archivedFiles(t, dir)
+	require.Len(t, files, 1)
+
+	u.err = nil
+	a.uploadRotated()
+	assert.Empty(t, archivedFiles(t, dir))
+	require.Len(t, u.objects, 1)
+	for key, body := range u.objects {
+		assert.True(t, strings.HasPrefix(key, "bucket/prefix/group/stream-"), key)
+		assert.True(t, strings.HasSuffix(key, archiveFileSuffix), key)
+		assert.NotEmpty(t, body)
+	}
+	require.NoError(t, a.Close())
+}
@@ -0,0 +1,247 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package archive
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+const (
+	archiveFileSuffix = ".log.gz"
+	partialFileSuffix = ".partial"
+	archiveDirMode    = 0755
+	archiveFileMode   = 0644
+
+	// flushInterval and flushSize bound how long and how many bytes the events written to an active
+	// file wait before the file is flushed and the events are done.
+	flushInterval = 5 * time.Second
+	flushSize     = 1024 * 1024
+)
+
+// archiveDest writes the events of one log stream to <directory>/<group>/<stream>.log.gz.partial
+// and renames the file to <stream>-<timestamp>.log.gz when it is rotated.
+type archiveDest struct {
+	mu               sync.Mutex
+	dir              string
+	name             string
+	maxSize          int64
+	rotationInterval time.Duration
+	log              telegraf.Logger
+
+	file     *os.File
+	gz       *gzip.Writer
+	size     int64
+	openedAt time.Time
+	stopped  bool
+	// pending are the events written since the last flush, which are done once flushed.
+	pending   []logs.LogEvent
+	unflushed int64
+}
+
+var _ logs.LogDest = (*archiveDest)(nil)
+
+func newArchiveDest(directory, group, stream string, maxSize int64, rotationInterval time.Duration, log telegraf.Logger) *archiveDest {
+	return &archiveDest{
+		dir:              filepath.Join(directory, url.PathEscape(group)),
+		name:             url.PathEscape(stream),
+		maxSize:          maxSize,
+		rotationInterval: rotationInterval,
+		log:              log,
+	}
+}
+
+func (d *archiveDest) Publish(events []logs.LogEvent) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return logs.ErrOutputStopped
+	}
+	if d.gz == nil {
+		if err := d.open(); err != nil {
+			return err
+		}
+	}
+	for _, e := range events {
+		if err := d.write(e); err != nil {
+			return err
+		}
+		d.pending = append(d.pending, e)
+	}
+	if d.maxSize > 0 && d.size >= d.maxSize {
+		d.rotate()
+	} else if d.unflushed >= flushSize {
+		return d.flush()
+	}
+	return nil
+}
+
+func (d *archiveDest) write(e logs.LogEvent) error {
+	msg := e.Message()
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	n, err := d.gz.Write([]byte(msg))
+	d.size += int64(n)
+	d.unflushed += int64(n)
+	if err != nil {
+		return fmt.Errorf("unable to write to archive file %v: %w", d.file.Name(), err)
+	}
+	return nil
+}
+
+// flushIfPending flushes the events written since the last flush, which is called every
+// flushInterval.
+func (d *archiveDest) flushIfPending() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.flush(); err != nil {
+		d.log.Errorf("%v", err)
+	}
+}
+
+// flush writes the compressed events to the disk and syncs the file before marking them done, so
+// that the offsets are only committed by the sources once the events are on disk.
+func (d *archiveDest) flush() error {
+	if d.gz == nil || len(d.pending) == 0 {
+		return nil
+	}
+	if err := d.gz.Flush(); err != nil {
+		return fmt.Errorf("unable to flush archive file %v: %w", d.file.Name(), err)
+	}
+	if err := d.file.Sync(); err != nil {
+		return fmt.Errorf("unable to sync archive file %v: %w", d.file.Name(), err)
+	}
+	d.done()
+	return nil
+}
+
+func (d *archiveDest) done() {
+	for _, e := range d.pending {
+		e.Done()
+	}
+	d.pending = nil
+	d.unflushed = 0
+}
+
+func (d *archiveDest) activePath() string {
+	return filepath.Join(d.dir, d.name+archiveFileSuffix+partialFileSuffix)
+}
+
+func (d *archiveDest) open() error {
+	if err := os.MkdirAll(d.dir, archiveDirMode); err != nil {
+		return fmt.Errorf("unable to create archive directory %v: %w", d.dir, err)
+	}
+	path := d.activePath()
+	// A file left over from a previous run was already finalized on startup, so this is normally a new file.
+	// Appending keeps the data if it is not, as concatenated gzip members are still a valid gzip file.
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, archiveFileMode)
+	if err != nil {
+		return fmt.Errorf("unable to open archive file %v: %w", path, err)
+	}
+	d.file = f
+	d.gz = gzip.NewWriter(f)
+	d.size = 0
+	d.unflushed = 0
+	d.openedAt = time.Now()
+	// The events pending when the previous file failed to be closed are written again.
+	for _, e := range d.pending {
+		if err := d.write(e); err != nil {
+			d.closeActive()
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *archiveDest) rotateIfExpired() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.gz != nil && d.rotationInterval > 0 && time.Since(d.openedAt) >= d.rotationInterval {
+		d.rotate()
+	}
+}
+
+// rotate closes the active file and renames it so that it is picked up by the uploader.
+// The next Publish opens a new active file. The pending events are only done once the file is
+// synced, otherwise they are written again to a new active file so that their sources don't
+// wait for them forever. They are left undone on stop, and read again by the sources after
+// the restart.
+func (d *archiveDest) rotate() {
+	if d.gz == nil {
+		return
+	}
+	path := d.file.Name()
+	if err := d.gz.Close(); err != nil {
+		d.log.Errorf("Unable to close gzip stream of archive file %v: %v", path, err)
+	} else if err = d.file.Sync(); err != nil {
+		d.log.Errorf("Unable to sync archive file %v: %v", path, err)
+	} else {
+		d.done()
+	}
+	if err := d.file.Close(); err != nil {
+		d.log.Errorf("Unable to close archive file %v: %v", path, err)
+	}
+	d.gz = nil
+	d.file = nil
+	if err := finalize(path); err != nil {
+		d.log.Errorf("Unable to rotate archive file %v: %v", path, err)
+	}
+	if len(d.pending) > 0 && !d.stopped {
+		if err := d.open(); err != nil {
+			// The next Publish opens the new active file again.
+			d.log.Errorf("Unable to write the pending events again: %v", err)
+		}
+	}
+}
+
+// closeActive closes the active file without renaming it, its events being still pending.
+func (d *archiveDest) closeActive() {
+	if d.file != nil {
+		d.file.Close()
+	}
+	d.gz = nil
+	d.file = nil
+}
+
+func (d *archiveDest) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	d.rotate()
+}
+
+// finalize renames an active file to a unique name ending with archiveFileSuffix.
+func finalize(path string) error {
+	base := strings.TrimSuffix(path, archiveFileSuffix+partialFileSuffix)
+	return os.Rename(path, fmt.Sprintf("%s-%s%s", base, time.Now().UTC().Format("20060102T150405.000000000Z"), archiveFileSuffix))
+}
+
+// recoverPartialFiles finalizes the active files left behind by a previous run. Their gzip stream
+// may be missing the trailer, but every flushed event can still be decompressed.
+func recoverPartialFiles(directory string) error {
+	return filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == directory {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, archiveFileSuffix+partialFileSuffix) {
+			return nil
+		}
+		return finalize(path)
+	})
+}
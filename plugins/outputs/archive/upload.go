@@ -0,0 +1,65 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package archive
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/influxdata/telegraf"
+)
+
+type uploader interface {
+	PutObject(*s3.PutObjectInput) (*s3.PutObjectOutput, error)
+}
+
+// uploadDir uploads every rotated file in the directory to the bucket, keyed by its path relative
+// to the directory, and removes it once uploaded. It stops at the first failed upload.
+func uploadDir(u uploader, directory, bucket, keyPrefix string, log telegraf.Logger) error {
+	return filepath.WalkDir(directory, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == directory {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(p, archiveFileSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(directory, p)
+		if err != nil {
+			return err
+		}
+		key := path.Join(keyPrefix, filepath.ToSlash(rel))
+		if err = uploadFile(u, p, bucket, key); err != nil {
+			return err
+		}
+		log.Debugf("Uploaded archive file %v to s3://%v/%v", p, bucket, key)
+		return os.Remove(p)
+	})
+}
+
+func uploadFile(u uploader, p, bucket, key string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = u.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        f,
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to upload %v: %w", p, err)
+	}
+	return nil
+}
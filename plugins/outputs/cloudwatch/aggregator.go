@@ -25,10 +25,16 @@ const (
 // If aggregationInterval is 0, then no aggregation is done.
 // If receivers set the special attribute "aws:AggregationInterval", then
 // this exporter will remove it and do aggregation.
+// The namespace is set by the special attribute "aws:Namespace", the
+// configured namespace is used when it is empty.
 type aggregationDatum struct {
 	cloudwatch.MetricDatum
 	aggregationInterval time.Duration
 	distribution        distribution.Distribution
+	namespace           string
+	// preAggregated is set when the values of the datum are aggregated by the statistic set mode,
+	// each of them would have been published as a datum otherwise.
+	preAggregated bool
 }
 
 type Aggregator interface {
@@ -63,6 +69,9 @@ func getAggregationKey(m *aggregationDatum, unixTime int64) string {
 		tmp[i] = fmt.Sprintf("%s=%s", *d.Name, *d.Value)
 	}
 	// Assume m.Dimensions was already sorted.
+	if m.namespace != "" {
+		return fmt.Sprintf("%s:%s:%s:%v", m.namespace, *m.MetricName, strings.Join(tmp, ","), unixTime)
+	}
 	return fmt.Sprintf("%s:%s:%v", *m.MetricName, strings.Join(tmp, ","), unixTime)
 }
 
@@ -0,0 +1,228 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+const (
+	defaultBufferMaxSizeMB = 100
+	defaultBufferMaxAge    = 24 * time.Hour
+	bufferReplayInterval   = time.Minute
+
+	bufferFileSuffix = ".json"
+	bufferTmpSuffix  = ".tmp"
+	bufferDirMode    = 0755
+	bufferFileMode   = 0644
+)
+
+var errBufferFull = errors.New("buffer size limit reached")
+
+// bufferedBatch is a batch of datums published along with the buffer file holding it.
+type bufferedBatch struct {
+	datumBatch
+	path string
+}
+
+// metricBuffer is a write-ahead buffer of PutMetricData batches. Every batch is written to
+// disk before it is handed to the publisher and removed once delivered, so the batches that
+// failed or were still queued when the agent stopped are replayed later. The files are named
+// after their creation time and datum count so that a directory listing is in replay order
+// and the backlog is known without reading them. The batches of the configured namespace are
+// stored as a list of datums, the others as a PutMetricData request holding their namespace.
+type metricBuffer struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	mu       sync.Mutex
+	size     int64
+	inFlight map[string]struct{}
+	seq      atomic.Int64
+	datums   atomic.Int64
+}
+
+func newMetricBuffer(dir string, maxSize int64, maxAge time.Duration) (*metricBuffer, error) {
+	if err := os.MkdirAll(dir, bufferDirMode); err != nil {
+		return nil, err
+	}
+	b := &metricBuffer{dir: dir, maxSize: maxSize, maxAge: maxAge, inFlight: map[string]struct{}{}}
+	b.seq.Store(time.Now().UnixNano())
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if strings.HasSuffix(e.Name(), bufferTmpSuffix) {
+			// leftover from an interrupted write, the batch was never handed to the publisher
+			os.Remove(path)
+			continue
+		}
+		if info, err := e.Info(); err == nil && !e.IsDir() && strings.HasSuffix(e.Name(), bufferFileSuffix) {
+			_, count, _ := parseBufferFileName(e.Name())
+			b.size += info.Size()
+			b.datums.Add(int64(count))
+		}
+	}
+	return b, nil
+}
+
+func parseBufferFileName(name string) (time.Time, int, error) {
+	var created, seq int64
+	var count int
+	if _, err := fmt.Sscanf(strings.TrimSuffix(name, bufferFileSuffix), "%d-%d-%d", &created, &seq, &count); err != nil {
+		return time.Time{}, 0, err
+	}
+	return time.Unix(0, created), count, nil
+}
+
+// write durably stores the batch and marks it in flight. The batch is only considered
+// buffered once the file has been synced and renamed into place.
+func (b *metricBuffer) write(batch datumBatch) (string, error) {
+	var content []byte
+	var err error
+	if batch.namespace == "" {
+		content, err = json.Marshal(batch.datums)
+	} else {
+		content, err = json.Marshal(cloudwatch.PutMetricDataInput{Namespace: &batch.namespace, MetricData: batch.datums})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.maxSize > 0 && b.size+int64(len(content)) > b.maxSize {
+		return "", errBufferFull
+	}
+	name := fmt.Sprintf("%020d-%020d-%d%s", time.Now().UnixNano(), b.seq.Add(1), len(batch.datums), bufferFileSuffix)
+	path := filepath.Join(b.dir, name)
+	tmp := path + bufferTmpSuffix
+	if err = writeFileSync(tmp, content); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	b.size += int64(len(content))
+	b.datums.Add(int64(len(batch.datums)))
+	b.inFlight[path] = struct{}{}
+	return path, nil
+}
+
+// acquire returns the oldest buffer files which are not in flight and marks them in flight.
+// Files older than the max age are removed instead.
+func (b *metricBuffer) acquire() ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), bufferFileSuffix) {
+			continue
+		}
+		path := filepath.Join(b.dir, e.Name())
+		if _, ok := b.inFlight[path]; ok {
+			continue
+		}
+		created, count, err := parseBufferFileName(e.Name())
+		if err != nil {
+			continue
+		}
+		if b.maxAge > 0 && time.Since(created) > b.maxAge {
+			log.Printf("W! cloudwatch: dropping %d buffered datums older than %v", count, b.maxAge)
+			b.removeLocked(path, count)
+			continue
+		}
+		b.inFlight[path] = struct{}{}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (b *metricBuffer) read(path string) (datumBatch, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return datumBatch{}, err
+	}
+	if content = bytes.TrimSpace(content); len(content) > 0 && content[0] == '{' {
+		var input cloudwatch.PutMetricDataInput
+		if err = json.Unmarshal(content, &input); err != nil {
+			return datumBatch{}, err
+		}
+		return datumBatch{namespace: aws.StringValue(input.Namespace), datums: input.MetricData}, nil
+	}
+	var datums []*cloudwatch.MetricDatum
+	if err = json.Unmarshal(content, &datums); err != nil {
+		return datumBatch{}, err
+	}
+	return datumBatch{datums: datums}, nil
+}
+
+// release marks the file as no longer in flight. The file is removed when the batch was delivered.
+func (b *metricBuffer) release(path string, delivered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.inFlight, path)
+	if delivered {
+		_, count, _ := parseBufferFileName(filepath.Base(path))
+		b.removeLocked(path, count)
+	}
+}
+
+func (b *metricBuffer) removeLocked(path string, count int) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if err = os.Remove(path); err != nil {
+		log.Printf("E! cloudwatch: unable to remove buffer file %v: %v", path, err)
+		return
+	}
+	b.size -= info.Size()
+	b.datums.Add(-int64(count))
+}
+
+// backlog returns the number of datums written to the buffer but not delivered yet.
+func (b *metricBuffer) backlog() int64 {
+	return b.datums.Load()
+}
+
+func writeFileSync(name string, content []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, bufferFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,230 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/publisher"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+func makeDatums(count int) []*cloudwatch.MetricDatum {
+	datums := make([]*cloudwatch.MetricDatum, count)
+	for i := range datums {
+		datums[i] = &cloudwatch.MetricDatum{
+			MetricName: aws.String("metric"),
+			Dimensions: []*cloudwatch.Dimension{{Name: aws.String("host"), Value: aws.String("h1")}},
+			Timestamp:  aws.Time(time.Unix(1700000000, 0).UTC()),
+			Value:      aws.Float64(float64(i)),
+		}
+	}
+	return datums
+}
+
+func TestMetricBufferWriteAndAcquire(t *testing.T) {
+	dir := t.TempDir()
+	b, err := newMetricBuffer(dir, 0, time.Hour)
+	require.NoError(t, err)
+
+	first, err := b.write(datumBatch{datums: makeDatums(2)})
+	require.NoError(t, err)
+	second, err := b.write(datumBatch{datums: makeDatums(3)})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, b.backlog())
+
+	// in flight batches are not replayed
+	paths, err := b.acquire()
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	b.release(first, false)
+	b.release(second, false)
+	paths, err = b.acquire()
+	require.NoError(t, err)
+	assert.Equal(t, []string{first, second}, paths)
+
+	batch, err := b.read(first)
+	require.NoError(t, err)
+	assert.Equal(t, datumBatch{datums: makeDatums(2)}, batch)
+
+	b.release(first, true)
+	assert.NoFileExists(t, first)
+	assert.EqualValues(t, 3, b.backlog())
+
+	// the backlog is restored when the buffer is opened again
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "interrupted"+bufferTmpSuffix), []byte("["), bufferFileMode))
+	b, err = newMetricBuffer(dir, 0, time.Hour)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, b.backlog())
+	assert.NoFileExists(t, filepath.Join(dir, "interrupted"+bufferTmpSuffix))
+	paths, err = b.acquire()
+	require.NoError(t, err)
+	assert.Equal(t, []string{second}, paths)
+}
+
+func TestMetricBufferLimits(t *testing.T) {
+	dir := t.TempDir()
+	b, err := newMetricBuffer(dir, 1024, time.Hour)
+	require.NoError(t, err)
+
+	_, err = b.write(datumBatch{datums: makeDatums(1)})
+	require.NoError(t, err)
+	_, err = b.write(datumBatch{datums: makeDatums(100)})
+	assert.ErrorIs(t, err, errBufferFull)
+	assert.EqualValues(t, 1, b.backlog())
+
+	b, err = newMetricBuffer(dir, 0, time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	// batches older than the max age are dropped
+	paths, err := b.acquire()
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+	assert.EqualValues(t, 0, b.backlog())
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestBufferReplay(t *testing.T) {
+	svc := new(mockCloudWatchClient)
+	requestErr := awserr.New(request.ErrCodeRequestError, "", nil)
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, requestErr).Once()
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, nil)
+
+	cw := &CloudWatch{
+		svc:          svc,
+		config:       &Config{Namespace: "namespace", BufferDir: t.TempDir()},
+		shutdownChan: make(chan struct{}),
+	}
+	cw.openBuffer()
+	require.NotNil(t, cw.buffer)
+
+	// the failed batch stays in the buffer
+	cw.WriteToCloudWatch(cw.bufferBatch(datumBatch{datums: makeDatums(4)}))
+	assert.EqualValues(t, 4, cw.BufferedDatums())
+
+	cw.replayBufferOnce()
+	assert.EqualValues(t, 0, cw.BufferedDatums())
+	svc.AssertNumberOfCalls(t, "PutMetricData", 2)
+	input := svc.Calls[1].Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+	assert.Equal(t, makeDatums(4), input.MetricData)
+	assert.Equal(t, "namespace", *input.Namespace)
+}
+
+func TestBufferReplayNamespace(t *testing.T) {
+	svc := new(mockCloudWatchClient)
+	requestErr := awserr.New(request.ErrCodeRequestError, "", nil)
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, requestErr).Once()
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, nil)
+
+	cw := &CloudWatch{
+		svc:          svc,
+		config:       &Config{Namespace: "namespace", BufferDir: t.TempDir()},
+		shutdownChan: make(chan struct{}),
+	}
+	cw.openBuffer()
+	require.NotNil(t, cw.buffer)
+
+	// the namespace of the batch is kept in the buffer
+	cw.WriteToCloudWatch(cw.bufferBatch(datumBatch{namespace: "other", datums: makeDatums(2)}))
+	assert.EqualValues(t, 2, cw.BufferedDatums())
+
+	cw.replayBufferOnce()
+	assert.EqualValues(t, 0, cw.BufferedDatums())
+	svc.AssertNumberOfCalls(t, "PutMetricData", 2)
+	for _, call := range svc.Calls {
+		input := call.Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+		assert.Equal(t, makeDatums(2), input.MetricData)
+		assert.Equal(t, "other", *input.Namespace)
+	}
+}
+
+func TestBufferReplayDropsRejectedBatches(t *testing.T) {
+	profiler.Profiler.ReportAndClear()
+	svc := new(mockCloudWatchClient)
+	requestErr := awserr.New(request.ErrCodeRequestError, "", nil)
+	invalidErr := awserr.New(cloudwatch.ErrCodeInvalidParameterValueException, "", nil)
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, requestErr).Twice()
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, invalidErr).Once()
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, nil)
+
+	cw := &CloudWatch{
+		svc:          svc,
+		config:       &Config{Namespace: "namespace", BufferDir: t.TempDir()},
+		shutdownChan: make(chan struct{}),
+	}
+	cw.openBuffer()
+	require.NotNil(t, cw.buffer)
+
+	cw.WriteToCloudWatch(cw.bufferBatch(datumBatch{datums: makeDatums(1)}))
+	cw.WriteToCloudWatch(cw.bufferBatch(datumBatch{datums: makeDatums(2)}))
+	assert.EqualValues(t, 3, cw.BufferedDatums())
+
+	// the rejected batch is dropped instead of blocking the replay of the next one
+	cw.replayBufferOnce()
+	assert.EqualValues(t, 0, cw.BufferedDatums())
+	svc.AssertNumberOfCalls(t, "PutMetricData", 4)
+	input := svc.Calls[3].Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+	assert.Equal(t, makeDatums(2), input.MetricData)
+	assert.EqualValues(t, 1, profiler.Profiler.GetStats()["cloudwatch_bufferDroppedDatums"])
+
+	// live batches rejected as invalid are not buffered either
+	svc.On("PutMetricData", mock.Anything).Unset()
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, invalidErr)
+	cw.WriteToCloudWatch(cw.bufferBatch(datumBatch{datums: makeDatums(3)}))
+	assert.EqualValues(t, 0, cw.BufferedDatums())
+}
+
+func TestBufferReplayQueueDrops(t *testing.T) {
+	svc := new(mockCloudWatchClient)
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, nil)
+
+	cw := &CloudWatch{
+		svc:          svc,
+		config:       &Config{Namespace: "namespace", BufferDir: t.TempDir()},
+		shutdownChan: make(chan struct{}),
+	}
+	cw.openBuffer()
+	require.NotNil(t, cw.buffer)
+
+	// the batch dropped by the full queue is released for the replay
+	queue := publisher.NewNonBlockingFifoQueueWithDropFn(1, cw.releaseDropped)
+	queue.Enqueue(cw.bufferBatch(datumBatch{datums: makeDatums(1)}))
+	queue.Enqueue(cw.bufferBatch(datumBatch{datums: makeDatums(2)}))
+	assert.EqualValues(t, 3, cw.BufferedDatums())
+
+	cw.replayBufferOnce()
+	assert.EqualValues(t, 2, cw.BufferedDatums())
+	assert.EqualValues(t, 3, profiler.Profiler.GetStats()["cloudwatch_bufferedDatums"])
+	svc.AssertNumberOfCalls(t, "PutMetricData", 1)
+	input := svc.Calls[0].Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+	assert.Equal(t, makeDatums(1), input.MetricData)
+
+	queued, ok := queue.Dequeue()
+	require.True(t, ok)
+	cw.WriteToCloudWatch(queued)
+	assert.EqualValues(t, 0, cw.BufferedDatums())
+}
+
+func TestBufferDisabled(t *testing.T) {
+	cw := &CloudWatch{config: &Config{}}
+	cw.openBuffer()
+	assert.Nil(t, cw.buffer)
+	batch := datumBatch{datums: makeDatums(1)}
+	assert.Equal(t, batch, cw.bufferBatch(batch))
+	assert.EqualValues(t, 0, cw.BufferedDatums())
+}
@@ -0,0 +1,144 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+const (
+	// OtherDimensionValue is the value of the dimensions of the datums whose dimension set is over
+	// the limit of their metric.
+	OtherDimensionValue = "Other"
+
+	defaultCardinalityRotationInterval = time.Hour
+)
+
+// cardinalityLimiter caps the distinct dimension sets published for each metric. Like the metrics
+// limiter of the application signals processor, the dimension sets are tracked over two rotating
+// windows, so that the dimension sets which are no longer published free their slot after one to
+// two rotation intervals. The datums of the dimension sets over the limit are folded into the
+// Other bucket of the metric, which has the same dimension names with the Other value.
+//
+// The limiter is only used by the goroutine building the datums, so it is not locked.
+type cardinalityLimiter struct {
+	maxDimensionSets int
+	rotationInterval time.Duration
+	now              func() time.Time
+
+	lastRotation time.Time
+	metrics      map[string]*metricDimensionSets
+}
+
+type metricDimensionSets struct {
+	name string
+	// current are the dimension sets seen since the last rotation, previous those of the window
+	// before which were not seen since.
+	current  map[string]struct{}
+	previous map[string]struct{}
+	// suppressed is the number of datums folded into Other since the last rotation.
+	suppressed int
+}
+
+func newCardinalityLimiter(maxDimensionSets int, rotationInterval time.Duration) *cardinalityLimiter {
+	if rotationInterval <= 0 {
+		rotationInterval = defaultCardinalityRotationInterval
+	}
+	return &cardinalityLimiter{
+		maxDimensionSets: maxDimensionSets,
+		rotationInterval: rotationInterval,
+		now:              time.Now,
+		lastRotation:     time.Now(),
+		metrics:          make(map[string]*metricDimensionSets),
+	}
+}
+
+// admit returns the dimensions of the datum, or the dimensions of the Other bucket when the metric
+// already has the max number of dimension sets.
+func (l *cardinalityLimiter) admit(namespace, metricName string, dimensions []*cloudwatch.Dimension) []*cloudwatch.Dimension {
+	if len(dimensions) == 0 {
+		return dimensions
+	}
+	if now := l.now(); now.Sub(l.lastRotation) >= l.rotationInterval {
+		l.rotate()
+		l.lastRotation = now
+	}
+	metricKey := namespace + "\x00" + metricName
+	m, ok := l.metrics[metricKey]
+	if !ok {
+		m = &metricDimensionSets{name: metricName, current: make(map[string]struct{}), previous: make(map[string]struct{})}
+		l.metrics[metricKey] = m
+	}
+	key := dimensionSetKey(dimensions)
+	if _, ok := m.current[key]; ok {
+		return dimensions
+	}
+	if _, ok := m.previous[key]; ok {
+		delete(m.previous, key)
+		m.current[key] = struct{}{}
+		return dimensions
+	}
+	if isOtherBucket(dimensions) || len(m.current)+len(m.previous) < l.maxDimensionSets {
+		m.current[key] = struct{}{}
+		return dimensions
+	}
+	if m.suppressed == 0 {
+		log.Printf("W! cloudwatch: metric %s has more than %d dimension sets, publishing the datums of the new dimension sets with the %s dimension values", metricName, l.maxDimensionSets, OtherDimensionValue)
+	}
+	m.suppressed++
+	profiler.Profiler.AddStats([]string{"cloudwatch", metricName, "dimensionSetsSuppressed"}, 1)
+	return otherBucket(dimensions)
+}
+
+// rotate starts a new window. The metrics without any dimension set in the last two windows are
+// forgotten.
+func (l *cardinalityLimiter) rotate() {
+	for key, m := range l.metrics {
+		if len(m.current) == 0 {
+			delete(l.metrics, key)
+			continue
+		}
+		if m.suppressed > 0 {
+			log.Printf("I! cloudwatch: %d datums of metric %s were published with the %s dimension values in the last %v", m.suppressed, m.name, OtherDimensionValue, l.rotationInterval)
+		}
+		m.previous, m.current = m.current, make(map[string]struct{})
+		m.suppressed = 0
+	}
+}
+
+// dimensionSetKey returns the key of the dimension set, which doesn't depend on the order of the
+// dimensions.
+func dimensionSetKey(dimensions []*cloudwatch.Dimension) string {
+	pairs := make([]string, len(dimensions))
+	for i, d := range dimensions {
+		pairs[i] = aws.StringValue(d.Name) + "=" + aws.StringValue(d.Value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+func isOtherBucket(dimensions []*cloudwatch.Dimension) bool {
+	for _, d := range dimensions {
+		if aws.StringValue(d.Value) != OtherDimensionValue {
+			return false
+		}
+	}
+	return true
+}
+
+func otherBucket(dimensions []*cloudwatch.Dimension) []*cloudwatch.Dimension {
+	other := make([]*cloudwatch.Dimension, len(dimensions))
+	for i, d := range dimensions {
+		other[i] = &cloudwatch.Dimension{Name: d.Name, Value: aws.String(OtherDimensionValue)}
+	}
+	return other
+}
@@ -0,0 +1,92 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+func dimensions(pairs ...string) []*cloudwatch.Dimension {
+	var dims []*cloudwatch.Dimension
+	for i := 0; i < len(pairs); i += 2 {
+		dims = append(dims, &cloudwatch.Dimension{Name: aws.String(pairs[i]), Value: aws.String(pairs[i+1])})
+	}
+	return dims
+}
+
+func TestCardinalityLimiter(t *testing.T) {
+	now := time.Now()
+	l := newCardinalityLimiter(2, time.Hour)
+	l.now = func() time.Time { return now }
+	l.lastRotation = now
+
+	assert.Equal(t, dimensions("host", "h1", "request_id", "r1"), l.admit("CWAgent", "latency", dimensions("host", "h1", "request_id", "r1")))
+	assert.Equal(t, dimensions("request_id", "r2", "host", "h1"), l.admit("CWAgent", "latency", dimensions("request_id", "r2", "host", "h1")))
+	// the dimension sets over the limit are folded into the Other bucket
+	assert.Equal(t, dimensions("host", "Other", "request_id", "Other"), l.admit("CWAgent", "latency", dimensions("host", "h1", "request_id", "r3")))
+	assert.Equal(t, dimensions("host", "Other", "request_id", "Other"), l.admit("CWAgent", "latency", dimensions("host", "h2", "request_id", "r4")))
+	// the dimension sets admitted are still published, in any order
+	assert.Equal(t, dimensions("host", "h1", "request_id", "r2"), l.admit("CWAgent", "latency", dimensions("host", "h1", "request_id", "r2")))
+	// the limit is per metric and namespace
+	assert.Equal(t, dimensions("host", "h1", "request_id", "r3"), l.admit("CWAgent", "errors", dimensions("host", "h1", "request_id", "r3")))
+	assert.Equal(t, dimensions("host", "h1", "request_id", "r3"), l.admit("App", "latency", dimensions("host", "h1", "request_id", "r3")))
+	assert.Empty(t, l.admit("CWAgent", "latency", nil))
+	assert.Equal(t, 2, l.metrics["CWAgent\x00latency"].suppressed)
+
+	// the dimension sets seen in the last window keep their slot, the others free it
+	now = now.Add(time.Hour)
+	assert.Equal(t, dimensions("host", "h1", "request_id", "r2"), l.admit("CWAgent", "latency", dimensions("host", "h1", "request_id", "r2")))
+	assert.Equal(t, dimensions("host", "Other", "request_id", "Other"), l.admit("CWAgent", "latency", dimensions("host", "h1", "request_id", "r5")))
+	now = now.Add(time.Hour)
+	assert.Equal(t, dimensions("host", "h1", "request_id", "r6"), l.admit("CWAgent", "latency", dimensions("host", "h1", "request_id", "r6")))
+	assert.Equal(t, dimensions("host", "Other", "request_id", "Other"), l.admit("CWAgent", "latency", dimensions("host", "h1", "request_id", "r1")))
+
+	// the metrics without dimension sets for two windows are forgotten
+	now = now.Add(2 * time.Hour)
+	l.admit("CWAgent", "latency", dimensions("host", "h1"))
+	assert.NotContains(t, l.metrics, "CWAgent\x00errors")
+}
+
+func TestBuildMetricDatumCardinalityLimit(t *testing.T) {
+	svc := new(mockCloudWatchClient)
+	cw := newCloudWatchClient(svc, time.Second)
+	cw.config.RollupDimensions = [][]string{{"host"}}
+	cw.cardinalityLimiter = newCardinalityLimiter(3, time.Hour)
+	statsKey := "cloudwatch_requests_dimensionSetsSuppressed"
+	suppressedBefore := profiler.Profiler.GetStats()[statsKey]
+
+	var got [][]*cloudwatch.Dimension
+	for i := 0; i < 4; i++ {
+		datums := cw.BuildMetricDatum(&aggregationDatum{
+			MetricDatum: cloudwatch.MetricDatum{
+				MetricName: aws.String("requests"),
+				Dimensions: dimensions("host", "h1", "request_id", fmt.Sprint(i)),
+				Value:      aws.Float64(1),
+			},
+		})
+		require.Len(t, datums, 2)
+		got = append(got, datums[0].Dimensions, datums[1].Dimensions)
+	}
+	// the rollup dimension set takes one of the slots
+	assert.Equal(t, [][]*cloudwatch.Dimension{
+		dimensions("host", "h1", "request_id", "0"),
+		dimensions("host", "h1"),
+		dimensions("host", "h1", "request_id", "1"),
+		dimensions("host", "h1"),
+		dimensions("host", "Other", "request_id", "Other"),
+		dimensions("host", "h1"),
+		dimensions("host", "Other", "request_id", "Other"),
+		dimensions("host", "h1"),
+	}, got)
+	assert.Equal(t, 2.0, profiler.Profiler.GetStats()[statsKey]-suppressedBefore)
+}
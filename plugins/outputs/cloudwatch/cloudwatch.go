@@ -5,6 +5,7 @@ package cloudwatch
 
 import (
 	"context"
+	"errors"
 	"log"
 	"reflect"
 	"sort"
@@ -32,6 +33,7 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent/internal/retryer"
 	"github.com/aws/amazon-cloudwatch-agent/internal/util/collections"
 	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
 )
 
 const (
@@ -43,6 +45,7 @@ const (
 	maxConcurrentPublisher                = 10 // the number of CloudWatch clients send request concurrently
 	defaultForceFlushInterval             = time.Minute
 	highResolutionTagKey                  = "aws:StorageResolution"
+	namespaceTagKey                       = "aws:Namespace"
 	defaultRetryCount                     = 5 // this is the retry count, the total attempts would be retry count + 1 at most.
 	backoffRetryBase                      = 200 * time.Millisecond
 	MaxDimensions                         = 30
@@ -60,9 +63,11 @@ type CloudWatch struct {
 	// todo: may want to increase the size of the chan since the type changed.
 	// 1 telegraf Metric could have many Fields.
 	// Each field corresponds to a MetricDatum.
-	metricChan             chan *aggregationDatum
-	datumBatchChan         chan []*cloudwatch.MetricDatum
-	metricDatumBatch       *MetricDatumBatch
+	metricChan       chan *aggregationDatum
+	datumBatchChan   chan datumBatch
+	metricDatumBatch *MetricDatumBatch
+	// namespaceBatches holds the batches of the datums overriding the configured namespace.
+	namespaceBatches       map[string]*MetricDatumBatch
 	shutdownChan           chan struct{}
 	retries                int
 	publisher              *publisher.Publisher
@@ -72,6 +77,9 @@ type CloudWatch struct {
 	aggregatorShutdownChan chan struct{}
 	aggregatorWaitGroup    sync.WaitGroup
 	lastRequestBytes       int
+	buffer                 *metricBuffer
+	cardinalityLimiter     *cardinalityLimiter
+	dropOriginalMatchers   []dropOriginalMatcher
 }
 
 // Compile time interface check.
@@ -83,7 +91,7 @@ func (c *CloudWatch) Capabilities() consumer.Capabilities {
 
 func (c *CloudWatch) Start(_ context.Context, host component.Host) error {
 	c.publisher, _ = publisher.NewPublisher(
-		publisher.NewNonBlockingFifoQueue(metricChanBufferSize),
+		publisher.NewNonBlockingFifoQueueWithDropFn(metricChanBufferSize, c.releaseDropped),
 		maxConcurrentPublisher,
 		2*time.Second,
 		c.WriteToCloudWatch)
@@ -115,21 +123,55 @@ func (c *CloudWatch) Start(_ context.Context, host component.Host) error {
 	c.config.RollupDimensions = GetUniqueRollupList(c.config.RollupDimensions)
 	c.svc = svc
 	c.retryer = logThrottleRetryer
+	c.openBuffer()
 	c.startRoutines()
 	return nil
 }
 
+func (c *CloudWatch) openBuffer() {
+	if c.config.BufferDir == "" {
+		return
+	}
+	maxSizeMB := c.config.BufferMaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultBufferMaxSizeMB
+	}
+	maxAge := c.config.BufferMaxAge
+	if maxAge <= 0 {
+		maxAge = defaultBufferMaxAge
+	}
+	b, err := newMetricBuffer(c.config.BufferDir, maxSizeMB*1024*1024, maxAge)
+	if err != nil {
+		log.Printf("E! cloudwatch: unable to use buffer directory %v, batches will not be buffered: %v", c.config.BufferDir, err)
+		return
+	}
+	log.Printf("I! cloudwatch: buffering batches in %v, %d datums backlogged", c.config.BufferDir, b.backlog())
+	c.buffer = b
+}
+
 func (c *CloudWatch) startRoutines() {
 	setNewDistributionFunc(c.config.MaxValuesPerDatum)
 	c.metricChan = make(chan *aggregationDatum, metricChanBufferSize)
-	c.datumBatchChan = make(chan []*cloudwatch.MetricDatum, datumBatchChanBufferSize)
+	c.datumBatchChan = make(chan datumBatch, datumBatchChanBufferSize)
 	c.shutdownChan = make(chan struct{})
 	c.aggregatorShutdownChan = make(chan struct{})
 	c.aggregator = NewAggregator(c.metricChan, c.aggregatorShutdownChan, &c.aggregatorWaitGroup)
 	perRequestConstSize := overallConstPerRequestSize + len(c.config.Namespace) + namespaceOverheads
 	c.metricDatumBatch = newMetricDatumBatch(c.config.MaxDatumsPerCall, perRequestConstSize)
+	c.namespaceBatches = make(map[string]*MetricDatumBatch)
+	if c.config.MaxDimensionSetsPerMetric > 0 {
+		c.cardinalityLimiter = newCardinalityLimiter(c.config.MaxDimensionSetsPerMetric, c.config.CardinalityRotationInterval)
+	}
+	if matchers, err := compileDropOriginalRules(c.config.DropOriginalRules); err != nil {
+		log.Printf("E! cloudwatch: ignoring the drop original rules: %v", err)
+	} else {
+		c.dropOriginalMatchers = matchers
+	}
 	go c.pushMetricDatum()
 	go c.publish()
+	if c.buffer != nil {
+		go c.replayBuffer()
+	}
 }
 
 func (c *CloudWatch) Shutdown(ctx context.Context) error {
@@ -158,6 +200,7 @@ func (c *CloudWatch) Shutdown(ctx context.Context) error {
 func (c *CloudWatch) ConsumeMetrics(ctx context.Context, metrics pmetric.Metrics) error {
 	datums := ConvertOtelMetrics(metrics)
 	for _, d := range datums {
+		c.setStatisticSetInterval(d)
 		c.aggregator.AddMetric(d)
 	}
 	return nil
@@ -172,23 +215,26 @@ func (c *CloudWatch) pushMetricDatum() {
 	for {
 		select {
 		case metric := <-c.metricChan:
+			batch := c.getMetricDatumBatch(metric.namespace)
 			datums := c.BuildMetricDatum(metric)
 			numberOfPartitions := len(datums)
 			for i := 0; i < numberOfPartitions; i++ {
-				c.metricDatumBatch.Partition = append(c.metricDatumBatch.Partition, datums[i])
-				c.metricDatumBatch.Size += payload(datums[i])
-				if c.metricDatumBatch.isFull() {
+				batch.Partition = append(batch.Partition, datums[i])
+				batch.Size += payload(datums[i])
+				if batch.isFull() {
 					// if batch is full
-					c.datumBatchChan <- c.metricDatumBatch.Partition
-					c.metricDatumBatch.clear()
+					c.datumBatchChan <- datumBatch{namespace: batch.namespace, datums: batch.Partition}
+					batch.clear()
 				}
 			}
 		case <-ticker.C:
-			if c.timeToPublish(c.metricDatumBatch) {
-				// if the time to publish comes
-				c.lastRequestBytes = c.metricDatumBatch.Size
-				c.datumBatchChan <- c.metricDatumBatch.Partition
-				c.metricDatumBatch.clear()
+			for _, batch := range append([]*MetricDatumBatch{c.metricDatumBatch}, maps.Values(c.namespaceBatches)...) {
+				if c.timeToPublish(batch) {
+					// if the time to publish comes
+					c.lastRequestBytes = batch.Size
+					c.datumBatchChan <- datumBatch{namespace: batch.namespace, datums: batch.Partition}
+					batch.clear()
+				}
 			}
 		case <-c.shutdownChan:
 			return
@@ -196,12 +242,35 @@ func (c *CloudWatch) pushMetricDatum() {
 	}
 }
 
+// getMetricDatumBatch returns the batch of the namespace, the batch of the configured namespace
+// if it is empty.
+func (c *CloudWatch) getMetricDatumBatch(namespace string) *MetricDatumBatch {
+	if namespace == "" || namespace == c.config.Namespace {
+		return c.metricDatumBatch
+	}
+	batch, ok := c.namespaceBatches[namespace]
+	if !ok {
+		batch = newMetricDatumBatch(c.config.MaxDatumsPerCall, overallConstPerRequestSize+len(namespace)+namespaceOverheads)
+		batch.namespace = namespace
+		c.namespaceBatches[namespace] = batch
+	}
+	return batch
+}
+
+// datumBatch is a batch of datums sent in a single PutMetricData request. The namespace is empty
+// for the configured namespace.
+type datumBatch struct {
+	namespace string
+	datums    []*cloudwatch.MetricDatum
+}
+
 type MetricDatumBatch struct {
 	MaxDatumsPerCall    int
 	Partition           []*cloudwatch.MetricDatum
 	BeginTime           time.Time
 	Size                int
 	perRequestConstSize int
+	namespace           string
 }
 
 func newMetricDatumBatch(maxDatumsPerCall, perRequestConstSize int) *MetricDatumBatch {
@@ -312,8 +381,8 @@ func (c *CloudWatch) metricDatumBatchFull() bool {
 func (c *CloudWatch) pushMetricDatumBatch() {
 	for {
 		select {
-		case datumBatch := <-c.datumBatchChan:
-			c.publisher.Publish(datumBatch)
+		case batch := <-c.datumBatchChan:
+			c.publisher.Publish(c.bufferBatch(batch))
 			continue
 		default:
 		}
@@ -334,11 +403,120 @@ func (c *CloudWatch) backoffSleep() {
 	time.Sleep(d)
 }
 
+// bufferBatch writes the batch to the buffer, if any, before it is published.
+func (c *CloudWatch) bufferBatch(batch datumBatch) interface{} {
+	if c.buffer == nil {
+		return batch
+	}
+	path, err := c.buffer.write(batch)
+	if err != nil {
+		log.Printf("W! cloudwatch: unable to buffer %d datums: %v", len(batch.datums), err)
+		return batch
+	}
+	return &bufferedBatch{datumBatch: batch, path: path}
+}
+
+// releaseDropped releases the buffered batches dropped by a full publisher queue, so that they are
+// replayed.
+func (c *CloudWatch) releaseDropped(req interface{}) {
+	if batch, ok := req.(*bufferedBatch); ok && c.buffer != nil {
+		c.buffer.release(batch.path, false)
+	}
+}
+
+// replayBuffer periodically sends the buffered batches which are not in flight, oldest first.
+// This includes the batches left by a previous run of the agent, and the batches dropped by a
+// full publisher queue.
+func (c *CloudWatch) replayBuffer() {
+	ticker := time.NewTicker(bufferReplayInterval)
+	defer ticker.Stop()
+	for {
+		c.replayBufferOnce()
+		select {
+		case <-ticker.C:
+		case <-c.shutdownChan:
+			return
+		}
+	}
+}
+
+func (c *CloudWatch) replayBufferOnce() {
+	paths, err := c.buffer.acquire()
+	if err != nil {
+		log.Printf("E! cloudwatch: unable to list buffer directory %v: %v", c.config.BufferDir, err)
+		return
+	}
+	backlog := c.buffer.backlog()
+	profiler.Profiler.SetStats([]string{"cloudwatch", "bufferedDatums"}, float64(backlog))
+	if backlog > 0 {
+		log.Printf("I! cloudwatch: %d datums backlogged in %v", backlog, c.config.BufferDir)
+	}
+	for i, path := range paths {
+		select {
+		case <-c.shutdownChan:
+			c.releaseAll(paths[i:])
+			return
+		default:
+		}
+		batch, err := c.buffer.read(path)
+		if err != nil {
+			log.Printf("E! cloudwatch: dropping unreadable buffer file %v: %v", path, err)
+			c.buffer.release(path, true)
+			continue
+		}
+		err = c.putMetricData(batch)
+		if err != nil && !isPermanentError(err) {
+			// retry this batch and the remaining ones on the next replay
+			c.releaseAll(paths[i:])
+			return
+		}
+		c.release(path, len(batch.datums), err)
+	}
+}
+
+// release removes the buffer file of the batch unless it failed and may be accepted later. The
+// batches rejected as invalid would be rejected again and block the replay, so they are dropped.
+func (c *CloudWatch) release(path string, datums int, err error) {
+	if err != nil && isPermanentError(err) {
+		log.Printf("E! cloudwatch: dropping %d buffered datums rejected by PutMetricData: %v", datums, err)
+		profiler.Profiler.AddStats([]string{"cloudwatch", "bufferDroppedDatums"}, float64(datums))
+	}
+	c.buffer.release(path, err == nil || isPermanentError(err))
+}
+
+func (c *CloudWatch) releaseAll(paths []string) {
+	for _, path := range paths {
+		c.buffer.release(path, false)
+	}
+}
+
+// BufferedDatums returns the number of datums in the buffer directory which have not been delivered yet.
+func (c *CloudWatch) BufferedDatums() int64 {
+	if c.buffer == nil {
+		return 0
+	}
+	return c.buffer.backlog()
+}
+
 func (c *CloudWatch) WriteToCloudWatch(req interface{}) {
-	datums := req.([]*cloudwatch.MetricDatum)
+	switch batch := req.(type) {
+	case *bufferedBatch:
+		err := c.putMetricData(batch.datumBatch)
+		// failed batches are kept in the buffer for the replay
+		c.release(batch.path, len(batch.datums), err)
+	case datumBatch:
+		c.putMetricData(batch)
+	}
+}
+
+func (c *CloudWatch) putMetricData(batch datumBatch) error {
+	namespace := batch.namespace
+	if namespace == "" {
+		namespace = c.config.Namespace
+	}
 	params := &cloudwatch.PutMetricDataInput{
-		MetricData: datums,
-		Namespace:  aws.String(c.config.Namespace),
+		MetricData: batch.datums,
+		Namespace:  aws.String(namespace),
 	}
 	var err error
 	for i := 0; i < defaultRetryCount; i++ {
@@ -370,6 +548,23 @@ func (c *CloudWatch) WriteToCloudWatch(req interface{}) {
 	if err != nil {
 		log.Println("E! cloudwatch: WriteToCloudWatch failure, err: ", err)
 	}
+	return err
+}
+
+// isPermanentError returns true for the PutMetricData errors caused by the request itself, which
+// fail again when the request is retried.
+func isPermanentError(err error) bool {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return false
+	}
+	switch awsErr.Code() {
+	case cloudwatch.ErrCodeInvalidParameterValueException,
+		cloudwatch.ErrCodeInvalidParameterCombinationException,
+		cloudwatch.ErrCodeMissingRequiredParameterException:
+		return true
+	}
+	return false
 }
 
 // BuildMetricDatum may just return the datum as-is.
@@ -389,14 +584,26 @@ func (c *CloudWatch) BuildMetricDatum(metric *aggregationDatum) []*cloudwatch.Me
 		}
 		distList = resize(metric.distribution, c.config.MaxValuesPerDatum)
 	}
+	statisticSet := c.isStatisticSet(metric)
+	valuesPublished := !statisticSet || c.config.StatisticSetCompression == StatisticSetCompressionSEH1
+	baselineDistList := distList
+	if !valuesPublished {
+		distList = []distribution.Distribution{metric.distribution}
+	} else if statisticSet {
+		distList = []distribution.Distribution{toSEH1(metric.distribution)}
+	}
+	bytesSaved := 0
 
 	dimensionsList := c.ProcessRollup(metric.Dimensions)
 	for index, dimensions := range dimensionsList {
 		//index == 0 means it's the original metrics, and if the metric name and dimension matches, skip creating
 		//metric datum
-		if index == 0 && c.IsDropping(*metric.MetricDatum.MetricName) {
+		if index == 0 && c.isDroppingOriginal(*metric.MetricDatum.MetricName, dimensions) {
 			continue
 		}
+		if c.cardinalityLimiter != nil {
+			dimensions = c.cardinalityLimiter.admit(metric.namespace, *metric.MetricName, dimensions)
+		}
 		if len(distList) == 0 {
 			if !distribution.IsSupportedValue(*metric.Value, distribution.MinValue, distribution.MaxValue) {
 				log.Printf("E! metric (%s) has an unsupported value: %v, dropping it", *metric.MetricName, *metric.Value)
@@ -413,8 +620,10 @@ func (c *CloudWatch) BuildMetricDatum(metric *aggregationDatum) []*cloudwatch.Me
 			}
 			datums = append(datums, datum)
 		} else {
+			if statisticSet {
+				bytesSaved += statisticSetBaseline(metric, dimensions, baselineDistList)
+			}
 			for _, dist := range distList {
-				values, counts := dist.ValuesAndCounts()
 				s := cloudwatch.StatisticSet{}
 				s.SetMaximum(dist.Maximum())
 				s.SetMinimum(dist.Minimum())
@@ -429,17 +638,37 @@ func (c *CloudWatch) BuildMetricDatum(metric *aggregationDatum) []*cloudwatch.Me
 					Timestamp:         metric.Timestamp,
 					Unit:              metric.Unit,
 					StorageResolution: metric.StorageResolution,
-					Values:            aws.Float64Slice(values),
-					Counts:            aws.Float64Slice(counts),
 					StatisticValues:   &s,
 				}
+				if valuesPublished {
+					values, counts := dist.ValuesAndCounts()
+					datum.Values = aws.Float64Slice(values)
+					datum.Counts = aws.Float64Slice(counts)
+				}
+				if statisticSet {
+					bytesSaved -= payload(datum)
+				}
 				datums = append(datums, datum)
 			}
 		}
 	}
+	if statisticSet {
+		reportStatisticSetBytesSaved(bytesSaved)
+	}
 	return datums
 }
 
+// isDroppingOriginal returns whether the original datum of the metric with the dimensions is
+// dropped, from the first drop original rule matching it or else from its name.
+func (c *CloudWatch) isDroppingOriginal(metricName string, dimensions []*cloudwatch.Dimension) bool {
+	for _, m := range c.dropOriginalMatchers {
+		if m.matches(metricName, dimensions) {
+			return m.drop
+		}
+	}
+	return c.IsDropping(metricName)
+}
+
 func (c *CloudWatch) IsDropping(metricName string) bool {
 	// Check if any metrics are provided in drop_original_metrics
 	if len(c.config.DropOriginalConfigs) == 0 {
@@ -436,6 +436,42 @@ func TestConsumeMetrics(t *testing.T) {
 	cw.Shutdown(ctx)
 }
 
+func TestConsumeMetricsNamespace(t *testing.T) {
+	svc := new(mockCloudWatchClient)
+	svc.On("PutMetricData", mock.Anything).Return(&cloudwatch.PutMetricDataOutput{}, nil)
+	cw := &CloudWatch{
+		svc: svc,
+		config: &Config{
+			Namespace:          "namespace",
+			ForceFlushInterval: time.Second,
+			MaxDatumsPerCall:   defaultMaxDatumsPerCall,
+			MaxValuesPerDatum:  defaultMaxValuesPerDatum,
+		},
+	}
+	cw.startRoutines()
+	cw.publisher, _ = publisher.NewPublisher(
+		publisher.NewNonBlockingFifoQueue(10),
+		10,
+		2*time.Second,
+		cw.WriteToCloudWatch)
+	// The datums overriding the namespace are sent in their own request.
+	pmetrics := createTestMetrics(4, 1, 1, "")
+	ms := pmetrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	ms.At(0).Gauge().DataPoints().At(0).Attributes().PutStr(namespaceTagKey, "other")
+	ms.At(2).Gauge().DataPoints().At(0).Attributes().PutStr(namespaceTagKey, "other")
+	ctx := context.Background()
+	cw.ConsumeMetrics(ctx, pmetrics)
+	time.Sleep(2*time.Second + 2*cw.config.ForceFlushInterval)
+	assert.True(t, svc.AssertNumberOfCalls(t, "PutMetricData", 2))
+	namespaces := map[string]int{}
+	for _, call := range svc.Calls {
+		input := call.Arguments.Get(0).(*cloudwatch.PutMetricDataInput)
+		namespaces[*input.Namespace] += len(input.MetricData)
+	}
+	assert.Equal(t, map[string]int{"namespace": 2, "other": 2}, namespaces)
+	cw.Shutdown(ctx)
+}
+
 func TestWriteError(t *testing.T) {
 	svc := new(mockCloudWatchClient)
 	res := cloudwatch.PutMetricDataOutput{}
@@ -572,11 +608,11 @@ func TestBackoffRetries(t *testing.T) {
 // Take 1 item out of the channel and verify it is no longer full.
 func TestCloudWatch_metricDatumBatchFull(t *testing.T) {
 	c := &CloudWatch{
-		datumBatchChan: make(chan []*cloudwatch.MetricDatum, datumBatchChanBufferSize),
+		datumBatchChan: make(chan datumBatch, datumBatchChanBufferSize),
 	}
 	assert.False(t, c.metricDatumBatchFull())
 	for i := 0; i < datumBatchChanBufferSize; i++ {
-		c.datumBatchChan <- []*cloudwatch.MetricDatum{}
+		c.datumBatchChan <- datumBatch{}
 	}
 	assert.True(t, c.metricDatumBatchFull())
 	<-c.datumBatchChan
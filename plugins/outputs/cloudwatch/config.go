@@ -5,6 +5,7 @@ package cloudwatch
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/resourcetotelemetry"
@@ -28,6 +29,32 @@ type Config struct {
 	DropOriginalConfigs      map[string]bool `mapstructure:"drop_original_metrics,omitempty"`
 	Namespace                string          `mapstructure:"namespace"`
 
+	// DropOriginalRules decide whether the original datums of the metrics are dropped from their
+	// name and dimensions. The first matching rule applies, DropOriginalConfigs being used for the
+	// datums without any.
+	DropOriginalRules []DropOriginalRule `mapstructure:"drop_original_rules,omitempty"`
+
+	// BufferDir is the directory of the write-ahead buffer of PutMetricData batches, which keeps
+	// the undelivered batches across restarts and outages. Buffering is disabled when empty.
+	BufferDir       string        `mapstructure:"buffer_dir,omitempty"`
+	BufferMaxSizeMB int64         `mapstructure:"buffer_max_size_mb,omitempty"`
+	BufferMaxAge    time.Duration `mapstructure:"buffer_max_age,omitempty"`
+
+	// MaxDimensionSetsPerMetric caps the distinct dimension sets published for each metric, the
+	// datums of the other dimension sets being published with the Other dimension values. The
+	// dimension sets not published for a rotation interval free their slot. Disabled when 0.
+	MaxDimensionSetsPerMetric   int           `mapstructure:"max_dimension_sets_per_metric,omitempty"`
+	CardinalityRotationInterval time.Duration `mapstructure:"cardinality_rotation_interval,omitempty"`
+
+	// StatisticSetOnly publishes the high resolution datums as statistic sets, without their values
+	// and counts. The high resolution datums without an aggregation interval are aggregated per
+	// StatisticSetInterval, keeping their high storage resolution although only one statistic set
+	// is published per interval. StatisticSetCompression may be seh1 to also publish the values in
+	// the buckets of the SEH1 distribution, keeping the percentiles.
+	StatisticSetOnly        bool          `mapstructure:"statistic_set_only,omitempty"`
+	StatisticSetInterval    time.Duration `mapstructure:"statistic_set_interval,omitempty"`
+	StatisticSetCompression string        `mapstructure:"statistic_set_compression,omitempty"`
+
 	// ResourceToTelemetrySettings is the option for converting resource
 	// attributes to telemetry attributes.
 	// "Enabled" - A boolean field to enable/disable this option. Default is `false`.
@@ -50,5 +77,17 @@ func (c *Config) Validate() error {
 	if c.ForceFlushInterval < time.Millisecond {
 		return errors.New("'force_flush_interval' must be at least 1 millisecond")
 	}
+	if c.MaxDimensionSetsPerMetric < 0 {
+		return errors.New("'max_dimension_sets_per_metric' must not be negative")
+	}
+	if c.StatisticSetInterval < 0 {
+		return errors.New("'statistic_set_interval' must not be negative")
+	}
+	if c.StatisticSetCompression != "" && c.StatisticSetCompression != StatisticSetCompressionSEH1 {
+		return fmt.Errorf("'statistic_set_compression' must be empty or %s", StatisticSetCompressionSEH1)
+	}
+	if _, err := compileDropOriginalRules(c.DropOriginalRules); err != nil {
+		return err
+	}
 	return nil
 }
@@ -69,6 +69,17 @@ func getAggregationInterval(attributes *pcommon.Map) time.Duration {
 	return interval
 }
 
+// getNamespace removes this special attribute and returns its value.
+func getNamespace(attributes *pcommon.Map) string {
+	v, ok := attributes.Get(namespaceTagKey)
+	if !ok {
+		return ""
+	}
+	namespace := v.AsString()
+	attributes.Remove(namespaceTagKey)
+	return namespace
+}
+
 // ConvertOtelNumberDataPoints converts each datapoint in the given slice to
 // 1 or more MetricDatums and returns them.
 func ConvertOtelNumberDataPoints(
@@ -84,6 +95,7 @@ func ConvertOtelNumberDataPoints(
 		attrs := dp.Attributes()
 		storageResolution := checkHighResolution(&attrs)
 		aggregationInterval := getAggregationInterval(&attrs)
+		namespace := getNamespace(&attrs)
 		dimensions := ConvertOtelDimensions(attrs)
 		value := NumberDataPointValue(dp) * scale
 		ad := aggregationDatum{
@@ -96,6 +108,7 @@ func ConvertOtelNumberDataPoints(
 				StorageResolution: aws.Int64(storageResolution),
 			},
 			aggregationInterval: aggregationInterval,
+			namespace:           namespace,
 		}
 		datums = append(datums, &ad)
 	}
@@ -116,6 +129,7 @@ func ConvertOtelHistogramDataPoints(
 		attrs := dp.Attributes()
 		storageResolution := checkHighResolution(&attrs)
 		aggregationInterval := getAggregationInterval(&attrs)
+		namespace := getNamespace(&attrs)
 		dimensions := ConvertOtelDimensions(attrs)
 		ad := aggregationDatum{
 			MetricDatum: cloudwatch.MetricDatum{
@@ -126,6 +140,7 @@ func ConvertOtelHistogramDataPoints(
 				StorageResolution: aws.Int64(storageResolution),
 			},
 			aggregationInterval: aggregationInterval,
+			namespace:           namespace,
 		}
 		// Assume function pointer is valid.
 		ad.distribution = distribution.NewDistribution()
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 
@@ -216,3 +217,17 @@ func TestInvalidMetric(t *testing.T) {
 	m.SetUnit("unit")
 	assert.Empty(t, ConvertOtelMetric(m))
 }
+
+func TestConvertOtelMetrics_Namespace(t *testing.T) {
+	metrics := createTestMetrics(2, 1, 2, "s")
+	dp := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0)
+	dp.Attributes().PutStr(namespaceTagKey, "other")
+	datums := ConvertOtelMetrics(metrics)
+	require.Len(t, datums, 2)
+	// the special attribute is not a dimension
+	for _, d := range datums {
+		assert.Len(t, d.Dimensions, 2)
+	}
+	assert.Equal(t, "other", datums[0].namespace)
+	assert.Equal(t, "", datums[1].namespace)
+}
@@ -0,0 +1,81 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/gobwas/glob"
+)
+
+const (
+	DropOriginalActionDrop = "drop"
+	DropOriginalActionKeep = "keep"
+)
+
+// DropOriginalRule decides whether the original datums of the metrics matching it are dropped,
+// their rollups being published either way. Metric and the values of Dimensions are glob patterns,
+// and all the dimensions must be present with a matching value for the datum to match.
+type DropOriginalRule struct {
+	Metric     string            `mapstructure:"metric"`
+	Dimensions map[string]string `mapstructure:"dimensions,omitempty"`
+	Action     string            `mapstructure:"action"`
+}
+
+type dropOriginalMatcher struct {
+	metric     glob.Glob
+	dimensions map[string]glob.Glob
+	drop       bool
+}
+
+// compileDropOriginalRules compiles the patterns of the rules, keeping their order.
+func compileDropOriginalRules(rules []DropOriginalRule) ([]dropOriginalMatcher, error) {
+	matchers := make([]dropOriginalMatcher, 0, len(rules))
+	for i, rule := range rules {
+		var m dropOriginalMatcher
+		switch rule.Action {
+		case DropOriginalActionDrop:
+			m.drop = true
+		case DropOriginalActionKeep:
+		default:
+			return nil, fmt.Errorf("drop original rule %d: invalid action %q, must be %q or %q", i, rule.Action, DropOriginalActionDrop, DropOriginalActionKeep)
+		}
+		if rule.Metric == "" {
+			return nil, fmt.Errorf("drop original rule %d: metric must be set", i)
+		}
+		var err error
+		if m.metric, err = glob.Compile(rule.Metric); err != nil {
+			return nil, fmt.Errorf("drop original rule %d: invalid metric pattern %q: %w", i, rule.Metric, err)
+		}
+		m.dimensions = make(map[string]glob.Glob, len(rule.Dimensions))
+		for name, pattern := range rule.Dimensions {
+			if m.dimensions[name], err = glob.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("drop original rule %d: invalid pattern %q of dimension %s: %w", i, pattern, name, err)
+			}
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func (m dropOriginalMatcher) matches(metricName string, dimensions []*cloudwatch.Dimension) bool {
+	if !m.metric.Match(metricName) {
+		return false
+	}
+	for name, pattern := range m.dimensions {
+		found := false
+		for _, d := range dimensions {
+			if aws.StringValue(d.Name) == name {
+				found = pattern.Match(aws.StringValue(d.Value))
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
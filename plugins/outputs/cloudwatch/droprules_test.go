@@ -0,0 +1,114 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileDropOriginalRules(t *testing.T) {
+	testCases := map[string]struct {
+		rules   []DropOriginalRule
+		wantErr string
+	}{
+		"Valid": {
+			rules: []DropOriginalRule{
+				{Metric: "disk_*", Dimensions: map[string]string{"path": "/mnt/*"}, Action: DropOriginalActionDrop},
+				{Metric: "cpu_usage_idle", Action: DropOriginalActionKeep},
+			},
+		},
+		"InvalidAction": {
+			rules:   []DropOriginalRule{{Metric: "disk_used_percent", Action: "skip"}},
+			wantErr: `drop original rule 0: invalid action "skip"`,
+		},
+		"MissingMetric": {
+			rules:   []DropOriginalRule{{Action: DropOriginalActionDrop}},
+			wantErr: "drop original rule 0: metric must be set",
+		},
+		"InvalidDimensionPattern": {
+			rules:   []DropOriginalRule{{Metric: "disk_*", Dimensions: map[string]string{"path": "[/mnt"}, Action: DropOriginalActionDrop}},
+			wantErr: "drop original rule 0: invalid pattern",
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			matchers, err := compileDropOriginalRules(testCase.rules)
+			if testCase.wantErr != "" {
+				assert.ErrorContains(t, err, testCase.wantErr)
+				cfg := Config{Region: "us-east-1", Namespace: "CWAgent", ForceFlushInterval: time.Minute, DropOriginalRules: testCase.rules}
+				assert.Error(t, cfg.Validate())
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, matchers, len(testCase.rules))
+		})
+	}
+}
+
+func TestBuildMetricDatumDropOriginalRules(t *testing.T) {
+	svc := new(mockCloudWatchClient)
+	cw := newCloudWatchClient(svc, time.Second)
+	cw.config.RollupDimensions = [][]string{{"host"}}
+	cw.config.DropOriginalConfigs = map[string]bool{"disk_used_percent": true}
+	var err error
+	cw.dropOriginalMatchers, err = compileDropOriginalRules([]DropOriginalRule{
+		{Metric: "disk_used_percent", Dimensions: map[string]string{"path": "/"}, Action: DropOriginalActionKeep},
+		{Metric: "disk_*", Dimensions: map[string]string{"path": "/mnt/ephemeral*"}, Action: DropOriginalActionDrop},
+	})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		metricName string
+		dimensions []*cloudwatch.Dimension
+		want       [][]*cloudwatch.Dimension
+	}{
+		"KeptByRule": {
+			metricName: "disk_used_percent",
+			dimensions: dimensions("host", "h1", "path", "/"),
+			want:       [][]*cloudwatch.Dimension{dimensions("host", "h1", "path", "/"), dimensions("host", "h1")},
+		},
+		"DroppedByRule": {
+			metricName: "disk_free",
+			dimensions: dimensions("host", "h1", "path", "/mnt/ephemeral0"),
+			want:       [][]*cloudwatch.Dimension{dimensions("host", "h1")},
+		},
+		"DroppedByName": {
+			metricName: "disk_used_percent",
+			dimensions: dimensions("host", "h1", "path", "/data"),
+			want:       [][]*cloudwatch.Dimension{dimensions("host", "h1")},
+		},
+		"MissingDimension": {
+			metricName: "disk_free",
+			dimensions: dimensions("host", "h1"),
+			want:       [][]*cloudwatch.Dimension{dimensions("host", "h1")},
+		},
+		"NoMatch": {
+			metricName: "disk_free",
+			dimensions: dimensions("host", "h1", "path", "/data"),
+			want:       [][]*cloudwatch.Dimension{dimensions("host", "h1", "path", "/data"), dimensions("host", "h1")},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			datums := cw.BuildMetricDatum(&aggregationDatum{
+				MetricDatum: cloudwatch.MetricDatum{
+					MetricName: aws.String(testCase.metricName),
+					Dimensions: testCase.dimensions,
+					Value:      aws.Float64(1),
+				},
+			})
+			var got [][]*cloudwatch.Dimension
+			for _, datum := range datums {
+				got = append(got, datum.Dimensions)
+			}
+			assert.Equal(t, testCase.want, got)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/seh1"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+const (
+	// StatisticSetCompressionSEH1 keeps the percentiles of the statistic sets by publishing their
+	// values in the buckets of the SEH1 distribution.
+	StatisticSetCompressionSEH1 = "seh1"
+
+	defaultStatisticSetInterval = time.Minute
+)
+
+func isHighResolution(m *aggregationDatum) bool {
+	return m.StorageResolution != nil && *m.StorageResolution == 1
+}
+
+// setStatisticSetInterval aggregates the high resolution datums without an aggregation interval
+// per statistic set interval in the statistic set mode, so that their values are published as a
+// single statistic set for each interval. The datums keep their high storage resolution, but only
+// one statistic set is published per interval. The values are aggregated in a SEH1 distribution
+// with the seh1 compression, so that its buckets are published.
+func (c *CloudWatch) setStatisticSetInterval(m *aggregationDatum) {
+	if !c.config.StatisticSetOnly || m.aggregationInterval != 0 || !isHighResolution(m) {
+		return
+	}
+	m.aggregationInterval = c.config.StatisticSetInterval
+	if m.aggregationInterval <= 0 {
+		m.aggregationInterval = defaultStatisticSetInterval
+	}
+	m.preAggregated = m.distribution == nil
+	if m.preAggregated && c.config.StatisticSetCompression == StatisticSetCompressionSEH1 && m.Value != nil {
+		m.distribution = seh1.NewSEH1Distribution()
+		var unit string
+		if m.Unit != nil {
+			unit = *m.Unit
+		}
+		if err := m.distribution.AddEntryWithUnit(*m.Value, 1, unit); err != nil {
+			log.Printf("W! err %s, metric %s", err, aws.StringValue(m.MetricName))
+		}
+	}
+}
+
+// toSEH1 returns the distribution with its values in the buckets of a SEH1 distribution.
+func toSEH1(dist distribution.Distribution) distribution.Distribution {
+	if _, ok := dist.(*seh1.SEH1Distribution); ok {
+		return dist
+	}
+	result := seh1.NewSEH1Distribution()
+	values, counts := dist.ValuesAndCounts()
+	for i, value := range values {
+		if err := result.AddEntryWithUnit(value, counts[i], dist.Unit()); err != nil {
+			log.Printf("D! err %s, value %v", err, value)
+		}
+	}
+	return result
+}
+
+// isStatisticSet returns whether the datum is published in the statistic set mode.
+func (c *CloudWatch) isStatisticSet(m *aggregationDatum) bool {
+	return c.config.StatisticSetOnly && m.distribution != nil && isHighResolution(m)
+}
+
+// statisticSetBaseline returns the payload of the datums which would have been published for the
+// dimensions without the statistic set mode: a datum for each value aggregated by the mode, or the
+// datums with the values and counts of the distributions.
+func statisticSetBaseline(m *aggregationDatum, dimensions []*cloudwatch.Dimension, distList []distribution.Distribution) int {
+	datum := &cloudwatch.MetricDatum{
+		MetricName:        m.MetricName,
+		Dimensions:        dimensions,
+		Unit:              m.Unit,
+		StorageResolution: m.StorageResolution,
+	}
+	if m.preAggregated {
+		return int(m.distribution.SampleCount()) * payload(datum)
+	}
+	size := 0
+	for _, dist := range distList {
+		values, _ := dist.ValuesAndCounts()
+		datum.Values = aws.Float64Slice(values)
+		size += payload(datum)
+	}
+	return size
+}
+
+// reportStatisticSetBytesSaved adds the bytes saved by the statistic set mode to the profiler stats,
+// which is negative when the statistic sets are larger than the values they aggregate.
+func reportStatisticSetBytesSaved(saved int) {
+	profiler.Profiler.AddStats([]string{"cloudwatch", "statisticSetBytesSaved"}, float64(saved))
+}
@@ -0,0 +1,139 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution"
+	"github.com/aws/amazon-cloudwatch-agent/metric/distribution/seh1"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+func TestSetStatisticSetInterval(t *testing.T) {
+	setNewDistributionFunc(maxValuesPerDatum)
+	cw := &CloudWatch{config: &Config{StatisticSetOnly: true}}
+	highResolution := &aggregationDatum{MetricDatum: cloudwatch.MetricDatum{StorageResolution: aws.Int64(1)}}
+	cw.setStatisticSetInterval(highResolution)
+	assert.Equal(t, time.Minute, highResolution.aggregationInterval)
+	assert.True(t, highResolution.preAggregated)
+
+	// the datums aggregated per their own interval keep it
+	aggregated := &aggregationDatum{MetricDatum: cloudwatch.MetricDatum{StorageResolution: aws.Int64(1)}, aggregationInterval: 10 * time.Second}
+	cw.setStatisticSetInterval(aggregated)
+	assert.Equal(t, 10*time.Second, aggregated.aggregationInterval)
+	assert.False(t, aggregated.preAggregated)
+
+	standardResolution := &aggregationDatum{MetricDatum: cloudwatch.MetricDatum{StorageResolution: aws.Int64(60)}}
+	cw.setStatisticSetInterval(standardResolution)
+	assert.Zero(t, standardResolution.aggregationInterval)
+
+	cw.config.StatisticSetInterval = 30 * time.Second
+	histogram := &aggregationDatum{MetricDatum: cloudwatch.MetricDatum{StorageResolution: aws.Int64(1)}, distribution: distribution.NewDistribution()}
+	cw.setStatisticSetInterval(histogram)
+	assert.Equal(t, 30*time.Second, histogram.aggregationInterval)
+	assert.False(t, histogram.preAggregated)
+
+	// the values are aggregated in a SEH1 distribution with the seh1 compression
+	cw.config.StatisticSetCompression = StatisticSetCompressionSEH1
+	compressed := &aggregationDatum{MetricDatum: cloudwatch.MetricDatum{StorageResolution: aws.Int64(1), Value: aws.Float64(5), Unit: aws.String("Count")}}
+	cw.setStatisticSetInterval(compressed)
+	assert.True(t, compressed.preAggregated)
+	require.IsType(t, &seh1.SEH1Distribution{}, compressed.distribution)
+	assert.Equal(t, float64(1), compressed.distribution.SampleCount())
+	assert.Equal(t, "Count", compressed.distribution.Unit())
+
+	cw.config.StatisticSetOnly = false
+	disabled := &aggregationDatum{MetricDatum: cloudwatch.MetricDatum{StorageResolution: aws.Int64(1)}}
+	cw.setStatisticSetInterval(disabled)
+	assert.Zero(t, disabled.aggregationInterval)
+}
+
+func TestBuildMetricDatumStatisticSet(t *testing.T) {
+	svc := new(mockCloudWatchClient)
+	cw := newCloudWatchClient(svc, time.Second)
+	cw.config.StatisticSetOnly = true
+	statsKey := "cloudwatch_statisticSetBytesSaved"
+
+	newDatum := func(resolution int64) *aggregationDatum {
+		dist := distribution.NewDistribution()
+		for i := 1; i <= 6; i++ {
+			require.NoError(t, dist.AddEntryWithUnit(float64(i), 1, "Count"))
+		}
+		return &aggregationDatum{
+			MetricDatum: cloudwatch.MetricDatum{
+				MetricName:        aws.String("requests"),
+				Dimensions:        dimensions("host", "h1"),
+				Timestamp:         aws.Time(time.Now()),
+				Unit:              aws.String("Count"),
+				StorageResolution: aws.Int64(resolution),
+			},
+			distribution:  dist,
+			preAggregated: true,
+		}
+	}
+
+	savedBefore := profiler.Profiler.GetStats()[statsKey]
+	datums := cw.BuildMetricDatum(newDatum(1))
+	require.Len(t, datums, 1)
+	assert.Empty(t, datums[0].Values)
+	assert.Empty(t, datums[0].Counts)
+	assert.Equal(t, &cloudwatch.StatisticSet{
+		Maximum:     aws.Float64(6),
+		Minimum:     aws.Float64(1),
+		SampleCount: aws.Float64(6),
+		Sum:         aws.Float64(21),
+	}, datums[0].StatisticValues)
+	value := &cloudwatch.MetricDatum{
+		MetricName:        aws.String("requests"),
+		Dimensions:        dimensions("host", "h1"),
+		Unit:              aws.String("Count"),
+		StorageResolution: aws.Int64(1),
+		Value:             aws.Float64(1),
+	}
+	assert.Equal(t, float64(6*payload(value)-payload(datums[0])), profiler.Profiler.GetStats()[statsKey]-savedBefore)
+
+	// the values are published in the buckets of the SEH1 distribution with the compression
+	cw.config.StatisticSetCompression = StatisticSetCompressionSEH1
+	datums = cw.BuildMetricDatum(newDatum(1))
+	require.Len(t, datums, 1)
+	assert.Len(t, datums[0].Values, 6)
+	assert.Equal(t, aws.Float64(6), datums[0].StatisticValues.SampleCount)
+	// the values of other distributions are moved to the buckets
+	nearby := newDatum(1)
+	nearby.distribution = distribution.NewDistribution()
+	require.NoError(t, nearby.distribution.AddEntryWithUnit(100, 2, "Count"))
+	require.NoError(t, nearby.distribution.AddEntryWithUnit(101, 1, "Count"))
+	datums = cw.BuildMetricDatum(nearby)
+	require.Len(t, datums, 1)
+	assert.Len(t, datums[0].Values, 1)
+	assert.Equal(t, []*float64{aws.Float64(3)}, datums[0].Counts)
+	assert.Equal(t, aws.Float64(101), datums[0].StatisticValues.Maximum)
+
+	// the standard resolution datums are not published as statistic sets
+	cw.config.StatisticSetCompression = ""
+	savedBefore = profiler.Profiler.GetStats()[statsKey]
+	datums = cw.BuildMetricDatum(newDatum(60))
+	require.Len(t, datums, 1)
+	assert.Len(t, datums[0].Values, 6)
+	assert.Equal(t, savedBefore, profiler.Profiler.GetStats()[statsKey])
+}
+
+func TestValidateStatisticSetConfig(t *testing.T) {
+	cfg := Config{Region: "us-east-1", Namespace: "CWAgent", ForceFlushInterval: time.Minute, StatisticSetOnly: true}
+	assert.NoError(t, cfg.Validate())
+	cfg.StatisticSetCompression = StatisticSetCompressionSEH1
+	assert.NoError(t, cfg.Validate())
+	cfg.StatisticSetCompression = "gzip"
+	assert.ErrorContains(t, cfg.Validate(), "'statistic_set_compression' must be empty or seh1")
+	cfg.StatisticSetCompression = ""
+	cfg.StatisticSetInterval = -time.Second
+	assert.ErrorContains(t, cfg.Validate(), "'statistic_set_interval' must not be negative")
+}
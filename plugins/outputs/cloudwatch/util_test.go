@@ -121,6 +121,24 @@ func TestPayload_ValuesAndCounts(t *testing.T) {
 	assert.Equal(t, 867, payload(datum))
 }
 
+func TestPayload_StatisticSet(t *testing.T) {
+	datum := new(cloudwatch.MetricDatum)
+	datum.SetStatisticValues(&cloudwatch.StatisticSet{
+		Sum:         aws.Float64(6),
+		SampleCount: aws.Float64(3),
+		Minimum:     aws.Float64(1),
+		Maximum:     aws.Float64(3),
+	})
+	datum.SetDimensions([]*cloudwatch.Dimension{
+		{Name: aws.String("DimensionName"), Value: aws.String("DimensionValue")},
+	})
+	datum.SetMetricName("MetricName")
+	datum.SetStorageResolution(1)
+	datum.SetTimestamp(time.Now())
+	datum.SetUnit("None")
+	assert.Equal(t, 555, payload(datum))
+}
+
 func TestPayload_Value(t *testing.T) {
 	datum := new(cloudwatch.MetricDatum)
 	datum.SetValue(1.23456789)
@@ -28,6 +28,8 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent/internal"
 	"github.com/aws/amazon-cloudwatch-agent/internal/retryer"
 	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/ratelimit"
+	"github.com/aws/amazon-cloudwatch-agent/logs/template"
 	"github.com/aws/amazon-cloudwatch-agent/tool/util"
 )
 
@@ -38,10 +40,11 @@ const (
 	LogTimestampField = "log_timestamp"
 	LogEntryField     = "value"
 
-	defaultFlushTimeout = 5 * time.Second
-	eventHeaderSize     = 200
-	truncatedSuffix     = "[Truncated...]"
-	msgSizeLimit        = 256*1024 - eventHeaderSize
+	defaultFlushTimeout   = 5 * time.Second
+	defaultSpoolMaxSizeMB = 100
+	eventHeaderSize       = 200
+	truncatedSuffix       = "[Truncated...]"
+	msgSizeLimit          = 256*1024 - eventHeaderSize
 
 	maxRetryTimeout    = 14*24*time.Hour + 10*time.Minute
 	metricRetryTimeout = 2 * time.Minute
@@ -74,12 +77,43 @@ type CloudWatchLogs struct {
 
 	ForceFlushInterval internal.Duration `toml:"force_flush_interval"` // unit is second
 
+	// Directory to persist batches that exhausted their retries, replayed once
+	// PutLogEvents succeeds again. Spooling is disabled when empty.
+	SpoolDir       string `toml:"spool_dir"`
+	SpoolMaxSizeMB int64  `toml:"spool_max_size_mb"`
+
+	// Rate limits of the events published to a log group by the log pipelines, shared by all
+	// the log streams of the group.
+	LogGroupRateLimits []LogGroupRateLimit `toml:"log_group_rate_limit"`
+
+	// Bound of the destinations of the log group and stream names evaluated for each event, the
+	// least recently used being closed once there are more. The destinations are also closed once
+	// idle for the timeout.
+	MaxDynamicDestinations        int               `toml:"max_dynamic_destinations"`
+	DynamicDestinationIdleTimeout internal.Duration `toml:"dynamic_destination_idle_timeout"`
+
+	// Max number of PutLogEvents requests in flight for each log stream, the done callbacks of the
+	// batches still being called in order. The requests are sent one at a time when not above 1.
+	Concurrency int `toml:"concurrency"`
+
 	Log telegraf.Logger `toml:"-"`
 
 	pusherStopChan  chan struct{}
 	pusherWaitGroup sync.WaitGroup
 	cwDests         map[Target]*cwDest
 	middleware      awsmiddleware.Middleware
+	spool           *spool
+	spoolOnce       sync.Once
+	rateLimiters    map[string]*ratelimit.Limiter
+	rateLimitersMu  sync.Mutex
+	dynamicDests    *destCache
+	dynamicOnce     sync.Once
+}
+
+// LogGroupRateLimit is the rate limit of a log group.
+type LogGroupRateLimit struct {
+	LogGroupName string `toml:"log_group_name"`
+	ratelimit.Config
 }
 
 func (c *CloudWatchLogs) Connect() error {
@@ -93,6 +127,9 @@ func (c *CloudWatchLogs) Close() error {
 	for _, d := range c.cwDests {
 		d.Stop()
 	}
+	if c.dynamicDests != nil {
+		c.dynamicDests.Stop()
+	}
 
 	return nil
 }
@@ -121,13 +158,35 @@ func (c *CloudWatchLogs) CreateDest(group, stream string, retention int, logGrou
 		Retention: retention,
 		Class:     logGroupClass,
 	}
+	if template.IsDynamic(group) || template.IsDynamic(stream) {
+		return c.getRouterDest(t)
+	}
 	return c.getDest(t)
 }
 
+// getRouterDest returns the destination publishing each event to the log group and stream of its
+// names, sharing the bounded cache of destinations with the other router destinations.
+func (c *CloudWatchLogs) getRouterDest(t Target) *routerDest {
+	c.dynamicOnce.Do(func() {
+		c.dynamicDests = newDestCache(c.MaxDynamicDestinations, c.DynamicDestinationIdleTimeout.Duration, c.newDest, c.pusherStopChan, c.Log)
+	})
+	return newRouterDest(c.dynamicDests, t)
+}
+
 func (c *CloudWatchLogs) getDest(t Target) *cwDest {
 	if cwd, ok := c.cwDests[t]; ok {
 		return cwd
 	}
+	cwd := c.newDest(t)
+	c.cwDests[t] = cwd
+	return cwd
+}
+
+// newDest creates the destination of the target, which may be called concurrently by the router
+// destinations.
+func (c *CloudWatchLogs) newDest(t Target) *cwDest {
+	// Connect is not called when the agent runs the OTel pipelines, so the spool is opened with the first destination.
+	c.spoolOnce.Do(c.openSpool)
 
 	credentialConfig := &configaws.CredentialConfig{
 		Region:    c.Region,
@@ -162,12 +221,53 @@ func (c *CloudWatchLogs) getDest(t Target) *cwDest {
 			c.Log.Info("Configured middleware on AWS client")
 		}
 	}
-	pusher := NewPusher(t, client, c.ForceFlushInterval.Duration, maxRetryTimeout, c.Log, c.pusherStopChan, &c.pusherWaitGroup)
+	pusher := NewPusher(t, client, c.ForceFlushInterval.Duration, maxRetryTimeout, c.Log, c.pusherStopChan, &c.pusherWaitGroup, c.spool, c.Concurrency)
 	cwd := &cwDest{pusher: pusher, retryer: logThrottleRetryer}
-	c.cwDests[t] = cwd
+	if limiter := c.getRateLimiter(t.Group); limiter != nil {
+		cwd.publisher = limiter.NewPublisher(cwd.AddEvent, c.pusherStopChan)
+	}
 	return cwd
 }
 
+// getRateLimiter returns the rate limiter of the log group, or nil if the group is not limited.
+func (c *CloudWatchLogs) getRateLimiter(group string) *ratelimit.Limiter {
+	c.rateLimitersMu.Lock()
+	defer c.rateLimitersMu.Unlock()
+	if limiter, ok := c.rateLimiters[group]; ok {
+		return limiter
+	}
+	var limiter *ratelimit.Limiter
+	for _, rl := range c.LogGroupRateLimits {
+		if rl.LogGroupName != group {
+			continue
+		}
+		cfg := rl.Config
+		if err := cfg.Init(); err != nil {
+			c.Log.Errorf("Invalid rate limit for log group %v, the group will not be limited: %v", group, err)
+			break
+		}
+		limiter = ratelimit.NewLimiter(cfg, []string{"cloudwatchlogs", group, "rateLimitDropped"})
+		break
+	}
+	if c.rateLimiters == nil {
+		c.rateLimiters = make(map[string]*ratelimit.Limiter)
+	}
+	c.rateLimiters[group] = limiter
+	return limiter
+}
+
+func (c *CloudWatchLogs) openSpool() {
+	if c.SpoolDir == "" {
+		return
+	}
+	s, err := newSpool(c.SpoolDir, c.SpoolMaxSizeMB*1024*1024)
+	if err != nil {
+		c.Log.Errorf("Unable to use spool directory %v, failed batches will be dropped: %v", c.SpoolDir, err)
+		return
+	}
+	c.spool = s
+}
+
 func (c *CloudWatchLogs) writeMetricAsStructuredLog(m telegraf.Metric) {
 	t, err := c.getTargetFromMetric(m)
 	if err != nil {
@@ -304,6 +404,9 @@ type cwDest struct {
 	isEMF   bool
 	stopped bool
 	retryer *retryer.LogThrottleRetryer
+	// publisher applies the rate limit of the log group to the published events, nil if the
+	// group is not limited
+	publisher *ratelimit.Publisher
 }
 
 func (cd *cwDest) Publish(events []logs.LogEvent) error {
@@ -314,7 +417,11 @@ func (cd *cwDest) Publish(events []logs.LogEvent) error {
 				cd.switchToEMF()
 			}
 		}
-		cd.AddEvent(e)
+		if cd.publisher != nil {
+			cd.publisher.Publish(e)
+		} else {
+			cd.AddEvent(e)
+		}
 	}
 	if cd.stopped {
 		return logs.ErrOutputStopped
@@ -323,10 +430,23 @@ func (cd *cwDest) Publish(events []logs.LogEvent) error {
 }
 
 func (cd *cwDest) Stop() {
+	if cd.publisher != nil {
+		cd.publisher.Close()
+	}
 	cd.retryer.Stop()
 	cd.stopped = true
 }
 
+// close publishes the events of the destination and stops it, for the destinations closed before
+// the output.
+func (cd *cwDest) close() {
+	if cd.publisher != nil {
+		cd.publisher.Close()
+	}
+	cd.pusher.close()
+	cd.retryer.Stop()
+}
+
 func (cd *cwDest) AddEvent(e logs.LogEvent) {
 	// Drop events for metric path logs when queue is full
 	if cd.isEMF {
@@ -386,6 +506,30 @@ var sampleConfig = `
 
   # The log stream name.
   log_stream_name = "<log_stream_name>"
+
+  ## Directory to persist log batches that could not be delivered, replayed
+  ## once the service is reachable again. Disabled when empty.
+  #spool_dir = ""
+  #spool_max_size_mb = 100
+
+  ## Limit the events published to a log group by the log pipelines. The
+  ## overflow policy is block (default), drop_newest, drop_oldest or sample.
+  #[[outputs.cloudwatchlogs.log_group_rate_limit]]
+  #  log_group_name = "noisy-group"
+  #  max_events_per_second = 1000.0
+  #  max_kb_per_second = 1024.0
+  #  overflow_policy = "drop_oldest"
+  #  buffer_size = 1000
+
+  ## Bound of the destinations of the log group and stream names evaluated for
+  ## each event, e.g. "/app/{field:tenant}". The least recently used destination
+  ## is closed once there are more, and the idle ones after the timeout.
+  #max_dynamic_destinations = 100
+  #dynamic_destination_idle_timeout = "5m"
+
+  ## Max number of PutLogEvents requests in flight for each log stream. The
+  ## offsets of the log files are still saved in order.
+  #concurrency = 1
 `
 
 // SampleConfig returns the default configuration of the Output
@@ -396,9 +540,12 @@ func (c *CloudWatchLogs) SampleConfig() string {
 func init() {
 	outputs.Add("cloudwatchlogs", func() telegraf.Output {
 		return &CloudWatchLogs{
-			ForceFlushInterval: internal.Duration{Duration: defaultFlushTimeout},
-			pusherStopChan:     make(chan struct{}),
-			cwDests:            make(map[Target]*cwDest),
+			ForceFlushInterval:            internal.Duration{Duration: defaultFlushTimeout},
+			SpoolMaxSizeMB:                defaultSpoolMaxSizeMB,
+			MaxDynamicDestinations:        defaultMaxDynamicDestinations,
+			DynamicDestinationIdleTimeout: internal.Duration{Duration: defaultDynamicDestinationIdleTimeout},
+			pusherStopChan:                make(chan struct{}),
+			cwDests:                       make(map[Target]*cwDest),
 			middleware: agenthealth.NewAgentHealth(
 				zap.NewNop(),
 				&agenthealth.Config{
@@ -4,10 +4,17 @@
 package cloudwatchlogs
 
 import (
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/models"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/ratelimit"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
 	"github.com/aws/amazon-cloudwatch-agent/tool/util"
 )
 
@@ -89,3 +96,43 @@ func TestDuplicateDestination(t *testing.T) {
 	// Then the destination for cloudwatchlogs endpoint would be the same
 	require.Equal(t, d1, d2)
 }
+
+func TestLogGroupRateLimit(t *testing.T) {
+	c := &CloudWatchLogs{
+		AccessKey: "access_key",
+		SecretKey: "secret_key",
+		LogGroupRateLimits: []LogGroupRateLimit{
+			{LogGroupName: "G1", Config: ratelimit.Config{MaxEventsPerSecond: 4, OverflowPolicy: ratelimit.PolicyDropNewest}},
+			{LogGroupName: "G3", Config: ratelimit.Config{MaxEventsPerSecond: 4, OverflowPolicy: "drop_all"}},
+		},
+		Log:            models.NewLogger("outputs", "cloudwatchlogs", ""),
+		cwDests:        make(map[Target]*cwDest),
+		pusherStopChan: make(chan struct{}),
+	}
+	d1 := c.CreateDest("G1", "S1", -1, "").(*cwDest)
+	d2 := c.CreateDest("G1", "S2", -1, "").(*cwDest)
+	d3 := c.CreateDest("G2", "S1", -1, "").(*cwDest)
+	d4 := c.CreateDest("G3", "S1", -1, "").(*cwDest)
+	require.NotNil(t, d1.publisher)
+	require.NotNil(t, d2.publisher)
+	require.Nil(t, d3.publisher)
+	require.Nil(t, d4.publisher, "Invalid rate limits are ignored")
+
+	// Events out of the accepted time range are discarded by the pusher, both the events dropped by
+	// the rate limit and those discarded being done.
+	droppedKey := "cloudwatchlogs_G1_rateLimitDropped"
+	droppedBefore := profiler.Profiler.GetStats()[droppedKey]
+	var done atomic.Int32
+	e := evtMock{"MSG", time.Now().Add(-30 * 24 * time.Hour), func() { done.Add(1) }}
+	for i := 0; i < 5; i++ {
+		require.NoError(t, d1.Publish([]logs.LogEvent{e}))
+		require.NoError(t, d2.Publish([]logs.LogEvent{e}))
+		require.NoError(t, d3.Publish([]logs.LogEvent{e}))
+	}
+	assert.EqualValues(t, 15, done.Load())
+	// The log streams of the group share its limit
+	assert.EqualValues(t, 6, profiler.Profiler.GetStats()[droppedKey]-droppedBefore)
+
+	close(c.pusherStopChan)
+	c.pusherWaitGroup.Wait()
+}
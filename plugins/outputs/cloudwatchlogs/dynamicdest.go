@@ -0,0 +1,241 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatchlogs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+	"github.com/aws/amazon-cloudwatch-agent/logs/template"
+	"github.com/aws/amazon-cloudwatch-agent/profiler"
+)
+
+const (
+	defaultMaxDynamicDestinations        = 100
+	defaultDynamicDestinationIdleTimeout = 5 * time.Minute
+	// evictionWarnInterval limits the warnings of the destinations evicted because the cache is full.
+	evictionWarnInterval = time.Minute
+)
+
+// routerDest is the destination of a source whose log group or stream names are evaluated for each
+// event. Each event is published to the destination of its names, the names of the source being
+// used with unknown values for the events without names.
+type routerDest struct {
+	cache *destCache
+	// group and stream are the names of the events without names, evaluated once.
+	group     string
+	stream    string
+	retention int
+	class     string
+}
+
+var _ logs.LogDest = (*routerDest)(nil)
+
+func newRouterDest(cache *destCache, t Target) *routerDest {
+	return &routerDest{
+		cache:     cache,
+		group:     template.Parse(t.Group).Execute(nil),
+		stream:    template.Parse(t.Stream).Execute(nil),
+		retention: t.Retention,
+		class:     t.Class,
+	}
+}
+
+func (r *routerDest) Publish(events []logs.LogEvent) error {
+	for _, e := range events {
+		t := Target{Class: r.class, Retention: r.retention}
+		if re, ok := e.(logs.RoutedLogEvent); ok {
+			t.Group, t.Stream = re.Group(), re.Stream()
+		}
+		if t.Group == "" {
+			t.Group = r.group
+		}
+		if t.Stream == "" {
+			t.Stream = r.stream
+		}
+		if err := r.cache.publish(t, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// destCache holds the destinations of the router destinations, shared by all the sources. Once it
+// holds maxSize destinations, the least recently used one is closed for each new destination. The
+// destinations are also closed once no event was published to them for idleTimeout. Closing a
+// destination sends its events, so that they are not lost.
+type destCache struct {
+	maxSize     int
+	idleTimeout time.Duration
+	newDest     func(Target) *cwDest
+	stop        <-chan struct{}
+	log         telegraf.Logger
+
+	mu                 sync.Mutex
+	dests              map[Target]*cachedDest
+	retentionAttempted map[string]bool
+	lastEvictionWarn   time.Time
+	uses               uint64
+	startOnce          sync.Once
+}
+
+type cachedDest struct {
+	*cwDest
+	lastUsed time.Time
+	// lastUse orders the uses of the destinations, which may happen at the same time.
+	lastUse uint64
+
+	// publishMu is held while the events are added, so that the destination is not closed meanwhile.
+	publishMu sync.Mutex
+	closed    bool
+}
+
+func newDestCache(maxSize int, idleTimeout time.Duration, newDest func(Target) *cwDest, stop <-chan struct{}, log telegraf.Logger) *destCache {
+	if maxSize <= 0 {
+		maxSize = defaultMaxDynamicDestinations
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = defaultDynamicDestinationIdleTimeout
+	}
+	return &destCache{
+		maxSize:            maxSize,
+		idleTimeout:        idleTimeout,
+		newDest:            newDest,
+		stop:               stop,
+		log:                log,
+		dests:              make(map[Target]*cachedDest),
+		retentionAttempted: make(map[string]bool),
+	}
+}
+
+func (c *destCache) publish(t Target, e logs.LogEvent) error {
+	for {
+		d, err := c.get(t)
+		if err != nil {
+			return err
+		}
+		d.publishMu.Lock()
+		if d.closed {
+			// the destination was evicted after it was found, so a new one is created
+			d.publishMu.Unlock()
+			continue
+		}
+		err = d.Publish([]logs.LogEvent{e})
+		d.publishMu.Unlock()
+		return err
+	}
+}
+
+func (c *destCache) get(t Target) (*cachedDest, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.stop:
+		return nil, logs.ErrOutputStopped
+	default:
+	}
+	now := time.Now()
+	c.uses++
+	if d, ok := c.dests[t]; ok {
+		d.lastUsed, d.lastUse = now, c.uses
+		return d, nil
+	}
+	if len(c.dests) >= c.maxSize {
+		c.evictOldest(now)
+	}
+	// the retention of a log group is only set by its first log stream, like the log agent does
+	pt := t
+	if pt.Retention > 0 {
+		if c.retentionAttempted[pt.Group] {
+			pt.Retention = -1
+		} else {
+			c.retentionAttempted[pt.Group] = true
+		}
+	}
+	d := &cachedDest{cwDest: c.newDest(pt), lastUsed: now, lastUse: c.uses}
+	c.dests[t] = d
+	c.startOnce.Do(func() {
+		go c.runEviction()
+	})
+	return d, nil
+}
+
+func (c *destCache) evictOldest(now time.Time) {
+	var oldestTarget Target
+	var oldest *cachedDest
+	for t, d := range c.dests {
+		if oldest == nil || d.lastUse < oldest.lastUse {
+			oldestTarget, oldest = t, d
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	if now.Sub(c.lastEvictionWarn) >= evictionWarnInterval {
+		c.log.Warnf("Too many log destinations, closing the least recently used %v/%v. Increase max_dynamic_destinations if the destinations are still in use.", oldestTarget.Group, oldestTarget.Stream)
+		c.lastEvictionWarn = now
+	}
+	profiler.Profiler.AddStats([]string{"cloudwatchlogs", "dynamicDestinationsEvicted"}, 1)
+	c.evict(oldestTarget, oldest)
+}
+
+// evict removes the destination from the cache and closes it once the events being added are. The
+// retention of its log group is forgotten with the last destination of the group.
+func (c *destCache) evict(t Target, d *cachedDest) {
+	delete(c.dests, t)
+	if c.retentionAttempted[t.Group] && !c.hasGroup(t.Group) {
+		delete(c.retentionAttempted, t.Group)
+	}
+	go func() {
+		d.publishMu.Lock()
+		d.closed = true
+		d.publishMu.Unlock()
+		d.close()
+	}()
+}
+
+func (c *destCache) hasGroup(group string) bool {
+	for t := range c.dests {
+		if t.Group == group {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *destCache) runEviction() {
+	ticker := time.NewTicker(c.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.evictIdle(time.Now())
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *destCache) evictIdle(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, d := range c.dests {
+		if now.Sub(d.lastUsed) >= c.idleTimeout {
+			c.log.Debugf("Closing idle log destination %v/%v", t.Group, t.Stream)
+			c.evict(t, d)
+		}
+	}
+}
+
+// Stop stops the destinations once the output is closed.
+func (c *destCache) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, d := range c.dests {
+		d.Stop()
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatchlogs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/influxdata/telegraf/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/internal/retryer"
+	"github.com/aws/amazon-cloudwatch-agent/logs"
+)
+
+type routedEvtMock struct {
+	evtMock
+	group, stream string
+}
+
+func (e routedEvtMock) Group() string  { return e.group }
+func (e routedEvtMock) Stream() string { return e.stream }
+
+// testDestCache creates the destinations with a service recording the messages sent to each target,
+// and a flush timeout long enough for the events to only be sent once their destination is closed.
+type testDestCache struct {
+	*destCache
+	mu         sync.Mutex
+	sent       map[string][]string
+	retentions map[string]int
+	created    int
+	wg         sync.WaitGroup
+}
+
+func newTestDestCache(maxSize int, idleTimeout time.Duration, stop chan struct{}) *testDestCache {
+	tc := &testDestCache{sent: map[string][]string{}, retentions: map[string]int{}}
+	logger := models.NewLogger("outputs", "cloudwatchlogs", "")
+	newDest := func(t Target) *cwDest {
+		tc.mu.Lock()
+		tc.created++
+		tc.retentions[t.Group+"/"+t.Stream] = t.Retention
+		tc.mu.Unlock()
+		s := &svcMock{ple: func(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+			tc.mu.Lock()
+			defer tc.mu.Unlock()
+			key := *in.LogGroupName + "/" + *in.LogStreamName
+			for _, e := range in.LogEvents {
+				tc.sent[key] = append(tc.sent[key], *e.Message)
+			}
+			return &cloudwatchlogs.PutLogEventsOutput{}, nil
+		}}
+		return &cwDest{
+			pusher:  NewPusher(t, s, time.Hour, maxRetryTimeout, logger, stop, &tc.wg, nil, 1),
+			retryer: retryer.NewLogThrottleRetryer(logger),
+		}
+	}
+	tc.destCache = newDestCache(maxSize, idleTimeout, newDest, stop, logger)
+	return tc
+}
+
+func (tc *testDestCache) messages(key string) []string {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return append([]string(nil), tc.sent[key]...)
+}
+
+func TestRouterDest(t *testing.T) {
+	stop := make(chan struct{})
+	tc := newTestDestCache(2, time.Hour, stop)
+	r := newRouterDest(tc.destCache, Target{Group: "/app/{field:tenant}", Stream: "{field:host}", Retention: 7})
+
+	var done sync.WaitGroup
+	event := func(msg, group, stream string) logs.LogEvent {
+		done.Add(1)
+		return routedEvtMock{evtMock{msg, time.Now(), done.Done}, group, stream}
+	}
+	require.NoError(t, r.Publish([]logs.LogEvent{
+		event("a1", "/app/a", "h1"),
+		event("a2", "/app/a", "h2"),
+		event("a3", "/app/a", "h1"),
+	}))
+	// the least recently used destination is closed for the third one, sending its events
+	require.NoError(t, r.Publish([]logs.LogEvent{event("b1", "/app/b", "h1")}))
+	assert.Eventually(t, func() bool { return len(tc.messages("/app/a/h2")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, tc.messages("/app/a/h1"))
+
+	// the events without names use the names of the source
+	done.Add(1)
+	require.NoError(t, r.Publish([]logs.LogEvent{evtMock{"u1", time.Now(), done.Done}}))
+	require.NoError(t, r.Publish([]logs.LogEvent{event("a4", "/app/a", "h2")}))
+	assert.Eventually(t, func() bool {
+		return len(tc.messages("/app/a/h1")) == 2 && len(tc.messages("/app/b/h1")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"a1", "a3"}, tc.messages("/app/a/h1"))
+
+	// the retention of a group is only set by its first stream, /app/a/h2 being created again once
+	// all the destinations of /app/a were closed
+	assert.Equal(t, map[string]int{"/app/a/h1": 7, "/app/a/h2": 7, "/app/b/h1": 7, "/app/unknown/unknown": 7}, tc.retentions)
+	assert.Equal(t, 5, tc.created)
+
+	close(stop)
+	tc.wg.Wait()
+	tc.Stop()
+	// the events of the destinations still open are sent once the output stops
+	assert.Equal(t, []string{"a2", "a4"}, tc.messages("/app/a/h2"))
+	assert.Equal(t, []string{"u1"}, tc.messages("/app/unknown/unknown"))
+	done.Wait()
+	assert.ErrorIs(t, r.Publish([]logs.LogEvent{event("a5", "/app/a", "h1")}), logs.ErrOutputStopped)
+}
+
+func TestDestCacheIdleEviction(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	tc := newTestDestCache(10, 100*time.Millisecond, stop)
+	r := newRouterDest(tc.destCache, Target{Group: "{field:tenant}", Stream: "s", Retention: 7})
+
+	require.NoError(t, r.Publish([]logs.LogEvent{routedEvtMock{evtMock{m: "m1", t: time.Now()}, "g", "s"}}))
+	assert.Eventually(t, func() bool { return len(tc.messages("g/s")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.destCache.mu.Lock()
+	defer tc.destCache.mu.Unlock()
+	assert.Empty(t, tc.destCache.dests)
+	assert.Empty(t, tc.destCache.retentionAttempted)
+}
+
+func TestCreateRouterDestination(t *testing.T) {
+	c := &CloudWatchLogs{
+		LogGroupName:   "G1",
+		LogStreamName:  "S1",
+		AccessKey:      "access_key",
+		SecretKey:      "secret_key",
+		pusherStopChan: make(chan struct{}),
+		cwDests:        make(map[Target]*cwDest),
+	}
+	r1, ok := c.CreateDest("/app/{field:tenant}", "", 7, "").(*routerDest)
+	require.True(t, ok)
+	assert.Equal(t, "S1", r1.stream)
+	assert.Equal(t, 7, r1.retention)
+	r2, ok := c.CreateDest("G2", "{field:host}", -1, "").(*routerDest)
+	require.True(t, ok)
+	assert.Same(t, r1.cache, r2.cache)
+	assert.Equal(t, defaultMaxDynamicDestinations, r1.cache.maxSize)
+	_, ok = c.CreateDest("/app/{file:basename}", "", -1, "").(*cwDest)
+	assert.True(t, ok)
+	assert.Empty(t, r1.cache.dests)
+	close(c.pusherStopChan)
+}
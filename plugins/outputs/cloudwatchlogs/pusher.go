@@ -23,6 +23,8 @@ const (
 	reqEventsLimit              = 10000
 	warnOldTimeStamp            = 1 * 24 * time.Hour
 	warnOldTimeStampLogInterval = 1 * 5 * time.Minute
+	// maxEventAge is the age of the events after which they are rejected by PutLogEvents.
+	maxEventAge = 14 * 24 * time.Hour
 )
 
 var (
@@ -56,14 +58,38 @@ type pusher struct {
 	lastWarnMessage     time.Time
 	needSort            bool
 	stop                <-chan struct{}
+	stopped             chan struct{}
 	lastSentTime        time.Time
 
 	initNonBlockingChOnce sync.Once
 	startNonBlockCh       chan struct{}
 	wg                    *sync.WaitGroup
+	spool                 *spool
+
+	// batchCh hands the batches over to the senders when more than one batch is sent at a time.
+	batchCh  chan *logEventBatch
+	senders  sync.WaitGroup
+	replayMu sync.Mutex
+
+	inFlightMu sync.Mutex
+	// inFlight are the batches handed over to the senders and not finished yet or following a
+	// batch which is not, oldest first.
+	inFlight []*logEventBatch
+	// notDone is set once a batch finished without being done, the batches following it not being
+	// done either.
+	notDone bool
 }
 
-func NewPusher(target Target, service CloudWatchLogsService, flushTimeout time.Duration, retryDuration time.Duration, logger telegraf.Logger, stop <-chan struct{}, wg *sync.WaitGroup) *pusher {
+// logEventBatch is a batch of events sent by one of the senders of the pusher.
+type logEventBatch struct {
+	events        []*cloudwatchlogs.InputLogEvent
+	doneCallbacks []func()
+	size          int
+	finished      bool
+	done          bool
+}
+
+func NewPusher(target Target, service CloudWatchLogsService, flushTimeout time.Duration, retryDuration time.Duration, logger telegraf.Logger, stop <-chan struct{}, wg *sync.WaitGroup, spool *spool, concurrency int) *pusher {
 	p := &pusher{
 		Target:          target,
 		Service:         service,
@@ -74,10 +100,19 @@ func NewPusher(target Target, service CloudWatchLogsService, flushTimeout time.D
 		eventsCh:        make(chan logs.LogEvent, 100),
 		flushTimer:      time.NewTimer(flushTimeout),
 		stop:            stop,
+		stopped:         make(chan struct{}),
 		startNonBlockCh: make(chan struct{}),
 		wg:              wg,
+		spool:           spool,
 	}
 	p.putRetentionPolicy()
+	if concurrency > 1 {
+		p.batchCh = make(chan *logEventBatch)
+		p.senders.Add(concurrency)
+		for i := 0; i < concurrency; i++ {
+			go p.runSender()
+		}
+	}
 	p.wg.Add(1)
 	go p.start()
 	return p
@@ -86,14 +121,18 @@ func NewPusher(target Target, service CloudWatchLogsService, flushTimeout time.D
 func (p *pusher) AddEvent(e logs.LogEvent) {
 	if !hasValidTime(e) {
 		p.Log.Errorf("The log entry in (%v/%v) with timestamp (%v) comparing to the current time (%v) is out of accepted time range. Discard the log entry.", p.Group, p.Stream, e.Time(), time.Now())
+		e.Done()
 		return
 	}
 	p.eventsCh <- e
 }
 
+// AddEventNonBlocking adds the event, dropping the oldest events waiting to be batched when too
+// many are. The events dropped are done, so that their sources move past them.
 func (p *pusher) AddEventNonBlocking(e logs.LogEvent) {
 	if !hasValidTime(e) {
 		p.Log.Errorf("The log entry in (%v/%v) with timestamp (%v) comparing to the current time (%v) is out of accepted time range. Discard the log entry.", p.Group, p.Stream, e.Time(), time.Now())
+		e.Done()
 		return
 	}
 
@@ -108,12 +147,27 @@ func (p *pusher) AddEventNonBlocking(e logs.LogEvent) {
 		case p.nonBlockingEventsCh <- e:
 			return
 		default:
-			<-p.nonBlockingEventsCh
-			p.addStats("emfMetricDrop", 1)
+			// the events may have been taken by the merge of the channels meanwhile
+			select {
+			case dropped := <-p.nonBlockingEventsCh:
+				dropped.Done()
+				p.addStats("emfMetricDrop", 1)
+			default:
+			}
 		}
 	}
 }
 
+// close sends the events added before and returns once the pusher is stopped. No event must be
+// added once the pusher is closed.
+func (p *pusher) close() {
+	select {
+	case p.eventsCh <- nil:
+	case <-p.stop:
+	}
+	<-p.stopped
+}
+
 func hasValidTime(e logs.LogEvent) bool {
 	//http://docs.aws.amazon.com/goto/SdkForGoV1/logs-2014-03-28/PutLogEvents
 	//* None of the log events in the batch can be more than 2 hours in the future.
@@ -130,6 +184,8 @@ func hasValidTime(e logs.LogEvent) bool {
 
 func (p *pusher) start() {
 	defer p.wg.Done()
+	defer close(p.stopped)
+	defer p.stopSenders()
 
 	ec := make(chan logs.LogEvent)
 
@@ -139,6 +195,9 @@ func (p *pusher) start() {
 			select {
 			case e := <-p.eventsCh:
 				ec <- e
+				if e == nil {
+					return
+				}
 			case e := <-p.nonBlockingEventsCh:
 				ec <- e
 			case <-p.startNonBlockCh:
@@ -151,6 +210,13 @@ func (p *pusher) start() {
 	for {
 		select {
 		case e := <-ec:
+			// The pusher is closed after its last event
+			if e == nil {
+				if len(p.events) > 0 {
+					p.send()
+				}
+				return
+			}
 			// Start timer when first event of the batch is added (happens after a flush timer timeout)
 			if len(p.events) == 0 {
 				p.resetFlushTimer()
@@ -187,6 +253,10 @@ func (p *pusher) start() {
 			if time.Since(p.lastSentTime) >= p.FlushTimeout && len(p.events) > 0 {
 				p.send()
 			} else {
+				if len(p.events) == 0 {
+					// the spooled batches are replayed while there are no events to send too
+					p.replaySpool()
+				}
 				p.resetFlushTimer()
 			}
 		case <-p.stop:
@@ -219,21 +289,46 @@ func (p *pusher) send() {
 		sort.Stable(ByTimestamp(p.events))
 	}
 
+	if p.batchCh != nil {
+		p.dispatch()
+		return
+	}
+
+	if p.putLogEvents(p.events, p.bufferredSize) {
+		callDone(p.doneCallbacks)
+		p.lastSentTime = time.Now()
+	}
+	p.reset()
+}
+
+// putLogEvents sends the events, retrying until the retry duration is exceeded. It returns whether
+// the events are done with: sent, spooled to disk or rejected by the service. The events dropped
+// as they can't be sent without a spool, or as the pusher stops before they are sent, are not done,
+// so that their sources don't move past them and read them again once the agent restarts.
+func (p *pusher) putLogEvents(events []*cloudwatchlogs.InputLogEvent, size int) bool {
 	input := &cloudwatchlogs.PutLogEventsInput{
-		LogEvents:     p.events,
+		LogEvents:     events,
 		LogGroupName:  &p.Group,
 		LogStreamName: &p.Stream,
-		SequenceToken: p.sequenceToken,
+	}
+
+	// the spooled batches are sent first, the batch being spooled behind them while they can't be
+	// sent or are being replayed by another sender, so that the events are sent in order
+	if !p.replaySpool() && p.spoolBatch(events) {
+		p.Log.Debugf("Spooled %v log events for %v/%v behind the batches waiting to be replayed", len(events), p.Group, p.Stream)
+		return true
 	}
 
 	startTime := time.Now()
 
 	retryCount := 0
 	for {
-		input.SequenceToken = p.sequenceToken
+		if p.usesSequenceToken() {
+			input.SequenceToken = p.sequenceToken
+		}
 		output, err := p.Service.PutLogEvents(input)
 		if err == nil {
-			if output.NextSequenceToken != nil {
+			if output.NextSequenceToken != nil && p.usesSequenceToken() {
 				p.sequenceToken = output.NextSequenceToken
 			}
 			if output.RejectedLogEventsInfo != nil {
@@ -248,26 +343,20 @@ func (p *pusher) send() {
 					p.Log.Warnf("%d log events for log '%s/%s' are expired", *info.ExpiredLogEventEndIndex, p.Group, p.Stream)
 				}
 			}
-			for i := len(p.doneCallbacks) - 1; i >= 0; i-- {
-				done := p.doneCallbacks[i]
-				done()
-			}
-
-			p.Log.Debugf("Pusher published %v log events to group: %v stream: %v with size %v KB in %v.", len(p.events), p.Group, p.Stream, p.bufferredSize/1024, time.Since(startTime))
-			p.addStats("rawSize", float64(p.bufferredSize))
-
-			p.reset()
-			p.lastSentTime = time.Now()
 
-			return
+			p.Log.Debugf("Pusher published %v log events to group: %v stream: %v with size %v KB in %v.", len(events), p.Group, p.Stream, size/1024, time.Since(startTime))
+			p.addStats("rawSize", float64(size))
+			return true
 		}
 
 		awsErr, ok := err.(awserr.Error)
 		if !ok {
-			p.Log.Errorf("Non aws error received when sending logs to %v/%v: %v. CloudWatch agent will not retry and logs will be missing!", p.Group, p.Stream, err)
-			// Messages will be discarded but done callbacks not called
-			p.reset()
-			return
+			if p.spoolBatch(events) {
+				p.Log.Errorf("Non aws error received when sending logs to %v/%v: %v. Request spooled to disk.", p.Group, p.Stream, err)
+				return true
+			}
+			p.Log.Errorf("Non aws error received when sending logs to %v/%v: %v. CloudWatch agent will not retry and logs will be missing until they are read again!", p.Group, p.Stream, err)
+			return false
 		}
 
 		switch e := awsErr.(type) {
@@ -279,6 +368,10 @@ func (p *pusher) send() {
 			}
 			p.putRetentionPolicy()
 		case *cloudwatchlogs.InvalidSequenceTokenException:
+			if !p.usesSequenceToken() {
+				p.Log.Warnf("Invalid SequenceToken while sending logs to %v/%v without token, will retry: %v", p.Group, p.Stream, e.Message())
+				break
+			}
 			if p.sequenceToken == nil {
 				p.Log.Infof("First time sending logs to %v/%v since startup so sequenceToken is nil, learned new token:(%v): %v", p.Group, p.Stream, e.ExpectedSequenceToken, e.Message())
 			} else {
@@ -291,32 +384,243 @@ func (p *pusher) send() {
 		case *cloudwatchlogs.InvalidParameterException,
 			*cloudwatchlogs.DataAlreadyAcceptedException:
 			p.Log.Errorf("%v, will not retry the request", e)
-			p.reset()
-			return
+			return true
 		default:
 			p.Log.Errorf("Aws error received when sending logs to %v/%v: %v", p.Group, p.Stream, awsErr)
 		}
 
 		wait := retryWait(retryCount)
 		if time.Since(startTime)+wait > p.RetryDuration {
+			if p.spoolBatch(events) {
+				p.Log.Errorf("All %v retries to %v/%v failed for PutLogEvents, request spooled to disk.", retryCount, p.Group, p.Stream)
+				return true
+			}
 			p.Log.Errorf("All %v retries to %v/%v failed for PutLogEvents, request dropped.", retryCount, p.Group, p.Stream)
-			p.reset()
-			return
+			return false
 		}
 
 		p.Log.Warnf("Retried %v time, going to sleep %v before retrying.", retryCount, wait)
 
 		select {
 		case <-p.stop:
+			if p.spoolBatch(events) {
+				p.Log.Errorf("Stop requested after %v retries to %v/%v failed for PutLogEvents, request spooled to disk.", retryCount, p.Group, p.Stream)
+				return true
+			}
 			p.Log.Errorf("Stop requested after %v retries to %v/%v failed for PutLogEvents, request dropped.", retryCount, p.Group, p.Stream)
-			p.reset()
-			return
+			return false
 		case <-time.After(wait):
 		}
 
 		retryCount++
 	}
+}
+
+// usesSequenceToken returns whether the batches are sent one at a time with the sequence token of
+// the stream. The batches sent concurrently are sent without, PutLogEvents no longer requiring it.
+func (p *pusher) usesSequenceToken() bool {
+	return p.batchCh == nil
+}
+
+// dispatch hands the current batch over to a sender, waiting for one to be available so that no
+// more than concurrency batches are buffered.
+func (p *pusher) dispatch() {
+	b := &logEventBatch{
+		events:        append([]*cloudwatchlogs.InputLogEvent(nil), p.events...),
+		doneCallbacks: append([]func(){}, p.doneCallbacks...),
+		size:          p.bufferredSize,
+	}
+	p.reset()
+
+	p.inFlightMu.Lock()
+	p.inFlight = append(p.inFlight, b)
+	p.reportQueue()
+	p.inFlightMu.Unlock()
+
+	p.batchCh <- b
+	p.lastSentTime = time.Now()
+}
+
+func (p *pusher) runSender() {
+	defer p.senders.Done()
+	for b := range p.batchCh {
+		p.finish(b, p.putLogEvents(b.events, b.size))
+	}
+}
+
+// finish marks the batch as no longer sent. The done callbacks of the batches done with are only
+// called once all the earlier batches are finished and done, so that the offsets are committed in
+// order. A batch which is not done, as it was dropped or the pusher stopped before it was sent,
+// holds the batches following it, which are not done either so that they are all read again once
+// the agent restarts.
+func (p *pusher) finish(b *logEventBatch, done bool) {
+	p.inFlightMu.Lock()
+	defer p.inFlightMu.Unlock()
+	b.finished, b.done = true, done
+	n := 0
+	for ; n < len(p.inFlight) && p.inFlight[n].finished; n++ {
+		if !p.inFlight[n].done {
+			p.notDone = true
+		}
+		if !p.notDone {
+			callDone(p.inFlight[n].doneCallbacks)
+		}
+		p.inFlight[n] = nil
+	}
+	p.inFlight = p.inFlight[n:]
+	p.reportQueue()
+}
 
+// reportQueue reports the batches in flight and the events waiting to be batched, inFlightMu being
+// held.
+func (p *pusher) reportQueue() {
+	p.setStats("inFlightBatches", float64(len(p.inFlight)))
+	p.setStats("queueDepth", float64(len(p.eventsCh)))
+}
+
+// stopSenders waits for the batches in flight once the last batch is dispatched.
+func (p *pusher) stopSenders() {
+	if p.batchCh == nil {
+		return
+	}
+	close(p.batchCh)
+	p.senders.Wait()
+}
+
+// callDone calls the done callbacks of a batch, the last event first.
+func callDone(doneCallbacks []func()) {
+	for i := len(doneCallbacks) - 1; i >= 0; i-- {
+		done := doneCallbacks[i]
+		done()
+	}
+}
+
+// spoolBatch writes the batch to the spool if one is configured. The batch is
+// delivered once durably stored, as the spool has taken over responsibility
+// for sending it.
+func (p *pusher) spoolBatch(events []*cloudwatchlogs.InputLogEvent) bool {
+	if p.spool == nil || len(events) == 0 {
+		return false
+	}
+	if err := p.spool.write(p.Target, events); err != nil {
+		p.Log.Errorf("Unable to spool %v log events for %v/%v: %v", len(events), p.Group, p.Stream, err)
+		return false
+	}
+	p.addStats("spooledEvents", float64(len(events)))
+	return true
+}
+
+// replaySpool sends the spooled batches of the target, oldest first, and returns whether none is
+// left. It stops at the first batch failing with an error which may not happen again, leaving it
+// and the batches following it on disk until the next replay, while the batches which will never be
+// accepted are dropped so that they don't hold the others forever.
+func (p *pusher) replaySpool() bool {
+	if p.spool == nil || !p.spool.hasPending(p.Target) {
+		return true
+	}
+	// the spool is replayed by one sender at a time, the others spooling their batches behind the
+	// batches left
+	if !p.replayMu.TryLock() {
+		return !p.spool.hasPending(p.Target)
+	}
+	defer p.replayMu.Unlock()
+	files, err := p.spool.pending(p.Target)
+	if err != nil {
+		p.Log.Errorf("Unable to list spooled log events for %v/%v: %v", p.Group, p.Stream, err)
+		return false
+	}
+	for _, f := range files {
+		events, err := p.spool.read(f)
+		if err != nil {
+			p.Log.Errorf("Unable to read spooled log events from %v, discarding: %v", f, err)
+			p.addStats("spoolDroppedBatches", 1)
+			p.spool.remove(f)
+			continue
+		}
+		events = p.dropExpiredEvents(events)
+		if len(events) > 0 {
+			if err = p.replayBatch(events); err != nil {
+				if !isPermanentError(err) {
+					p.Log.Warnf("Unable to replay spooled log events to %v/%v, will retry: %v", p.Group, p.Stream, err)
+					return false
+				}
+				p.Log.Errorf("Spooled log events rejected by %v/%v, dropping %v log events from %v: %v", p.Group, p.Stream, len(events), f, err)
+				p.addStats("spoolDroppedEvents", float64(len(events)))
+			} else {
+				p.Log.Debugf("Pusher replayed %v spooled log events to group: %v stream: %v", len(events), p.Group, p.Stream)
+				p.addStats("spoolReplayedEvents", float64(len(events)))
+			}
+		}
+		if err = p.spool.remove(f); err != nil {
+			p.Log.Errorf("Unable to remove replayed spool file %v: %v", f, err)
+		}
+	}
+	return true
+}
+
+// dropExpiredEvents drops the spooled events which are too old to be accepted by PutLogEvents.
+func (p *pusher) dropExpiredEvents(events []*cloudwatchlogs.InputLogEvent) []*cloudwatchlogs.InputLogEvent {
+	valid := events[:0]
+	for _, e := range events {
+		if time.Since(time.UnixMilli(*e.Timestamp)) < maxEventAge {
+			valid = append(valid, e)
+		}
+	}
+	if expired := len(events) - len(valid); expired > 0 {
+		p.Log.Errorf("Dropping %v spooled log events for %v/%v older than %v", expired, p.Group, p.Stream, maxEventAge)
+		p.addStats("spoolDroppedEvents", float64(expired))
+	}
+	return valid
+}
+
+// replayBatch sends a spooled batch once, creating the log group and stream or updating the
+// sequence token first if needed.
+func (p *pusher) replayBatch(events []*cloudwatchlogs.InputLogEvent) error {
+	input := &cloudwatchlogs.PutLogEventsInput{
+		LogEvents:     events,
+		LogGroupName:  &p.Group,
+		LogStreamName: &p.Stream,
+	}
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if p.usesSequenceToken() {
+			input.SequenceToken = p.sequenceToken
+		}
+		var output *cloudwatchlogs.PutLogEventsOutput
+		output, err = p.Service.PutLogEvents(input)
+		if err == nil {
+			if output != nil && output.NextSequenceToken != nil && p.usesSequenceToken() {
+				p.sequenceToken = output.NextSequenceToken
+			}
+			return nil
+		}
+		switch e := err.(type) {
+		case *cloudwatchlogs.ResourceNotFoundException:
+			if cerr := p.createLogGroupAndStream(); cerr != nil {
+				return err
+			}
+			p.putRetentionPolicy()
+		case *cloudwatchlogs.InvalidSequenceTokenException:
+			if !p.usesSequenceToken() || e.ExpectedSequenceToken == nil {
+				return err
+			}
+			p.sequenceToken = e.ExpectedSequenceToken
+		default:
+			return err
+		}
+	}
+	return err
+}
+
+// isPermanentError returns whether PutLogEvents will fail with the error however many times the
+// request is sent.
+func isPermanentError(err error) bool {
+	switch err.(type) {
+	case *cloudwatchlogs.InvalidParameterException,
+		*cloudwatchlogs.DataAlreadyAcceptedException:
+		return true
+	}
+	return false
 }
 
 func retryWait(n int) time.Duration {
@@ -454,6 +758,11 @@ func (p *pusher) addStats(statsName string, value float64) {
 	profiler.Profiler.AddStats(statsKey, value)
 }
 
+func (p *pusher) setStats(statsName string, value float64) {
+	statsKey := []string{"cloudwatchlogs", p.Group, p.Stream, statsName}
+	profiler.Profiler.SetStats(statsKey, value)
+}
+
 type ByTimestamp []*cloudwatchlogs.InputLogEvent
 
 func (inputLogEvents ByTimestamp) Len() int {
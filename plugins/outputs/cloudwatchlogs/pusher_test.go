@@ -21,6 +21,7 @@ import (
 	"github.com/influxdata/telegraf/models"
 	"github.com/stretchr/testify/require"
 
+	"github.com/aws/amazon-cloudwatch-agent/logs/acktracker"
 	"github.com/aws/amazon-cloudwatch-agent/tool/util"
 )
 
@@ -445,7 +446,8 @@ func TestUnhandledErrorWouldNotResend(t *testing.T) {
 	log.SetOutput(io.MultiWriter(&logbuf, os.Stdout))
 
 	stop, p := testPreparation(-1, &s, 1*time.Hour, maxRetryTimeout)
-	p.AddEvent(evtMock{"msg", time.Now(), nil})
+	done := false
+	p.AddEvent(evtMock{"msg", time.Now(), func() { done = true }})
 	p.FlushTimeout = 10 * time.Millisecond
 	time.Sleep(2 * time.Second)
 
@@ -458,6 +460,7 @@ func TestUnhandledErrorWouldNotResend(t *testing.T) {
 	close(stop)
 	wg.Wait()
 	require.Equal(t, 1, cnt, fmt.Sprintf("Expecting pusher to call send 1 time, but %d times called", cnt))
+	require.False(t, done, "The dropped event should not be done so that it is read again")
 }
 
 func TestCreateLogGroupAndLogStreamWhenNotFound(t *testing.T) {
@@ -763,8 +766,140 @@ func TestResendWouldStopAfterExhaustedRetries(t *testing.T) {
 	wg.Wait()
 }
 
+func TestConcurrentBatchesAreDoneInOrder(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var sent, done []string
+	var s svcMock
+	s.ple = func(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+		if in.SequenceToken != nil {
+			t.Errorf("PutLogEvents called with a sequenceToken while sending concurrently")
+		}
+		msg := *in.LogEvents[0].Message
+		switch msg {
+		case "m0":
+			<-release
+		case "m1":
+			return nil, errors.New("dropped")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, msg)
+		return &cloudwatchlogs.PutLogEventsOutput{NextSequenceToken: aws.String("token")}, nil
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	var pwg sync.WaitGroup
+	p := NewPusher(Target{"G", "S", util.StandardLogGroupClass, -1}, &s, time.Hour, time.Second, models.NewLogger("cloudwatchlogs", "test", ""), stop, &pwg, nil, 3)
+
+	// the events span more than 24 hours, so that each event is sent in its own batch
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		msg := fmt.Sprintf("m%d", i)
+		p.AddEvent(evtMock{msg, now.Add(time.Duration(i*2-13) * 24 * time.Hour), func() {
+			mu.Lock()
+			defer mu.Unlock()
+			done = append(done, msg)
+		}})
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the batch sent after the first one is not done while the first one is still in flight
+	mu.Lock()
+	require.Equal(t, []string{"m2"}, sent)
+	require.Empty(t, done)
+	mu.Unlock()
+	p.inFlightMu.Lock()
+	require.Len(t, p.inFlight, 3)
+	p.inFlightMu.Unlock()
+
+	close(release)
+	p.close()
+	require.ElementsMatch(t, []string{"m0", "m2", "m3"}, sent)
+	// the dropped batch is not done, nor the batches following it, so that they are read again
+	require.Equal(t, []string{"m0"}, done)
+	require.Empty(t, p.inFlight)
+	require.Nil(t, p.sequenceToken)
+}
+
+func TestConcurrentBatchesAreHeldByBatchNotDone(t *testing.T) {
+	var done []int
+	p := &pusher{}
+	batches := make([]*logEventBatch, 3)
+	for i := range batches {
+		i := i
+		batches[i] = &logEventBatch{doneCallbacks: []func(){func() { done = append(done, i) }}}
+	}
+	p.inFlight = append(p.inFlight, batches...)
+
+	p.finish(batches[1], true)
+	require.Empty(t, done)
+	// the first batch was not sent before the pusher stopped, so the batches following it are
+	// not done either
+	p.finish(batches[0], false)
+	p.finish(batches[2], true)
+	require.Empty(t, done)
+	require.Empty(t, p.inFlight)
+}
+
 func testPreparation(retention int, s *svcMock, flushTimeout time.Duration, retryDuration time.Duration) (chan struct{}, *pusher) {
 	stop := make(chan struct{})
-	p := NewPusher(Target{"G", "S", util.StandardLogGroupClass, retention}, s, flushTimeout, retryDuration, models.NewLogger("cloudwatchlogs", "test", ""), stop, &wg)
+	p := NewPusher(Target{"G", "S", util.StandardLogGroupClass, retention}, s, flushTimeout, retryDuration, models.NewLogger("cloudwatchlogs", "test", ""), stop, &wg, nil, 1)
 	return stop, p
 }
+
+// TestAddEventNonBlockingDropsAreDone overflows the events waiting to be batched while a request is
+// sent, and checks that the offset of the source still moves past the events dropped.
+func TestAddEventNonBlockingDropsAreDone(t *testing.T) {
+	release := make(chan struct{})
+	sending := make(chan struct{}, 1)
+	var s svcMock
+	s.ple = func(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+		select {
+		case sending <- struct{}{}:
+		default:
+		}
+		<-release
+		return &cloudwatchlogs.PutLogEventsOutput{}, nil
+	}
+	stop, p := testPreparation(-1, &s, 10*time.Millisecond, maxRetryTimeout)
+
+	var acks acktracker.Tracker[int]
+	var mu sync.Mutex
+	committed := -1
+	add := func(i int, et time.Time) {
+		id, _ := acks.Track(i, nil)
+		p.AddEventNonBlocking(evtMock{fmt.Sprintf("MSG - %v", i), et, func() {
+			if offset, ok := acks.Ack(id); ok {
+				mu.Lock()
+				committed = offset
+				mu.Unlock()
+			}
+		}})
+	}
+
+	// the event out of the accepted time range is dropped
+	add(0, time.Now().Add(-15*24*time.Hour))
+	add(1, time.Now())
+	<-sending
+	// the pusher is blocked sending the first event, so that the events waiting overflow
+	const n = reqEventsLimit*2 + 100
+	for i := 2; i < n; i++ {
+		add(i, time.Now())
+	}
+	close(release)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return committed == n-1
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, acks.Unacked())
+
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,208 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatchlogs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	spoolFileSuffix = ".json"
+	spoolTmpSuffix  = ".tmp"
+	spoolDirMode    = 0755
+	spoolFileMode   = 0644
+)
+
+var errSpoolFull = errors.New("spool size limit reached")
+
+// spool persists log event batches that could not be delivered to CloudWatch
+// Logs, so they can be replayed once PutLogEvents succeeds again. Batches are
+// stored as one file per batch under a directory per log group and stream,
+// named after the earliest timestamp in the batch so that a directory listing
+// is already in replay order.
+type spool struct {
+	dir     string
+	maxSize int64
+
+	mu   sync.Mutex
+	size int64
+	// files counts the spooled batch files of each target directory, so that the senders only
+	// list the directory when there are batches to replay.
+	files map[string]int
+	seq   atomic.Int64
+}
+
+type spooledEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+func newSpool(dir string, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, spoolDirMode); err != nil {
+		return nil, err
+	}
+	s := &spool{dir: dir, maxSize: maxSize, files: make(map[string]int)}
+	s.seq.Store(time.Now().UnixNano())
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(path, spoolTmpSuffix) {
+			// leftover from an interrupted write, never completed so nothing was acknowledged
+			return os.Remove(path)
+		}
+		if info, err := d.Info(); err == nil && strings.HasSuffix(path, spoolFileSuffix) {
+			s.size += info.Size()
+			s.files[filepath.Dir(path)]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *spool) targetDir(t Target) string {
+	return filepath.Join(s.dir, url.PathEscape(t.Group), url.PathEscape(t.Stream))
+}
+
+// write durably stores the batch for the target. The batch is only considered
+// spooled once the file has been synced and renamed into place.
+func (s *spool) write(t Target, events []*cloudwatchlogs.InputLogEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	minT := *events[0].Timestamp
+	batch := make([]spooledEvent, len(events))
+	for i, e := range events {
+		batch[i] = spooledEvent{Timestamp: *e.Timestamp, Message: *e.Message}
+		if *e.Timestamp < minT {
+			minT = *e.Timestamp
+		}
+	}
+	content, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxSize > 0 && s.size+int64(len(content)) > s.maxSize {
+		return errSpoolFull
+	}
+
+	dir := s.targetDir(t)
+	if err = os.MkdirAll(dir, spoolDirMode); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, fmt.Sprintf("%020d-%020d%s", minT, s.seq.Add(1), spoolFileSuffix))
+	tmp := name + spoolTmpSuffix
+	if err = writeFileSync(tmp, content); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err = os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.size += int64(len(content))
+	s.files[dir]++
+	return nil
+}
+
+// hasPending returns whether batches of the target are spooled.
+func (s *spool) hasPending(t Target) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.files[s.targetDir(t)] > 0
+}
+
+// pending returns the spooled batch files of the target in timestamp order.
+func (s *spool) pending(t Target) ([]string, error) {
+	entries, err := os.ReadDir(s.targetDir(t))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spoolFileSuffix) {
+			files = append(files, filepath.Join(s.targetDir(t), e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (s *spool) read(path string) ([]*cloudwatchlogs.InputLogEvent, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var batch []spooledEvent
+	if err = json.Unmarshal(content, &batch); err != nil {
+		return nil, err
+	}
+	events := make([]*cloudwatchlogs.InputLogEvent, len(batch))
+	for i := range batch {
+		events[i] = &cloudwatchlogs.InputLogEvent{
+			Message:   &batch[i].Message,
+			Timestamp: &batch[i].Timestamp,
+		}
+	}
+	return events, nil
+}
+
+func (s *spool) remove(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(path); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.size -= info.Size()
+	dir := filepath.Dir(path)
+	if s.files[dir]--; s.files[dir] <= 0 {
+		delete(s.files, dir)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func writeFileSync(name string, content []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, spoolFileMode)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,242 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package cloudwatchlogs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/influxdata/telegraf/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/tool/util"
+)
+
+func testEvents(timestamps ...int64) []*cloudwatchlogs.InputLogEvent {
+	events := make([]*cloudwatchlogs.InputLogEvent, len(timestamps))
+	for i, ts := range timestamps {
+		events[i] = &cloudwatchlogs.InputLogEvent{Message: aws.String("msg"), Timestamp: aws.Int64(ts)}
+	}
+	return events
+}
+
+func TestSpoolWriteReadRemove(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, 0)
+	require.NoError(t, err)
+
+	target := Target{Group: "/aws/G", Stream: "S"}
+	require.NoError(t, s.write(target, testEvents(3000, 3001)))
+	require.NoError(t, s.write(target, testEvents(1000, 1001, 1002)))
+
+	files, err := s.pending(target)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(dir, "%2Faws%2FG", "S"), filepath.Dir(files[0]))
+	assert.True(t, s.hasPending(target))
+
+	// replayed in timestamp order regardless of write order
+	events, err := s.read(files[0])
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+	assert.EqualValues(t, 1000, *events[0].Timestamp)
+
+	for _, f := range files {
+		require.NoError(t, s.remove(f))
+	}
+	assert.EqualValues(t, 0, s.size)
+	assert.False(t, s.hasPending(target))
+	files, err = s.pending(target)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	other, err := s.pending(Target{Group: "other", Stream: "S"})
+	require.NoError(t, err)
+	assert.Empty(t, other)
+}
+
+func TestSpoolSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newSpool(dir, 100)
+	require.NoError(t, err)
+
+	target := Target{Group: "G", Stream: "S"}
+	require.NoError(t, s.write(target, testEvents(1)))
+	assert.ErrorIs(t, s.write(target, testEvents(1, 2, 3, 4, 5)), errSpoolFull)
+
+	// size is recovered from disk on restart and incomplete writes are discarded
+	require.NoError(t, os.WriteFile(filepath.Join(s.targetDir(target), "partial"+spoolFileSuffix+spoolTmpSuffix), []byte("["), spoolFileMode))
+	restarted, err := newSpool(dir, 100)
+	require.NoError(t, err)
+	assert.Equal(t, s.size, restarted.size)
+	files, err := restarted.pending(target)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.True(t, restarted.hasPending(target))
+}
+
+func TestPusherSpoolsFailedBatchAndReplays(t *testing.T) {
+	var s svcMock
+	var sent []string
+	fail := true
+	s.ple = func(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+		if fail {
+			return nil, errors.New("connection refused")
+		}
+		for _, e := range in.LogEvents {
+			sent = append(sent, *e.Message)
+		}
+		return &cloudwatchlogs.PutLogEventsOutput{}, nil
+	}
+
+	sp, err := newSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	stop := make(chan struct{})
+	p := NewPusher(Target{"G", "S", util.StandardLogGroupClass, -1}, &s, time.Hour, maxRetryTimeout, models.NewLogger("cloudwatchlogs", "test", ""), stop, &wg, sp, 1)
+
+	var done int
+	now := time.Now()
+	p.AddEvent(evtMock{"old", now.Add(-time.Minute), func() { done++ }})
+	time.Sleep(10 * time.Millisecond)
+	p.send()
+	assert.Equal(t, 1, done, "done callback should be called once the batch is spooled")
+	files, err := sp.pending(p.Target)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	fail = false
+	p.AddEvent(evtMock{"new", now, func() { done++ }})
+	time.Sleep(10 * time.Millisecond)
+	p.send()
+	assert.Equal(t, 2, done)
+	assert.Equal(t, []string{"old", "new"}, sent, "the spooled events are sent before the new ones")
+	files, err = sp.pending(p.Target)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	close(stop)
+	wg.Wait()
+}
+
+func TestPusherReplayDropsRejectedBatches(t *testing.T) {
+	var s svcMock
+	var sent []string
+	s.ple = func(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+		if *in.LogEvents[0].Message == "poison" {
+			return nil, &cloudwatchlogs.InvalidParameterException{Message_: aws.String("invalid")}
+		}
+		for _, e := range in.LogEvents {
+			sent = append(sent, *e.Message)
+		}
+		return &cloudwatchlogs.PutLogEventsOutput{}, nil
+	}
+
+	sp, err := newSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	target := Target{"G", "S", util.StandardLogGroupClass, -1}
+	now := time.Now()
+	expired := now.Add(-15 * 24 * time.Hour).UnixMilli()
+	require.NoError(t, sp.write(target, []*cloudwatchlogs.InputLogEvent{{Message: aws.String("poison"), Timestamp: aws.Int64(now.Add(-3 * time.Minute).UnixMilli())}}))
+	require.NoError(t, sp.write(target, []*cloudwatchlogs.InputLogEvent{{Message: aws.String("expired"), Timestamp: aws.Int64(expired)}}))
+	require.NoError(t, sp.write(target, []*cloudwatchlogs.InputLogEvent{{Message: aws.String("spooled"), Timestamp: aws.Int64(now.Add(-time.Minute).UnixMilli())}}))
+
+	stop := make(chan struct{})
+	p := NewPusher(target, &s, time.Hour, maxRetryTimeout, models.NewLogger("cloudwatchlogs", "test", ""), stop, &wg, sp, 1)
+	var done int
+	p.AddEvent(evtMock{"new", now, func() { done++ }})
+	time.Sleep(10 * time.Millisecond)
+	p.send()
+
+	// the batches which are never accepted don't block the batches following them
+	assert.Equal(t, 1, done)
+	assert.Equal(t, []string{"spooled", "new"}, sent)
+	files, err := sp.pending(target)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	close(stop)
+	wg.Wait()
+}
+
+func TestPusherSpoolsBehindPendingBatches(t *testing.T) {
+	var s svcMock
+	var sent []string
+	fail := true
+	s.ple = func(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+		if fail {
+			return nil, &cloudwatchlogs.ServiceUnavailableException{Message_: aws.String("unavailable")}
+		}
+		for _, e := range in.LogEvents {
+			sent = append(sent, *e.Message)
+		}
+		return &cloudwatchlogs.PutLogEventsOutput{}, nil
+	}
+
+	sp, err := newSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	target := Target{"G", "S", util.StandardLogGroupClass, -1}
+	now := time.Now()
+	require.NoError(t, sp.write(target, []*cloudwatchlogs.InputLogEvent{{Message: aws.String("spooled"), Timestamp: aws.Int64(now.Add(-time.Minute).UnixMilli())}}))
+
+	stop := make(chan struct{})
+	p := NewPusher(target, &s, time.Hour, maxRetryTimeout, models.NewLogger("cloudwatchlogs", "test", ""), stop, &wg, sp, 1)
+	var done int
+	p.AddEvent(evtMock{"new", now, func() { done++ }})
+	time.Sleep(10 * time.Millisecond)
+	p.send()
+
+	// the new batch is spooled behind the batch which can't be replayed yet, without being sent
+	assert.Equal(t, 1, done)
+	assert.Empty(t, sent)
+	files, err := sp.pending(target)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	fail = false
+	assert.True(t, p.replaySpool())
+	assert.Equal(t, []string{"spooled", "new"}, sent)
+
+	close(stop)
+	wg.Wait()
+}
+
+func TestPusherSendsWhileAnotherSenderReplays(t *testing.T) {
+	var s svcMock
+	var sent []string
+	s.ple = func(in *cloudwatchlogs.PutLogEventsInput) (*cloudwatchlogs.PutLogEventsOutput, error) {
+		for _, e := range in.LogEvents {
+			sent = append(sent, *e.Message)
+		}
+		return &cloudwatchlogs.PutLogEventsOutput{}, nil
+	}
+
+	sp, err := newSpool(t.TempDir(), 0)
+	require.NoError(t, err)
+	target := Target{"G", "S", util.StandardLogGroupClass, -1}
+	stop := make(chan struct{})
+	p := NewPusher(target, &s, time.Hour, maxRetryTimeout, models.NewLogger("cloudwatchlogs", "test", ""), stop, &wg, sp, 1)
+
+	// another sender holds the replay while the spool is empty, so the batch is sent right away
+	p.replayMu.Lock()
+	var done int
+	p.AddEvent(evtMock{"live", time.Now(), func() { done++ }})
+	time.Sleep(10 * time.Millisecond)
+	p.send()
+	p.replayMu.Unlock()
+
+	assert.Equal(t, 1, done)
+	assert.Equal(t, []string{"live"}, sent)
+	files, err := sp.pending(target)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,51 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Config is the configuration of the derived metrics processor.
+type Config struct {
+	// Interval is the collection interval of the metrics. The values of the operands of an
+	// expression are only combined when they were collected in the same interval, each
+	// collection of the operands in the interval being derived on its own.
+	Interval time.Duration  `mapstructure:"interval"`
+	Metrics  []MetricConfig `mapstructure:"metrics"`
+}
+
+// MetricConfig is a metric computed from the expression over the values of other metrics with the
+// same dimensions.
+type MetricConfig struct {
+	Name       string `mapstructure:"name"`
+	Expression string `mapstructure:"expression"`
+	Unit       string `mapstructure:"unit,omitempty"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+func (cfg *Config) Validate() error {
+	if cfg.Interval <= 0 {
+		return errors.New("'interval' must be positive")
+	}
+	names := make(map[string]bool, len(cfg.Metrics))
+	for _, m := range cfg.Metrics {
+		if m.Name == "" {
+			return errors.New("derived metric name must be set")
+		}
+		if names[m.Name] {
+			return fmt.Errorf("duplicate derived metric %s", m.Name)
+		}
+		names[m.Name] = true
+		if _, err := parseExpression(m.Expression); err != nil {
+			return fmt.Errorf("invalid expression of derived metric %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
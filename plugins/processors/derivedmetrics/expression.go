@@ -0,0 +1,215 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// expression is an arithmetic expression over metrics, e.g. `mem_used / mem_total * 100`. It
+// supports +, -, *, /, parentheses and numbers. The metrics are referred to by name, names with
+// other characters than letters, digits, _ and . being quoted with backticks.
+type expression struct {
+	root node
+	// operands are the names of the metrics of the expression, without duplicates.
+	operands []string
+}
+
+type node interface {
+	eval(values map[string]float64) float64
+}
+
+type number float64
+
+func (n number) eval(map[string]float64) float64 {
+	return float64(n)
+}
+
+type operand string
+
+func (o operand) eval(values map[string]float64) float64 {
+	return values[string(o)]
+}
+
+type negation struct {
+	operand node
+}
+
+func (n negation) eval(values map[string]float64) float64 {
+	return -n.operand.eval(values)
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (b binary) eval(values map[string]float64) float64 {
+	left, right := b.left.eval(values), b.right.eval(values)
+	switch b.op {
+	case '+':
+		return left + right
+	case '-':
+		return left - right
+	case '*':
+		return left * right
+	default:
+		return left / right
+	}
+}
+
+// eval returns the value of the expression with the values of its operands, false when it isn't a
+// finite number, e.g. when dividing by 0.
+func (e *expression) eval(values map[string]float64) (float64, bool) {
+	v := e.root.eval(values)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+func parseExpression(s string) (*expression, error) {
+	p := &parser{input: s}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	e := &expression{root: root}
+	seen := make(map[string]bool)
+	for _, name := range p.operands {
+		if !seen[name] {
+			seen[name] = true
+			e.operands = append(e.operands, name)
+		}
+	}
+	if len(e.operands) == 0 {
+		return nil, fmt.Errorf("expression has no metric")
+	}
+	return e, nil
+}
+
+type parser struct {
+	input    string
+	pos      int
+	operands []string
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next character which is not a space, 0 at the end of the input.
+func (p *parser) peek() byte {
+	p.skipSpaces()
+	if p.pos == len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// parseSum parses the terms added or subtracted.
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseProduct parses the factors multiplied or divided.
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (node, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '-':
+		p.pos++
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return negation{operand}, nil
+	case c == '(':
+		p.pos++
+		n, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at position %d", p.pos)
+		}
+		p.pos++
+		return n, nil
+	case c == '`':
+		end := strings.IndexByte(p.input[p.pos+1:], '`')
+		if end <= 0 {
+			return nil, fmt.Errorf("invalid quoted metric name at position %d", p.pos)
+		}
+		name := p.input[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		p.operands = append(p.operands, name)
+		return operand(name), nil
+	case c == '.' || isDigit(c):
+		start := p.pos
+		for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", p.input[start:p.pos], start)
+		}
+		return number(v), nil
+	case isNameStart(c):
+		start := p.pos
+		for p.pos < len(p.input) && (isNameStart(p.input[p.pos]) || isDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+		p.operands = append(p.operands, name)
+		return operand(name), nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,84 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpression(t *testing.T) {
+	values := map[string]float64{
+		"mem_used":  2,
+		"mem_total": 8,
+		"errors":    3,
+		"requests":  0,
+		"app.hits":  5,
+		"my-metric": 4,
+	}
+	testCases := map[string]struct {
+		expression   string
+		wantOperands []string
+		want         float64
+		wantOk       bool
+	}{
+		"Ratio": {
+			expression:   "mem_used / mem_total * 100",
+			wantOperands: []string{"mem_used", "mem_total"},
+			want:         25,
+			wantOk:       true,
+		},
+		"Precedence": {
+			expression:   "mem_total - mem_used * 2 + 1",
+			wantOperands: []string{"mem_total", "mem_used"},
+			want:         5,
+			wantOk:       true,
+		},
+		"Parentheses": {
+			expression:   "(mem_total - mem_used) / -(mem_used)",
+			wantOperands: []string{"mem_total", "mem_used"},
+			want:         -3,
+			wantOk:       true,
+		},
+		"DottedAndQuotedNames": {
+			expression:   "app.hits + `my-metric` + app.hits",
+			wantOperands: []string{"app.hits", "my-metric"},
+			want:         14,
+			wantOk:       true,
+		},
+		"DivisionByZero": {
+			expression:   "errors / requests",
+			wantOperands: []string{"errors", "requests"},
+			wantOk:       false,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			e, err := parseExpression(testCase.expression)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.wantOperands, e.operands)
+			got, ok := e.eval(values)
+			assert.Equal(t, testCase.wantOk, ok)
+			assert.Equal(t, testCase.want, got)
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"100",
+		"mem_used /",
+		"(mem_used / mem_total",
+		"mem_used mem_total",
+		"mem_used % mem_total",
+		"`mem_used",
+		"1.2.3 * mem_used",
+	} {
+		_, err := parseExpression(expression)
+		assert.Error(t, err, expression)
+	}
+}
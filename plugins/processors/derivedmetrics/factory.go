@@ -0,0 +1,61 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	stability       = component.StabilityLevelAlpha
+	defaultInterval = time.Minute
+)
+
+var (
+	TypeStr, _            = component.NewType("derivedmetrics")
+	processorCapabilities = consumer.Capabilities{MutatesData: true}
+)
+
+func NewFactory() processor.Factory {
+	return processor.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		processor.WithMetrics(createMetricsProcessor, stability))
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{Interval: defaultInterval}
+}
+
+func createMetricsProcessor(
+	ctx context.Context,
+	set processor.CreateSettings,
+	cfg component.Config,
+	nextConsumer consumer.Metrics,
+) (processor.Metrics, error) {
+	processorConfig, ok := cfg.(*Config)
+	if !ok {
+		return nil, fmt.Errorf("configuration parsing error")
+	}
+
+	metricsProcessor, err := newDerivedMetricsProcessor(processorConfig, set.Logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return processorhelper.NewMetricsProcessor(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		metricsProcessor.processMetrics,
+		processorhelper.WithCapabilities(processorCapabilities))
+}
@@ -0,0 +1,87 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+func TestCreateDefaultConfig(t *testing.T) {
+	factory := NewFactory()
+	require.NotNil(t, factory)
+
+	cfg := factory.CreateDefaultConfig()
+	assert.NotNil(t, cfg, "failed to create default config")
+	assert.NoError(t, componenttest.CheckConfigStruct(cfg))
+	assert.NoError(t, component.ValidateConfig(cfg))
+}
+
+func TestCreateProcessor(t *testing.T) {
+	factory := NewFactory()
+	require.NotNil(t, factory)
+
+	cfg := factory.CreateDefaultConfig()
+	setting := processortest.NewNopCreateSettings()
+
+	tProcessor, err := factory.CreateTracesProcessor(context.Background(), setting, cfg, consumertest.NewNop())
+	assert.Equal(t, err, component.ErrDataTypeIsNotSupported)
+	assert.Nil(t, tProcessor)
+
+	mProcessor, err := factory.CreateMetricsProcessor(context.Background(), setting, cfg, consumertest.NewNop())
+	assert.NoError(t, err)
+	assert.NotNil(t, mProcessor)
+
+	lProcessor, err := factory.CreateLogsProcessor(context.Background(), setting, cfg, consumertest.NewNop())
+	assert.Equal(t, err, component.ErrDataTypeIsNotSupported)
+	assert.Nil(t, lProcessor)
+}
+
+func TestValidateConfig(t *testing.T) {
+	testCases := map[string]struct {
+		cfg     *Config
+		wantErr string
+	}{
+		"Valid": {
+			cfg: &Config{Interval: time.Minute, Metrics: []MetricConfig{{Name: "mem_used_ratio", Expression: "mem_used / mem_total"}}},
+		},
+		"InvalidInterval": {
+			cfg:     &Config{},
+			wantErr: "'interval' must be positive",
+		},
+		"MissingName": {
+			cfg:     &Config{Interval: time.Minute, Metrics: []MetricConfig{{Expression: "mem_used / mem_total"}}},
+			wantErr: "derived metric name must be set",
+		},
+		"DuplicateName": {
+			cfg: &Config{Interval: time.Minute, Metrics: []MetricConfig{
+				{Name: "ratio", Expression: "mem_used / mem_total"},
+				{Name: "ratio", Expression: "errors / requests"},
+			}},
+			wantErr: "duplicate derived metric ratio",
+		},
+		"InvalidExpression": {
+			cfg:     &Config{Interval: time.Minute, Metrics: []MetricConfig{{Name: "ratio", Expression: "mem_used /"}}},
+			wantErr: "invalid expression of derived metric ratio",
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := testCase.cfg.Validate()
+			if testCase.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, testCase.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+type derivedMetric struct {
+	MetricConfig
+	expression *expression
+}
+
+// pendingKey identifies the values of the operands of a derived metric with the same dimensions
+// collected in the same interval.
+type pendingKey struct {
+	metric     int
+	dimensions string
+	interval   int64
+}
+
+// pendingValues are the values of the operands of the current collection. An input may collect
+// its metrics several times in an interval, a value of an operand which is already set starts
+// a new collection so that the operands of different collections are not combined.
+type pendingValues struct {
+	values     map[string]float64
+	attributes pcommon.Map
+	timestamp  pcommon.Timestamp
+}
+
+// completed is a derived metric whose operands all have a value, to be added to the scope of the
+// last of them.
+type completed struct {
+	key       pendingKey
+	scope     pmetric.ScopeMetrics
+	values    map[string]float64
+	timestamp pcommon.Timestamp
+}
+
+// derivedMetricsProcessor adds the metrics computed from the metrics going through it. The operands
+// of an expression may be in different batches, e.g. the metrics of a service input which are
+// consumed one at a time, so their values are kept until the interval they were collected in ends.
+type derivedMetricsProcessor struct {
+	logger   *zap.Logger
+	interval time.Duration
+	metrics  []derivedMetric
+	// byOperand are the indexes of the derived metrics using each metric.
+	byOperand map[string][]int
+	now       func() time.Time
+
+	mu          sync.Mutex
+	pending     map[pendingKey]*pendingValues
+	lastCleanup int64
+}
+
+func newDerivedMetricsProcessor(cfg *Config, logger *zap.Logger) (*derivedMetricsProcessor, error) {
+	p := &derivedMetricsProcessor{
+		logger:    logger,
+		interval:  cfg.Interval,
+		byOperand: make(map[string][]int),
+		now:       time.Now,
+		pending:   make(map[pendingKey]*pendingValues),
+	}
+	if p.interval <= 0 {
+		p.interval = defaultInterval
+	}
+	for i, m := range cfg.Metrics {
+		e, err := parseExpression(m.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression of derived metric %s: %w", m.Name, err)
+		}
+		p.metrics = append(p.metrics, derivedMetric{MetricConfig: m, expression: e})
+		for _, name := range e.operands {
+			p.byOperand[name] = append(p.byOperand[name], i)
+		}
+	}
+	return p, nil
+}
+
+func (p *derivedMetricsProcessor) processMetrics(_ context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+	if len(p.metrics) == 0 {
+		return md, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cleanup()
+
+	var done []completed
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		sms := rms.At(i).ScopeMetrics()
+		for j := 0; j < sms.Len(); j++ {
+			sm := sms.At(j)
+			ms := sm.Metrics()
+			for k := 0; k < ms.Len(); k++ {
+				m := ms.At(k)
+				indexes, ok := p.byOperand[m.Name()]
+				if !ok {
+					continue
+				}
+				var dps pmetric.NumberDataPointSlice
+				switch m.Type() {
+				case pmetric.MetricTypeGauge:
+					dps = m.Gauge().DataPoints()
+				case pmetric.MetricTypeSum:
+					dps = m.Sum().DataPoints()
+				default:
+					continue
+				}
+				for l := 0; l < dps.Len(); l++ {
+					done = p.record(m.Name(), dps.At(l), indexes, sm, done)
+				}
+			}
+		}
+	}
+	for _, c := range done {
+		p.emit(c)
+	}
+	return md, nil
+}
+
+// record keeps the value of the data point for the derived metrics using it, and returns the
+// derived metrics whose operands all have a value with the same dimensions in the interval. The
+// values are emitted once per collection and then forgotten.
+func (p *derivedMetricsProcessor) record(name string, dp pmetric.NumberDataPoint, indexes []int, sm pmetric.ScopeMetrics, done []completed) []completed {
+	var value float64
+	switch dp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		value = float64(dp.IntValue())
+	case pmetric.NumberDataPointValueTypeDouble:
+		value = dp.DoubleValue()
+	default:
+		return done
+	}
+	dimensions := dimensionsKey(dp.Attributes())
+	interval := int64(dp.Timestamp()) / int64(p.interval)
+	for _, index := range indexes {
+		key := pendingKey{metric: index, dimensions: dimensions, interval: interval}
+		pv, ok := p.pending[key]
+		if !ok {
+			pv = &pendingValues{values: make(map[string]float64), attributes: pcommon.NewMap()}
+			dp.Attributes().CopyTo(pv.attributes)
+			p.pending[key] = pv
+		}
+		if _, ok = pv.values[name]; ok {
+			// the operands collected before are not combined with the new collection
+			pv.values = make(map[string]float64)
+			pv.timestamp = 0
+		}
+		pv.values[name] = value
+		if dp.Timestamp() > pv.timestamp {
+			pv.timestamp = dp.Timestamp()
+		}
+		if len(pv.values) == len(p.metrics[index].expression.operands) {
+			done = append(done, completed{key: key, scope: sm, values: pv.values, timestamp: pv.timestamp})
+			pv.values = make(map[string]float64)
+			pv.timestamp = 0
+		}
+	}
+	return done
+}
+
+func (p *derivedMetricsProcessor) emit(c completed) {
+	dm := p.metrics[c.key.metric]
+	pv := p.pending[c.key]
+	value, ok := dm.expression.eval(c.values)
+	if !ok {
+		p.logger.Debug("Derived metric is not a finite number", zap.String("name", dm.Name), zap.Any("values", c.values))
+		return
+	}
+	m := c.scope.Metrics().AppendEmpty()
+	m.SetName(dm.Name)
+	m.SetUnit(dm.Unit)
+	dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+	pv.attributes.CopyTo(dp.Attributes())
+	dp.SetTimestamp(c.timestamp)
+	dp.SetDoubleValue(value)
+}
+
+// cleanup forgets the values collected before the previous interval, once per interval.
+func (p *derivedMetricsProcessor) cleanup() {
+	current := p.now().UnixNano() / int64(p.interval)
+	if current == p.lastCleanup {
+		return
+	}
+	p.lastCleanup = current
+	for key := range p.pending {
+		if key.interval < current-1 {
+			delete(p.pending, key)
+		}
+	}
+}
+
+// dimensionsKey returns the key of the attributes, which doesn't depend on their order.
+func dimensionsKey(attributes pcommon.Map) string {
+	pairs := make([]string, 0, attributes.Len())
+	attributes.Range(func(k string, v pcommon.Value) bool {
+		pairs = append(pairs, k+"="+v.AsString())
+		return true
+	})
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
@@ -0,0 +1,166 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+type testDataPoint struct {
+	name       string
+	value      float64
+	attributes map[string]any
+	timestamp  time.Time
+}
+
+func newMetrics(dps ...testDataPoint) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	ms := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	for _, d := range dps {
+		m := ms.AppendEmpty()
+		m.SetName(d.name)
+		dp := m.SetEmptyGauge().DataPoints().AppendEmpty()
+		dp.SetDoubleValue(d.value)
+		dp.SetTimestamp(pcommon.NewTimestampFromTime(d.timestamp))
+		_ = dp.Attributes().FromRaw(d.attributes)
+	}
+	return md
+}
+
+// derived returns the values of the metric by the host attribute.
+func derived(md pmetric.Metrics, name string) map[string]float64 {
+	values := make(map[string]float64)
+	ms := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < ms.Len(); i++ {
+		if ms.At(i).Name() != name {
+			continue
+		}
+		dp := ms.At(i).Gauge().DataPoints().At(0)
+		host, _ := dp.Attributes().Get("host")
+		values[host.AsString()] = dp.DoubleValue()
+	}
+	return values
+}
+
+func TestProcessMetrics(t *testing.T) {
+	p, err := newDerivedMetricsProcessor(&Config{
+		Interval: time.Minute,
+		Metrics: []MetricConfig{
+			{Name: "mem_used_ratio", Expression: "mem_used / mem_total * 100", Unit: "Percent"},
+			{Name: "error_ratio", Expression: "errors / requests"},
+		},
+	}, zap.NewNop())
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	h1 := map[string]any{"host": "h1"}
+	h2 := map[string]any{"host": "h2"}
+
+	// the operands with the same dimensions in the same batch
+	md, err := p.processMetrics(context.Background(), newMetrics(
+		testDataPoint{"mem_used", 2, h1, now},
+		testDataPoint{"mem_total", 8, h1, now},
+		testDataPoint{"mem_used", 4, h2, now},
+		testDataPoint{"mem_total", 16, map[string]any{"host": "h3"}, now},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"h1": 25}, derived(md, "mem_used_ratio"))
+	ms := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	assert.Equal(t, 5, ms.Len())
+	assert.Equal(t, "Percent", ms.At(4).Unit())
+	assert.Equal(t, pcommon.NewTimestampFromTime(now), ms.At(4).Gauge().DataPoints().At(0).Timestamp())
+
+	// the operands consumed in different batches of the same interval
+	md, err = p.processMetrics(context.Background(), newMetrics(testDataPoint{"errors", 3, h1, now.Add(time.Second)}))
+	require.NoError(t, err)
+	assert.Empty(t, derived(md, "error_ratio"))
+	md, err = p.processMetrics(context.Background(), newMetrics(
+		testDataPoint{"requests", 12, h1, now.Add(2 * time.Second)},
+		testDataPoint{"requests", 0, h2, now.Add(2 * time.Second)},
+		testDataPoint{"errors", 0, h2, now.Add(2 * time.Second)},
+	))
+	require.NoError(t, err)
+	// the ratio of h2 is not a number
+	assert.Equal(t, map[string]float64{"h1": 0.25}, derived(md, "error_ratio"))
+
+	// the metric is only derived once per collection of its operands
+	md, err = p.processMetrics(context.Background(), newMetrics(testDataPoint{"errors", 6, h1, now.Add(3 * time.Second)}))
+	require.NoError(t, err)
+	assert.Empty(t, derived(md, "error_ratio"))
+
+	// the operands of different intervals are not combined
+	md, err = p.processMetrics(context.Background(), newMetrics(testDataPoint{"mem_total", 8, h2, now.Add(time.Minute)}))
+	require.NoError(t, err)
+	assert.Empty(t, derived(md, "mem_used_ratio"))
+
+	// the values of the intervals before the previous one are forgotten
+	now = now.Add(2 * time.Minute)
+	_, err = p.processMetrics(context.Background(), newMetrics())
+	require.NoError(t, err)
+	assert.Len(t, p.pending, 1)
+}
+
+func TestProcessMetricsCollectionsInInterval(t *testing.T) {
+	p, err := newDerivedMetricsProcessor(&Config{
+		Interval: time.Minute,
+		Metrics:  []MetricConfig{{Name: "error_ratio", Expression: "errors / requests"}},
+	}, zap.NewNop())
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	h1 := map[string]any{"host": "h1"}
+
+	// an input collecting its metrics every 10s in the interval of 1m
+	md, err := p.processMetrics(context.Background(), newMetrics(
+		testDataPoint{"errors", 1, h1, now},
+		testDataPoint{"requests", 10, h1, now},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"h1": 0.1}, derived(md, "error_ratio"))
+	md, err = p.processMetrics(context.Background(), newMetrics(
+		testDataPoint{"errors", 4, h1, now.Add(10 * time.Second)},
+		testDataPoint{"requests", 16, h1, now.Add(10 * time.Second)},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"h1": 0.25}, derived(md, "error_ratio"))
+	dp := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(2).Gauge().DataPoints().At(0)
+	assert.Equal(t, pcommon.NewTimestampFromTime(now.Add(10*time.Second)), dp.Timestamp())
+
+	// the operands of different collections are not combined, the new value of errors
+	// starts a new collection
+	md, err = p.processMetrics(context.Background(), newMetrics(testDataPoint{"errors", 5, h1, now.Add(20 * time.Second)}))
+	require.NoError(t, err)
+	assert.Empty(t, derived(md, "error_ratio"))
+	md, err = p.processMetrics(context.Background(), newMetrics(
+		testDataPoint{"errors", 2, h1, now.Add(30 * time.Second)},
+		testDataPoint{"requests", 8, h1, now.Add(30 * time.Second)},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"h1": 0.25}, derived(md, "error_ratio"))
+
+	// two collections in the same batch are both derived
+	md, err = p.processMetrics(context.Background(), newMetrics(
+		testDataPoint{"errors", 1, h1, now.Add(40 * time.Second)},
+		testDataPoint{"requests", 2, h1, now.Add(40 * time.Second)},
+		testDataPoint{"errors", 1, h1, now.Add(50 * time.Second)},
+		testDataPoint{"requests", 4, h1, now.Add(50 * time.Second)},
+	))
+	require.NoError(t, err)
+	var values []float64
+	ms := md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < ms.Len(); i++ {
+		if ms.At(i).Name() == "error_ratio" {
+			values = append(values, ms.At(i).Gauge().DataPoints().At(0).DoubleValue())
+		}
+	}
+	assert.Equal(t, []float64{0.5, 0.25}, values)
+}
@@ -30,11 +30,23 @@ func (p *profiler) AddStats(key []string, value float64) {
 	p.stats[k] += value
 }
 
-// GetStats for testing purposes
+// SetStats replaces the value of the stats, for the stats which are a level rather than a count.
+func (p *profiler) SetStats(key []string, value float64) {
+	p.Lock()
+	defer p.Unlock()
+	k := strings.Join(key, "_")
+	p.stats[k] = value
+}
+
+// GetStats for testing purposes, returns a copy so that it can be read while the stats are added
 func (p *profiler) GetStats() map[string]float64 {
 	p.Lock()
 	defer p.Unlock()
-	return p.stats
+	stats := make(map[string]float64, len(p.stats))
+	for k, v := range p.stats {
+		stats[k] = v
+	}
+	return stats
 }
 
 func (p *profiler) ReportAndClear() {
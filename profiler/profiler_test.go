@@ -54,3 +54,11 @@ func TestProfilerGetStats(t *testing.T) {
 	_, ok = stats[name]
 	assert.False(t, ok)
 }
+
+func TestProfilerSetStats(t *testing.T) {
+	Profiler.AddStats([]string{t.Name(), "StatsA"}, 2)
+	Profiler.SetStats([]string{t.Name(), "StatsA"}, 1)
+	Profiler.SetStats([]string{t.Name(), "StatsA"}, 3)
+	assert.Equal(t, 3.0, Profiler.GetStats()[t.Name()+"_StatsA"])
+	Profiler.ReportAndClear()
+}
@@ -12,7 +12,7 @@ import (
 
 const (
 	receiversCount  = 6
-	processorCount  = 10
+	processorCount  = 12
 	exportersCount  = 5
 	extensionsCount = 2
 )
@@ -41,14 +41,18 @@ func TestComponents(t *testing.T) {
 	ec2taggerType, _ := component.NewType("ec2tagger")
 	metricstransformType, _ := component.NewType("metricstransform")
 	transformType, _ := component.NewType("transform")
+	tailSamplingType, _ := component.NewType("tail_sampling")
 	gpuattributesType, _ := component.NewType("gpuattributes")
+	derivedmetricsType, _ := component.NewType("derivedmetrics")
 	assert.NotNil(t, processors[awsapplicationsignalsType])
 	assert.NotNil(t, processors[batchType])
 	assert.NotNil(t, processors[cumulativetodeltaType])
 	assert.NotNil(t, processors[ec2taggerType])
 	assert.NotNil(t, processors[metricstransformType])
 	assert.NotNil(t, processors[transformType])
+	assert.NotNil(t, processors[tailSamplingType])
 	assert.NotNil(t, processors[gpuattributesType])
+	assert.NotNil(t, processors[derivedmetricsType])
 
 	exporters := factories.Exporters
 	assert.Len(t, exporters, exportersCount)
@@ -36,6 +36,7 @@ type (
 		Disk            []diskConfig
 		DiskIo          []diskioConfig
 		Ethtool         []ethtoolConfig
+		Journald        []journaldConfig
 		K8sapiserver    []k8sApiServerConfig
 		Logfile         []logFileConfig
 		Mem             []memConfig
@@ -48,10 +49,12 @@ type (
 		SocketListener  []socketListenerConfig `toml:"socket_listener"`
 		Statsd          []statsdConfig
 		Swap            []swapConfig
+		Syslog          []syslogInputConfig
 		WindowsEventLog []windowsEventLogConfig `toml:"windows_event_log"`
 	}
 
 	outputConfig struct {
+		Archive        []archiveOutputConfig
 		CloudWatch     []cloudWatchOutputConfig
 		CloudWatchLogs []cloudWatchLogsConfig
 	}
@@ -118,16 +121,55 @@ type (
 	}
 
 	fileConfig struct {
-		AutoRemoval     bool   `toml:"auto_removal"`
-		FilePath        string `toml:"file_path"`
-		FromBeginning   bool   `toml:"from_beginning"`
+		AutoRemoval         bool                    `toml:"auto_removal"`
+		ContainerLog        *fileConfigContainerLog `toml:"container_log"`
+		Destination         string
+		FilePath            string                   `toml:"file_path"`
+		FromBeginning       bool                     `toml:"from_beginning"`
+		LogGroupName        string                   `toml:"log_group_name"`
+		LogStreamName       string                   `toml:"log_stream_name"`
+		MetricFilters       []fileConfigMetricFilter `toml:"metric_filters"`
+		Pipe                bool
+		ReadCompressedFiles bool `toml:"read_compressed_files"`
+		RetentionInDays     int  `toml:"retention_in_days"`
+		Timezone            string
+		Tags                map[string]string
+		Filters             []fileConfigFilter
+		Parser              *fileConfigParser
+		RateLimit           *rateLimitConfig `toml:"rate_limit"`
+	}
+
+	fileConfigContainerLog struct {
+		Format             string
+		KubernetesMetadata bool `toml:"kubernetes_metadata"`
+	}
+
+	fileConfigMetricFilter struct {
+		MetricName  string `toml:"metric_name"`
+		Namespace   string
+		Pattern     string
+		Aggregation string
+		Field       string
+		Dimensions  []string
+	}
+
+	journaldConfig struct {
+		Destination     string
+		FileStateFolder string          `toml:"file_state_folder"`
+		JournalConfig   []journalConfig `toml:"journal_config"`
+	}
+
+	journalConfig struct {
+		Directories     []string
+		Identifiers     []string
+		LogGroupClass   string `toml:"log_group_class"`
 		LogGroupName    string `toml:"log_group_name"`
 		LogStreamName   string `toml:"log_stream_name"`
-		Pipe            bool
+		Matches         []string
+		MaxSources      int `toml:"max_sources"`
+		Priority        string
 		RetentionInDays int `toml:"retention_in_days"`
-		Timezone        string
-		Tags            map[string]string
-		Filters         []fileConfigFilter
+		Units           []string
 	}
 
 	k8sApiServerConfig struct {
@@ -170,9 +212,15 @@ type (
 		ClusterName          string                              `toml:"cluster_name"`
 		PrometheusConfigPath string                              `toml:"prometheus_config_path"`
 		EcsServiceDiscovery  prometheusEcsServiceDiscoveryConfig `toml:"ecs_service_discovery"`
+		RemoteWrite          prometheusRemoteWriteConfig         `toml:"remote_write"`
 		Tags                 map[string]string
 	}
 
+	prometheusRemoteWriteConfig struct {
+		ListenAddress string `toml:"listen_address"`
+		Path          string `toml:"path"`
+	}
+
 	prometheusEcsServiceDiscoveryConfig struct {
 		SdClusterRegion         string                    `toml:"sd_cluster_region"`
 		SdFrequency             string                    `toml:"sd_frequency"`
@@ -218,12 +266,21 @@ type (
 	}
 
 	statsdConfig struct {
-		AllowedPendingMessages int `toml:"allowed_pending_messages"`
+		AllowedPendingMessages int    `toml:"allowed_pending_messages"`
+		ConnectionIdleTimeout  string `toml:"connection_idle_timeout"`
+		EventsLogGroupName     string `toml:"events_log_group_name"`
+		EventsLogStreamName    string `toml:"events_log_stream_name"`
 		Interval               string
+		MaxConnections         int    `toml:"max_connections"`
+		MaxLineLength          int    `toml:"max_line_length"`
 		MetricSeparator        string `toml:"metric_separator"`
 		ParseDataDogTags       bool   `toml:"parse_data_dog_tags"`
 		ServiceAddress         string `toml:"service_address"`
 		Tags                   map[string]string
+		TimingPercentiles      []float64 `toml:"timing_percentiles"`
+		TimingSketch           bool      `toml:"timing_sketch"`
+		TimingSketchMaxBuckets int       `toml:"timing_sketch_max_buckets"`
+		TimingSketchAccuracy   float64   `toml:"timing_sketch_relative_accuracy"`
 	}
 
 	swapConfig struct {
@@ -231,6 +288,25 @@ type (
 		Tags      map[string]string
 	}
 
+	syslogInputConfig struct {
+		Destination  string
+		SyslogConfig []syslogConfig `toml:"syslog_config"`
+	}
+
+	syslogConfig struct {
+		Address           string
+		LogGroupClass     string `toml:"log_group_class"`
+		LogGroupName      string `toml:"log_group_name"`
+		LogStreamName     string `toml:"log_stream_name"`
+		MaxConnections    int    `toml:"max_connections"`
+		MaxSources        int    `toml:"max_sources"`
+		Protocol          string
+		RetentionInDays   int      `toml:"retention_in_days"`
+		TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+		TLSCert           string   `toml:"tls_cert"`
+		TLSKey            string   `toml:"tls_key"`
+	}
+
 	windowsEventLogConfig struct {
 		Destination     string
 		FileStateFolder string        `toml:"file_state_folder"`
@@ -263,20 +339,67 @@ type (
 	}
 
 	cloudWatchLogsConfig struct {
-		EndpointOverride   string `toml:"endpoint_override"`
-		ForceFlushInterval string `toml:"force_flush_interval"`
-		LogStreamName      string `toml:"log_stream_name"`
+		Concurrency                   int
+		DynamicDestinationIdleTimeout string                    `toml:"dynamic_destination_idle_timeout"`
+		EndpointOverride              string                    `toml:"endpoint_override"`
+		ForceFlushInterval            string                    `toml:"force_flush_interval"`
+		LogGroupRateLimit             []logGroupRateLimitConfig `toml:"log_group_rate_limit"`
+		LogStreamName                 string                    `toml:"log_stream_name"`
+		MaxDynamicDestinations        int                       `toml:"max_dynamic_destinations"`
+		Region                        string
+		RoleArn                       string `toml:"role_arn"`
+		SpoolDir                      string `toml:"spool_dir"`
+		SpoolMaxSizeMB                int    `toml:"spool_max_size_mb"`
+		TagExclude                    []string
+		TagPass                       map[string][]string
+	}
+
+	archiveOutputConfig struct {
+		Directory          string
+		MaxFileSizeMB      int `toml:"max_file_size_mb"`
 		Region             string
 		RoleArn            string `toml:"role_arn"`
-		TagExclude         []string
-		TagPass            map[string][]string
+		RotationInterval   string `toml:"rotation_interval"`
+		S3Bucket           string `toml:"s3_bucket"`
+		S3EndpointOverride string `toml:"s3_endpoint_override"`
+		S3ForcePathStyle   bool   `toml:"s3_force_path_style"`
+		S3KeyPrefix        string `toml:"s3_key_prefix"`
 	}
 
 	fileConfigFilter struct {
+		Detectors  []string
 		Expression string
+		HashKey    string `toml:"hash_key"`
+		Strategy   string
 		Type       string
 	}
 
+	fileConfigParser struct {
+		DropFields      []string `toml:"drop_fields"`
+		Format          string
+		Pattern         string
+		RenameFields    map[string]string `toml:"rename_fields"`
+		TimestampField  string            `toml:"timestamp_field"`
+		TimestampLayout []string          `toml:"timestamp_layout"`
+	}
+
+	rateLimitConfig struct {
+		BufferSize         int     `toml:"buffer_size"`
+		MaxEventsPerSecond float64 `toml:"max_events_per_second"`
+		MaxKBPerSecond     float64 `toml:"max_kb_per_second"`
+		OverflowPolicy     string  `toml:"overflow_policy"`
+		SampleRatio        int     `toml:"sample_ratio"`
+	}
+
+	logGroupRateLimitConfig struct {
+		BufferSize         int     `toml:"buffer_size"`
+		LogGroupName       string  `toml:"log_group_name"`
+		MaxEventsPerSecond float64 `toml:"max_events_per_second"`
+		MaxKBPerSecond     float64 `toml:"max_kb_per_second"`
+		OverflowPolicy     string  `toml:"overflow_policy"`
+		SampleRatio        int     `toml:"sample_ratio"`
+	}
+
 	// Processors
 	processorDelta struct {
 	}
@@ -18,6 +18,10 @@ var ChildRule = map[string]Rule{}
 const (
 	SectionKey             = "logs"
 	Output_Cloudwatch_Logs = "cloudwatchlogs"
+	Output_Archive         = "archive"
+
+	metricsSectionKey = "metrics"
+	metricFiltersPath = "logs_collected/files/collect_list/metric_filters"
 )
 
 func GetCurPath() string {
@@ -42,6 +46,7 @@ func (l *Logs) ApplyRule(input interface{}) (returnKey string, returnVal interfa
 	inputs := map[string]interface{}{}
 	processors := map[string]interface{}{}
 	cloudwatchConfig := map[string]interface{}{}
+	archiveConfig := map[string]interface{}{}
 	GlobalLogConfig.MetadataInfo = util.GetMetadataInfo(util.Ec2MetadataInfoProvider)
 
 	//Check if this plugin exist in the input instance
@@ -74,12 +79,22 @@ func (l *Logs) ApplyRule(input interface{}) (returnKey string, returnVal interfa
 					inputs = translator.MergeTwoUniqueMaps(inputs, val.(map[string]interface{}))
 				} else if key == Output_Cloudwatch_Logs {
 					cloudwatchConfig = translator.MergeTwoUniqueMaps(cloudwatchConfig, val.(map[string]interface{}))
+				} else if key == Output_Archive {
+					archiveConfig = val.(map[string]interface{})
 				}
 			}
 		}
 
+		// the metric filters are gathered by the metrics pipeline, which needs the metrics section
+		if _, ok := im[metricsSectionKey]; !ok && hasMetricFilters(im[SectionKey]) {
+			translator.AddErrorMessages(GetCurPath()+metricFiltersPath, "metric_filters require the metrics section")
+		}
+
 		cloudwatchInfo := map[string]interface{}{}
 		cloudwatchInfo["cloudwatchlogs"] = []interface{}{cloudwatchConfig}
+		if len(archiveConfig) > 0 {
+			cloudwatchInfo[Output_Archive] = []interface{}{archiveConfig}
+		}
 		result["outputs"] = cloudwatchInfo
 
 		if len(inputs) > 0 {
@@ -95,6 +110,22 @@ func (l *Logs) ApplyRule(input interface{}) (returnKey string, returnVal interfa
 	return
 }
 
+// hasMetricFilters returns whether any of the collected files has metric filters.
+func hasMetricFilters(input interface{}) bool {
+	logs, _ := input.(map[string]interface{})
+	logsCollected, _ := logs["logs_collected"].(map[string]interface{})
+	files, _ := logsCollected["files"].(map[string]interface{})
+	collectList, _ := files["collect_list"].([]interface{})
+	for _, entry := range collectList {
+		if entryMap, ok := entry.(map[string]interface{}); ok {
+			if _, ok = entryMap["metric_filters"]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 var MergeRuleMap = map[string]mergeJsonRule.MergeRule{}
 
 func (l *Logs) Merge(source map[string]interface{}, result map[string]interface{}) {
@@ -61,6 +61,56 @@ func TestFileConfigOverride(t *testing.T) {
 	assert.Equal(t, expectVal, val)
 }
 
+func TestFileConfigDestination(t *testing.T) {
+	f := new(FileConfig)
+	var input interface{}
+	e := json.Unmarshal([]byte(`{"collect_list":[{"file_path":"path1",
+            "log_group_name":"group1","destination":"archive"}]}`), &input)
+	if e != nil {
+		assert.Fail(t, e.Error())
+	}
+	_, val := f.ApplyRule(input)
+	expectVal := []interface{}{map[string]interface{}{
+		"file_path":         "path1",
+		"from_beginning":    true,
+		"log_group_name":    "group1",
+		"destination":       "archive",
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+	}}
+	assert.Equal(t, expectVal, val)
+}
+
+func TestFileConfigParser(t *testing.T) {
+	f := new(FileConfig)
+	var input interface{}
+	e := json.Unmarshal([]byte(`{"collect_list":[{"file_path":"path1","log_group_name":"group1",
+            "parser":{"format":"regex","pattern":"(?P<time>\\S+) (?P<msg>.*)","timestamp_field":"time",
+            "timestamp_format":"%Y-%m-%dT%H:%M:%S","drop_fields":["time"],"rename_fields":{"msg":"message"}}}]}`), &input)
+	if e != nil {
+		assert.Fail(t, e.Error())
+	}
+	_, val := f.ApplyRule(input)
+	expectVal := []interface{}{map[string]interface{}{
+		"file_path":         "path1",
+		"from_beginning":    true,
+		"log_group_name":    "group1",
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+		"parser": map[string]interface{}{
+			"format":           "regex",
+			"pattern":          "(?P<time>\\S+) (?P<msg>.*)",
+			"timestamp_field":  "time",
+			"timestamp_layout": []string{"2006-01-_2T15:04:05", "2006-1-_2T15:04:05"},
+			"drop_fields":      []string{"time"},
+			"rename_fields":    map[string]interface{}{"msg": "message"},
+		},
+	}}
+	assert.Equal(t, expectVal, val)
+}
+
 func TestTimestampFormat(t *testing.T) {
 	f := new(FileConfig)
 	var input interface{}
@@ -549,6 +599,191 @@ func TestAutoRemoval(t *testing.T) {
 	assert.Equal(t, expectVal, val)
 }
 
+func TestReadCompressedFiles(t *testing.T) {
+	f := new(FileConfig)
+	var input interface{}
+	e := json.Unmarshal([]byte(`{
+		"collect_list":[
+			{
+				"file_path":"path1",
+				"read_compressed_files": true
+			}
+		]
+	}`), &input)
+	if e != nil {
+		assert.Fail(t, e.Error())
+	}
+	_, val := f.ApplyRule(input)
+	expectVal := []interface{}{map[string]interface{}{
+		"file_path":             "path1",
+		"from_beginning":        true,
+		"pipe":                  false,
+		"retention_in_days":     -1,
+		"log_group_class":       "",
+		"read_compressed_files": true,
+	}}
+	assert.Equal(t, expectVal, val)
+}
+
+func TestContainerLog(t *testing.T) {
+	f := new(FileConfig)
+	var input interface{}
+	e := json.Unmarshal([]byte(`{
+		"collect_list":[
+			{
+				"file_path":"/var/log/containers/*.log",
+				"log_group_name":"/eks/{namespace}",
+				"container_log": {"kubernetes_metadata": true}
+			},
+			{
+				"file_path":"path2",
+				"container_log": {"format": "journald"}
+			}
+		]
+	}`), &input)
+	if e != nil {
+		assert.Fail(t, e.Error())
+	}
+	translator.ResetMessages()
+	_, val := f.ApplyRule(input)
+	expectVal := []interface{}{map[string]interface{}{
+		"file_path":         "/var/log/containers/*.log",
+		"log_group_name":    "/eks/{namespace}",
+		"from_beginning":    true,
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+		"container_log": map[string]interface{}{
+			"format":              "auto",
+			"kubernetes_metadata": true,
+		},
+	}, map[string]interface{}{
+		"file_path":         "path2",
+		"from_beginning":    true,
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+	}}
+	assert.Equal(t, expectVal, val)
+	assert.Len(t, translator.ErrorMessages, 1)
+	translator.ResetMessages()
+}
+
+func TestRateLimit(t *testing.T) {
+	f := new(FileConfig)
+	var input interface{}
+	e := json.Unmarshal([]byte(`{
+		"collect_list":[
+			{
+				"file_path":"path1",
+				"rate_limit": {"max_events_per_second": 100, "overflow_policy": "drop_oldest", "buffer_size": 500}
+			},
+			{
+				"file_path":"path2",
+				"rate_limit": {"max_kb_per_second": 256, "overflow_policy": "drop_all"}
+			}
+		]
+	}`), &input)
+	if e != nil {
+		assert.Fail(t, e.Error())
+	}
+	translator.ResetMessages()
+	_, val := f.ApplyRule(input)
+	expectVal := []interface{}{map[string]interface{}{
+		"file_path":         "path1",
+		"from_beginning":    true,
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+		"rate_limit": map[string]interface{}{
+			"max_events_per_second": float64(100),
+			"overflow_policy":       "drop_oldest",
+			"buffer_size":           500,
+		},
+	}, map[string]interface{}{
+		"file_path":         "path2",
+		"from_beginning":    true,
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+	}}
+	assert.Equal(t, expectVal, val)
+	assert.Len(t, translator.ErrorMessages, 1)
+	translator.ResetMessages()
+}
+
+func TestMetricFilters(t *testing.T) {
+	f := new(FileConfig)
+	var input interface{}
+	e := json.Unmarshal([]byte(`{
+		"collect_list":[
+			{
+				"file_path":"path1",
+				"metric_filters": [
+					{"metric_name": "errors", "namespace": "app", "pattern": "ERROR \\[(?P<component>\\w+)\\]", "dimensions": ["component"]},
+					{"metric_name": "bytes", "pattern": "sent (?P<bytes>\\d+)", "aggregation": "sum", "field": "bytes"},
+					{"metric_name": "latency", "aggregation": "distribution", "field": "latency"},
+					{"metric_name": "average", "aggregation": "avg", "pattern": "ERROR"},
+					{"pattern": "ERROR"}
+				]
+			},
+			{
+				"file_path":"path2",
+				"parser": {"format": "json"},
+				"metric_filters": [
+					{"metric_name": "latency", "aggregation": "distribution", "field": "latency", "dimensions": ["path"]}
+				]
+			}
+		]
+	}`), &input)
+	if e != nil {
+		assert.Fail(t, e.Error())
+	}
+	translator.ResetMessages()
+	_, val := f.ApplyRule(input)
+	expectVal := []interface{}{map[string]interface{}{
+		"file_path":         "path1",
+		"from_beginning":    true,
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+		"metric_filters": []interface{}{
+			map[string]interface{}{
+				"metric_name": "errors",
+				"namespace":   "app",
+				"pattern":     "ERROR \\[(?P<component>\\w+)\\]",
+				"aggregation": "count",
+				"dimensions":  []string{"component"},
+			},
+			map[string]interface{}{
+				"metric_name": "bytes",
+				"pattern":     "sent (?P<bytes>\\d+)",
+				"aggregation": "sum",
+				"field":       "bytes",
+			},
+		},
+	}, map[string]interface{}{
+		"file_path":         "path2",
+		"from_beginning":    true,
+		"pipe":              false,
+		"retention_in_days": -1,
+		"log_group_class":   "",
+		"parser":            map[string]interface{}{"format": "json"},
+		"metric_filters": []interface{}{
+			map[string]interface{}{
+				"metric_name": "latency",
+				"aggregation": "distribution",
+				"field":       "latency",
+				"dimensions":  []string{"path"},
+			},
+		},
+	}}
+	assert.Equal(t, expectVal, val)
+	// the latency of path1 has no parsed field, the average has an unsupported aggregation and the last has no name
+	assert.Len(t, translator.ErrorMessages, 3)
+	translator.ResetMessages()
+}
+
 func TestFileConfigOutputFile(t *testing.T) {
 	dir, err := os.MkdirTemp("", "")
 	assert.NoError(t, err)
@@ -0,0 +1,54 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collect_list
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	ContainerLogSectionKey                = "container_log"
+	containerLogFormatKey                 = "format"
+	containerLogKubernetesMetadataKey     = "kubernetes_metadata"
+	containerLogFormatAutoValue           = "auto"
+	containerLogFormatCRIValue            = "cri"
+	containerLogFormatDockerValue         = "docker"
+	containerLogKubernetesMetadataDefault = false
+)
+
+type ContainerLog struct {
+}
+
+func (c *ContainerLog) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	containerLog, ok := im[ContainerLogSectionKey].(map[string]interface{})
+	if !ok {
+		return
+	}
+	res := map[string]interface{}{}
+	_, format := translator.DefaultCase(containerLogFormatKey, containerLogFormatAutoValue, containerLog)
+	switch format {
+	case containerLogFormatAutoValue, containerLogFormatCRIValue, containerLogFormatDockerValue:
+		res[containerLogFormatKey] = format
+	default:
+		translator.AddErrorMessages(GetCurPath()+ContainerLogSectionKey, fmt.Sprintf("Container log format %v is not supported", format))
+		return
+	}
+	_, metadata := translator.DefaultCase(containerLogKubernetesMetadataKey, containerLogKubernetesMetadataDefault, containerLog)
+	if _, ok := metadata.(bool); !ok {
+		metadata = containerLogKubernetesMetadataDefault
+	}
+	res[containerLogKubernetesMetadataKey] = metadata
+
+	returnKey = ContainerLogSectionKey
+	returnVal = res
+	return
+}
+
+func init() {
+	c := new(ContainerLog)
+	RegisterRule(ContainerLogSectionKey, []Rule{c})
+}
@@ -0,0 +1,29 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collect_list
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const DestinationSectionKey = "destination"
+
+// Destination routes the file to a log backend other than the default cloudwatchlogs output.
+type Destination struct {
+}
+
+func (d *Destination) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	_, returnVal = translator.DefaultCase(DestinationSectionKey, "", input)
+	if returnVal == "" {
+		return
+	}
+	returnKey = DestinationSectionKey
+	return
+}
+
+func init() {
+	d := new(Destination)
+	r := []Rule{d}
+	RegisterRule(DestinationSectionKey, r)
+}
@@ -14,6 +14,11 @@ const (
 	FiltersSectionKey           = "filters"
 	FiltersTypeSectionKey       = "type"
 	FiltersExpressionSectionKey = "expression"
+	FiltersDetectorsSectionKey  = "detectors"
+	FiltersStrategySectionKey   = "strategy"
+	FiltersHashKeySectionKey    = "hash_key"
+	filtersRedactType           = "redact"
+	filtersHashStrategy         = "hash"
 )
 
 type LogFilter struct {
@@ -33,8 +38,30 @@ func (lf *LogFilter) ApplyRule(input interface{}) (returnKey string, returnVal i
 				continue
 			}
 			filterMap[FiltersTypeSectionKey] = filterVal
+			redact := filterVal == filtersRedactType
+			if redact {
+				if _, ok := filter.(map[string]interface{})[FiltersDetectorsSectionKey]; ok {
+					_, filterMap[FiltersDetectorsSectionKey] = translator.DefaultStringArrayCase(FiltersDetectorsSectionKey, nil, filter)
+				}
+				if _, strategy := translator.DefaultCase(FiltersStrategySectionKey, "", filter); strategy != "" {
+					filterMap[FiltersStrategySectionKey] = strategy
+				}
+				if filterMap[FiltersStrategySectionKey] == filtersHashStrategy {
+					_, hashKey := translator.DefaultCase(FiltersHashKeySectionKey, "", filter)
+					if hashKey == "" {
+						translator.AddErrorMessages(GetCurPath()+FiltersSectionKey, fmt.Sprintf("Filter %s with the hash strategy requires a hash_key", filter))
+						continue
+					}
+					filterMap[FiltersHashKeySectionKey] = hashKey
+				}
+			}
 			_, filterVal = translator.DefaultCase(FiltersExpressionSectionKey, "", filter)
 			if filterVal == "" {
+				// redact filters may only use the built-in detectors
+				if redact && filterMap[FiltersDetectorsSectionKey] != nil {
+					res = append(res, filterMap)
+					continue
+				}
 				translator.AddErrorMessages(GetCurPath()+FiltersSectionKey, fmt.Sprintf("Filter %s is invalid", filter))
 				continue
 			}
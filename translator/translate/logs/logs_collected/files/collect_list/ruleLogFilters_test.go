@@ -78,3 +78,25 @@ func TestApplyLogFiltersRuleInvalidRegex(t *testing.T) {
 	assert.Nil(t, retVal)
 	assert.Len(t, translator.ErrorMessages, 1)
 }
+
+func TestApplyLogFiltersRuleRedact(t *testing.T) {
+	translator.ResetMessages()
+	r := new(LogFilter)
+	var input interface{}
+	e := json.Unmarshal([]byte(`{
+		"filters": [
+			{"type": "redact", "detectors": ["email", "credit_card"]},
+			{"type": "redact", "expression": "password=(\\S+)", "strategy": "hash", "hash_key": "secret"},
+			{"type": "redact", "expression": "token=(\\S+)", "strategy": "hash"},
+			{"type": "redact"}
+		]
+	}`), &input)
+	assert.Nil(t, e)
+
+	_, retVal := r.ApplyRule(input)
+	assert.Len(t, translator.ErrorMessages, 2)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "redact", "detectors": []string{"email", "credit_card"}},
+		map[string]interface{}{"type": "redact", "expression": "password=(\\S+)", "strategy": "hash", "hash_key": "secret"},
+	}, retVal)
+}
@@ -0,0 +1,123 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collect_list
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	MetricFiltersSectionKey   = "metric_filters"
+	metricFilterMetricNameKey = "metric_name"
+	metricFilterNamespaceKey  = "namespace"
+	metricFilterPatternKey    = "pattern"
+	metricFilterAggregation   = "aggregation"
+	metricFilterFieldKey      = "field"
+	metricFilterDimensionsKey = "dimensions"
+
+	metricFilterCountAggregation = "count"
+)
+
+var metricFilterAggregations = map[string]bool{
+	metricFilterCountAggregation: true,
+	"sum":                        true,
+	"distribution":               true,
+}
+
+type MetricFilters struct {
+}
+
+func (mf *MetricFilters) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	filters, ok := im[MetricFiltersSectionKey].([]interface{})
+	if !ok {
+		return
+	}
+	_, hasParser := im[ParserSectionKey]
+	curPath := GetCurPath() + MetricFiltersSectionKey
+	var res []interface{}
+	for _, filter := range filters {
+		filterMap, ok := filter.(map[string]interface{})
+		if !ok {
+			translator.AddErrorMessages(curPath, fmt.Sprintf("Metric filter %v is invalid", filter))
+			continue
+		}
+		if f, ok := translateMetricFilter(filterMap, hasParser, curPath); ok {
+			res = append(res, f)
+		}
+	}
+	if len(res) == 0 {
+		return
+	}
+	returnKey = MetricFiltersSectionKey
+	returnVal = res
+	return
+}
+
+func translateMetricFilter(filter map[string]interface{}, hasParser bool, curPath string) (map[string]interface{}, bool) {
+	res := map[string]interface{}{}
+	_, name := translator.DefaultCase(metricFilterMetricNameKey, "", filter)
+	if name == "" {
+		translator.AddErrorMessages(curPath, fmt.Sprintf("Metric filter %v has no %s", filter, metricFilterMetricNameKey))
+		return nil, false
+	}
+	res[metricFilterMetricNameKey] = name
+	if _, namespace := translator.DefaultCase(metricFilterNamespaceKey, "", filter); namespace != "" {
+		res[metricFilterNamespaceKey] = namespace
+	}
+
+	_, aggregation := translator.DefaultCase(metricFilterAggregation, metricFilterCountAggregation, filter)
+	if !metricFilterAggregations[aggregation.(string)] {
+		translator.AddErrorMessages(curPath, fmt.Sprintf("Metric filter %v has unsupported %s %v", name, metricFilterAggregation, aggregation))
+		return nil, false
+	}
+	res[metricFilterAggregation] = aggregation
+
+	var patternP *regexp.Regexp
+	if _, pattern := translator.DefaultCase(metricFilterPatternKey, "", filter); pattern != "" {
+		var err error
+		if patternP, err = regexp.Compile(pattern.(string)); err != nil {
+			translator.AddErrorMessages(curPath, fmt.Sprintf("Metric filter pattern %v is invalid", pattern))
+			return nil, false
+		}
+		res[metricFilterPatternKey] = pattern
+	}
+
+	var names []string
+	if _, ok := filter[metricFilterDimensionsKey]; ok {
+		_, dimensions := translator.DefaultStringArrayCase(metricFilterDimensionsKey, nil, filter)
+		if dimensions, ok := dimensions.([]string); ok {
+			res[metricFilterDimensionsKey] = dimensions
+			names = append(names, dimensions...)
+		}
+	}
+	_, field := translator.DefaultCase(metricFilterFieldKey, "", filter)
+	if aggregation != metricFilterCountAggregation {
+		if field == "" {
+			translator.AddErrorMessages(curPath, fmt.Sprintf("Metric filter %v has no %s for the %v aggregation", name, metricFilterFieldKey, aggregation))
+			return nil, false
+		}
+		res[metricFilterFieldKey] = field
+		names = append(names, field.(string))
+	}
+
+	// without a parser, the value and the dimensions can only come from the pattern
+	if !hasParser {
+		for _, n := range names {
+			if patternP == nil || patternP.SubexpIndex(n) < 0 {
+				translator.AddErrorMessages(curPath, fmt.Sprintf("Metric filter %v uses %q which is neither a capture group of its pattern nor a parsed field", name, n))
+				return nil, false
+			}
+		}
+	}
+	return res, true
+}
+
+func init() {
+	mf := new(MetricFilters)
+	RegisterRule(MetricFiltersSectionKey, []Rule{mf})
+}
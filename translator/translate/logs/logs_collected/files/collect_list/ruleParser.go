@@ -0,0 +1,77 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collect_list
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	ParserSectionKey       = "parser"
+	parserFormatKey        = "format"
+	parserPatternKey       = "pattern"
+	parserTimestampField   = "timestamp_field"
+	parserTimestampFormat  = "timestamp_format"
+	parserTimestampLayout  = "timestamp_layout"
+	parserDropFieldsKey    = "drop_fields"
+	parserRenameFieldsKey  = "rename_fields"
+	parserFormatRegexValue = "regex"
+)
+
+type Parser struct {
+}
+
+func (p *Parser) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	parser, ok := im[ParserSectionKey].(map[string]interface{})
+	if !ok {
+		return
+	}
+	res := map[string]interface{}{}
+	_, format := translator.DefaultCase(parserFormatKey, "", parser)
+	if format == "" {
+		translator.AddErrorMessages(GetCurPath()+ParserSectionKey, fmt.Sprintf("Parser %v has no format", parser))
+		return
+	}
+	res[parserFormatKey] = format
+
+	if format == parserFormatRegexValue {
+		_, pattern := translator.DefaultCase(parserPatternKey, "", parser)
+		if pattern == "" {
+			translator.AddErrorMessages(GetCurPath()+ParserSectionKey, fmt.Sprintf("Parser %v has no pattern", parser))
+			return
+		}
+		if _, err := regexp.Compile(pattern.(string)); err != nil {
+			translator.AddErrorMessages(GetCurPath()+ParserSectionKey, fmt.Sprintf("Parser pattern %v is invalid", pattern))
+			return
+		}
+		res[parserPatternKey] = pattern
+	}
+
+	if _, field := translator.DefaultCase(parserTimestampField, "", parser); field != "" {
+		res[parserTimestampField] = field
+		if _, format := translator.DefaultCase(parserTimestampFormat, "", parser); format != "" {
+			res[parserTimestampLayout] = timestampLayouts(format.(string))
+		}
+	}
+	if _, ok := parser[parserDropFieldsKey]; ok {
+		_, res[parserDropFieldsKey] = translator.DefaultStringArrayCase(parserDropFieldsKey, nil, parser)
+	}
+	if fields, ok := parser[parserRenameFieldsKey].(map[string]interface{}); ok && len(fields) > 0 {
+		res[parserRenameFieldsKey] = fields
+	}
+
+	returnKey = ParserSectionKey
+	returnVal = res
+	return
+}
+
+func init() {
+	p := new(Parser)
+	r := []Rule{p}
+	RegisterRule(ParserSectionKey, r)
+}
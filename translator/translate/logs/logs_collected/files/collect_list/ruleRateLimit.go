@@ -0,0 +1,31 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collect_list
+
+import (
+	logUtil "github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/util"
+)
+
+type RateLimit struct {
+}
+
+func (r *RateLimit) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	rateLimit, ok := im[logUtil.RateLimitSectionKey].(map[string]interface{})
+	if !ok {
+		return
+	}
+	res, ok := logUtil.TranslateRateLimit(rateLimit, GetCurPath()+logUtil.RateLimitSectionKey)
+	if !ok {
+		return
+	}
+	returnKey = logUtil.RateLimitSectionKey
+	returnVal = res
+	return
+}
+
+func init() {
+	r := new(RateLimit)
+	RegisterRule(logUtil.RateLimitSectionKey, []Rule{r})
+}
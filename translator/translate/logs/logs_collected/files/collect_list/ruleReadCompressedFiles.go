@@ -0,0 +1,31 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collect_list
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const ReadCompressedFilesSectionKey = "read_compressed_files"
+
+type ReadCompressedFiles struct {
+}
+
+func (r *ReadCompressedFiles) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	_, returnVal = translator.DefaultCase(ReadCompressedFilesSectionKey, "", input)
+	if returnVal == "" {
+		return
+	}
+	returnKey = ReadCompressedFilesSectionKey
+	var ok bool
+	if returnVal, ok = returnVal.(bool); !ok {
+		returnVal = false
+	}
+	return
+}
+
+func init() {
+	r := new(ReadCompressedFiles)
+	RegisterRule(ReadCompressedFilesSectionKey, []Rule{r})
+}
@@ -187,27 +187,30 @@ func (t *TimestampLayout) ApplyRule(input interface{}) (returnKey string, return
 		fmt.Printf("timestamp_format set file_path : %s is the same as agent log file %s thus do not use timestamp_layout \n", m["file_path"], context.CurrentContext().GetAgentLogFile())
 		return "", ""
 	} else {
-		res := checkAndReplace(val.(string), TimeFormatMap)
 		//If user provide with the specific timestamp_format, use the one that user provide
 		returnKey = "timestamp_layout"
-		timestampInput := val.(string)
-		// Go doesn't support _2 option for month in day as a result need to set
-		// timestamp_layout with 2 strings which support %m and %-m
-		if strings.Contains(timestampInput, "%m") {
-			timestampInput := strings.Replace(timestampInput, "%m", "%-m", -1)
-			alternativeLayout := checkAndReplace(timestampInput, TimeFormatMap)
-			returnVal = []string{res, alternativeLayout}
-		} else if strings.Contains(timestampInput, "%-m") {
-			timestampInput = strings.Replace(timestampInput, "%-m", "%m", -1)
-			alternativeLayout := checkAndReplace(timestampInput, TimeFormatMap)
-			returnVal = []string{res, alternativeLayout}
-		} else {
-			returnVal = []string{res}
-		}
+		returnVal = timestampLayouts(val.(string))
 	}
 	return
 }
 
+// timestampLayouts converts the timestamp_format into the Golang layouts used to parse it.
+func timestampLayouts(timestampInput string) []string {
+	res := checkAndReplace(timestampInput, TimeFormatMap)
+	// Go doesn't support _2 option for month in day as a result need to set
+	// timestamp_layout with 2 strings which support %m and %-m
+	if strings.Contains(timestampInput, "%m") {
+		timestampInput := strings.Replace(timestampInput, "%m", "%-m", -1)
+		alternativeLayout := checkAndReplace(timestampInput, TimeFormatMap)
+		return []string{res, alternativeLayout}
+	} else if strings.Contains(timestampInput, "%-m") {
+		timestampInput = strings.Replace(timestampInput, "%-m", "%m", -1)
+		alternativeLayout := checkAndReplace(timestampInput, TimeFormatMap)
+		return []string{res, alternativeLayout}
+	}
+	return []string{res}
+}
+
 type Timezone struct {
 }
 
@@ -0,0 +1,77 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collectlist
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+func TestApplyRule(t *testing.T) {
+	c := new(CollectList)
+	var rawJsonString = `
+{
+    "collect_list": [
+      {
+        "units": ["sshd.service", "nginx.service"],
+        "priority": "warning",
+        "log_group_name": "journal/{_SYSTEMD_UNIT}",
+        "log_stream_name": "{_HOSTNAME}",
+        "retention_in_days": 7
+      },
+      {
+        "identifiers": ["sudo"],
+        "matches": ["_UID=0"],
+        "directories": ["/var/log/journal"],
+        "log_group_name": "sudo",
+        "max_sources": 500
+      }
+    ]
+}
+`
+	var input interface{}
+	require.NoError(t, json.Unmarshal([]byte(rawJsonString), &input))
+
+	var expected = []interface{}{
+		map[string]interface{}{
+			"units":             []interface{}{"sshd.service", "nginx.service"},
+			"priority":          "warning",
+			"log_group_name":    "journal/{_SYSTEMD_UNIT}",
+			"log_stream_name":   "{_HOSTNAME}",
+			"retention_in_days": 7,
+			"log_group_class":   "",
+		},
+		map[string]interface{}{
+			"identifiers":       []interface{}{"sudo"},
+			"matches":           []interface{}{"_UID=0"},
+			"directories":       []interface{}{"/var/log/journal"},
+			"log_group_name":    "sudo",
+			"max_sources":       500,
+			"retention_in_days": -1,
+			"log_group_class":   "",
+		},
+	}
+
+	translator.ResetMessages()
+	key, actual := c.ApplyRule(input)
+	assert.Equal(t, JournalConfigTomlKey, key)
+	assert.Equal(t, expected, actual)
+	assert.Empty(t, translator.ErrorMessages)
+}
+
+func TestInvalidPriority(t *testing.T) {
+	c := new(CollectList)
+	var input interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"collect_list": [{"priority": "warn", "log_group_name": "journal"}]}`), &input))
+
+	translator.ResetMessages()
+	_, actual := c.ApplyRule(input)
+	assert.NotContains(t, actual.([]interface{})[0], PrioritySectionKey)
+	assert.Len(t, translator.ErrorMessages, 1)
+}
@@ -0,0 +1,27 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collectlist
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const MaxSourcesSectionKey = "max_sources"
+
+// MaxSources is the number of log groups and streams receiving entries after which the least
+// recently used one is closed, the default of the plugin when not set.
+type MaxSources struct {
+}
+
+func (f *MaxSources) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	if _, ok := input.(map[string]interface{})[MaxSourcesSectionKey]; !ok {
+		return
+	}
+	return translator.DefaultIntegralCase(MaxSourcesSectionKey, float64(0), input)
+}
+
+func init() {
+	l := new(MaxSources)
+	RegisterRule(MaxSourcesSectionKey, l)
+}
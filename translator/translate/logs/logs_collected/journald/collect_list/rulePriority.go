@@ -0,0 +1,44 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collectlist
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const PrioritySectionKey = "priority"
+
+var priorities = map[string]bool{
+	"emerg":   true,
+	"alert":   true,
+	"crit":    true,
+	"err":     true,
+	"warning": true,
+	"notice":  true,
+	"info":    true,
+	"debug":   true,
+}
+
+type Priority struct {
+}
+
+func (p *Priority) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	_, returnVal = translator.DefaultCase(PrioritySectionKey, "", input)
+	if returnVal == "" {
+		return
+	}
+	if !priorities[returnVal.(string)] {
+		translator.AddErrorMessages(GetCurPath()+PrioritySectionKey, fmt.Sprintf("priority value %s is not a valid value.", returnVal))
+		return
+	}
+	returnKey = PrioritySectionKey
+	return
+}
+
+func init() {
+	p := new(Priority)
+	RegisterRule(PrioritySectionKey, p)
+}
@@ -0,0 +1,64 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journald
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+	"github.com/aws/amazon-cloudwatch-agent/translator/jsonconfig/mergeJsonRule"
+	"github.com/aws/amazon-cloudwatch-agent/translator/jsonconfig/mergeJsonUtil"
+	parent "github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/logs_collected"
+)
+
+var ChildRule = map[string]translator.Rule{}
+
+type Journald struct {
+}
+
+const (
+	SectionKey       = "journald"
+	SectionMappedKey = "journald"
+)
+
+func GetCurPath() string {
+	return parent.GetCurPath() + SectionKey + "/"
+}
+
+func RegisterRule(ruleName string, r translator.Rule) {
+	ChildRule[ruleName] = r
+}
+
+func (j *Journald) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	journaldConfig := map[string]interface{}{
+		"destination": "cloudwatchlogs",
+	}
+
+	if _, ok := im[SectionKey]; ok {
+		for _, rule := range ChildRule {
+			key, val := rule.ApplyRule(im[SectionKey])
+			if key != "" {
+				journaldConfig[key] = val
+			}
+		}
+
+		return "inputs", map[string]interface{}{
+			SectionMappedKey: []interface{}{journaldConfig},
+		}
+	} else {
+		translator.AddInfoMessages("", "No journald configuration found.")
+		return "", ""
+	}
+}
+
+var MergeRuleMap = map[string]mergeJsonRule.MergeRule{}
+
+func (j *Journald) Merge(source map[string]interface{}, result map[string]interface{}) {
+	mergeJsonUtil.MergeMap(source, result, SectionKey, MergeRuleMap, GetCurPath())
+}
+
+func init() {
+	obj := new(Journald)
+	parent.RegisterLinuxRule(SectionKey, obj)
+	parent.MergeRuleMap[SectionKey] = obj
+}
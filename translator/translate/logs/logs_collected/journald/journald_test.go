@@ -0,0 +1,48 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package journald
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator/config"
+	"github.com/aws/amazon-cloudwatch-agent/translator/context"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/util"
+)
+
+func TestApplyRule(t *testing.T) {
+	j := new(Journald)
+	var rawJsonString = `
+{
+	"journald": {
+        "collect_list": [
+          {
+            "units": ["sshd.service"],
+            "log_group_name": "journal"
+          }
+        ]
+      }
+}
+`
+	var input interface{}
+	require.NoError(t, json.Unmarshal([]byte(rawJsonString), &input))
+
+	var expected = map[string]interface{}{
+		"journald": []interface{}{
+			map[string]interface{}{
+				"destination":       "cloudwatchlogs",
+				"file_state_folder": util.File_State_Folder_Linux,
+			},
+		},
+	}
+
+	context.CurrentContext().SetOs(config.OS_TYPE_LINUX)
+	key, actual := j.ApplyRule(input)
+	assert.Equal(t, "inputs", key)
+	assert.Equal(t, expected, actual)
+}
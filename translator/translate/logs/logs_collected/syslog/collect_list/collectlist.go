@@ -0,0 +1,77 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collectlist
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+	"github.com/aws/amazon-cloudwatch-agent/translator/jsonconfig/mergeJsonRule"
+	"github.com/aws/amazon-cloudwatch-agent/translator/jsonconfig/mergeJsonUtil"
+	parent "github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/logs_collected/syslog"
+	logUtil "github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/util"
+	"github.com/aws/amazon-cloudwatch-agent/translator/util"
+)
+
+type Rule translator.Rule
+
+const (
+	SectionKey          = "collect_list"
+	SyslogConfigTomlKey = "syslog_config"
+)
+
+var ChildRule = map[string]Rule{}
+
+func RegisterRule(fieldname string, r Rule) {
+	ChildRule[fieldname] = r
+}
+
+type CollectList struct {
+}
+
+var customizedJsonConfigKeys = []string{"address", "tls_cert", "tls_key", "tls_allowed_cacerts"}
+
+func GetCurPath() string {
+	curPath := parent.GetCurPath() + SectionKey + "/"
+	return curPath
+}
+
+func (c *CollectList) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	result := []interface{}{}
+
+	if _, ok := im[SectionKey]; ok {
+		for _, singleConfig := range im[SectionKey].([]interface{}) {
+			singleTransformedConfig := getTransformedConfig(singleConfig)
+			result = append(result, singleTransformedConfig)
+		}
+	}
+	logUtil.ValidateLogGroupFields(result, GetCurPath())
+	return SyslogConfigTomlKey, result
+}
+
+var MergeRuleMap = map[string]mergeJsonRule.MergeRule{}
+
+func (c *CollectList) Merge(source map[string]interface{}, result map[string]interface{}) {
+	mergeJsonUtil.MergeList(source, result, SectionKey)
+}
+
+func init() {
+	obj := new(CollectList)
+	parent.RegisterRule("syslog_collectList", obj)
+	parent.MergeRuleMap[SectionKey] = obj
+}
+
+func getTransformedConfig(input interface{}) interface{} {
+	result := map[string]interface{}{}
+	// Extract customer specified config
+	util.SetWithSameKeyIfFound(input, customizedJsonConfigKeys, result)
+
+	for _, rule := range ChildRule {
+		key, val := rule.ApplyRule(input)
+		if key != "" {
+			result[key] = val
+		}
+	}
+
+	return result
+}
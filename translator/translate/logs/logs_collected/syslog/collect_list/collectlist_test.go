@@ -0,0 +1,80 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collectlist
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+func TestApplyRule(t *testing.T) {
+	c := new(CollectList)
+	var rawJsonString = `
+{
+    "collect_list": [
+      {
+        "log_group_name": "syslog/{HOSTNAME}",
+        "log_stream_name": "{APP_NAME}",
+        "retention_in_days": 7
+      },
+      {
+        "protocol": "tls",
+        "address": "0.0.0.0:6514",
+        "tls_cert": "/etc/ssl/syslog.crt",
+        "tls_key": "/etc/ssl/syslog.key",
+        "tls_allowed_cacerts": ["/etc/ssl/ca.crt"],
+        "log_group_name": "syslog/{FACILITY}",
+        "max_sources": 500,
+        "max_connections": 100
+      }
+    ]
+}
+`
+	var input interface{}
+	require.NoError(t, json.Unmarshal([]byte(rawJsonString), &input))
+
+	var expected = []interface{}{
+		map[string]interface{}{
+			"protocol":          "udp",
+			"log_group_name":    "syslog/{HOSTNAME}",
+			"log_stream_name":   "{APP_NAME}",
+			"retention_in_days": 7,
+			"log_group_class":   "",
+		},
+		map[string]interface{}{
+			"protocol":            "tls",
+			"address":             "0.0.0.0:6514",
+			"tls_cert":            "/etc/ssl/syslog.crt",
+			"tls_key":             "/etc/ssl/syslog.key",
+			"tls_allowed_cacerts": []interface{}{"/etc/ssl/ca.crt"},
+			"log_group_name":      "syslog/{FACILITY}",
+			"max_sources":         500,
+			"max_connections":     100,
+			"retention_in_days":   -1,
+			"log_group_class":     "",
+		},
+	}
+
+	translator.ResetMessages()
+	key, actual := c.ApplyRule(input)
+	assert.Equal(t, SyslogConfigTomlKey, key)
+	assert.Equal(t, expected, actual)
+	assert.Empty(t, translator.ErrorMessages)
+}
+
+func TestInvalidProtocol(t *testing.T) {
+	c := new(CollectList)
+	var input interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"collect_list": [{"protocol": "sctp", "log_group_name": "syslog"}, {"protocol": "tls", "tls_cert": "/etc/ssl/syslog.crt", "log_group_name": "syslog"}]}`), &input))
+
+	translator.ResetMessages()
+	_, actual := c.ApplyRule(input)
+	assert.NotContains(t, actual.([]interface{})[0], ProtocolSectionKey)
+	assert.Len(t, translator.ErrorMessages, 2)
+}
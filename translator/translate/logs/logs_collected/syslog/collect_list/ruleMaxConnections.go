@@ -0,0 +1,27 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collectlist
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const MaxConnectionsSectionKey = "max_connections"
+
+// MaxConnections is the number of TCP or TLS connections after which the new ones are closed,
+// the default of the plugin when not set.
+type MaxConnections struct {
+}
+
+func (f *MaxConnections) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	if _, ok := input.(map[string]interface{})[MaxConnectionsSectionKey]; !ok {
+		return
+	}
+	return translator.DefaultIntegralCase(MaxConnectionsSectionKey, float64(0), input)
+}
+
+func init() {
+	l := new(MaxConnections)
+	RegisterRule(MaxConnectionsSectionKey, l)
+}
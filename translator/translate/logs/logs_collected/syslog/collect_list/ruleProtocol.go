@@ -0,0 +1,47 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package collectlist
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	ProtocolSectionKey = "protocol"
+	protocolTLS        = "tls"
+)
+
+var protocols = map[string]bool{
+	"udp":       true,
+	"tcp":       true,
+	protocolTLS: true,
+}
+
+type Protocol struct {
+}
+
+func (p *Protocol) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	_, returnVal = translator.DefaultCase(ProtocolSectionKey, "udp", input)
+	protocol, _ := returnVal.(string)
+	if !protocols[protocol] {
+		translator.AddErrorMessages(GetCurPath()+ProtocolSectionKey, fmt.Sprintf("protocol value %s is not a valid value.", protocol))
+		return "", nil
+	}
+	// the tls protocol needs the certificate and key of the server
+	if protocol == protocolTLS {
+		m := input.(map[string]interface{})
+		if m["tls_cert"] == nil || m["tls_key"] == nil {
+			translator.AddErrorMessages(GetCurPath()+ProtocolSectionKey, "tls_cert and tls_key are required by the tls protocol.")
+		}
+	}
+	returnKey = ProtocolSectionKey
+	return
+}
+
+func init() {
+	p := new(Protocol)
+	RegisterRule(ProtocolSectionKey, p)
+}
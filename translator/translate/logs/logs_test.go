@@ -5,11 +5,13 @@ package logs
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/aws/amazon-cloudwatch-agent/translator"
 	"github.com/aws/amazon-cloudwatch-agent/translator/config"
 	"github.com/aws/amazon-cloudwatch-agent/translator/context"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/agent"
@@ -200,3 +202,204 @@ func TestLogs_EndpointOverride(t *testing.T) {
 
 	ctx.SetMode(config.ModeEC2) //reset back to default mode
 }
+
+func TestLogs_Spool(t *testing.T) {
+	context.ResetContext()
+	l := new(Logs)
+	agent.Global_Config.Region = "us-east-1"
+	agent.Global_Config.RegionType = "any"
+
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"logs":{"log_stream_name":"LOG_STREAM_NAME","spool":{"directory":"/var/spool/cwagent","max_size_mb":50}}}`), &input)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+
+	_, actual := l.ApplyRule(input)
+	expected := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"cloudwatchlogs": []interface{}{
+				map[string]interface{}{
+					"region":               "us-east-1",
+					"region_type":          "any",
+					"mode":                 "",
+					"log_stream_name":      "LOG_STREAM_NAME",
+					"force_flush_interval": "5s",
+					"spool_dir":            "/var/spool/cwagent",
+					"spool_max_size_mb":    50,
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, actual, "Expected to be equal")
+}
+
+func TestLogs_DynamicDestinations(t *testing.T) {
+	context.ResetContext()
+	l := new(Logs)
+	agent.Global_Config.Region = "us-east-1"
+	agent.Global_Config.RegionType = "any"
+
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"logs":{"log_stream_name":"LOG_STREAM_NAME","dynamic_destinations":{"max_destinations":500}}}`), &input)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+
+	_, actual := l.ApplyRule(input)
+	expected := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"cloudwatchlogs": []interface{}{
+				map[string]interface{}{
+					"region":                           "us-east-1",
+					"region_type":                      "any",
+					"mode":                             "",
+					"log_stream_name":                  "LOG_STREAM_NAME",
+					"force_flush_interval":             "5s",
+					"max_dynamic_destinations":         500,
+					"dynamic_destination_idle_timeout": "300s",
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, actual, "Expected to be equal")
+}
+
+func TestLogs_LogGroupRateLimits(t *testing.T) {
+	context.ResetContext()
+	l := new(Logs)
+	agent.Global_Config.Region = "us-east-1"
+	agent.Global_Config.RegionType = "any"
+
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"logs":{"log_stream_name":"LOG_STREAM_NAME","log_group_rate_limits":[
+		{"log_group_name":"noisy","max_events_per_second":1000,"max_kb_per_second":512,"overflow_policy":"sample","sample_ratio":20},
+		{"max_events_per_second":10}]}}`), &input)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+
+	translator.ResetMessages()
+	_, actual := l.ApplyRule(input)
+	expected := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"cloudwatchlogs": []interface{}{
+				map[string]interface{}{
+					"region":               "us-east-1",
+					"region_type":          "any",
+					"mode":                 "",
+					"log_stream_name":      "LOG_STREAM_NAME",
+					"force_flush_interval": "5s",
+					"log_group_rate_limit": []interface{}{
+						map[string]interface{}{
+							"log_group_name":        "noisy",
+							"max_events_per_second": float64(1000),
+							"max_kb_per_second":     float64(512),
+							"overflow_policy":       "sample",
+							"sample_ratio":          20,
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, actual, "Expected to be equal")
+	assert.Len(t, translator.ErrorMessages, 1)
+	translator.ResetMessages()
+}
+
+func TestLogs_MetricFiltersRequireMetrics(t *testing.T) {
+	context.ResetContext()
+	l := new(Logs)
+	agent.Global_Config.Region = "us-east-1"
+
+	config := `{"logs":{"logs_collected":{"files":{"collect_list":[
+		{"file_path":"path1","metric_filters":[{"metric_name":"errors","pattern":"ERROR"}]}]}}}%s}`
+	for _, tc := range []struct {
+		metrics string
+		errors  int
+	}{
+		{"", 1},
+		{`,"metrics":{"metrics_collected":{"cpu":{}}}`, 0},
+	} {
+		var input interface{}
+		err := json.Unmarshal([]byte(fmt.Sprintf(config, tc.metrics)), &input)
+		if err != nil {
+			assert.Fail(t, err.Error())
+		}
+		translator.ResetMessages()
+		l.ApplyRule(input)
+		assert.Len(t, translator.ErrorMessages, tc.errors)
+	}
+	translator.ResetMessages()
+}
+
+func TestLogs_Archive(t *testing.T) {
+	context.ResetContext()
+	l := new(Logs)
+	agent.Global_Config.Region = "us-east-1"
+	agent.Global_Config.RegionType = "any"
+
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"logs":{"log_stream_name":"LOG_STREAM_NAME","archive":{"directory":"/var/log/cwagent-archive","rotation_interval":600,"s3_bucket":"bucket","s3_key_prefix":"host1","s3_force_path_style":true}}}`), &input)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+
+	_, actual := l.ApplyRule(input)
+	expected := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"cloudwatchlogs": []interface{}{
+				map[string]interface{}{
+					"region":               "us-east-1",
+					"region_type":          "any",
+					"mode":                 "",
+					"log_stream_name":      "LOG_STREAM_NAME",
+					"force_flush_interval": "5s",
+				},
+			},
+			"archive": []interface{}{
+				map[string]interface{}{
+					"directory":           "/var/log/cwagent-archive",
+					"max_file_size_mb":    100,
+					"rotation_interval":   "600s",
+					"s3_bucket":           "bucket",
+					"s3_key_prefix":       "host1",
+					"s3_force_path_style": true,
+					"region":              "us-east-1",
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, actual, "Expected to be equal")
+}
+
+func TestLogs_Concurrency(t *testing.T) {
+	context.ResetContext()
+	l := new(Logs)
+	agent.Global_Config.Region = "us-east-1"
+	agent.Global_Config.RegionType = "any"
+
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"logs":{"log_stream_name":"LOG_STREAM_NAME","concurrency":4}}`), &input)
+	if err != nil {
+		assert.Fail(t, err.Error())
+	}
+
+	_, actual := l.ApplyRule(input)
+	expected := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"cloudwatchlogs": []interface{}{
+				map[string]interface{}{
+					"region":               "us-east-1",
+					"region_type":          "any",
+					"mode":                 "",
+					"log_stream_name":      "LOG_STREAM_NAME",
+					"force_flush_interval": "5s",
+					"concurrency":          4,
+				},
+			},
+		},
+	}
+	assert.Equal(t, expected, actual, "Expected to be equal")
+}
@@ -0,0 +1,54 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package remotewrite
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+	parent "github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/metrics_collected/prometheus"
+)
+
+type Rule translator.Rule
+
+var ChildRule = map[string]Rule{}
+
+const (
+	SubSectionKey = "remote_write"
+)
+
+func GetCurPath() string {
+	curPath := parent.GetCurPath() + SubSectionKey + "/"
+	return curPath
+}
+
+func RegisterRule(fieldname string, r Rule) {
+	ChildRule[fieldname] = r
+}
+
+type RemoteWrite struct {
+}
+
+func (r *RemoteWrite) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	result := map[string]interface{}{}
+
+	if _, ok := im[SubSectionKey]; !ok {
+		returnKey = ""
+		returnVal = ""
+	} else {
+		for _, rule := range ChildRule {
+			key, val := rule.ApplyRule(im[SubSectionKey])
+			if key != "" {
+				result[key] = val
+			}
+		}
+		returnKey = SubSectionKey
+		returnVal = result
+	}
+	return
+}
+
+func init() {
+	r := new(RemoteWrite)
+	parent.RegisterRule(SubSectionKey, r)
+}
@@ -0,0 +1,26 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package remotewrite
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	SectionKeyListenAddress = "listen_address"
+
+	defaultListenAddress = "127.0.0.1:9201"
+)
+
+type ListenAddress struct {
+}
+
+func (l *ListenAddress) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	returnKey, returnVal = translator.DefaultCase(SectionKeyListenAddress, defaultListenAddress, input)
+	return
+}
+
+func init() {
+	RegisterRule(SectionKeyListenAddress, new(ListenAddress))
+}
@@ -0,0 +1,26 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package remotewrite
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	SectionKeyPath = "path"
+
+	defaultPath = "/api/v1/write"
+)
+
+type Path struct {
+}
+
+func (p *Path) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	returnKey, returnVal = translator.DefaultCase(SectionKeyPath, defaultPath, input)
+	return
+}
+
+func init() {
+	RegisterRule(SectionKeyPath, new(Path))
+}
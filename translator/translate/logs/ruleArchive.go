@@ -0,0 +1,73 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logs
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/agent"
+)
+
+const (
+	ArchiveSectionKey              = "archive"
+	archiveDirectoryKey            = "directory"
+	archiveMaxFileSizeMBKey        = "max_file_size_mb"
+	archiveRotationIntervalKey     = "rotation_interval"
+	archiveS3BucketKey             = "s3_bucket"
+	archiveS3KeyPrefixKey          = "s3_key_prefix"
+	archiveS3EndpointOverrideKey   = "s3_endpoint_override"
+	archiveS3ForcePathStyleKey     = "s3_force_path_style"
+	defaultArchiveMaxFileSizeMB    = 100
+	defaultArchiveRotationInterval = 3600
+)
+
+type Archive struct {
+}
+
+func (a *Archive) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	archive, ok := im[ArchiveSectionKey]
+	if !ok {
+		return
+	}
+	res := map[string]interface{}{}
+	_, dir := translator.DefaultCase(archiveDirectoryKey, "", archive)
+	if dir == "" {
+		translator.AddErrorMessages(GetCurPath()+ArchiveSectionKey, "archive directory must be set")
+		return
+	}
+	res[archiveDirectoryKey] = dir
+	_, res[archiveMaxFileSizeMBKey] = translator.DefaultIntegralCase(archiveMaxFileSizeMBKey, float64(defaultArchiveMaxFileSizeMB), archive)
+	_, res[archiveRotationIntervalKey] = translator.DefaultTimeIntervalCase(archiveRotationIntervalKey, float64(defaultArchiveRotationInterval), archive)
+
+	if _, bucket := translator.DefaultCase(archiveS3BucketKey, "", archive); bucket != "" {
+		res[archiveS3BucketKey] = bucket
+		for _, key := range []string{archiveS3KeyPrefixKey, archiveS3EndpointOverrideKey} {
+			if _, val := translator.DefaultCase(key, "", archive); val != "" {
+				res[key] = val
+			}
+		}
+		if _, val := translator.DefaultCase(archiveS3ForcePathStyleKey, false, archive); val == true {
+			res[archiveS3ForcePathStyleKey] = true
+		}
+		// the upload uses the same region and credentials as the cloudwatchlogs output
+		res = translator.MergeTwoUniqueMaps(res, agent.Global_Config.Credentials)
+		res[agent.RegionKey] = agent.Global_Config.Region
+		if agent.Global_Config.Role_arn != "" {
+			res[Role_Arn_Key] = agent.Global_Config.Role_arn
+		}
+		if val, ok := im[CredentialsSectionKey]; ok {
+			if roleArn, ok := val.(map[string]interface{})[Role_Arn_Key]; ok {
+				res[Role_Arn_Key] = roleArn
+			}
+		}
+	}
+
+	returnKey = Output_Archive
+	returnVal = res
+	return
+}
+
+func init() {
+	RegisterRule(ArchiveSectionKey, new(Archive))
+}
@@ -0,0 +1,32 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logs
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	ConcurrencyKey     = "concurrency"
+	defaultConcurrency = 1
+)
+
+// Concurrency is the max number of PutLogEvents requests in flight for each log stream.
+type Concurrency struct {
+}
+
+func (c *Concurrency) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	if _, ok := im[ConcurrencyKey]; !ok {
+		return
+	}
+	key, val := translator.DefaultIntegralCase(ConcurrencyKey, float64(defaultConcurrency), input)
+	returnKey = Output_Cloudwatch_Logs
+	returnVal = map[string]interface{}{key: val}
+	return
+}
+
+func init() {
+	RegisterRule(ConcurrencyKey, new(Concurrency))
+}
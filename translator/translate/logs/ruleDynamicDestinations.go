@@ -0,0 +1,41 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logs
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	DynamicDestinationsSectionKey           = "dynamic_destinations"
+	dynamicDestinationsMaxKey               = "max_destinations"
+	dynamicDestinationsIdleTimeoutKey       = "idle_timeout"
+	maxDynamicDestinationsTomlKey           = "max_dynamic_destinations"
+	dynamicDestinationIdleTimeoutTomlKey    = "dynamic_destination_idle_timeout"
+	defaultMaxDynamicDestinations           = 100
+	defaultDynamicDestinationIdleTimeoutSec = 300
+)
+
+// DynamicDestinations bounds the destinations of the log group and stream names evaluated for
+// each event.
+type DynamicDestinations struct {
+}
+
+func (d *DynamicDestinations) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	dynamicDestinations, ok := im[DynamicDestinationsSectionKey]
+	if !ok {
+		return
+	}
+	res := map[string]interface{}{}
+	_, res[maxDynamicDestinationsTomlKey] = translator.DefaultIntegralCase(dynamicDestinationsMaxKey, float64(defaultMaxDynamicDestinations), dynamicDestinations)
+	_, res[dynamicDestinationIdleTimeoutTomlKey] = translator.DefaultTimeIntervalCase(dynamicDestinationsIdleTimeoutKey, float64(defaultDynamicDestinationIdleTimeoutSec), dynamicDestinations)
+	returnKey = Output_Cloudwatch_Logs
+	returnVal = res
+	return
+}
+
+func init() {
+	RegisterRule(DynamicDestinationsSectionKey, new(DynamicDestinations))
+}
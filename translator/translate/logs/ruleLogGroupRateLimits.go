@@ -0,0 +1,57 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logs
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+	logUtil "github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/util"
+)
+
+const (
+	LogGroupRateLimitsSectionKey = "log_group_rate_limits"
+	logGroupRateLimitTomlKey     = "log_group_rate_limit"
+	rateLimitLogGroupNameKey     = "log_group_name"
+)
+
+type LogGroupRateLimits struct {
+}
+
+func (l *LogGroupRateLimits) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	rateLimits, ok := im[LogGroupRateLimitsSectionKey].([]interface{})
+	if !ok {
+		return
+	}
+	path := GetCurPath() + LogGroupRateLimitsSectionKey
+	var res []interface{}
+	for _, rateLimit := range rateLimits {
+		rl, ok := rateLimit.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		_, group := translator.DefaultCase(rateLimitLogGroupNameKey, "", rl)
+		if group == "" {
+			translator.AddErrorMessages(path, fmt.Sprintf("Rate limit %v has no log group name", rl))
+			continue
+		}
+		limit, ok := logUtil.TranslateRateLimit(rl, path)
+		if !ok {
+			continue
+		}
+		limit[rateLimitLogGroupNameKey] = group
+		res = append(res, limit)
+	}
+	if len(res) == 0 {
+		return
+	}
+	returnKey = Output_Cloudwatch_Logs
+	returnVal = map[string]interface{}{logGroupRateLimitTomlKey: res}
+	return
+}
+
+func init() {
+	RegisterRule(LogGroupRateLimitsSectionKey, new(LogGroupRateLimits))
+}
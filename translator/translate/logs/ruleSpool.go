@@ -0,0 +1,43 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package logs
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	SpoolSectionKey       = "spool"
+	spoolDirectoryKey     = "directory"
+	spoolMaxSizeMBKey     = "max_size_mb"
+	spoolDirTomlKey       = "spool_dir"
+	spoolMaxSizeMBTomlKey = "spool_max_size_mb"
+	defaultSpoolMaxSizeMB = 100
+)
+
+type Spool struct {
+}
+
+func (s *Spool) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	im := input.(map[string]interface{})
+	spool, ok := im[SpoolSectionKey]
+	if !ok {
+		return
+	}
+	res := map[string]interface{}{}
+	_, dir := translator.DefaultCase(spoolDirectoryKey, "", spool)
+	if dir == "" {
+		translator.AddErrorMessages(GetCurPath()+SpoolSectionKey, "spool directory must be set")
+		return
+	}
+	res[spoolDirTomlKey] = dir
+	_, res[spoolMaxSizeMBTomlKey] = translator.DefaultIntegralCase(spoolMaxSizeMBKey, float64(defaultSpoolMaxSizeMB), spool)
+	returnKey = Output_Cloudwatch_Logs
+	returnVal = res
+	return
+}
+
+func init() {
+	RegisterRule(SpoolSectionKey, new(Spool))
+}
@@ -0,0 +1,56 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-cloudwatch-agent/logs/ratelimit"
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	RateLimitSectionKey         = "rate_limit"
+	rateLimitMaxEventsPerSecond = "max_events_per_second"
+	rateLimitMaxKBPerSecond     = "max_kb_per_second"
+	rateLimitOverflowPolicy     = "overflow_policy"
+	rateLimitBufferSize         = "buffer_size"
+	rateLimitSampleRatio        = "sample_ratio"
+)
+
+// TranslateRateLimit returns the rate limit config of the logs plugins. Only the keys set in the
+// json config are translated, the plugins apply the defaults of the others.
+func TranslateRateLimit(input map[string]interface{}, curPath string) (map[string]interface{}, bool) {
+	res := map[string]interface{}{}
+	for _, key := range []string{rateLimitMaxEventsPerSecond, rateLimitMaxKBPerSecond} {
+		if _, ok := input[key]; ok {
+			_, res[key] = translator.DefaultCase(key, float64(0), input)
+		}
+	}
+	if len(res) == 0 {
+		translator.AddErrorMessages(curPath, fmt.Sprintf("Rate limit %v has neither %s nor %s", input, rateLimitMaxEventsPerSecond, rateLimitMaxKBPerSecond))
+		return nil, false
+	}
+	if _, policy := translator.DefaultCase(rateLimitOverflowPolicy, "", input); policy != "" {
+		res[rateLimitOverflowPolicy] = policy
+	}
+	for _, key := range []string{rateLimitBufferSize, rateLimitSampleRatio} {
+		if _, ok := input[key]; ok {
+			_, res[key] = translator.DefaultIntegralCase(key, float64(0), input)
+		}
+	}
+
+	// validate the same way the plugins do
+	cfg := ratelimit.Config{}
+	cfg.MaxEventsPerSecond, _ = res[rateLimitMaxEventsPerSecond].(float64)
+	cfg.MaxKBPerSecond, _ = res[rateLimitMaxKBPerSecond].(float64)
+	cfg.OverflowPolicy, _ = res[rateLimitOverflowPolicy].(string)
+	cfg.BufferSize, _ = res[rateLimitBufferSize].(int)
+	cfg.SampleRatio, _ = res[rateLimitSampleRatio].(int)
+	if err := cfg.Init(); err != nil {
+		translator.AddErrorMessages(curPath, fmt.Sprintf("Rate limit %v is invalid: %v", input, err))
+		return nil, false
+	}
+	return res, true
+}
@@ -0,0 +1,55 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+// The connection limits only apply to the tcp:// and unix:// service addresses.
+const (
+	SectionKey_MaxConnections        = "max_connections"
+	SectionKey_ConnectionIdleTimeout = "connection_idle_timeout"
+	SectionKey_MaxLineLength         = "max_line_length"
+)
+
+type MaxConnections struct {
+}
+
+func (obj *MaxConnections) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	key, val := translator.DefaultCase(SectionKey_MaxConnections, "", input)
+	if val != "" {
+		// By default json unmarshal will store number as float64
+		return key, int(val.(float64))
+	}
+	return
+}
+
+type ConnectionIdleTimeout struct {
+}
+
+func (obj *ConnectionIdleTimeout) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	if _, val := translator.DefaultCase(SectionKey_ConnectionIdleTimeout, "", input); val == "" {
+		return
+	}
+	return translator.DefaultTimeIntervalCase(SectionKey_ConnectionIdleTimeout, float64(0), input)
+}
+
+type MaxLineLength struct {
+}
+
+func (obj *MaxLineLength) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	key, val := translator.DefaultCase(SectionKey_MaxLineLength, "", input)
+	if val != "" {
+		// By default json unmarshal will store number as float64
+		return key, int(val.(float64))
+	}
+	return
+}
+
+func init() {
+	RegisterRule(SectionKey_MaxConnections, new(MaxConnections))
+	RegisterRule(SectionKey_ConnectionIdleTimeout, new(ConnectionIdleTimeout))
+	RegisterRule(SectionKey_MaxLineLength, new(MaxLineLength))
+}
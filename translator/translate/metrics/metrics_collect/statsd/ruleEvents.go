@@ -0,0 +1,44 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/util"
+)
+
+// The dogstatsd events and service checks are published through the cloudwatchlogs output,
+// which is only configured along with the logs section.
+const (
+	SectionKey_EventsLogGroupName  = "events_log_group_name"
+	SectionKey_EventsLogStreamName = "events_log_stream_name"
+)
+
+type EventsLogGroupName struct {
+}
+
+func (obj *EventsLogGroupName) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	key, val := translator.DefaultCase(SectionKey_EventsLogGroupName, "", input)
+	if val != "" {
+		return key, val
+	}
+	return
+}
+
+type EventsLogStreamName struct {
+}
+
+func (obj *EventsLogStreamName) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	if _, group := translator.DefaultCase(SectionKey_EventsLogGroupName, "", input); group == "" {
+		return
+	}
+	_, val := translator.DefaultCase(SectionKey_EventsLogStreamName, "", input)
+	// the stream defaults to the instance id
+	return SectionKey_EventsLogStreamName, util.ResolvePlaceholder(val.(string), util.GetMetadataInfo(util.Ec2MetadataInfoProvider))
+}
+
+func init() {
+	RegisterRule(SectionKey_EventsLogGroupName, new(EventsLogGroupName))
+	RegisterRule(SectionKey_EventsLogStreamName, new(EventsLogStreamName))
+}
@@ -0,0 +1,36 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+type TimingPercentiles struct {
+}
+
+const SectionKey_TimingPercentiles = "timing_percentiles"
+
+func (obj *TimingPercentiles) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	key, val := translator.DefaultCase(SectionKey_TimingPercentiles, "", input)
+	if val == "" {
+		return
+	}
+	percentiles, ok := val.([]interface{})
+	if !ok || len(percentiles) == 0 {
+		return
+	}
+	for _, p := range percentiles {
+		if f, ok := p.(float64); !ok || f <= 0 || f >= 100 {
+			translator.AddErrorMessages(GetCurPath()+SectionKey_TimingPercentiles, "timing percentiles must be numbers between 0 and 100 exclusive")
+			return
+		}
+	}
+	return key, percentiles
+}
+
+func init() {
+	obj := new(TimingPercentiles)
+	RegisterRule(SectionKey_TimingPercentiles, obj)
+}
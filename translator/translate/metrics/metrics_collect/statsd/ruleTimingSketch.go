@@ -0,0 +1,54 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package statsd
+
+import (
+	"github.com/aws/amazon-cloudwatch-agent/translator"
+)
+
+const (
+	SectionKey_TimingSketch                 = "timing_sketch"
+	SectionKey_TimingSketchRelativeAccuracy = "timing_sketch_relative_accuracy"
+	SectionKey_TimingSketchMaxBuckets       = "timing_sketch_max_buckets"
+)
+
+type TimingSketch struct {
+}
+
+func (obj *TimingSketch) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	key, val := translator.DefaultCase(SectionKey_TimingSketch, false, input)
+	if val == true {
+		return key, val
+	}
+	return
+}
+
+type TimingSketchRelativeAccuracy struct {
+}
+
+func (obj *TimingSketchRelativeAccuracy) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	key, val := translator.DefaultCase(SectionKey_TimingSketchRelativeAccuracy, "", input)
+	if val != "" {
+		return key, val
+	}
+	return
+}
+
+type TimingSketchMaxBuckets struct {
+}
+
+func (obj *TimingSketchMaxBuckets) ApplyRule(input interface{}) (returnKey string, returnVal interface{}) {
+	key, val := translator.DefaultCase(SectionKey_TimingSketchMaxBuckets, "", input)
+	if val != "" {
+		// By default json unmarshal will store number as float64
+		return key, int(val.(float64))
+	}
+	return
+}
+
+func init() {
+	RegisterRule(SectionKey_TimingSketch, new(TimingSketch))
+	RegisterRule(SectionKey_TimingSketchRelativeAccuracy, new(TimingSketchRelativeAccuracy))
+	RegisterRule(SectionKey_TimingSketchMaxBuckets, new(TimingSketchMaxBuckets))
+}
@@ -100,3 +100,85 @@ func TestStatsD_MetricSeparator(t *testing.T) {
 
 	assert.Equal(t, expect, actual)
 }
+
+func TestStatsD_TimingSketch(t *testing.T) {
+	obj := new(StatsD)
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"statsd": {
+					"timing_sketch": true,
+					"timing_sketch_relative_accuracy": 0.1,
+					"timing_sketch_max_buckets": 512,
+					"timing_percentiles": [50, 90, 99.9]
+					}}`), &input)
+	assert.NoError(t, err)
+
+	_, actual := obj.ApplyRule(input)
+
+	expect := []interface{}{
+		map[string]interface{}{
+			"service_address":                 ":8125",
+			"interval":                        "10s",
+			"parse_data_dog_tags":             true,
+			"tags":                            map[string]interface{}{"aws:AggregationInterval": "60s"},
+			"timing_sketch":                   true,
+			"timing_sketch_relative_accuracy": 0.1,
+			"timing_sketch_max_buckets":       512,
+			"timing_percentiles":              []interface{}{50.0, 90.0, 99.9},
+		},
+	}
+
+	assert.Equal(t, expect, actual)
+}
+
+func TestStatsD_StreamListener(t *testing.T) {
+	obj := new(StatsD)
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"statsd": {
+					"service_address": "unix:///var/run/statsd.sock",
+					"max_connections": 10,
+					"connection_idle_timeout": 300,
+					"max_line_length": 4096
+					}}`), &input)
+	assert.NoError(t, err)
+
+	_, actual := obj.ApplyRule(input)
+
+	expect := []interface{}{
+		map[string]interface{}{
+			"service_address":         "unix:///var/run/statsd.sock",
+			"interval":                "10s",
+			"parse_data_dog_tags":     true,
+			"tags":                    map[string]interface{}{"aws:AggregationInterval": "60s"},
+			"max_connections":         10,
+			"connection_idle_timeout": "300s",
+			"max_line_length":         4096,
+		},
+	}
+
+	assert.Equal(t, expect, actual)
+}
+
+func TestStatsD_Events(t *testing.T) {
+	obj := new(StatsD)
+	var input interface{}
+	err := json.Unmarshal([]byte(`{"statsd": {
+					"events_log_group_name": "statsd-events",
+					"events_log_stream_name": "my-stream"
+					}}`), &input)
+	assert.NoError(t, err)
+
+	_, actual := obj.ApplyRule(input)
+
+	expect := []interface{}{
+		map[string]interface{}{
+			"service_address":        ":8125",
+			"interval":               "10s",
+			"parse_data_dog_tags":    true,
+			"tags":                   map[string]interface{}{"aws:AggregationInterval": "60s"},
+			"events_log_group_name":  "statsd-events",
+			"events_log_stream_name": "my-stream",
+		},
+	}
+
+	assert.Equal(t, expect, actual)
+}
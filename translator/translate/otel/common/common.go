@@ -32,6 +32,7 @@ const (
 	DisableMetricExtraction            = "disable_metric_extraction"
 	XrayKey                            = "xray"
 	OtlpKey                            = "otlp"
+	TailSamplingKey                    = "tail_sampling"
 	JmxKey                             = "jmx"
 	TLSKey                             = "tls"
 	Endpoint                           = "endpoint"
@@ -45,6 +46,7 @@ const (
 	MetricsCollectionIntervalKey       = "metrics_collection_interval"
 	MeasurementKey                     = "measurement"
 	DropOriginalMetricsKey             = "drop_original_metrics"
+	DerivedKey                         = "derived"
 	ForceFlushIntervalKey              = "force_flush_interval"
 	ContainerInsightsMetricGranularity = "metric_granularity" // replaced with enhanced_container_insights
 	EnhancedContainerInsights          = "enhanced_container_insights"
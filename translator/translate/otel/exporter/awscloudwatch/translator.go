@@ -20,9 +20,22 @@ import (
 )
 
 const (
-	namespaceKey          = "namespace"
-	forceFlushIntervalKey = "force_flush_interval"
-	dropOriginalWildcard  = "*"
+	namespaceKey           = "namespace"
+	forceFlushIntervalKey  = "force_flush_interval"
+	bufferKey              = "buffer"
+	bufferDirectoryKey     = "directory"
+	bufferMaxSizeMBKey     = "max_size_mb"
+	bufferMaxAgeKey        = "max_age"
+	cardinalityLimitKey    = "cardinality_limit"
+	maxDimensionSetsKey    = "max_dimension_sets_per_metric"
+	rotationIntervalKey    = "rotation_interval"
+	statisticSetKey        = "statistic_set"
+	aggregationIntervalKey = "aggregation_interval"
+	compressionKey         = "compression"
+	dropOriginalWildcard   = "*"
+	dropRuleMetricKey      = "metric"
+	dropRuleDimensionsKey  = "dimensions"
+	dropRuleActionKey      = "action"
 
 	internalMaxValuesPerDatum = 5000
 )
@@ -76,10 +89,52 @@ func (t *translator) Translate(conf *confmap.Conf) (component.Config, error) {
 	if dropOriginalMetrics := getDropOriginalMetrics(conf); len(dropOriginalMetrics) != 0 {
 		cfg.DropOriginalConfigs = dropOriginalMetrics
 	}
+	cfg.DropOriginalRules = getDropOriginalRules(conf)
+	setBuffer(conf, cfg)
+	setCardinalityLimit(conf, cfg)
+	setStatisticSet(conf, cfg)
 	cfg.MiddlewareID = &agenthealth.MetricsID
 	return cfg, nil
 }
 
+func setBuffer(conf *confmap.Conf, cfg *cloudwatch.Config) {
+	directory, ok := common.GetString(conf, common.ConfigKey(common.MetricsKey, bufferKey, bufferDirectoryKey))
+	if !ok {
+		return
+	}
+	cfg.BufferDir = directory
+	if maxSizeMB, ok := common.GetNumber(conf, common.ConfigKey(common.MetricsKey, bufferKey, bufferMaxSizeMBKey)); ok {
+		cfg.BufferMaxSizeMB = int64(maxSizeMB)
+	}
+	if maxAge, ok := common.GetDuration(conf, common.ConfigKey(common.MetricsKey, bufferKey, bufferMaxAgeKey)); ok {
+		cfg.BufferMaxAge = maxAge
+	}
+}
+
+func setCardinalityLimit(conf *confmap.Conf, cfg *cloudwatch.Config) {
+	maxDimensionSets, ok := common.GetNumber(conf, common.ConfigKey(common.MetricsKey, cardinalityLimitKey, maxDimensionSetsKey))
+	if !ok {
+		return
+	}
+	cfg.MaxDimensionSetsPerMetric = int(maxDimensionSets)
+	if rotationInterval, ok := common.GetDuration(conf, common.ConfigKey(common.MetricsKey, cardinalityLimitKey, rotationIntervalKey)); ok {
+		cfg.CardinalityRotationInterval = rotationInterval
+	}
+}
+
+func setStatisticSet(conf *confmap.Conf, cfg *cloudwatch.Config) {
+	if !conf.IsSet(common.ConfigKey(common.MetricsKey, statisticSetKey)) {
+		return
+	}
+	cfg.StatisticSetOnly = true
+	if interval, ok := common.GetDuration(conf, common.ConfigKey(common.MetricsKey, statisticSetKey, aggregationIntervalKey)); ok {
+		cfg.StatisticSetInterval = interval
+	}
+	if compression, ok := common.GetString(conf, common.ConfigKey(common.MetricsKey, statisticSetKey, compressionKey)); ok {
+		cfg.StatisticSetCompression = compression
+	}
+}
+
 func getRoleARN(conf *confmap.Conf) string {
 	key := common.ConfigKey(common.MetricsKey, common.CredentialsKey, common.RoleARNKey)
 	roleARN, ok := common.GetString(conf, key)
@@ -237,3 +292,28 @@ func getDropOriginalMetrics(conf *confmap.Conf) map[string]bool {
 	}
 	return dropOriginalMetrics
 }
+
+// getDropOriginalRules returns the rules of metrics.drop_original_metrics, which unlike the
+// drop_original_metrics of the plugins match the metrics by their name and dimensions.
+func getDropOriginalRules(conf *confmap.Conf) []cloudwatch.DropOriginalRule {
+	var rules []cloudwatch.DropOriginalRule
+	for _, value := range common.GetArray[any](conf, common.ConfigKey(common.MetricsKey, common.DropOriginalMetricsKey)) {
+		ruleMap, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rule := cloudwatch.DropOriginalRule{}
+		rule.Metric, _ = ruleMap[dropRuleMetricKey].(string)
+		rule.Action, _ = ruleMap[dropRuleActionKey].(string)
+		if dimensions, ok := ruleMap[dropRuleDimensionsKey].(map[string]interface{}); ok {
+			rule.Dimensions = make(map[string]string, len(dimensions))
+			for name, pattern := range dimensions {
+				if pattern, ok := pattern.(string); ok {
+					rule.Dimensions[name] = pattern
+				}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
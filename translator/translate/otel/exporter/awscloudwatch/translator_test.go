@@ -63,6 +63,105 @@ func TestTranslator(t *testing.T) {
 				RoleARN:            "global_arn",
 			},
 		},
+		"WithBuffer": {
+			input: map[string]interface{}{"metrics": map[string]interface{}{
+				"buffer": map[string]interface{}{
+					"directory":   "/var/lib/amazon-cloudwatch-agent/buffer",
+					"max_size_mb": float64(500),
+					"max_age":     float64(7200),
+				},
+			}},
+			want: &cloudwatch.Config{
+				Namespace:          "CWAgent",
+				Region:             "us-east-1",
+				ForceFlushInterval: time.Minute,
+				MaxValuesPerDatum:  150,
+				RoleARN:            "global_arn",
+				BufferDir:          "/var/lib/amazon-cloudwatch-agent/buffer",
+				BufferMaxSizeMB:    500,
+				BufferMaxAge:       2 * time.Hour,
+			},
+		},
+		"WithCardinalityLimit": {
+			input: map[string]interface{}{"metrics": map[string]interface{}{
+				"cardinality_limit": map[string]interface{}{
+					"max_dimension_sets_per_metric": float64(500),
+					"rotation_interval":             float64(1800),
+				},
+			}},
+			want: &cloudwatch.Config{
+				Namespace:                   "CWAgent",
+				Region:                      "us-east-1",
+				ForceFlushInterval:          time.Minute,
+				MaxValuesPerDatum:           150,
+				RoleARN:                     "global_arn",
+				MaxDimensionSetsPerMetric:   500,
+				CardinalityRotationInterval: 30 * time.Minute,
+			},
+		},
+		"WithStatisticSet": {
+			input: map[string]interface{}{"metrics": map[string]interface{}{
+				"statistic_set": map[string]interface{}{
+					"aggregation_interval": float64(30),
+					"compression":          "seh1",
+				},
+			}},
+			want: &cloudwatch.Config{
+				Namespace:               "CWAgent",
+				Region:                  "us-east-1",
+				ForceFlushInterval:      time.Minute,
+				MaxValuesPerDatum:       150,
+				RoleARN:                 "global_arn",
+				StatisticSetOnly:        true,
+				StatisticSetInterval:    30 * time.Second,
+				StatisticSetCompression: "seh1",
+			},
+		},
+		"WithEmptyStatisticSet": {
+			input: map[string]interface{}{"metrics": map[string]interface{}{
+				"statistic_set": map[string]interface{}{},
+			}},
+			want: &cloudwatch.Config{
+				Namespace:          "CWAgent",
+				Region:             "us-east-1",
+				ForceFlushInterval: time.Minute,
+				MaxValuesPerDatum:  150,
+				RoleARN:            "global_arn",
+				StatisticSetOnly:   true,
+			},
+		},
+		"WithDropOriginalRules": {
+			input: map[string]interface{}{"metrics": map[string]interface{}{
+				"drop_original_metrics": []interface{}{
+					map[string]interface{}{
+						"metric":     "disk_used_percent",
+						"dimensions": map[string]interface{}{"path": "/"},
+						"action":     "keep",
+					},
+					map[string]interface{}{
+						"metric":     "disk_*",
+						"dimensions": map[string]interface{}{"path": "/mnt/ephemeral*"},
+						"action":     "drop",
+					},
+					map[string]interface{}{
+						"metric": "disk_used_percent",
+						"action": "drop",
+					},
+				},
+			}},
+			want: &cloudwatch.Config{
+				Namespace:          "CWAgent",
+				Region:             "us-east-1",
+				ForceFlushInterval: time.Minute,
+				MaxValuesPerDatum:  150,
+				RoleARN:            "global_arn",
+				DropOriginalRules: []cloudwatch.DropOriginalRule{
+					{Metric: "disk_used_percent", Dimensions: map[string]string{"path": "/"}, Action: "keep"},
+					{Metric: "disk_*", Dimensions: map[string]string{"path": "/mnt/ephemeral*"}, Action: "drop"},
+					{Metric: "disk_used_percent", Action: "drop"},
+				},
+			},
+		},
 		"WithInvalidCredentialFields": {
 			input: map[string]interface{}{"metrics": map[string]interface{}{}},
 			credentials: map[string]interface{}{
@@ -159,6 +258,15 @@ func TestTranslator(t *testing.T) {
 				assert.Equal(t, testCase.want.SharedCredentialFilename, gotCfg.SharedCredentialFilename)
 				assert.Equal(t, testCase.want.MaxValuesPerDatum, gotCfg.MaxValuesPerDatum)
 				assert.Equal(t, testCase.want.RollupDimensions, gotCfg.RollupDimensions)
+				assert.Equal(t, testCase.want.BufferDir, gotCfg.BufferDir)
+				assert.Equal(t, testCase.want.BufferMaxSizeMB, gotCfg.BufferMaxSizeMB)
+				assert.Equal(t, testCase.want.BufferMaxAge, gotCfg.BufferMaxAge)
+				assert.Equal(t, testCase.want.MaxDimensionSetsPerMetric, gotCfg.MaxDimensionSetsPerMetric)
+				assert.Equal(t, testCase.want.CardinalityRotationInterval, gotCfg.CardinalityRotationInterval)
+				assert.Equal(t, testCase.want.DropOriginalRules, gotCfg.DropOriginalRules)
+				assert.Equal(t, testCase.want.StatisticSetOnly, gotCfg.StatisticSetOnly)
+				assert.Equal(t, testCase.want.StatisticSetInterval, gotCfg.StatisticSetInterval)
+				assert.Equal(t, testCase.want.StatisticSetCompression, gotCfg.StatisticSetCompression)
 				assert.NotNil(t, gotCfg.MiddlewareID)
 				assert.Equal(t, "agenthealth/metrics", gotCfg.MiddlewareID.String())
 				if testCase.wantWindows != nil && runtime.GOOS == "windows" {
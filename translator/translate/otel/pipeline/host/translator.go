@@ -13,6 +13,7 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/exporter/awscloudwatch"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/extension/agenthealth"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/processor/cumulativetodeltaprocessor"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/processor/derivedmetrics"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/processor/ec2taggerprocessor"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/processor/metricsdecorator"
 	otlpReceiver "github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/receiver/otlp"
@@ -87,5 +88,11 @@ func (t translator) Translate(conf *confmap.Conf) (*common.ComponentTranslators,
 		log.Printf("D! metric decorator required because measurement fields are set")
 		translators.Processors.Set(mdt)
 	}
+
+	// the derived metrics are computed from the decorated metrics, with the dimensions appended
+	if conf.IsSet(common.ConfigKey(common.MetricsKey, common.DerivedKey)) {
+		log.Printf("D! derived metrics processor required because derived metrics are set")
+		translators.Processors.Set(derivedmetrics.NewTranslator())
+	}
 	return &translators, nil
 }
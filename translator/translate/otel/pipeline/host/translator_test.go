@@ -105,6 +105,29 @@ func TestTranslator(t *testing.T) {
 				extensions: []string{"agenthealth/metrics"},
 			},
 		},
+		"WithDerivedMetrics": {
+			input: map[string]interface{}{
+				"metrics": map[string]interface{}{
+					"append_dimensions": map[string]interface{}{
+						"InstanceId": "${aws:InstanceId}",
+					},
+					"derived": []interface{}{
+						map[string]interface{}{
+							"name":       "mem_used_ratio",
+							"expression": "mem_used / mem_total",
+						},
+					},
+				},
+			},
+			pipelineName: common.PipelineNameHost,
+			want: &want{
+				pipelineID: "metrics/host",
+				receivers:  []string{"nop", "other"},
+				processors: []string{"ec2tagger", "derivedmetrics"},
+				exporters:  []string{"awscloudwatch"},
+				extensions: []string{"agenthealth/metrics"},
+			},
+		},
 		"WithoutMetricDecoration": {
 			input: map[string]interface{}{
 				"metrics": map[string]interface{}{
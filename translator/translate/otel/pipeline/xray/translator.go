@@ -14,6 +14,7 @@ import (
 	awsxrayexporter "github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/exporter/awsxray"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/extension/agenthealth"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/processor"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/processor/tailsamplingprocessor"
 	awsxrayreceiver "github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/receiver/awsxray"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/receiver/otlp"
 )
@@ -25,6 +26,8 @@ const (
 var (
 	xrayKey = common.ConfigKey(common.TracesKey, common.TracesCollectedKey, common.XrayKey)
 	otlpKey = common.ConfigKey(common.TracesKey, common.TracesCollectedKey, common.OtlpKey)
+
+	tailSamplingKey = common.ConfigKey(common.TracesKey, common.TracesCollectedKey, common.TailSamplingKey)
 )
 
 type translator struct {
@@ -46,7 +49,7 @@ func (t *translator) Translate(conf *confmap.Conf) (*common.ComponentTranslators
 	}
 	translators := &common.ComponentTranslators{
 		Receivers:  common.NewTranslatorMap[component.Config](),
-		Processors: common.NewTranslatorMap(processor.NewDefaultTranslatorWithName(pipelineName, batchprocessor.NewFactory())),
+		Processors: common.NewTranslatorMap[component.Config](),
 		Exporters:  common.NewTranslatorMap(awsxrayexporter.NewTranslator()),
 		Extensions: common.NewTranslatorMap(agenthealth.NewTranslator(component.DataTypeTraces, []string{agenthealth.OperationPutTraceSegments})),
 	}
@@ -56,5 +59,10 @@ func (t *translator) Translate(conf *confmap.Conf) (*common.ComponentTranslators
 	if conf.IsSet(otlpKey) {
 		translators.Receivers.Set(otlp.NewTranslator(otlp.WithDataType(component.DataTypeTraces)))
 	}
+	// sampling decisions need every span of the trace, so they're made before batching
+	if conf.IsSet(tailSamplingKey) {
+		translators.Processors.Set(tailsamplingprocessor.NewTranslatorWithName(pipelineName))
+	}
+	translators.Processors.Set(processor.NewDefaultTranslatorWithName(pipelineName, batchprocessor.NewFactory()))
 	return translators, nil
 }
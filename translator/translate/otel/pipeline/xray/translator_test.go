@@ -80,6 +80,22 @@ func TestTranslator(t *testing.T) {
 				extensions: []string{"agenthealth/traces"},
 			},
 		},
+		"WithTailSamplingKey": {
+			input: map[string]interface{}{
+				"traces": map[string]interface{}{
+					"traces_collected": map[string]interface{}{
+						"xray":          nil,
+						"tail_sampling": map[string]interface{}{},
+					},
+				},
+			},
+			want: &want{
+				receivers:  []string{"awsxray"},
+				processors: []string{"tail_sampling/xray", "batch/xray"},
+				exporters:  []string{"awsxray"},
+				extensions: []string{"agenthealth/traces"},
+			},
+		},
 	}
 	for name, testCase := range testCases {
 		t.Run(name, func(t *testing.T) {
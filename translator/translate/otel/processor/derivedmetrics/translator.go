@@ -0,0 +1,68 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/processor"
+
+	"github.com/aws/amazon-cloudwatch-agent/plugins/processors/derivedmetrics"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/common"
+)
+
+const (
+	nameKey       = "name"
+	expressionKey = "expression"
+	unitKey       = "unit"
+
+	defaultMetricsCollectionInterval = time.Minute
+)
+
+var configKey = common.ConfigKey(common.MetricsKey, common.DerivedKey)
+
+type translator struct {
+	name    string
+	factory processor.Factory
+}
+
+var _ common.Translator[component.Config] = (*translator)(nil)
+
+func NewTranslator() common.Translator[component.Config] {
+	return NewTranslatorWithName("")
+}
+
+func NewTranslatorWithName(name string) common.Translator[component.Config] {
+	return &translator{name, derivedmetrics.NewFactory()}
+}
+
+func (t *translator) ID() component.ID {
+	return component.NewIDWithName(t.factory.Type(), t.name)
+}
+
+// Translate creates a derived metrics processor config from the metrics.derived section, the
+// metrics being collected every metrics_collection_interval of the agent section. The inputs
+// with a shorter interval collect their metrics several times in it, which the processor
+// derives one collection at a time.
+func (t *translator) Translate(conf *confmap.Conf) (component.Config, error) {
+	if conf == nil || !conf.IsSet(configKey) {
+		return nil, &common.MissingKeyError{ID: t.ID(), JsonKey: configKey}
+	}
+	cfg := t.factory.CreateDefaultConfig().(*derivedmetrics.Config)
+	cfg.Interval = common.GetOrDefaultDuration(conf, []string{common.ConfigKey(common.AgentKey, common.MetricsCollectionIntervalKey)}, defaultMetricsCollectionInterval)
+	for _, value := range common.GetArray[any](conf, configKey) {
+		metric, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var m derivedmetrics.MetricConfig
+		m.Name, _ = metric[nameKey].(string)
+		m.Expression, _ = metric[expressionKey].(string)
+		m.Unit, _ = metric[unitKey].(string)
+		cfg.Metrics = append(cfg.Metrics, m)
+	}
+	return cfg, nil
+}
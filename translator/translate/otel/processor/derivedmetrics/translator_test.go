@@ -0,0 +1,78 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package derivedmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/aws/amazon-cloudwatch-agent/plugins/processors/derivedmetrics"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/common"
+)
+
+func TestTranslator(t *testing.T) {
+	dmTranslator := NewTranslator()
+	require.EqualValues(t, "derivedmetrics", dmTranslator.ID().String())
+	derived := []interface{}{
+		map[string]interface{}{
+			"name":       "mem_used_ratio",
+			"expression": "mem_used / mem_total * 100",
+			"unit":       "Percent",
+		},
+		map[string]interface{}{
+			"name":       "error_ratio",
+			"expression": "errors / requests",
+		},
+	}
+	testCases := map[string]struct {
+		input   map[string]interface{}
+		want    *derivedmetrics.Config
+		wantErr error
+	}{
+		"MissingDerived": {
+			input: map[string]interface{}{"metrics": map[string]interface{}{}},
+			wantErr: &common.MissingKeyError{
+				ID:      dmTranslator.ID(),
+				JsonKey: "metrics::derived",
+			},
+		},
+		"WithDefaultInterval": {
+			input: map[string]interface{}{"metrics": map[string]interface{}{"derived": derived}},
+			want: &derivedmetrics.Config{
+				Interval: time.Minute,
+				Metrics: []derivedmetrics.MetricConfig{
+					{Name: "mem_used_ratio", Expression: "mem_used / mem_total * 100", Unit: "Percent"},
+					{Name: "error_ratio", Expression: "errors / requests"},
+				},
+			},
+		},
+		"WithAgentInterval": {
+			input: map[string]interface{}{
+				"agent":   map[string]interface{}{"metrics_collection_interval": 10},
+				"metrics": map[string]interface{}{"derived": derived[1:]},
+			},
+			want: &derivedmetrics.Config{
+				Interval: 10 * time.Second,
+				Metrics: []derivedmetrics.MetricConfig{
+					{Name: "error_ratio", Expression: "errors / requests"},
+				},
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf := confmap.NewFromStringMap(testCase.input)
+			got, err := dmTranslator.Translate(conf)
+			assert.Equal(t, testCase.wantErr, err)
+			if testCase.want != nil {
+				require.NoError(t, err)
+				assert.Equal(t, testCase.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package tailsamplingprocessor
+
+import (
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/confmap"
+	"go.opentelemetry.io/collector/processor"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/common"
+)
+
+const (
+	decisionWaitKey            = "decision_wait"
+	numTracesKey               = "num_traces"
+	expectedNewTracesPerSecKey = "expected_new_traces_per_sec"
+	latencyThresholdKey        = "latency_threshold_ms"
+	samplingPercentageKey      = "sampling_percentage"
+
+	defaultDecisionWait       = 30 * time.Second
+	defaultNumTraces          = 50000
+	defaultSamplingPercentage = 10
+
+	errorsPolicyName        = "errors"
+	latencyPolicyName       = "latency"
+	probabilisticPolicyName = "probabilistic"
+	statusCodeError         = "ERROR"
+)
+
+var (
+	tailSamplingKey = common.ConfigKey(common.TracesKey, common.TracesCollectedKey, common.TailSamplingKey)
+)
+
+type translator struct {
+	name    string
+	factory processor.Factory
+}
+
+var _ common.Translator[component.Config] = (*translator)(nil)
+
+func NewTranslatorWithName(name string) common.Translator[component.Config] {
+	return &translator{name, tailsamplingprocessor.NewFactory()}
+}
+
+func (t *translator) ID() component.ID {
+	return component.NewIDWithName(t.factory.Type(), t.name)
+}
+
+// Translate creates a tail sampling processor config that keeps every trace with an
+// error or a span over the latency threshold and samples the remaining traces by rate.
+// The policies are evaluated independently, so a trace is sampled if any of them match.
+// Spans are held in memory per trace ID for the decision wait and the number of traces
+// held at once is bounded by num_traces.
+func (t *translator) Translate(conf *confmap.Conf) (component.Config, error) {
+	if conf == nil || !conf.IsSet(tailSamplingKey) {
+		return nil, &common.MissingKeyError{ID: t.ID(), JsonKey: tailSamplingKey}
+	}
+	cfg := t.factory.CreateDefaultConfig().(*tailsamplingprocessor.Config)
+	cfg.DecisionWait = defaultDecisionWait
+	if decisionWait, ok := common.GetDuration(conf, common.ConfigKey(tailSamplingKey, decisionWaitKey)); ok {
+		cfg.DecisionWait = decisionWait
+	}
+	cfg.NumTraces = uint64(common.GetOrDefaultNumber(conf, common.ConfigKey(tailSamplingKey, numTracesKey), defaultNumTraces))
+	if expected, ok := common.GetNumber(conf, common.ConfigKey(tailSamplingKey, expectedNewTracesPerSecKey)); ok {
+		cfg.ExpectedNewTracesPerSec = uint64(expected)
+	}
+
+	var errorsPolicy tailsamplingprocessor.PolicyCfg
+	errorsPolicy.Name = errorsPolicyName
+	errorsPolicy.Type = tailsamplingprocessor.StatusCode
+	errorsPolicy.StatusCodeCfg.StatusCodes = []string{statusCodeError}
+	cfg.PolicyCfgs = append(cfg.PolicyCfgs, errorsPolicy)
+
+	if threshold, ok := common.GetNumber(conf, common.ConfigKey(tailSamplingKey, latencyThresholdKey)); ok && threshold > 0 {
+		var latencyPolicy tailsamplingprocessor.PolicyCfg
+		latencyPolicy.Name = latencyPolicyName
+		latencyPolicy.Type = tailsamplingprocessor.Latency
+		latencyPolicy.LatencyCfg.ThresholdMs = int64(threshold)
+		cfg.PolicyCfgs = append(cfg.PolicyCfgs, latencyPolicy)
+	}
+
+	var probabilisticPolicy tailsamplingprocessor.PolicyCfg
+	probabilisticPolicy.Name = probabilisticPolicyName
+	probabilisticPolicy.Type = tailsamplingprocessor.Probabilistic
+	probabilisticPolicy.ProbabilisticCfg.SamplingPercentage = common.GetOrDefaultNumber(conf, common.ConfigKey(tailSamplingKey, samplingPercentageKey), defaultSamplingPercentage)
+	cfg.PolicyCfgs = append(cfg.PolicyCfgs, probabilisticPolicy)
+	return cfg, nil
+}
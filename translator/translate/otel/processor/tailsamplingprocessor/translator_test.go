@@ -0,0 +1,115 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package tailsamplingprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/tailsamplingprocessor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/confmap"
+
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/otel/common"
+)
+
+func TestTranslator(t *testing.T) {
+	type policy struct {
+		name  string
+		ptype tailsamplingprocessor.PolicyType
+	}
+	tt := NewTranslatorWithName("xray")
+	require.EqualValues(t, "tail_sampling/xray", tt.ID().String())
+	testCases := map[string]struct {
+		input                  map[string]interface{}
+		wantDecisionWait       time.Duration
+		wantNumTraces          uint64
+		wantExpectedNewTraces  uint64
+		wantPolicies           []policy
+		wantLatencyThresholdMs int64
+		wantSamplingPercentage float64
+		wantErr                error
+	}{
+		"WithoutTailSampling": {
+			input: map[string]interface{}{
+				"traces": map[string]interface{}{
+					"traces_collected": map[string]interface{}{
+						"xray": map[string]interface{}{},
+					},
+				},
+			},
+			wantErr: &common.MissingKeyError{ID: tt.ID(), JsonKey: tailSamplingKey},
+		},
+		"WithDefaults": {
+			input: map[string]interface{}{
+				"traces": map[string]interface{}{
+					"traces_collected": map[string]interface{}{
+						"tail_sampling": map[string]interface{}{},
+					},
+				},
+			},
+			wantDecisionWait: defaultDecisionWait,
+			wantNumTraces:    defaultNumTraces,
+			wantPolicies: []policy{
+				{name: errorsPolicyName, ptype: tailsamplingprocessor.StatusCode},
+				{name: probabilisticPolicyName, ptype: tailsamplingprocessor.Probabilistic},
+			},
+			wantSamplingPercentage: defaultSamplingPercentage,
+		},
+		"WithLatencyThreshold": {
+			input: map[string]interface{}{
+				"traces": map[string]interface{}{
+					"traces_collected": map[string]interface{}{
+						"tail_sampling": map[string]interface{}{
+							"decision_wait":               10,
+							"num_traces":                  1000,
+							"expected_new_traces_per_sec": 50,
+							"latency_threshold_ms":        2000,
+							"sampling_percentage":         2.5,
+						},
+					},
+				},
+			},
+			wantDecisionWait:      10 * time.Second,
+			wantNumTraces:         1000,
+			wantExpectedNewTraces: 50,
+			wantPolicies: []policy{
+				{name: errorsPolicyName, ptype: tailsamplingprocessor.StatusCode},
+				{name: latencyPolicyName, ptype: tailsamplingprocessor.Latency},
+				{name: probabilisticPolicyName, ptype: tailsamplingprocessor.Probabilistic},
+			},
+			wantLatencyThresholdMs: 2000,
+			wantSamplingPercentage: 2.5,
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conf := confmap.NewFromStringMap(testCase.input)
+			got, err := tt.Translate(conf)
+			require.Equal(t, testCase.wantErr, err)
+			if err != nil {
+				return
+			}
+			gotCfg, ok := got.(*tailsamplingprocessor.Config)
+			require.True(t, ok)
+			assert.Equal(t, testCase.wantDecisionWait, gotCfg.DecisionWait)
+			assert.Equal(t, testCase.wantNumTraces, gotCfg.NumTraces)
+			assert.Equal(t, testCase.wantExpectedNewTraces, gotCfg.ExpectedNewTracesPerSec)
+			require.Len(t, gotCfg.PolicyCfgs, len(testCase.wantPolicies))
+			for i, p := range testCase.wantPolicies {
+				assert.Equal(t, p.name, gotCfg.PolicyCfgs[i].Name)
+				assert.Equal(t, p.ptype, gotCfg.PolicyCfgs[i].Type)
+				switch p.ptype {
+				case tailsamplingprocessor.StatusCode:
+					assert.Equal(t, []string{statusCodeError}, gotCfg.PolicyCfgs[i].StatusCodeCfg.StatusCodes)
+				case tailsamplingprocessor.Latency:
+					assert.Equal(t, testCase.wantLatencyThresholdMs, gotCfg.PolicyCfgs[i].LatencyCfg.ThresholdMs)
+				case tailsamplingprocessor.Probabilistic:
+					assert.Equal(t, testCase.wantSamplingPercentage, gotCfg.PolicyCfgs[i].ProbabilisticCfg.SamplingPercentage)
+				}
+			}
+		})
+	}
+}
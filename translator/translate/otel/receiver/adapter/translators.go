@@ -14,6 +14,9 @@ import (
 	"github.com/aws/amazon-cloudwatch-agent/internal/util/collections"
 	translatorconfig "github.com/aws/amazon-cloudwatch-agent/translator/config"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/logs_collected/files"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/logs_collected/files/collect_list"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/logs_collected/journald"
+	"github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/logs_collected/syslog"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/logs/logs_collected/windows_events"
 	"github.com/aws/amazon-cloudwatch-agent/translator/translate/metrics/metrics_collect"
 	collectd "github.com/aws/amazon-cloudwatch-agent/translator/translate/metrics/metrics_collect/collectd"
@@ -33,7 +36,7 @@ const (
 var (
 	logKey           = common.ConfigKey(common.LogsKey, common.LogsCollectedKey)
 	metricKey        = common.ConfigKey(common.MetricsKey, common.MetricsCollectedKey)
-	skipInputSet     = collections.NewSet[string](common.JmxKey, files.SectionKey, windows_events.SectionKey)
+	skipInputSet     = collections.NewSet[string](common.JmxKey, files.SectionKey, journald.SectionKey, syslog.SectionKey, windows_events.SectionKey)
 	multipleInputSet = collections.NewSet[string](
 		procstat.SectionKey,
 	)
@@ -154,7 +157,7 @@ func fromInputs(conf *confmap.Conf, validInputs map[string]bool, baseKey string)
 	translators := common.NewTranslatorMap[component.Config]()
 	if inputs, ok := conf.Get(baseKey).(map[string]interface{}); ok {
 		for inputName := range inputs {
-			if skipInputSet.Contains(inputName) {
+			if skipInputSet.Contains(inputName) && !(inputName == files.SectionKey && hasLogMetricFilters(conf)) {
 				// logs agent is separate from otel agent
 				continue
 			}
@@ -182,6 +185,19 @@ func fromInputs(conf *confmap.Conf, validInputs map[string]bool, baseKey string)
 	return translators
 }
 
+// hasLogMetricFilters returns whether any of the collected files has metric filters, which are
+// gathered from the logfile plugin by an adapter receiver.
+func hasLogMetricFilters(conf *confmap.Conf) bool {
+	for _, entry := range common.GetArray[any](conf, common.ConfigKey(logKey, files.SectionKey, collect_list.SectionKey)) {
+		if entryMap, ok := entry.(map[string]any); ok {
+			if _, ok = entryMap[collect_list.MetricFiltersSectionKey]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // fromMultipleInput generates multiple receivers with unique ID depends on the number of inputs.
 // Since there plugins from Telegraf that allows multiple inputs such as procstat, window_perf_counter;
 // therefore, generate a hash of the monitored process (e.g exe: hash(amazon-cloudwatch-agent))
@@ -21,6 +21,7 @@ import (
 func TestFindReceiversInConfig(t *testing.T) {
 	telegrafSocketListenerType, _ := component.NewType("telegraf_socket_listener")
 	telegrafCPUType, _ := component.NewType("telegraf_cpu")
+	telegrafLogfileType, _ := component.NewType("telegraf_logfile")
 	telegrafEthtoolType, _ := component.NewType("telegraf_ethtool")
 	telegrafNvidiaSmiType, _ := component.NewType("telegraf_nvidia_smi")
 	telegrafStatsdType, _ := component.NewType("telegraf_statsd")
@@ -124,6 +125,28 @@ func TestFindReceiversInConfig(t *testing.T) {
 			os:   translatorconfig.OS_TYPE_WINDOWS,
 			want: map[component.ID]wantResult{},
 		},
+		"WithLogMetricFilters": {
+			input: map[string]interface{}{
+				"logs": map[string]interface{}{
+					"logs_collected": map[string]interface{}{
+						"files": map[string]interface{}{
+							"collect_list": []interface{}{
+								map[string]interface{}{"file_path": "path1"},
+								map[string]interface{}{
+									"file_path":      "path2",
+									"metric_filters": []interface{}{map[string]interface{}{"metric_name": "errors"}},
+								},
+							},
+						},
+						"windows_events": map[string]interface{}{},
+					},
+				},
+			},
+			os: translatorconfig.OS_TYPE_LINUX,
+			want: map[component.ID]wantResult{
+				component.NewID(telegrafLogfileType): {"logs::logs_collected::files", time.Minute},
+			},
+		},
 		"WithNoSocketListener": {
 			input: map[string]interface{}{
 				"logs": map[string]interface{}{